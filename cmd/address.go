@@ -1,22 +1,23 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	lnbot "github.com/lnbotdev/go-sdk"
 
+	"github.com/lnbotdev/cli/internal/bech32"
 	"github.com/lnbotdev/cli/internal/format"
 )
 
 var addressCmd = &cobra.Command{
 	Use:   "address <command>",
 	Short: "Manage Lightning addresses",
-	Long: `Buy, list, transfer, and delete Lightning addresses.
+	Long: `Buy, list, transfer, and delete Lightning addresses, or show one as a
+QR code.
 
 Every wallet gets a free auto-generated address (e.g. x8km2n@ln.bot).
 You can also buy vanity addresses like alice@ln.bot.`,
@@ -25,10 +26,14 @@ You can also buy vanity addresses like alice@ln.bot.`,
 func init() {
 	addressTransferCmd.Flags().String("target-key", "", "target wallet API key")
 
+	addressQRCmd.Flags().Bool("raw", false, "encode the address text instead of an LNURL")
+	addQRFlags(addressQRCmd)
+
 	addressCmd.AddCommand(addressListCmd)
 	addressCmd.AddCommand(addressBuyCmd)
 	addressCmd.AddCommand(addressTransferCmd)
 	addressCmd.AddCommand(addressDeleteCmd)
+	addressCmd.AddCommand(addressQRCmd)
 }
 
 var addressListCmd = &cobra.Command{
@@ -39,18 +44,18 @@ var addressListCmd = &cobra.Command{
 	Example: `  lnbot address list
   lnbot address list --json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		w, err := resolveWallet()
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		addrs, err := w.Addresses.List(context.Background())
+		addrs, err := w.Addresses.List(cmd.Context())
 		if err != nil {
 			return apiError("listing addresses", err)
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(addrs)
+			return printOutput(addrs)
 		}
 
 		if len(addrs) == 0 {
@@ -85,18 +90,18 @@ is included automatically (alice+anything@ln.bot).`,
 		name := args[0]
 
 		if !yesFlag {
-			if !confirm(fmt.Sprintf("Claim %s@ln.bot?", name)) {
+			if !confirm(cmd.Context(), fmt.Sprintf("Claim %s@ln.bot?", name)) {
 				fmt.Println("Cancelled.")
-				return nil
+				return errCancelled
 			}
 		}
 
-		w, err := resolveWallet()
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		addr, err := w.Addresses.Create(context.Background(), &lnbot.CreateAddressParams{
+		addr, err := w.Addresses.Create(cmd.Context(), &lnbot.CreateAddressParams{
 			Address: lnbot.Ptr(name),
 		})
 		if err != nil {
@@ -104,7 +109,7 @@ is included automatically (alice+anything@ln.bot).`,
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(addr)
+			return printOutput(addr)
 		}
 
 		printSuccess(fmt.Sprintf("Address claimed: %s", addr.Address))
@@ -128,22 +133,22 @@ Specify the target by API key (--target-key).`,
 		targetKey, _ := cmd.Flags().GetString("target-key")
 
 		if targetKey == "" {
-			return fmt.Errorf("specify --target-key <api-key>")
+			return usageErrorf("specify --target-key <api-key>")
 		}
 
-		w, err := resolveWallet()
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
 		if !yesFlag {
-			if !confirm(fmt.Sprintf("Transfer %s to another wallet?", address)) {
+			if !confirm(cmd.Context(), fmt.Sprintf("Transfer %s to another wallet?", address)) {
 				fmt.Println("Cancelled.")
-				return nil
+				return errCancelled
 			}
 		}
 
-		result, err := w.Addresses.Transfer(context.Background(), address, &lnbot.TransferAddressParams{
+		result, err := w.Addresses.Transfer(cmd.Context(), address, &lnbot.TransferAddressParams{
 			TargetWalletKey: targetKey,
 		})
 		if err != nil {
@@ -151,7 +156,7 @@ Specify the target by API key (--target-key).`,
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(result)
+			return printOutput(result)
 		}
 
 		printSuccess(fmt.Sprintf("Transferred %s to wallet %s", result.Address, result.TransferredTo))
@@ -170,18 +175,18 @@ var addressDeleteCmd = &cobra.Command{
 		address := args[0]
 
 		if !yesFlag {
-			if !confirm(fmt.Sprintf("Delete address %s?", address)) {
+			if !confirm(cmd.Context(), fmt.Sprintf("Delete address %s?", address)) {
 				fmt.Println("Cancelled.")
-				return nil
+				return errCancelled
 			}
 		}
 
-		w, err := resolveWallet()
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		if err := w.Addresses.Delete(context.Background(), address); err != nil {
+		if err := w.Addresses.Delete(cmd.Context(), address); err != nil {
 			return apiError("deleting address", err)
 		}
 
@@ -189,3 +194,75 @@ var addressDeleteCmd = &cobra.Command{
 		return nil
 	},
 }
+
+var addressQRCmd = &cobra.Command{
+	Use:   "qr <address>",
+	Short: "Show a QR code for a Lightning address",
+	Long: `Render a QR code that wallets can scan to pay a Lightning address.
+
+The QR encodes the address's LNURL-pay endpoint as a bech32 LNURL, which
+more wallets understand than the bare address. Use --raw to encode the
+address text instead. A name without a domain is treated as an ln.bot
+address.
+
+The code is drawn only when stdout is a terminal; otherwise the LNURL is
+printed on its own. Use --qr png|svg with --out to save an image.`,
+	Example: `  lnbot address qr alice@ln.bot
+  lnbot address qr alice --qr svg --out alice.svg
+  lnbot address qr alice@ln.bot --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		address := strings.ToLower(strings.TrimSpace(args[0]))
+		if !strings.Contains(address, "@") {
+			address += "@ln.bot"
+		}
+		user, domain, _ := strings.Cut(address, "@")
+		if user == "" || domain == "" || strings.Contains(domain, "@") {
+			return usageErrorf("invalid Lightning address: %s", args[0])
+		}
+
+		qrKind, qrPath, err := qrFileFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		url := fmt.Sprintf("https://%s/.well-known/lnurlp/%s", domain, user)
+		words, _ := bech32.ConvertBits([]byte(url), 8, 5, true)
+		lnurl, err := bech32.Encode("lnurl", words)
+		if err != nil {
+			return err
+		}
+
+		content := lightningURI(lnurl)
+		if raw, _ := cmd.Flags().GetBool("raw"); raw {
+			content = address
+		}
+
+		if qrPath != "" {
+			if err := writeQRFile(content, qrKind, qrPath); err != nil {
+				return err
+			}
+		}
+
+		if jsonFlag {
+			out := map[string]string{"address": address, "lnurl": strings.ToUpper(lnurl), "url": url}
+			if qrPath != "" {
+				out["file"] = qrPath
+			}
+			return printOutput(out)
+		}
+
+		if !isTerminal(os.Stdout) && qrPath == "" {
+			fmt.Println(strings.ToUpper(lnurl))
+			return nil
+		}
+
+		printTerminalQR(content)
+		fmt.Printf("  address: %s\n", address)
+		fmt.Printf("  lnurl:   %s\n", strings.ToUpper(lnurl))
+		if qrPath != "" {
+			fmt.Printf("  qr:      saved to %s\n", qrPath)
+		}
+		return nil
+	},
+}
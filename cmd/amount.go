@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/amount"
+	"github.com/lnbotdev/cli/internal/config"
+	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/output"
+	"github.com/lnbotdev/cli/internal/rates"
+)
+
+// amountUsage is appended to the help of amount flags.
+const amountUsage = "e.g. 1000, 21k, 1.5M, 0.001btc, 5usd"
+
+// rateSource is where fiat amounts are converted: LNBOT_RATES_FILE or
+// LNBOT_RATES_URL, then the profile's rates-file or rates-url. Fetched
+// quotes are cached next to the config file.
+func rateSource() *rates.Source {
+	s := &rates.Source{
+		File:      os.Getenv("LNBOT_RATES_FILE"),
+		URL:       os.Getenv("LNBOT_RATES_URL"),
+		CachePath: filepath.Join(filepath.Dir(config.Path()), "rates-cache.json"),
+	}
+	if s.File == "" && s.URL == "" && cfg != nil {
+		s.File, s.URL = cfg.RatesFile, cfg.RatesURL
+	}
+	return s
+}
+
+// amountFlag parses an amount flag such as --amount. Unset is zero.
+func amountFlag(cmd *cobra.Command, name string) (amount.Amount, error) {
+	s, _ := cmd.Flags().GetString(name)
+	a, err := amount.Parse(s, rateSource())
+	if err != nil {
+		return a, usageErrorf("--%s: %w", name, err)
+	}
+	return a, nil
+}
+
+// satsFlag is amountFlag for flags where only the sats matter.
+func satsFlag(cmd *cobra.Command, name string) (int64, error) {
+	a, err := amountFlag(cmd, name)
+	return a.Sats, err
+}
+
+// addDisplayFlags adds --unit and --fiat to a command that shows amounts.
+func addDisplayFlags(cmd *cobra.Command) {
+	cmd.Flags().String("unit", "", "show amounts in sats, btc, msat or bits (default: 'lnbot config' unit, or sats)")
+	cmd.Flags().String("fiat", "", "also show amounts in this currency, e.g. usd ('none' to turn off)")
+}
+
+// applyDisplay sets how amounts are shown from --unit and --fiat, falling
+// back to the profile's settings. A fiat rate that can't be found is
+// reported and the amounts are shown without it.
+func applyDisplay(cmd *cobra.Command) error {
+	unit, _ := cmd.Flags().GetString("unit")
+	fiat, _ := cmd.Flags().GetString("fiat")
+	if cfg != nil {
+		if unit == "" {
+			unit = cfg.Unit
+		}
+		if fiat == "" {
+			fiat = cfg.Fiat
+		}
+	}
+	u, err := format.ParseUnit(unit)
+	if err != nil {
+		return usageErrorf("--unit: %w", err)
+	}
+	d := format.Display{Unit: u}
+
+	if fiat != "" && !strings.EqualFold(fiat, "none") && !jsonFlag {
+		if !isCurrencyCode(fiat) {
+			return usageErrorf("--fiat: %q is not a currency code such as usd", fiat)
+		}
+		price, err := rateSource().Price(strings.ToUpper(fiat))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ not showing %s amounts: %s\n", strings.ToUpper(fiat), err)
+		} else {
+			d.Fiat, d.Price = fiat, price
+		}
+	}
+	format.SetDisplay(d)
+	return nil
+}
+
+// isCurrencyCode reports whether s looks like an ISO 4217 code.
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range strings.ToLower(s) {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// amountColumn is an amount column for listings, in the display unit.
+func amountColumn[T any](sats func(T) int64) output.Column[T] {
+	return output.Column[T]{
+		Name:  "amount",
+		Right: true,
+		Text:  func(row T) string { return format.AmountPlain(sats(row)) + " " + format.UnitLabel() },
+		Value: func(row T) any { return sats(row) },
+	}
+}
+
+// fiatColumn is the fiat value of an amount, shown when --fiat or the
+// profile's fiat setting gives one.
+func fiatColumn[T any](sats func(T) int64) output.Column[T] {
+	return output.Column[T]{
+		Name:     "fiat",
+		Optional: true,
+		Right:    true,
+		Text:     func(row T) string { return format.FiatValue(sats(row)) },
+	}
+}
@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 
@@ -51,13 +49,13 @@ recovery passphrase becomes invalid.`,
 			return err
 		}
 
-		backup, err := cfg.Client().Backup.Recovery(context.Background())
+		backup, err := cfg.Client().Backup.Recovery(cmd.Context())
 		if err != nil {
 			return apiError("generating recovery passphrase", err)
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(backup)
+			return printOutput(backup)
 		}
 
 		printWarning("Recovery passphrase (save this — shown only once):")
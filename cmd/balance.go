@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 
@@ -14,28 +11,40 @@ import (
 var balanceCmd = &cobra.Command{
 	Use:   "balance",
 	Short: "Show wallet balance",
-	Long:  `Display the current balance, available amount, and on-hold amount for the active wallet.`,
+	Long: `Display the current balance, available amount, and on-hold amount for the active wallet.
+
+Amounts are shown in sats unless --unit (or 'lnbot config set unit') says
+otherwise. --fiat adds the value in a currency, using the exchange rate
+source set up for fiat amounts (see 'lnbot config').`,
 	Example: `  lnbot balance
   lnbot balance --wallet wal_abc
+  lnbot balance --unit btc --fiat usd
   lnbot balance --json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		w, err := resolveWallet()
+		if err := applyDisplay(cmd); err != nil {
+			return err
+		}
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		wal, err := w.Get(context.Background())
+		wal, err := w.Get(cmd.Context())
 		if err != nil {
 			return apiError("fetching balance", err)
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(wal)
+			return printOutput(wal)
 		}
 
-		fmt.Printf("  balance:   %s\n", format.Sats(wal.Balance))
-		fmt.Printf("  available: %s\n", format.Sats(wal.Available))
-		fmt.Printf("  on hold:   %s\n", format.Sats(wal.OnHold))
+		fmt.Printf("  balance:   %s\n", format.Amount(wal.Balance))
+		fmt.Printf("  available: %s\n", format.Amount(wal.Available))
+		fmt.Printf("  on hold:   %s\n", format.Amount(wal.OnHold))
 		return nil
 	},
 }
+
+func init() {
+	addDisplayFlags(balanceCmd)
+}
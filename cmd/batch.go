@@ -0,0 +1,415 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/batch"
+	"github.com/lnbotdev/cli/internal/bolt11"
+	"github.com/lnbotdev/cli/internal/fees"
+	"github.com/lnbotdev/cli/internal/format"
+)
+
+var payBatchCmd = &cobra.Command{
+	Use:   "batch <file>",
+	Short: "Send payouts listed in a CSV or JSON file",
+	Long: `Pay every row of a payout file, after one confirmation.
+
+The file is CSV with a header row, or a JSON array of objects (*.json).
+Columns:
+
+  target   Lightning address, LNURL or BOLT11 invoice (required)
+  amount   sats (required unless the invoice has an amount)
+  max_fee  routing fee limit in sats (default: --max-fee, or the
+           profile's fee limit from 'lnbot config')
+  memo     sent as the payment's reference
+
+A preview with the totals is shown before anything is sent. Payments run
+a few at a time (--concurrency) and each row's status is saved to a state
+file next to the payout file (--state). Rerunning the same command skips
+rows that already settled and retries the rest; payments are sent with
+idempotency keys, so a row whose outcome was lost is never paid twice.
+
+If the wallet has a spending policy, the whole batch is checked up front
+and nothing is sent if any row would be denied (exit code 8).`,
+	Example: `  lnbot pay batch payouts.csv
+  lnbot pay batch payouts.csv --dry-run
+  lnbot pay batch payouts.json --yes --report results.csv
+  lnbot pay batch payouts.csv --concurrency 8 --max-fee 20 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		statePath, _ := cmd.Flags().GetString("state")
+		reportPath, _ := cmd.Flags().GetString("report")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		var defaultFee fees.Limit
+		sats, err := satsFlag(cmd, "max-fee")
+		if err != nil {
+			return err
+		}
+		if sats > 0 {
+			defaultFee.Sats = sats
+		} else if cfg != nil && cfg.FeeLimit != nil {
+			defaultFee = *cfg.FeeLimit
+		}
+
+		if concurrency < 1 {
+			return usageErrorf("--concurrency must be at least 1")
+		}
+		reportFormat := ""
+		if reportPath != "" {
+			reportFormat = "csv"
+			if strings.EqualFold(filepath.Ext(reportPath), ".json") {
+				reportFormat = "json"
+			}
+		}
+
+		rows, err := batch.Load(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		id, err := resolveWalletID(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := cfg.Client().Wallet(id)
+
+		if statePath == "" {
+			statePath = batch.StatePath(path)
+		}
+		state, err := batch.LoadState(statePath, id)
+		if err != nil {
+			return err
+		}
+
+		var (
+			todo    []batch.Result
+			params  []*lnbot.CreatePaymentParams
+			settled int
+		)
+		for _, r := range state.Results(rows) {
+			if r.Status == batch.Settled {
+				settled++
+				continue
+			}
+			if bolt11.IsInvoice(r.Target) {
+				if _, err := checkInvoice(r.Target); err != nil {
+					return fmt.Errorf("line %d: %w", r.Line, err)
+				}
+			}
+			todo = append(todo, r)
+			params = append(params, batchPaymentParams(state, r, defaultFee))
+		}
+
+		ctx := cmd.Context()
+
+		if i, err := enforceBatchPolicy(ctx, w, params); err != nil {
+			if i >= 0 {
+				return fmt.Errorf("line %d: %w", todo[i].Line, err)
+			}
+			return err
+		}
+
+		var total, fees int64
+		for i, r := range todo {
+			total += r.Amount
+			if r.Amount == 0 {
+				if inv, err := bolt11.Decode(r.Target); err == nil {
+					amount, _ := inv.AmountSats()
+					total += amount
+				}
+			}
+			if params[i].MaxFee != nil {
+				fees += *params[i].MaxFee
+			}
+		}
+
+		if dryRun && jsonFlag {
+			return printOutput(map[string]any{
+				"rows":     todo,
+				"settled":  settled,
+				"total":    total,
+				"max_fees": fees,
+			})
+		}
+		if len(todo) == 0 {
+			if !jsonFlag {
+				printSuccess(fmt.Sprintf("All %d payouts in %s have already settled", len(rows), path))
+			}
+			return writeBatchResult(state, rows, reportPath, reportFormat)
+		}
+
+		if !jsonFlag {
+			printBatchPreview(todo, params)
+			fmt.Println()
+			if settled > 0 {
+				fmt.Printf("  already settled: %d (skipped)\n", settled)
+			}
+			fmt.Printf("  payouts:  %d\n", len(todo))
+			fmt.Printf("  total:    %s\n", format.Sats(total))
+			if fees > 0 {
+				fmt.Printf("  max fees: %s\n", format.Sats(fees))
+			}
+			if wal, err := w.Get(ctx); err == nil {
+				fmt.Printf("  balance:  %s\n", format.Sats(wal.Available))
+				if wal.Available < total+fees {
+					printWarning("The balance may not cover every payout")
+				}
+			}
+			fmt.Println()
+		}
+		if dryRun {
+			return nil
+		}
+		if !yesFlag && !confirm(cmd.Context(), fmt.Sprintf("Send %d payments totalling %s?", len(todo), format.Sats(total))) {
+			fmt.Println("Cancelled.")
+			return errCancelled
+		}
+
+		var (
+			wg  sync.WaitGroup
+			out sync.Mutex
+			sem = make(chan struct{}, concurrency)
+		)
+	send:
+		for i := range todo {
+			select {
+			case <-ctx.Done():
+				break send
+			case sem <- struct{}{}:
+			}
+			wg.Add(1)
+			go func(r batch.Result, p *lnbot.CreatePaymentParams) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				res := sendBatchRow(ctx, w, state, r, p)
+				if !jsonFlag {
+					out.Lock()
+					printBatchRow(res)
+					out.Unlock()
+				}
+			}(todo[i], params[i])
+		}
+		wg.Wait()
+
+		if err := writeBatchResult(state, rows, reportPath, reportFormat); err != nil {
+			return err
+		}
+
+		var unsettled int
+		for _, r := range state.Results(rows) {
+			if r.Status != batch.Settled {
+				unsettled++
+			}
+		}
+		if unsettled > 0 {
+			// Under --json the summary above already reports the failures.
+			return &exitError{
+				code:     exitPaymentFailed,
+				err:      fmt.Errorf("%d of %d payouts did not settle — rerun 'lnbot pay batch %s' to retry them", unsettled, len(rows), path),
+				reported: jsonFlag,
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	payBatchCmd.Flags().Int("concurrency", 4, "payments in flight at once")
+	payBatchCmd.Flags().String("state", "", "state file for resuming (default: <file>.state.json)")
+	payBatchCmd.Flags().String("report", "", "write per-row results to this file (.csv or .json)")
+	payBatchCmd.Flags().Bool("dry-run", false, "show the preview without sending anything")
+	payBatchCmd.Flags().String("max-fee", "", "routing fee limit in sats for rows without max_fee")
+
+	payCmd.AddCommand(payBatchCmd)
+}
+
+// batchPaymentParams is the payment request for a row. Rows without a
+// max_fee get defaultFee, worked out from the row's amount.
+func batchPaymentParams(state *batch.State, r batch.Result, defaultFee fees.Limit) *lnbot.CreatePaymentParams {
+	p := &lnbot.CreatePaymentParams{
+		Target:         r.Target,
+		IdempotencyKey: lnbot.Ptr(state.IdempotencyKey(r.Row, r.NextAttempt())),
+	}
+	if r.Amount > 0 {
+		p.Amount = lnbot.Ptr(r.Amount)
+	}
+	if r.MaxFee > 0 {
+		p.MaxFee = lnbot.Ptr(r.MaxFee)
+	} else {
+		var inv *bolt11.Invoice
+		if r.Amount == 0 {
+			inv, _ = bolt11.Decode(r.Target)
+		}
+		if fee, ok := defaultFee.For(paymentAmount(inv, r.Amount)); ok {
+			p.MaxFee = lnbot.Ptr(fee)
+		}
+	}
+	if r.Memo != "" {
+		p.Reference = lnbot.Ptr(r.Memo)
+	}
+	return p
+}
+
+// sendBatchRow pays one row and waits for the outcome, recording each step
+// in the state file. A row whose outcome is unknown (the request failed in
+// transit, or the batch was interrupted) stays "sending"; its idempotency
+// key makes the next run pick up the same payment.
+func sendBatchRow(ctx context.Context, w *lnbot.WalletHandle, state *batch.State, r batch.Result, params *lnbot.CreatePaymentParams) batch.Result {
+	update := func(change func(*batch.Result)) {
+		if err := state.Update(r.Key, func(res *batch.Result) {
+			change(res)
+			r = *res
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ saving batch state: %s\n", err)
+		}
+	}
+
+	attempt := r.NextAttempt()
+	update(func(res *batch.Result) {
+		res.Status = batch.Sending
+		res.Attempts = attempt
+		res.Error = ""
+	})
+
+	payment, err := w.Payments.Create(ctx, params)
+	if err != nil {
+		var apiErr *lnbot.APIError
+		update(func(res *batch.Result) {
+			if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+				res.Status = batch.Failed
+				res.Error = apiErr.Message
+			} else {
+				res.Error = err.Error()
+			}
+		})
+		return r
+	}
+	update(func(res *batch.Result) { res.PaymentNumber = payment.Number })
+
+	if payment.Status != "settled" && payment.Status != "failed" {
+		payment, _ = waitForPayment(ctx, w, payment)
+	}
+	update(func(res *batch.Result) {
+		switch payment.Status {
+		case "settled":
+			res.Status = batch.Settled
+			if payment.ActualFee != nil {
+				res.Fee = *payment.ActualFee
+			}
+		case "failed":
+			res.Status = batch.Failed
+			res.Error = "payment failed"
+			if payment.FailureReason != nil {
+				res.Error = *payment.FailureReason
+			}
+		}
+	})
+	return r
+}
+
+func printBatchPreview(todo []batch.Result, params []*lnbot.CreatePaymentParams) {
+	for i, r := range todo {
+		amount := "from invoice"
+		if r.Amount > 0 {
+			amount = format.Sats(r.Amount)
+		}
+		line := fmt.Sprintf("  %4d  %-40s  %14s", r.Line, format.Truncate(r.Target, 40), amount)
+		if params[i].MaxFee != nil {
+			line += fmt.Sprintf("  fee ≤ %-8s", format.Sats(*params[i].MaxFee))
+		}
+		if r.Memo != "" {
+			line += "  " + format.Truncate(r.Memo, 30)
+		}
+		if r.Status != batch.Pending {
+			line += "  (retry)"
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+func printBatchRow(r batch.Result) {
+	target := format.Truncate(r.Target, 40)
+	switch r.Status {
+	case batch.Settled:
+		line := fmt.Sprintf("  ✓ line %d  %s  %s", r.Line, target, format.Sats(r.Amount))
+		if r.Fee > 0 {
+			line += fmt.Sprintf("  fee %s", format.Sats(r.Fee))
+		}
+		fmt.Println(line)
+	case batch.Failed:
+		fmt.Printf("  ✗ line %d  %s  %s\n", r.Line, target, r.Error)
+	default:
+		msg := "outcome unknown"
+		if r.Error != "" {
+			msg += ": " + r.Error
+		}
+		fmt.Printf("  ? line %d  %s  %s\n", r.Line, target, msg)
+	}
+}
+
+// writeBatchResult writes the report file, if asked for, and the JSON
+// summary under --json.
+func writeBatchResult(state *batch.State, rows []batch.Row, reportPath, reportFormat string) error {
+	results := state.Results(rows)
+	if reportPath != "" {
+		f, err := os.Create(reportPath)
+		if err != nil {
+			return err
+		}
+		if err := batch.WriteReport(f, reportFormat, results); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	if jsonFlag {
+		return printOutput(map[string]any{
+			"settled": counts[batch.Settled],
+			"failed":  counts[batch.Failed],
+			"unknown": counts[batch.Sending],
+			"pending": counts[batch.Pending],
+			"state":   state.Path(),
+			"rows":    results,
+		})
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("%d settled", counts[batch.Settled])
+	if n := counts[batch.Failed]; n > 0 {
+		summary += fmt.Sprintf(", %d failed", n)
+	}
+	if n := counts[batch.Sending]; n > 0 {
+		summary += fmt.Sprintf(", %d unknown", n)
+	}
+	if n := counts[batch.Pending]; n > 0 {
+		summary += fmt.Sprintf(", %d not sent", n)
+	}
+	if counts[batch.Settled] == len(results) {
+		printSuccess(summary)
+	} else {
+		printWarning(summary)
+	}
+	fmt.Printf("  state:  %s\n", state.Path())
+	if reportPath != "" {
+		fmt.Printf("  report: %s\n", reportPath)
+	}
+	return nil
+}
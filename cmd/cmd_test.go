@@ -1,17 +1,37 @@
 package cmd
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	lnbot "github.com/lnbotdev/go-sdk"
 
+	"github.com/lnbotdev/cli/internal/batch"
+	"github.com/lnbotdev/cli/internal/bech32"
+	"github.com/lnbotdev/cli/internal/bolt11"
 	"github.com/lnbotdev/cli/internal/config"
+	"github.com/lnbotdev/cli/internal/devserver"
+	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/journal"
+	"github.com/lnbotdev/cli/internal/output"
+	"github.com/lnbotdev/cli/internal/store"
+	"github.com/lnbotdev/cli/pkg/webhook"
 )
 
 // ---------------------------------------------------------------------------
@@ -23,6 +43,29 @@ func resetState() {
 	walletFlag = ""
 	jsonFlag = false
 	yesFlag = false
+	outputFormat = output.Table
+	config.SetAPIURL("")
+	resetFlags(rootCmd)
+}
+
+// resetFlags restores every flag in the command tree to its default so that
+// values set by one executeCmd call don't leak into the next. It also drops
+// the context cobra keeps from the last run, which Execute has cancelled.
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	c.SetContext(nil)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
 }
 
 func executeCmd(args ...string) (stdout, stderr string, err error) {
@@ -36,18 +79,36 @@ func executeCmd(args ...string) (stdout, stderr string, err error) {
 	rErr, wErr, _ := os.Pipe()
 	os.Stderr = wErr
 
+	// Drain the pipes while the command runs so that large output (e.g.
+	// completion scripts) can't fill the pipe buffer and block it.
+	outc, errc := make(chan []byte), make(chan []byte)
+	go func() { b, _ := io.ReadAll(rOut); outc <- b }()
+	go func() { b, _ := io.ReadAll(rErr); errc <- b }()
+
 	rootCmd.SetArgs(args)
-	err = rootCmd.Execute()
+	err = Execute()
 
 	wOut.Close()
 	wErr.Close()
 	os.Stdout = oldStdout
 	os.Stderr = oldStderr
 
-	outBytes, _ := io.ReadAll(rOut)
-	errBytes, _ := io.ReadAll(rErr)
+	return string(<-outc), string(<-errc), err
+}
+
+// executeCmdWithStdin runs the command with input piped to os.Stdin.
+func executeCmdWithStdin(input string, args ...string) (stdout, stderr string, err error) {
+	oldStdin := os.Stdin
+	rIn, wIn, _ := os.Pipe()
+	os.Stdin = rIn
+	defer func() { os.Stdin = oldStdin }()
+
+	go func() {
+		io.WriteString(wIn, input)
+		wIn.Close()
+	}()
 
-	return string(outBytes), string(errBytes), err
+	return executeCmd(args...)
 }
 
 func setupConfig(t *testing.T, c *config.Config) string {
@@ -56,6 +117,7 @@ func setupConfig(t *testing.T, c *config.Config) string {
 	p := filepath.Join(dir, "config.json")
 	t.Setenv("LNBOT_CONFIG", p)
 	t.Setenv("LNBOT_NO_UPDATE_CHECK", "1")
+	t.Setenv("LNBOT_API_URL", "")
 	if c != nil {
 		data, _ := json.MarshalIndent(c, "", "  ")
 		os.WriteFile(p, data, 0o600)
@@ -78,6 +140,37 @@ func testConfig() *config.Config {
 	}
 }
 
+// devAccount points the CLI at a dev server with a new account whose active
+// wallet holds balance sats.
+func devAccount(t *testing.T, balance int64) (*devserver.Server, *lnbot.WalletHandle) {
+	t.Helper()
+	dev := devserver.New(devserver.Options{StartingBalance: balance})
+	srv := httptest.NewServer(dev)
+	t.Cleanup(func() {
+		dev.Close()
+		srv.Close()
+	})
+
+	ctx := context.Background()
+	account, err := config.NewClient("", srv.URL).Register(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := config.NewClient(account.PrimaryKey, srv.URL)
+	wallet, err := client.Wallets.Create(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setupConfig(t, &config.Config{
+		PrimaryKey:     account.PrimaryKey,
+		SecondaryKey:   account.SecondaryKey,
+		ActiveWalletID: wallet.WalletID,
+	})
+	t.Setenv("LNBOT_API_URL", srv.URL)
+	return dev, client.Wallet(wallet.WalletID)
+}
+
 // ---------------------------------------------------------------------------
 // Helper function tests
 // ---------------------------------------------------------------------------
@@ -241,15 +334,18 @@ func TestWhoami_JSON(t *testing.T) {
 // MCP
 // ---------------------------------------------------------------------------
 
-func TestMcpConfig_NoRemote(t *testing.T) {
-	setupNoConfig(t)
+func TestMcpConfig_Local(t *testing.T) {
+	setupConfig(t, testConfig())
 
 	stdout, _, err := executeCmd("mcp", "config")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !strings.Contains(stdout, "not available yet") {
-		t.Errorf("output should contain 'not available yet', got %q", stdout)
+	if strings.Contains(stdout, "uk_primary_abcdefghijklmnop") {
+		t.Errorf("local config should not contain the API key, got %q", stdout)
+	}
+	if !strings.Contains(stdout, `"serve"`) || !strings.Contains(stdout, "wal_main123") {
+		t.Errorf("output should launch 'mcp serve' for the wallet, got %q", stdout)
 	}
 }
 
@@ -268,15 +364,106 @@ func TestMcpConfig_Remote(t *testing.T) {
 	}
 }
 
-func TestMcpServe(t *testing.T) {
+func mcpResponses(t *testing.T, stdout string) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		var msg map[string]any
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("invalid JSON-RPC frame %q: %v", line, err)
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+func TestMcpServe_Handshake(t *testing.T) {
+	setupNoConfig(t)
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+{"jsonrpc":"2.0","id":2,"method":"tools/list"}
+`
+	stdout, _, err := executeCmdWithStdin(input, "mcp", "serve")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resps := mcpResponses(t, stdout)
+	if len(resps) != 2 {
+		t.Fatalf("got %d responses, want 2 (notifications get none): %q", len(resps), stdout)
+	}
+
+	init := resps[0]["result"].(map[string]any)
+	if init["protocolVersion"] != "2024-11-05" {
+		t.Errorf("protocolVersion = %v", init["protocolVersion"])
+	}
+
+	tools := resps[1]["result"].(map[string]any)["tools"].([]any)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	for _, want := range []string{"get_balance", "create_invoice", "pay", "list_transactions", "list_addresses"} {
+		if !strings.Contains(strings.Join(names, ","), want) {
+			t.Errorf("tools/list missing %q, got %v", want, names)
+		}
+	}
+}
+
+func TestMcpServe_ReadOnly(t *testing.T) {
+	setupNoConfig(t)
+
+	stdout, _, err := executeCmdWithStdin(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`+"\n", "mcp", "serve", "--read-only")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(stdout, `"pay"`) || strings.Contains(stdout, "create_invoice") {
+		t.Errorf("read-only server should not expose tools that write, got %q", stdout)
+	}
+}
+
+func TestMcpServe_ToolErrorNoConfig(t *testing.T) {
 	setupNoConfig(t)
 
-	stdout, _, err := executeCmd("mcp", "serve")
+	input := `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"get_balance","arguments":{}}}` + "\n"
+	stdout, _, err := executeCmdWithStdin(input, "mcp", "serve")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !strings.Contains(stdout, "not available yet") {
-		t.Errorf("output should contain 'not available yet', got %q", stdout)
+	resps := mcpResponses(t, stdout)
+	result := resps[0]["result"].(map[string]any)
+	if result["isError"] != true {
+		t.Errorf("isError = %v, want true", result["isError"])
+	}
+	if !strings.Contains(stdout, "no config found") {
+		t.Errorf("tool error should explain missing config, got %q", stdout)
+	}
+}
+
+func TestMcpServe_PayLikeCLI(t *testing.T) {
+	_, w := devAccount(t, 10000)
+	if _, _, err := executeCmd("config", "set", "max-fee", "7"); err != nil {
+		t.Fatal(err)
+	}
+	invoice := freshInvoice(t, 2100, "tea")
+
+	// Paying the same invoice twice goes through the journal like 'lnbot pay'.
+	call := `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"pay","arguments":{"target":%q}}}` + "\n"
+	stdout, _, err := executeCmdWithStdin(fmt.Sprintf(call, 1, invoice)+fmt.Sprintf(call, 2, invoice), "mcp", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, resp := range mcpResponses(t, stdout) {
+		if result := resp["result"].(map[string]any); result["isError"] == true {
+			t.Errorf("pay failed: %v", result)
+		}
+	}
+	if strings.Count(stdout, "payment #1 settled") != 2 {
+		t.Errorf("stdout = %q", stdout)
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 1 || payments[0].MaxFee != 7 {
+		t.Errorf("payments = %+v, want one with the profile's max fee", payments)
 	}
 }
 
@@ -305,7 +492,7 @@ func TestCompletion_Bash(t *testing.T) {
 
 func TestResolveWalletID_NoConfig(t *testing.T) {
 	cfg = nil
-	_, err := resolveWalletID()
+	_, err := resolveWalletID(context.Background())
 	if err == nil {
 		t.Fatal("expected error for nil config")
 	}
@@ -314,7 +501,7 @@ func TestResolveWalletID_NoConfig(t *testing.T) {
 func TestResolveWalletID_NoActiveWallet(t *testing.T) {
 	cfg = &config.Config{PrimaryKey: "uk_test"}
 	walletFlag = ""
-	_, err := resolveWalletID()
+	_, err := resolveWalletID(context.Background())
 	if err == nil {
 		t.Fatal("expected error for no active wallet")
 	}
@@ -327,7 +514,7 @@ func TestResolveWalletID_NoActiveWallet(t *testing.T) {
 func TestResolveWalletID_WithActiveWallet(t *testing.T) {
 	cfg = &config.Config{PrimaryKey: "uk_test", ActiveWalletID: "wal_abc"}
 	walletFlag = ""
-	id, err := resolveWalletID()
+	id, err := resolveWalletID(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -340,7 +527,7 @@ func TestResolveWalletID_WithActiveWallet(t *testing.T) {
 func TestResolveWalletID_WithFlag(t *testing.T) {
 	cfg = &config.Config{PrimaryKey: "uk_test", ActiveWalletID: "wal_abc"}
 	walletFlag = "wal_override"
-	id, err := resolveWalletID()
+	id, err := resolveWalletID(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -401,6 +588,9 @@ func TestPay_UnrecognizedTarget(t *testing.T) {
 	if !strings.Contains(err.Error(), "unrecognized target") {
 		t.Errorf("unexpected error: %v", err)
 	}
+	if code := ExitCode(err); code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
+	}
 }
 
 func TestPay_AddressNoAmount(t *testing.T) {
@@ -510,3 +700,2253 @@ func TestKeyRotate_NonNumericSlot(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Spending policy
+// ---------------------------------------------------------------------------
+
+func writePolicy(t *testing.T, data string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "policy.json")
+	os.WriteFile(p, []byte(data), 0o600)
+	return p
+}
+
+func setupPolicy(t *testing.T, data string) {
+	t.Helper()
+	c := testConfig()
+	c.SetPolicyPath(c.ActiveWalletID, writePolicy(t, data))
+	setupConfig(t, c)
+}
+
+func TestPolicySetAndShow(t *testing.T) {
+	p := setupConfig(t, testConfig())
+	policyFile := writePolicy(t, `{"max_per_payment":5000,"deny":["evil.com"]}`)
+
+	stdout, _, err := executeCmd("policy", "set", policyFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout, "Spending policy attached") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	data, _ := os.ReadFile(p)
+	if !strings.Contains(string(data), policyFile) {
+		t.Errorf("config should reference the policy file, got %s", data)
+	}
+
+	stdout, _, err = executeCmd("policy", "show")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout, "5,000 sats") || !strings.Contains(stdout, "evil.com") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+
+	if _, _, err := executeCmd("policy", "unset", "--yes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stdout, _, _ = executeCmd("policy", "show")
+	if !strings.Contains(stdout, "No spending policy set") {
+		t.Errorf("policy should be removed, got %q", stdout)
+	}
+}
+
+func TestPolicySet_Invalid(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	_, _, err := executeCmd("policy", "set", writePolicy(t, `{"daily_budget":-5}`))
+	if err == nil {
+		t.Fatal("expected error for invalid policy")
+	}
+	if !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPay_PolicyMaxPerPayment(t *testing.T) {
+	setupPolicy(t, `{"max_per_payment":1000}`)
+
+	_, _, err := executeCmd("pay", "alice@ln.bot", "--amount", "1001", "--yes")
+	if err == nil {
+		t.Fatal("expected policy violation")
+	}
+	if ExitCode(err) != exitPolicyDenied {
+		t.Errorf("exit code = %d, want %d", ExitCode(err), exitPolicyDenied)
+	}
+	if !strings.Contains(err.Error(), "per-payment limit") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPay_PolicyDeny_JSON(t *testing.T) {
+	setupPolicy(t, `{"deny":["evil.com"]}`)
+
+	stdout, _, err := executeCmd("pay", "bob@pay.evil.com", "--amount", "10", "--yes", "--json")
+	if err == nil {
+		t.Fatal("expected policy violation")
+	}
+	var result struct {
+		Error struct {
+			Code string `json:"code"`
+			Rule string `json:"rule"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", stdout, err)
+	}
+	if result.Error.Code != "policy_denied" || result.Error.Rule != "deny" {
+		t.Errorf("error = %+v, want policy_denied/deny", result.Error)
+	}
+}
+
+func TestPay_PolicyMaxFee(t *testing.T) {
+	setupPolicy(t, `{"max_fee":50}`)
+
+	_, _, err := executeCmd("pay", "alice@ln.bot", "--amount", "100", "--max-fee", "51", "--yes")
+	if ExitCode(err) != exitPolicyDenied {
+		t.Errorf("exit code = %d (%v), want %d", ExitCode(err), err, exitPolicyDenied)
+	}
+}
+
+func TestPay_PolicyFileMissing(t *testing.T) {
+	c := testConfig()
+	c.SetPolicyPath(c.ActiveWalletID, filepath.Join(t.TempDir(), "gone.json"))
+	setupConfig(t, c)
+
+	_, _, err := executeCmd("pay", "alice@ln.bot", "--amount", "100", "--yes")
+	if err == nil || !strings.Contains(err.Error(), "loading spending policy") {
+		t.Errorf("missing policy file should block payments, got %v", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if got := ExitCode(nil); got != 0 {
+		t.Errorf("ExitCode(nil) = %d, want 0", got)
+	}
+	if got := ExitCode(errors.New("boom")); got != 1 {
+		t.Errorf("ExitCode(plain) = %d, want 1", got)
+	}
+	wrapped := fmt.Errorf("outer: %w", &exitError{code: 8, err: errors.New("inner")})
+	if got := ExitCode(wrapped); got != 8 {
+		t.Errorf("ExitCode(wrapped) = %d, want 8", got)
+	}
+
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{apiError("getting balance", &lnbot.UnauthorizedError{APIError: &lnbot.APIError{StatusCode: 401, Message: "invalid key"}}), exitAuth},
+		{&lnbot.APIError{StatusCode: 403}, exitAuth},
+		{&lnbot.NotFoundError{APIError: &lnbot.APIError{StatusCode: 404}}, exitNotFound},
+		{&lnbot.APIError{StatusCode: 400, Message: "Insufficient balance: 5 sats available"}, exitInsufficientFunds},
+		{&lnbot.APIError{StatusCode: 503}, exitNetwork},
+		{&lnbot.APIError{StatusCode: 500}, exitGeneral},
+		{fmt.Errorf("sending payment: %w", context.DeadlineExceeded), exitNetwork},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, exitNetwork},
+		{usageErrors(errors.New(`unknown command "foo" for "lnbot"`)), exitUsage},
+		{usageErrors(errors.New("accepts 1 arg(s), received 0")), exitUsage},
+	} {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	devAccount(t, 100)
+
+	tests := []struct {
+		args   []string
+		exit   int
+		code   string
+		status int
+		action string
+	}{
+		{[]string{"pay", "carol@example.com", "--amount", "5000", "--yes", "--json"}, exitInsufficientFunds, "insufficient_balance", 400, "sending payment"},
+		{[]string{"balance", "--json", "--bogus"}, exitUsage, "usage", 0, ""},
+		{[]string{"invoice", "create", "-o", "yaml"}, exitUsage, "usage", 0, ""},
+		{[]string{"wallet", "use", "a", "b", "--query", "name"}, exitUsage, "usage", 0, ""},
+		{[]string{"balance", "--wallet", "nope", "--json"}, exitNotFound, "not_found", 0, ""},
+	}
+	for _, tt := range tests {
+		stdout, stderr, err := executeCmd(tt.args...)
+		if ExitCode(err) != tt.exit {
+			t.Errorf("%v: exit code = %d (%v), want %d", tt.args, ExitCode(err), err, tt.exit)
+		}
+		var result struct {
+			Error struct {
+				Code    string  `json:"code"`
+				Message string  `json:"message"`
+				Status  *int    `json:"status"`
+				Action  *string `json:"action"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+			t.Errorf("%v: invalid JSON %q: %v", tt.args, stdout, err)
+			continue
+		}
+		e := result.Error
+		if e.Code != tt.code || e.Message == "" || stderr != "" {
+			t.Errorf("%v: error = %+v, stderr %q", tt.args, e, stderr)
+		}
+		if (e.Status == nil) != (tt.status == 0) || (e.Status != nil && *e.Status != tt.status) {
+			t.Errorf("%v: status = %v, want %d", tt.args, e.Status, tt.status)
+		}
+		if (e.Action == nil) != (tt.action == "") || (e.Action != nil && *e.Action != tt.action) {
+			t.Errorf("%v: action = %v, want %q", tt.args, e.Action, tt.action)
+		}
+	}
+
+	stdout, stderr, err := executeCmd("pay", "carol@example.com", "--amount", "5000", "--yes")
+	if ExitCode(err) != exitInsufficientFunds || stdout != "" || !strings.HasPrefix(stderr, "Error: sending payment: insufficient balance") {
+		t.Errorf("human error: exit %d, stdout %q, stderr %q", ExitCode(err), stdout, stderr)
+	}
+}
+
+func TestErrors_NoConfig(t *testing.T) {
+	setupNoConfig(t)
+
+	stdout, _, err := executeCmd("balance", "--json")
+	if ExitCode(err) != exitAuth || !strings.Contains(stdout, `"code":"auth"`) {
+		t.Errorf("exit %d, stdout %q", ExitCode(err), stdout)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// BOLT11 decoding
+// ---------------------------------------------------------------------------
+
+// expiredInvoice is the "1 cup coffee" example from the BOLT11 spec (2017).
+const expiredInvoice = "lnbc2500u1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpu9qrsgquk0rl77nj30yxdy8j9vdx85fkpmdla2087ne0xh8nhedh8w27kyke0lp53ut353s06fv3qfegext0eh0ymjpf39tuven09sam30g4vgpfna3rh"
+
+// freshInvoice encodes an unsigned invoice created now. sats == 0 means
+// "any amount".
+func freshInvoice(t *testing.T, sats int64, memo string) string {
+	t.Helper()
+	inv := &bolt11.Invoice{
+		Prefix:      "bc",
+		Timestamp:   time.Now(),
+		Expiry:      time.Hour,
+		PaymentHash: strings.Repeat("ab", 32),
+		Description: memo,
+	}
+	if sats > 0 {
+		inv.AmountMsat = lnbot.Ptr(sats * 1000)
+	}
+	s, err := bolt11.Encode(inv)
+	if err != nil {
+		t.Fatalf("encoding invoice: %v", err)
+	}
+	return s
+}
+
+func TestDecode(t *testing.T) {
+	setupNoConfig(t)
+
+	stdout, _, err := executeCmd("decode", expiredInvoice)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"mainnet", "250,000 sats", "1 cup coffee", "0001020304050607", "expired"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestDecode_JSON(t *testing.T) {
+	setupNoConfig(t)
+
+	stdout, _, err := executeCmd("decode", "lightning:"+expiredInvoice, "--json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var result map[string]any
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", stdout, err)
+	}
+	if result["network"] != "mainnet" || result["amountSats"] != float64(250000) ||
+		result["expirySeconds"] != float64(60) || result["expired"] != true {
+		t.Errorf("unexpected result: %v", result)
+	}
+	if result["description"] != "1 cup coffee" {
+		t.Errorf("description = %v", result["description"])
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	setupNoConfig(t)
+
+	_, _, err := executeCmd("decode", "lnbc1notaninvoice")
+	if err == nil || !strings.Contains(err.Error(), "invalid invoice") {
+		t.Errorf("expected invalid invoice error, got %v", err)
+	}
+}
+
+func TestPay_ExpiredInvoice(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	_, _, err := executeCmd("pay", expiredInvoice, "--yes")
+	if err == nil || !strings.Contains(err.Error(), "invoice expired") {
+		t.Fatalf("expected expired invoice error, got %v", err)
+	}
+	if ExitCode(err) != exitInvoiceExpired {
+		t.Errorf("exit code = %d, want %d", ExitCode(err), exitInvoiceExpired)
+	}
+}
+
+func TestPay_InvoiceNoAmount(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	_, _, err := executeCmd("pay", freshInvoice(t, 0, ""), "--yes")
+	if err == nil || !strings.Contains(err.Error(), "--amount is required") {
+		t.Errorf("expected --amount error, got %v", err)
+	}
+}
+
+func TestPay_InvoicePreview(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	stdout, _, err := executeCmdWithStdin("n\n", "pay", freshInvoice(t, 2100, "tea"))
+	if ExitCode(err) != exitCancelled {
+		t.Fatalf("exit code = %d (%v), want %d", ExitCode(err), err, exitCancelled)
+	}
+	for _, want := range []string{"2,100 sats", "memo:    tea", "valid, expires in 59m", "Pay this invoice?", "Cancelled."} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestPay_PolicyUsesInvoiceAmount(t *testing.T) {
+	setupPolicy(t, `{"max_per_payment":1000}`)
+
+	_, _, err := executeCmd("pay", freshInvoice(t, 2000, ""), "--yes")
+	if ExitCode(err) != exitPolicyDenied {
+		t.Fatalf("exit code = %d (%v), want %d", ExitCode(err), err, exitPolicyDenied)
+	}
+	if !strings.Contains(err.Error(), "2000 sats exceeds") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// QR codes
+// ---------------------------------------------------------------------------
+
+func TestInvoiceCreate_QRFlags(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	tests := map[string][]string{
+		"unknown format": {"--qr", "gif", "--out", "x.gif"},
+		"missing out":    {"--qr", "png"},
+		"bad extension":  {"--out", "invoice.jpg"},
+	}
+	for name, flags := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := append([]string{"invoice", "create", "--amount", "100"}, flags...)
+			_, _, err := executeCmd(args...)
+			if ExitCode(err) != exitUsage || !strings.Contains(err.Error(), "--") {
+				t.Errorf("expected flag error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAddressQR_NotTerminal(t *testing.T) {
+	setupNoConfig(t)
+
+	stdout, _, err := executeCmd("address", "qr", "Alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lnurl := strings.TrimSpace(stdout)
+	if strings.ContainsAny(stdout, "█▀▄") {
+		t.Errorf("QR should not be drawn when stdout is not a terminal: %q", stdout)
+	}
+	hrp, words, err := bech32.Decode(lnurl)
+	if err != nil || hrp != "lnurl" {
+		t.Fatalf("output %q is not an LNURL: %v", lnurl, err)
+	}
+	url, _ := bech32.ConvertBits(words, 5, 8, false)
+	if string(url) != "https://ln.bot/.well-known/lnurlp/alice" {
+		t.Errorf("LNURL decodes to %s", url)
+	}
+}
+
+func TestAddressQR_File(t *testing.T) {
+	setupNoConfig(t)
+	out := filepath.Join(t.TempDir(), "alice.svg")
+
+	stdout, _, err := executeCmd("address", "qr", "alice@example.com", "--out", out, "--json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var result map[string]string
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", stdout, err)
+	}
+	if result["address"] != "alice@example.com" || result["file"] != out ||
+		result["url"] != "https://example.com/.well-known/lnurlp/alice" {
+		t.Errorf("unexpected result: %v", result)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || !strings.Contains(string(data), "<svg") {
+		t.Errorf("expected SVG file, got %q (%v)", data, err)
+	}
+}
+
+func TestAddressQR_Invalid(t *testing.T) {
+	setupNoConfig(t)
+
+	_, _, err := executeCmd("address", "qr", "@ln.bot")
+	if err == nil || !strings.Contains(err.Error(), "invalid Lightning address") || ExitCode(err) != exitUsage {
+		t.Errorf("expected invalid address usage error, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Profiles
+// ---------------------------------------------------------------------------
+
+func TestProfile_AddUseRenameRemove(t *testing.T) {
+	p := setupConfig(t, testConfig())
+	t.Setenv("LNBOT_PROFILE", "")
+
+	stdout, _, err := executeCmd("profile", "add", "staging", "--key", "uk_staging", "--wallet", "wal_stg", "--api-url", "https://staging.example.com")
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if !strings.Contains(stdout, "Profile staging added") || !strings.Contains(stdout, "lnbot profile use staging") {
+		t.Errorf("unexpected add output: %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("profile", "list")
+	if !strings.Contains(stdout, "● default") || !strings.Contains(stdout, "staging") || !strings.Contains(stdout, "https://staging.example.com") {
+		t.Errorf("unexpected list output: %q", stdout)
+	}
+
+	stdout, _, err = executeCmd("whoami", "--json", "--profile", "staging")
+	if err != nil {
+		t.Fatalf("whoami: %v", err)
+	}
+	if !strings.Contains(stdout, `"wallet_id":"wal_stg"`) || !strings.Contains(stdout, `"profile":"staging"`) {
+		t.Errorf("--profile should select the staging keys, got %q", stdout)
+	}
+
+	if _, _, err := executeCmd("profile", "use", "staging"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	stdout, _, _ = executeCmd("whoami", "--json")
+	if !strings.Contains(stdout, `"wallet_id":"wal_stg"`) {
+		t.Errorf("current profile should be staging, got %q", stdout)
+	}
+
+	if _, _, err := executeCmd("profile", "rename", "default", "prod"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if _, _, err := executeCmd("profile", "remove", "staging", "--yes"); err == nil {
+		t.Error("removing the current profile should fail")
+	}
+	executeCmd("profile", "use", "prod")
+	if _, _, err := executeCmd("profile", "remove", "staging", "--yes"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	data, _ := os.ReadFile(p)
+	var saved struct {
+		CurrentProfile string                     `json:"current_profile"`
+		Profiles       map[string]json.RawMessage `json:"profiles"`
+	}
+	json.Unmarshal(data, &saved)
+	if saved.CurrentProfile != "prod" || len(saved.Profiles) != 1 || saved.Profiles["prod"] == nil {
+		t.Errorf("unexpected config file: %s", data)
+	}
+}
+
+func TestProfile_AddSavesEnvAPIURL(t *testing.T) {
+	devAccount(t, 0)
+
+	if _, _, err := executeCmd("profile", "add", "sandbox"); err != nil {
+		t.Fatal(err)
+	}
+	file, err := config.LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := file.Profile("sandbox"); p == nil || p.APIURL != os.Getenv("LNBOT_API_URL") {
+		t.Errorf("profile = %+v, want api_url %s", p, os.Getenv("LNBOT_API_URL"))
+	}
+}
+
+func TestProfile_Unknown(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	_, _, err := executeCmd("balance", "--profile", "nope")
+	if err == nil || !strings.Contains(err.Error(), `profile "nope" not found`) {
+		t.Errorf("expected unknown profile error, got %v", err)
+	}
+
+	t.Setenv("LNBOT_PROFILE", "nope")
+	stdout, _, err := executeCmd("profile", "list")
+	if err != nil || !strings.Contains(stdout, "default") {
+		t.Errorf("profile list should work with a bad LNBOT_PROFILE, got %q, %v", stdout, err)
+	}
+}
+
+func TestProfile_LegacyConfigMigrated(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(p, []byte(`{"primary_key":"uk_legacy","active_wallet_id":"wal_legacy"}`), 0o600)
+	t.Setenv("LNBOT_CONFIG", p)
+	t.Setenv("LNBOT_NO_UPDATE_CHECK", "1")
+
+	stdout, _, err := executeCmd("whoami", "--json")
+	if err != nil || !strings.Contains(stdout, `"wallet_id":"wal_legacy"`) || !strings.Contains(stdout, `"profile":"default"`) {
+		t.Errorf("legacy config should load as the default profile, got %q, %v", stdout, err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// API endpoint
+// ---------------------------------------------------------------------------
+
+// standIn serves the wallet endpoints status needs and records request paths.
+func standIn(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/wallets/wal_main123":
+			io.WriteString(w, `{"walletId":"wal_main123","name":"main","balance":1000,"available":1000}`)
+		case "/v1/wallets/wal_main123/addresses":
+			io.WriteString(w, `[]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &paths
+}
+
+func TestAPIURL_Flag(t *testing.T) {
+	setupConfig(t, testConfig())
+	srv, paths := standIn(t)
+
+	stdout, _, err := executeCmd("status", "--api-url", srv.URL)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(*paths) == 0 {
+		t.Fatal("status should talk to the --api-url server")
+	}
+	if !strings.Contains(stdout, "endpoint:  "+srv.URL) || !strings.Contains(stdout, "non-default API endpoint") {
+		t.Errorf("status should flag the custom endpoint, got %q", stdout)
+	}
+}
+
+func TestAPIURL_EnvAndProfile(t *testing.T) {
+	c := testConfig()
+	c.APIURL = "http://127.0.0.1:1"
+	setupConfig(t, c)
+	srv, _ := standIn(t)
+
+	t.Setenv("LNBOT_API_URL", srv.URL)
+	stdout, _, err := executeCmd("status", "--json")
+	if err != nil {
+		t.Fatalf("LNBOT_API_URL should override the profile's api_url: %v", err)
+	}
+	if !strings.Contains(stdout, `"apiUrl":"`+srv.URL+`"`) || !strings.Contains(stdout, `"customApi":true`) {
+		t.Errorf("unexpected status JSON: %q", stdout)
+	}
+
+	t.Setenv("LNBOT_API_URL", "")
+	if _, _, err := executeCmd("status"); err == nil {
+		t.Error("status should use the profile's api_url when no override is set")
+	}
+}
+
+func TestAPIURL_Invalid(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	_, _, err := executeCmd("balance", "--api-url", "api.ln.bot")
+	if err == nil || !strings.Contains(err.Error(), "invalid API URL") {
+		t.Errorf("expected invalid API URL error, got %v", err)
+	}
+
+	t.Setenv("LNBOT_API_URL", "ftp://example.com")
+	_, _, err = executeCmd("balance")
+	if err == nil || !strings.Contains(err.Error(), "LNBOT_API_URL") {
+		t.Errorf("expected LNBOT_API_URL error, got %v", err)
+	}
+}
+
+func TestMCPConfig_CustomAPIURL(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	stdout, _, err := executeCmd("mcp", "config", "--remote", "--api-url", "http://localhost:8080/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "http://localhost:8080/v1/wallets/wal_main123/mcp") {
+		t.Errorf("remote config should use the custom endpoint, got %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("mcp", "config", "--api-url", "http://localhost:8080")
+	if !strings.Contains(stdout, `"--api-url"`) {
+		t.Errorf("local config should pass --api-url to 'mcp serve', got %q", stdout)
+	}
+}
+
+func TestDevServer_InvalidFlags(t *testing.T) {
+	setupNoConfig(t)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--balance", "-1"}, "--balance"},
+		{[]string{"--settle-after", "-1s"}, "--settle-after"},
+		{[]string{"--addr", "not-an-address"}, "not-an-address"},
+	}
+	for _, tt := range tests {
+		_, _, err := executeCmd(append([]string{"dev", "server", "--quiet"}, tt.args...)...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("dev server %v: err = %v, want mention of %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestDevServer_IgnoresConfig(t *testing.T) {
+	setupConfig(t, testConfig())
+	os.WriteFile(os.Getenv("LNBOT_CONFIG"), []byte("{not json"), 0o600)
+
+	_, _, err := executeCmd("dev", "server", "--addr", "not-an-address")
+	if err == nil || strings.Contains(err.Error(), "config") {
+		t.Errorf("dev server should not read the config file, got %v", err)
+	}
+}
+
+func TestTransactionsExport_AllPages(t *testing.T) {
+	_, w := devAccount(t, 1_000_000)
+	ctx := context.Background()
+	for i := 0; i < exportPageSize+5; i++ {
+		if _, err := w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(10))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, _, err := executeCmd("transactions", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Header, the starting balance credit, and every payment.
+	if len(rows) != exportPageSize+7 {
+		t.Fatalf("got %d rows, want %d", len(rows), exportPageSize+7)
+	}
+	if rows[1][0] != "1" || rows[len(rows)-1][0] != fmt.Sprint(exportPageSize+6) {
+		t.Errorf("rows should run oldest to newest, got %s … %s", rows[1][0], rows[len(rows)-1][0])
+	}
+}
+
+func TestTransactionsExport_OutFile(t *testing.T) {
+	_, _ = devAccount(t, 5000)
+	out := filepath.Join(t.TempDir(), "books.beancount")
+
+	stdout, _, err := executeCmd("transactions", "export", "--out", out, "--account", "Assets:Lightning:Main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "Exported 1 transactions") || !strings.Contains(stdout, "(beancount)") {
+		t.Errorf("stdout = %q", stdout)
+	}
+	data, _ := os.ReadFile(out)
+	if !strings.Contains(string(data), "balance Assets:Lightning:Main") {
+		t.Errorf("format should be inferred from the extension, got:\n%s", data)
+	}
+}
+
+func TestTransactionsExport_DateRange(t *testing.T) {
+	_, _ = devAccount(t, 5000)
+	today := time.Now().UTC().Format("2006-01-02")
+
+	stdout, _, err := executeCmd("transactions", "export", "--from", today, "--to", today, "--format", "ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "= 5000 sats") {
+		t.Errorf("today's transactions should be included, got:\n%s", stdout)
+	}
+
+	stdout, _, _ = executeCmd("transactions", "export", "--to", "2020-01-01", "--format", "ledger")
+	if strings.Contains(stdout, "sats") {
+		t.Errorf("no transactions should be before 2020, got:\n%s", stdout)
+	}
+}
+
+func TestTransactionsExport_InclusiveBounds(t *testing.T) {
+	at := func(s string) *time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return &t
+	}
+	txs := []lnbot.Transaction{
+		{Number: 1, CreatedAt: at("2024-03-01T00:00:00Z")},
+		{Number: 2, CreatedAt: at("2024-03-01T12:00:00Z")},
+		{Number: 3, CreatedAt: at("2024-03-01T23:59:59Z")},
+		{Number: 4, CreatedAt: at("2024-03-02T00:00:00Z")},
+	}
+	numbers := func(from, to string) []int {
+		f, _ := parseExportDate(from, false)
+		t, _ := parseExportDate(to, true)
+		var out []int
+		for _, tx := range inRange(txs, f, t) {
+			out = append(out, tx.Number)
+		}
+		return out
+	}
+
+	// A transaction exactly at an RFC 3339 bound is included.
+	if got := numbers("2024-03-01T12:00:00Z", "2024-03-01T12:00:00Z"); !slices.Equal(got, []int{2}) {
+		t.Errorf("RFC 3339 bounds = %v, want [2]", got)
+	}
+	// A bare --to date includes the whole day and nothing after it.
+	if got := numbers("2024-03-01", "2024-03-01"); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("date bounds = %v, want [1 2 3]", got)
+	}
+}
+
+func TestTransactionsExport_Invalid(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--format", "xlsx"}, "unknown format"},
+		{[]string{"--unit", "msat"}, "unknown unit"},
+		{[]string{"--from", "yesterday"}, "--from"},
+		{[]string{"--from", "2024-02-01", "--to", "2024-01-01"}, "--to must not be before --from"},
+		{[]string{"--format", "beancount", "--account", "Wallet"}, "invalid Beancount account"},
+	}
+	for _, tt := range tests {
+		_, _, err := executeCmd(append([]string{"transactions", "export"}, tt.args...)...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("export %v: err = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestSync_Offline(t *testing.T) {
+	dev, w := devAccount(t, 10000)
+	ctx := context.Background()
+	w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(100))})
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 700, Memo: lnbot.Ptr("rent for March")})
+
+	stdout, _, err := executeCmd("sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "transactions:  2 new, 2 total") || !strings.Contains(stdout, "invoices:      1 new") {
+		t.Errorf("sync output = %q", stdout)
+	}
+
+	// Later syncs only fetch deltas and refresh pending records.
+	dev.SettleInvoice(inv.Bolt11)
+	stdout, _, _ = executeCmd("sync", "--json")
+	var res map[string]any
+	if err := json.Unmarshal([]byte(stdout), &res); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	if res["transactions"] != 1.0 || res["invoices"] != 0.0 || res["updated"] != 1.0 {
+		t.Errorf("incremental sync = %v", res)
+	}
+
+	// Offline reads never touch the network.
+	t.Setenv("LNBOT_API_URL", "http://127.0.0.1:1")
+	stdout, _, err = executeCmd("transactions", "--offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(stdout, " sats ") != 3 || !strings.Contains(stdout, "offline — last synced") {
+		t.Errorf("offline transactions = %q", stdout)
+	}
+
+	stdout, _, err = executeCmd("invoice", "list", "--offline", "--search", "MARCH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "settled") || !strings.Contains(stdout, "700 sats") {
+		t.Errorf("offline invoice search = %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("payment", "list", "--offline", "--search", "nobody@", "--json")
+	if strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("payment search should find nothing, got %q", stdout)
+	}
+
+	stdout, _, err = executeCmd("transactions", "export", "--offline", "--format", "ledger")
+	if err != nil || !strings.Contains(stdout, "= 10599 sats") {
+		t.Errorf("offline export = %q, %v", stdout, err)
+	}
+}
+
+func TestSync_OnlineListsFetchDeltas(t *testing.T) {
+	_, w := devAccount(t, 10000)
+	ctx := context.Background()
+
+	if _, _, err := executeCmd("sync"); err != nil {
+		t.Fatal(err)
+	}
+	w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(100))})
+
+	stdout, _, err := executeCmd("payment", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "carol@example.com") {
+		t.Errorf("online list should include new payments, got %q", stdout)
+	}
+
+	t.Setenv("LNBOT_API_URL", "http://127.0.0.1:1")
+	stdout, _, _ = executeCmd("payment", "list", "--offline")
+	if !strings.Contains(stdout, "carol@example.com") {
+		t.Errorf("the online list should have updated the mirror, got %q", stdout)
+	}
+}
+
+func TestSearch_NeedsSync(t *testing.T) {
+	_, w := devAccount(t, 0)
+
+	for _, args := range [][]string{
+		{"transactions", "--search", "rent"},
+		{"invoice", "list", "--search", "rent"},
+		{"payment", "list", "--search", "carol@"},
+	} {
+		_, _, err := executeCmd(args...)
+		if err == nil || !strings.Contains(err.Error(), "lnbot sync") || ExitCode(err) != exitUsage {
+			t.Errorf("%v: err = %v, want usage error hinting to run 'lnbot sync'", args, err)
+		}
+	}
+	if store.Exists(storeDir(), w.WalletID) {
+		t.Error("--search should not create a mirror")
+	}
+}
+
+func TestSync_WalletByNameOffline(t *testing.T) {
+	_, w := devAccount(t, 10000)
+	w.Update(context.Background(), &lnbot.UpdateWalletParams{Name: "agent01"})
+	if _, _, err := executeCmd("sync"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LNBOT_API_URL", "http://127.0.0.1:1")
+	stdout, _, err := executeCmd("transactions", "--offline", "--wallet", "agent01")
+	if err != nil || !strings.Contains(stdout, "10,000") {
+		t.Errorf("stdout = %q, err = %v", stdout, err)
+	}
+	if _, _, err := executeCmd("transactions", "--offline", "--wallet", "other"); err == nil {
+		t.Error("unknown wallet name should fail offline")
+	}
+}
+
+func TestOffline_NotSynced(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	for _, args := range [][]string{
+		{"transactions", "--offline"},
+		{"invoice", "list", "--offline"},
+		{"payment", "list", "--offline"},
+	} {
+		_, _, err := executeCmd(args...)
+		if err == nil || !strings.Contains(err.Error(), "lnbot sync") {
+			t.Errorf("%v: err = %v, want hint to run 'lnbot sync'", args, err)
+		}
+	}
+}
+
+func TestMatchEvent(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		name     string
+		want     bool
+	}{
+		{nil, "invoice.settled", true},
+		{[]string{"invoice.settled"}, "invoice.settled", true},
+		{[]string{"invoice.settled"}, "invoice.created", false},
+		{[]string{"payment.*"}, "payment.failed", true},
+		{[]string{"payment"}, "payment.settled", true},
+		{[]string{"pay"}, "payment.settled", false},
+		{[]string{"invoice.created", "payment.*"}, "payment.settled", true},
+		{[]string{"*"}, "anything", true},
+	}
+	for _, tt := range tests {
+		if got := matchEvent(tt.patterns, tt.name); got != tt.want {
+			t.Errorf("matchEvent(%v, %q) = %v, want %v", tt.patterns, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEventEnv(t *testing.T) {
+	ev := lnbot.WalletEvent{
+		Event:     "invoice.settled",
+		CreatedAt: "2024-03-01T12:00:00Z",
+		Data:      json.RawMessage(`{"number":7,"amount":1000,"memo":"coffee","txNumber":null,"paymentHash":"abc"}`),
+	}
+	env := strings.Join(eventEnv("wal_abc", ev), "\n")
+	for _, want := range []string{
+		"LNBOT_EVENT=invoice.settled",
+		"LNBOT_WALLET_ID=wal_abc",
+		`LNBOT_DATA={"number":7`,
+		"LNBOT_DATA_NUMBER=7",
+		"LNBOT_DATA_AMOUNT=1000",
+		"LNBOT_DATA_MEMO=coffee",
+		"LNBOT_DATA_PAYMENT_HASH=abc",
+	} {
+		if !strings.Contains(env, want) {
+			t.Errorf("missing %q in:\n%s", want, env)
+		}
+	}
+	if strings.Contains(env, "TX_NUMBER") {
+		t.Errorf("null fields should be skipped:\n%s", env)
+	}
+}
+
+// keepCreatingInvoices creates an invoice every few milliseconds until the
+// returned stop function is called, so that a watcher sees an event no
+// matter when its stream connects.
+func keepCreatingInvoices(w *lnbot.WalletHandle) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			w.Invoices.Create(context.Background(), &lnbot.CreateInvoiceParams{Amount: 21, Memo: lnbot.Ptr("ping")})
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func TestWatch_JSONOnce(t *testing.T) {
+	_, w := devAccount(t, 1000)
+	stop := keepCreatingInvoices(w)
+	defer stop()
+
+	stdout, _, err := executeCmd("watch", "--json", "--once", "--events", "invoice.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("want one NDJSON line, got %q", stdout)
+	}
+	var ev lnbot.WalletEvent
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil || ev.Event != "invoice.created" {
+		t.Errorf("event = %+v, %v", ev, err)
+	}
+}
+
+func TestWatch_Human(t *testing.T) {
+	_, w := devAccount(t, 1000)
+	stop := keepCreatingInvoices(w)
+	defer stop()
+
+	stdout, _, err := executeCmd("watch", "--once")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "invoice.created") || !strings.Contains(stdout, "+21 sats  ping") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestWatch_Exec(t *testing.T) {
+	_, w := devAccount(t, 1000)
+	stop := keepCreatingInvoices(w)
+	defer stop()
+
+	dir := t.TempDir()
+	hook := fmt.Sprintf(`cat > %s/stdin.json; env | grep ^LNBOT_ > %s/env; echo hook ran`, dir, dir)
+	stdout, _, err := executeCmd("watch", "--once", "--json", "--exec", hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stdout, "hook ran") {
+		t.Error("hook output should not mix with NDJSON on stdout")
+	}
+
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin.json"))
+	var ev lnbot.WalletEvent
+	if err := json.Unmarshal(stdin, &ev); err != nil || ev.Event != "invoice.created" {
+		t.Errorf("hook stdin = %q", stdin)
+	}
+	env, _ := os.ReadFile(filepath.Join(dir, "env"))
+	for _, want := range []string{"LNBOT_EVENT=invoice.created", "LNBOT_DATA_AMOUNT=21", "LNBOT_DATA_STATUS=pending"} {
+		if !strings.Contains(string(env), want) {
+			t.Errorf("hook env missing %q:\n%s", want, env)
+		}
+	}
+}
+
+func TestWatch_Reconnects(t *testing.T) {
+	setupConfig(t, testConfig())
+	old := watchBackoff
+	watchBackoff = 10 * time.Millisecond
+	defer func() { watchBackoff = old }()
+
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			http.Error(w, `{"message":"unavailable"}`, http.StatusServiceUnavailable)
+		case 2:
+			// Connected, then dropped without an event.
+			w.Header().Set("Content-Type", "text/event-stream")
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "data: {\"event\":\"payment.settled\",\"createdAt\":\"2024-03-01T12:00:00Z\",\"data\":{\"number\":3,\"amount\":50}}\n\n")
+		}
+	}))
+	defer srv.Close()
+
+	stdout, stderr, err := executeCmd("watch", "--once", "--json", "--api-url", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || strings.Count(stderr, "reconnecting") != 2 {
+		t.Errorf("attempts = %d, stderr = %q", attempts, stderr)
+	}
+	if !strings.Contains(stdout, `"payment.settled"`) {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestWatch_Unauthorized(t *testing.T) {
+	setupConfig(t, testConfig())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"invalid api key"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, _, err := executeCmd("watch", "--api-url", srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "invalid api key") {
+		t.Errorf("err = %v, want the auth error without retrying", err)
+	}
+}
+
+func TestWebhookListen_ForwardsSigned(t *testing.T) {
+	_, w := devAccount(t, 1000)
+	stop := keepCreatingInvoices(w)
+	defer stop()
+
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer receiver.Close()
+
+	stdout, _, err := executeCmd("webhook", "listen", "--forward-to", receiver.URL+"/hook", "--secret", "whsec_test", "--once", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Method != http.MethodPost || got.URL.Path != "/hook" {
+		t.Fatalf("request = %+v", got)
+	}
+	var ts int64
+	fmt.Sscan(got.Header.Get(webhook.HeaderTimestamp), &ts)
+	if sig := got.Header.Get(webhook.HeaderSignature); sig != webhook.Sign("whsec_test", ts, body) {
+		t.Errorf("signature %q does not match body", sig)
+	}
+	if got.Header.Get(webhook.HeaderEvent) != "invoice.created" {
+		t.Errorf("event header = %q", got.Header.Get(webhook.HeaderEvent))
+	}
+	var ev lnbot.WalletEvent
+	if err := json.Unmarshal(body, &ev); err != nil || ev.Event != "invoice.created" {
+		t.Errorf("body = %s", body)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("stdout = %q", stdout)
+	}
+	if result["status"] != float64(200) || result["ok"] != true {
+		t.Errorf("result = %v", result)
+	}
+}
+
+func TestWebhookListen_Retries(t *testing.T) {
+	_, w := devAccount(t, 1000)
+	stop := keepCreatingInvoices(w)
+	defer stop()
+	old := listenRetryDelay
+	listenRetryDelay = 10 * time.Millisecond
+	defer func() { listenRetryDelay = old }()
+
+	var attempts int
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(rw, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	stdout, _, err := executeCmd("webhook", "listen", "--forward-to", receiver.URL, "--once")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	for _, want := range []string{"signing secret: whsec_", "500 Internal Server Error", "retry 1/3", "200 OK"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestWebhookListen_InvalidURL(t *testing.T) {
+	setupConfig(t, testConfig())
+	for _, u := range []string{"localhost:3000", "ftp://example.com", "http://"} {
+		_, _, err := executeCmd("webhook", "listen", "--forward-to", u)
+		if err == nil || !strings.Contains(err.Error(), "invalid --forward-to") {
+			t.Errorf("%s: err = %v", u, err)
+		}
+	}
+}
+
+func TestWebhook_GlobalTimeoutNotShadowed(t *testing.T) {
+	for _, c := range []*cobra.Command{webhookListenCmd, webhookTriggerCmd} {
+		if f := c.LocalNonPersistentFlags().Lookup("timeout"); f != nil {
+			t.Errorf("%s defines its own --timeout", c.CommandPath())
+		}
+		if f := c.InheritedFlags().Lookup("timeout"); f == nil {
+			t.Errorf("%s doesn't inherit the global --timeout", c.CommandPath())
+		}
+	}
+}
+
+func TestWebhookVerify(t *testing.T) {
+	setupNoConfig(t)
+	body := `{"event":"invoice.settled","createdAt":"2024-03-01T12:00:00Z","data":{"number":1}}`
+	now := time.Now().Unix()
+	ts := fmt.Sprint(now)
+	sig := webhook.Sign("whsec_test", now, []byte(body))
+
+	stdout, _, err := executeCmdWithStdin(body, "webhook", "verify", "--secret", "whsec_test", "--signature", sig, "--timestamp", ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "Signature valid") || !strings.Contains(stdout, "invoice.settled") {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	t.Setenv("LNBOT_WEBHOOK_SECRET", "whsec_test")
+	stdout, _, err = executeCmdWithStdin(body, "webhook", "verify", "--signature", sig, "--timestamp", ts, "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]any
+	json.Unmarshal([]byte(stdout), &result)
+	if result["valid"] != true || result["event"] != "invoice.settled" {
+		t.Errorf("result = %v", result)
+	}
+
+	// A trailing newline is a different body.
+	_, _, err = executeCmdWithStdin(body+"\n", "webhook", "verify", "--signature", sig, "--timestamp", ts)
+	if code := ExitCode(err); code != exitSignatureInvalid {
+		t.Errorf("modified body: exit %d, err %v", code, err)
+	}
+}
+
+func TestWebhookVerify_Timestamp(t *testing.T) {
+	setupNoConfig(t)
+	old := time.Now().Add(-time.Hour).Unix()
+	sig := webhook.Sign("whsec_test", old, []byte("{}"))
+	args := []string{"webhook", "verify", "--secret", "whsec_test", "--signature", sig, "--timestamp", fmt.Sprint(old)}
+
+	_, _, err := executeCmdWithStdin("{}", args...)
+	if code := ExitCode(err); code != exitTimestampTolerance {
+		t.Errorf("stale: exit %d, err %v", code, err)
+	}
+	if _, _, err := executeCmdWithStdin("{}", append(args, "--tolerance", "2h")...); err != nil {
+		t.Errorf("within --tolerance: %v", err)
+	}
+	if _, _, err := executeCmdWithStdin("{}", append(args, "--tolerance", "0")...); err != nil {
+		t.Errorf("--tolerance 0: %v", err)
+	}
+}
+
+func TestWebhookVerify_InvalidInput(t *testing.T) {
+	setupNoConfig(t)
+	t.Setenv("LNBOT_WEBHOOK_SECRET", "")
+	_, _, err := executeCmdWithStdin("{}", "webhook", "verify", "--signature", "v1=00", "--timestamp", "1")
+	if err == nil || !strings.Contains(err.Error(), "--secret is required") {
+		t.Errorf("missing secret: %v", err)
+	}
+	_, _, err = executeCmdWithStdin("{}", "webhook", "verify", "--secret", "s", "--signature", "v1=00", "--timestamp", "yesterday")
+	if err == nil || !strings.Contains(err.Error(), "invalid --timestamp") || ExitCode(err) != exitUsage {
+		t.Errorf("bad timestamp: %v", err)
+	}
+}
+
+func TestWebhookTrigger(t *testing.T) {
+	setupNoConfig(t)
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		rw.Header().Set("X-Handler", "test")
+		io.WriteString(rw, "received")
+	}))
+	defer receiver.Close()
+
+	stdout, _, err := executeCmd("webhook", "trigger", "payment.failed", "--url", receiver.URL, "--secret", "whsec_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("no request received")
+	}
+	ts, _ := webhook.ParseTimestamp(got.Header.Get(webhook.HeaderTimestamp))
+	if err := webhook.Verify("whsec_test", ts, got.Header.Get(webhook.HeaderSignature), body, webhook.DefaultTolerance, time.Now()); err != nil {
+		t.Errorf("delivery does not verify: %v", err)
+	}
+	ev, err := webhook.ParseEvent(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ev.Payment()
+	if err != nil || ev.Type != "payment.failed" || p.FailureReason == nil {
+		t.Errorf("event = %+v, payment = %+v, err = %v", ev, p, err)
+	}
+	for _, want := range []string{"Sent payment.failed", "200 OK", "X-Handler: test", "received"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestWebhookTrigger_ErrorStatus(t *testing.T) {
+	setupNoConfig(t)
+	receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		http.Error(rw, "bad signature", http.StatusBadRequest)
+	}))
+	defer receiver.Close()
+
+	stdout, _, err := executeCmd("webhook", "trigger", "invoice.settled", "--url", receiver.URL, "--json")
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("err = %v", err)
+	}
+	var result map[string]any
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("stdout = %q", stdout)
+	}
+	if result["status"] != float64(400) || result["ok"] != false || result["body"] != "bad signature\n" {
+		t.Errorf("result = %v", result)
+	}
+}
+
+func TestWebhookTrigger_Print(t *testing.T) {
+	setupNoConfig(t)
+	stdout, _, err := executeCmd("webhook", "trigger", "invoice.created", "--secret", "whsec_test", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("stdout = %q", stdout)
+	}
+	h := func(name string) string { return out.Headers[http.CanonicalHeaderKey(name)] }
+	ts, _ := webhook.ParseTimestamp(h(webhook.HeaderTimestamp))
+	if err := webhook.VerifySignature("whsec_test", ts, h(webhook.HeaderSignature), out.Body); err != nil {
+		t.Errorf("printed request does not verify: %v", err)
+	}
+
+	if _, _, err := executeCmd("webhook", "trigger", "invoice.paid"); err == nil || !strings.Contains(err.Error(), "choose one of") || ExitCode(err) != exitUsage {
+		t.Errorf("unknown event: %v", err)
+	}
+}
+
+func TestWebhook_CreateValidation(t *testing.T) {
+	devAccount(t, 0)
+
+	_, _, err := executeCmd("webhook", "create", "--url", "http://example.com/hook")
+	if err == nil || !strings.Contains(err.Error(), "--allow-http") || ExitCode(err) != exitUsage {
+		t.Errorf("http URL: %v", err)
+	}
+	_, _, err = executeCmd("webhook", "create", "--url", "example.com/hook")
+	if err == nil || !strings.Contains(err.Error(), "invalid webhook URL") || ExitCode(err) != exitUsage {
+		t.Errorf("relative URL: %v", err)
+	}
+	_, _, err = executeCmd("webhook", "create", "--url", "https://example.com/hook", "--events", "invoice.paid")
+	if err == nil || !strings.Contains(err.Error(), `unknown event "invoice.paid"`) || ExitCode(err) != exitUsage {
+		t.Errorf("unknown event: %v", err)
+	}
+
+	stdout, _, err := executeCmd("webhook", "create", "--url", "http://10.0.0.5/hook", "--allow-http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "url:    http://10.0.0.5/hook") || !strings.Contains(stdout, "secret: whsec_") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestWebhook_ShowAndUnsupported(t *testing.T) {
+	devAccount(t, 0)
+
+	stdout, _, err := executeCmd("webhook", "create", "--url", "https://example.com/hook", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+	json.Unmarshal([]byte(stdout), &created)
+
+	stdout, _, err = executeCmd("webhook", "show", created.ID, "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hook map[string]any
+	json.Unmarshal([]byte(stdout), &hook)
+	if hook["url"] != "https://example.com/hook" || hook["active"] != true || hook["secret"] != nil {
+		t.Errorf("show = %v", hook)
+	}
+	if _, _, err := executeCmd("webhook", "show", "whk_missing"); ExitCode(err) != exitNotFound {
+		t.Errorf("show missing: %v", err)
+	}
+
+	// The API can only create, list and delete webhooks.
+	for _, args := range [][]string{
+		{"webhook", "create", "--url", "https://example.com/hook", "--events", "invoice.settled"},
+		{"webhook", "update", created.ID, "--url", "https://example.com/v2"},
+		{"webhook", "enable", created.ID},
+		{"webhook", "disable", created.ID},
+	} {
+		_, _, err := executeCmd(args...)
+		if err == nil || !strings.Contains(err.Error(), "not supported by this API") {
+			t.Errorf("%v: err = %v", args[:2], err)
+		}
+	}
+	if _, _, err := executeCmd("webhook", "update", created.ID); err == nil || !strings.Contains(err.Error(), "nothing to update") {
+		t.Errorf("update without flags: %v", err)
+	}
+	stdout, _, _ = executeCmd("webhook", "list", "--json")
+	if strings.Count(stdout, `"id"`) != 1 {
+		t.Errorf("only the first webhook should have been created: %s", stdout)
+	}
+}
+
+func writePayouts(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "payouts.csv")
+	os.WriteFile(p, []byte(content), 0o600)
+	return p
+}
+
+func TestPayBatch(t *testing.T) {
+	_, w := devAccount(t, 10000)
+	file := writePayouts(t, "target,amount,max_fee,memo\n"+
+		"carol@example.com,100,5,week 12\n"+
+		"fail@example.com,50,,\n"+
+		"dave@example.com,200,,\n")
+	report := filepath.Join(filepath.Dir(file), "report.csv")
+
+	stdout, _, err := executeCmd("pay", "batch", file, "--yes", "--report", report)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 payouts did not settle") {
+		t.Fatalf("err = %v\n%s", err, stdout)
+	}
+	for _, want := range []string{"total:    350 sats", "✓ line 2  carol@example.com  100 sats", "✗ line 3  fail@example.com  no route found", "2 settled, 1 failed"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+	data, _ := os.ReadFile(report)
+	if !strings.Contains(string(data), "carol@example.com,100,5,week 12,settled,") {
+		t.Errorf("report:\n%s", data)
+	}
+
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 3 {
+		t.Fatalf("payments = %d, want 3", len(payments))
+	}
+
+	// A rerun only retries the failed row.
+	stdout, _, err = executeCmd("pay", "batch", file, "--yes", "--json")
+	if err == nil {
+		t.Fatal("expected the failed row to fail again")
+	}
+	var summary struct {
+		Settled int `json:"settled"`
+		Failed  int `json:"failed"`
+		Rows    []struct {
+			Status   string `json:"status"`
+			Attempts int    `json:"attempts"`
+		} `json:"rows"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &summary); err != nil {
+		t.Fatalf("stdout = %q", stdout)
+	}
+	if summary.Settled != 2 || summary.Failed != 1 || summary.Rows[0].Attempts != 1 || summary.Rows[1].Attempts != 2 {
+		t.Errorf("summary = %+v", summary)
+	}
+	payments, _ = w.Payments.List(context.Background(), nil)
+	if len(payments) != 4 {
+		t.Errorf("payments after rerun = %d, want 4", len(payments))
+	}
+}
+
+func TestPayBatch_ResumesUnknownWithoutPayingTwice(t *testing.T) {
+	_, w := devAccount(t, 10000)
+	file := writePayouts(t, "target,amount\ncarol@example.com,100\n")
+	rows, _ := batch.Load(file)
+	state, _ := batch.LoadState(batch.StatePath(file), w.WalletID)
+	state.Results(rows)
+
+	// The payment went through, but the run died before recording it.
+	state.Update(rows[0].Key, func(r *batch.Result) {
+		r.Status = batch.Sending
+		r.Attempts = 1
+	})
+	w.Payments.Create(context.Background(), &lnbot.CreatePaymentParams{
+		Target: "carol@example.com", Amount: lnbot.Ptr(int64(100)),
+		IdempotencyKey: lnbot.Ptr(state.IdempotencyKey(rows[0], 1)),
+	})
+
+	stdout, _, err := executeCmd("pay", "batch", file, "--yes")
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stdout)
+	}
+	if !strings.Contains(stdout, "(retry)") || !strings.Contains(stdout, "1 settled") {
+		t.Errorf("stdout = %q", stdout)
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 1 {
+		t.Errorf("payments = %d, want 1", len(payments))
+	}
+
+	stdout, _, err = executeCmd("pay", "batch", file, "--yes")
+	if err != nil || !strings.Contains(stdout, "already settled") {
+		t.Errorf("third run: %v\n%s", err, stdout)
+	}
+}
+
+func TestPayBatch_DryRun(t *testing.T) {
+	_, w := devAccount(t, 100)
+	file := writePayouts(t, "target,amount\ncarol@example.com,100\ndave@example.com,250\n")
+
+	stdout, _, err := executeCmd("pay", "batch", file, "--dry-run", "--max-fee", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"carol@example.com", "fee ≤ 3 sats", "total:    350 sats", "max fees: 6 sats", "may not cover"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 0 {
+		t.Errorf("dry run sent %d payments", len(payments))
+	}
+	if _, err := os.Stat(batch.StatePath(file)); !os.IsNotExist(err) {
+		t.Error("dry run should not write a state file")
+	}
+}
+
+func TestPayBatch_PolicyDeniesWholeBatch(t *testing.T) {
+	_, w := devAccount(t, 10000)
+	pol := filepath.Join(t.TempDir(), "policy.json")
+	os.WriteFile(pol, []byte(`{"daily_budget": 250}`), 0o600)
+	if _, _, err := executeCmd("policy", "set", pol); err != nil {
+		t.Fatal(err)
+	}
+	file := writePayouts(t, "target,amount\ncarol@example.com,100\ndave@example.com,100\nerin@example.com,100\n")
+
+	_, _, err := executeCmd("pay", "batch", file, "--yes")
+	if ExitCode(err) != exitPolicyDenied || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("err = %v (exit %d)", err, ExitCode(err))
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 0 {
+		t.Errorf("denied batch sent %d payments", len(payments))
+	}
+}
+
+func TestPayBatch_InvalidFile(t *testing.T) {
+	devAccount(t, 0)
+	file := writePayouts(t, "target,amount\nalice@ln.bot,\n")
+	_, _, err := executeCmd("pay", "batch", file)
+	if err == nil || !strings.Contains(err.Error(), "line 2: amount is required") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Idempotent payments
+// ---------------------------------------------------------------------------
+
+func TestPay_IdempotencyKey(t *testing.T) {
+	_, w := devAccount(t, 10000)
+
+	if _, _, err := executeCmd("pay", "carol@example.com", "--amount", "100", "--yes", "--idempotency-key", "order-1"); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, err := executeCmd("pay", "carol@example.com", "--amount", "100", "--yes", "--idempotency-key", "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "Already sent as payment #1") || !strings.Contains(stdout, "100 sats") ||
+		!strings.Contains(stdout, "Payment #1 settled") || strings.Contains(stdout, "Sent!") {
+		t.Errorf("stdout = %q", stdout)
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 1 {
+		t.Errorf("payments = %d, want 1", len(payments))
+	}
+
+	stdout, _, err = executeCmd("pay", "carol@example.com", "--amount", "100", "--yes", "--idempotency-key", "order-1", "--json")
+	var p lnbot.Payment
+	if err != nil || json.Unmarshal([]byte(stdout), &p) != nil || p.Number != 1 || p.Status != "settled" {
+		t.Errorf("json rerun: %v %q", err, stdout)
+	}
+
+	_, _, err = executeCmd("pay", "dave@example.com", "--amount", "100", "--yes", "--idempotency-key", "order-1")
+	if err == nil || !strings.Contains(err.Error(), "already used for payment #1") {
+		t.Errorf("expected key reuse error, got %v", err)
+	}
+}
+
+func TestPay_InvoicePaidOnce(t *testing.T) {
+	_, w := devAccount(t, 10000)
+	invoice := freshInvoice(t, 2100, "tea")
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := executeCmd("pay", invoice, "--yes"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 1 {
+		t.Errorf("payments = %d, want 1", len(payments))
+	}
+}
+
+func TestPay_FailedInvoiceCanBeRetried(t *testing.T) {
+	_, w := devAccount(t, 10000)
+	invoice := freshInvoice(t, 2100, "tea")
+	ctx := context.Background()
+
+	// A failed attempt recorded by an earlier run.
+	inv, _ := bolt11.Decode(invoice)
+	failed, err := w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "fail@example.com", Amount: lnbot.Ptr(int64(2100))})
+	if err != nil || failed.Status != "failed" {
+		t.Fatalf("setup: %v %+v", err, failed)
+	}
+	j, _ := journal.Load(journalPath())
+	j.Record(w.WalletID, "bolt11-"+inv.PaymentHash, journal.Entry{Number: failed.Number, Target: invoice, Attempt: 1})
+
+	stdout, _, err := executeCmd("pay", invoice, "--yes")
+	if err != nil || !strings.Contains(stdout, "Sent!") {
+		t.Fatalf("%v\n%s", err, stdout)
+	}
+	payments, _ := w.Payments.List(ctx, nil)
+	if len(payments) != 2 {
+		t.Errorf("payments = %d, want 2", len(payments))
+	}
+}
+
+func TestPay_RetryWithFeeSteps(t *testing.T) {
+	_, w := devAccount(t, 100000)
+
+	// The dev server charges 21 sats to route 20,000: the first step is too low.
+	stdout, _, err := executeCmd("pay", "carol@example.com", "--amount", "20000", "--yes", "--json",
+		"--max-fee-steps", "10,50", "--retry-backoff", "1ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Status   string       `json:"status"`
+		Attempts []payAttempt `json:"attempts"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("stdout = %q", stdout)
+	}
+	if result.Status != "settled" || len(result.Attempts) != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	if a := result.Attempts[0]; a.Status != "failed" || !a.Retryable || *a.MaxFee != 10 || *a.FailureReason != "fee exceeds max fee" {
+		t.Errorf("first attempt = %+v", a)
+	}
+	if *result.Attempts[1].MaxFee != 50 {
+		t.Errorf("second attempt = %+v", result.Attempts[1])
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 2 {
+		t.Errorf("payments = %d, want 2", len(payments))
+	}
+}
+
+func TestPay_RetryReportsEachAttempt(t *testing.T) {
+	_, w := devAccount(t, 10000)
+
+	stdout, stderr, err := executeCmd("pay", "fail@example.com", "--amount", "100", "--yes", "--retry", "2", "--retry-backoff", "1ms")
+	if ExitCode(err) != exitPaymentFailed {
+		t.Fatalf("exit code = %d (%v), want %d", ExitCode(err), err, exitPaymentFailed)
+	}
+	for _, want := range []string{"attempt 1/3 failed: no route found — retrying in 1ms", "attempt 2/3 failed: no route found — retrying in 2ms"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+	if !strings.Contains(stderr, "Payment failed: no route found") {
+		t.Errorf("stderr = %q", stderr)
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 3 {
+		t.Errorf("payments = %d, want 3", len(payments))
+	}
+}
+
+func TestPay_RetryTimeoutKeepsAttempts(t *testing.T) {
+	devAccount(t, 10000)
+
+	stdout, _, err := executeCmd("pay", "fail@example.com", "--amount", "100", "--yes", "--json",
+		"--retry", "2", "--retry-backoff", "1h", "--wait-timeout", "200ms")
+	if ExitCode(err) != exitNetwork {
+		t.Fatalf("exit code = %d (%v), want %d", ExitCode(err), err, exitNetwork)
+	}
+	var result struct {
+		Number   int          `json:"number"`
+		Attempts []payAttempt `json:"attempts"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("stdout = %q", stdout)
+	}
+	if len(result.Attempts) != 1 || result.Attempts[0].Number != result.Number || result.Attempts[0].Status != "failed" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestPay_RetryFlags(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	tests := map[string][]string{
+		"no-wait":    {"--retry", "2", "--no-wait"},
+		"both fees":  {"--max-fee", "5", "--max-fee-steps", "10,50"},
+		"decreasing": {"--max-fee-steps", "50,10"},
+		"negative":   {"--retry", "-1"},
+	}
+	for name, flags := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := append([]string{"pay", "carol@example.com", "--amount", "100", "--yes"}, flags...)
+			if _, _, err := executeCmd(args...); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestRetryableFailure(t *testing.T) {
+	tests := map[string]bool{
+		"no route found":       true,
+		"Timeout":              true,
+		"fee exceeds max fee":  true,
+		"temporary failure":    true,
+		"invoice expired":      false,
+		"insufficient balance": false,
+		"invoice already paid": false,
+	}
+	for reason, want := range tests {
+		if got := retryableFailure(reason); got != want {
+			t.Errorf("retryableFailure(%q) = %v, want %v", reason, got, want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Fee limits
+// ---------------------------------------------------------------------------
+
+func TestConfig_FeeLimit(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	if _, _, err := executeCmd("config", "set", "max-fee-percent", "0.5"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := executeCmd("config", "set", "max-fee-ceiling", "500"); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, _ := executeCmd("config", "get", "max-fee-percent")
+	if strings.TrimSpace(stdout) != "0.5" {
+		t.Errorf("get = %q", stdout)
+	}
+
+	// A fixed limit replaces the percentage; the ceiling stays.
+	executeCmd("config", "set", "max-fee", "20")
+	stdout, _, _ = executeCmd("config", "get", "--json")
+	var got map[string]any
+	json.Unmarshal([]byte(stdout), &got)
+	if got["max-fee"] != float64(20) || got["max-fee-percent"] != nil || got["max-fee-ceiling"] != float64(500) {
+		t.Errorf("config = %v", got)
+	}
+
+	executeCmd("config", "unset", "max-fee")
+	executeCmd("config", "unset", "max-fee-ceiling")
+	saved, _ := config.Load()
+	if saved.FeeLimit != nil {
+		t.Errorf("FeeLimit = %+v, want nil", saved.FeeLimit)
+	}
+
+	for _, args := range [][]string{
+		{"config", "set", "max-fees", "1"},
+		{"config", "set", "max-fee-percent", "abc"},
+		{"config", "set", "max-fee-percent", "150"},
+		{"config", "get", "colour"},
+	} {
+		if _, _, err := executeCmd(args...); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestPay_MaxFeePercent(t *testing.T) {
+	_, w := devAccount(t, 100000)
+
+	// 0.05% of 20,000 is 10 sats, below the dev server's 21-sat routing fee.
+	stdout, _, err := executeCmd("pay", "carol@example.com", "--amount", "20000", "--max-fee-percent", "0.05", "--yes", "--json")
+	var p lnbot.Payment
+	if ExitCode(err) != exitPaymentFailed || json.Unmarshal([]byte(stdout), &p) != nil {
+		t.Fatalf("%v: %q", err, stdout)
+	}
+	if p.MaxFee != 10 || p.Status != "failed" {
+		t.Errorf("payment = %+v", p)
+	}
+
+	// The profile default applies without flags, and flags bound it.
+	executeCmd("config", "set", "max-fee-ppm", "2000")
+	executeCmd("pay", "carol@example.com", "--amount", "20000", "--yes")
+	executeCmd("pay", "carol@example.com", "--amount", "20000", "--yes", "--max-fee-ceiling", "30")
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 3 || payments[1].MaxFee != 40 || payments[0].MaxFee != 30 {
+		for _, p := range payments {
+			t.Logf("#%d max fee %d", p.Number, p.MaxFee)
+		}
+		t.Error("unexpected fee limits")
+	}
+}
+
+func TestPay_MaxFeeInPrompt(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	stdout, _, _ := executeCmdWithStdin("n\n", "pay", "carol@example.com", "--amount", "20000", "--max-fee-percent", "0.5")
+	if !strings.Contains(stdout, "Send 20,000 sats to carol@example.com, max fee 100 sats (0.5%)?") {
+		t.Errorf("stdout = %q", stdout)
+	}
+	stdout, _, _ = executeCmdWithStdin("n\n", "pay", freshInvoice(t, 2100, "tea"), "--max-fee-ppm", "10000")
+	if !strings.Contains(stdout, "max fee: 21 sats (10000 ppm)") {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	for _, flags := range [][]string{{"--max-fee", "5", "--max-fee-percent", "1"}, {"--max-fee-percent", "200"}, {"--max-fee-ppm", "10", "--max-fee-steps", "10,50"}} {
+		args := append([]string{"pay", "carol@example.com", "--amount", "100", "--yes"}, flags...)
+		if _, _, err := executeCmd(args...); err == nil {
+			t.Errorf("%v: expected an error", flags)
+		}
+	}
+}
+
+func TestPayBatch_DefaultFeeLimit(t *testing.T) {
+	devAccount(t, 100000)
+	file := writePayouts(t, "target,amount,max_fee\ncarol@example.com,20000,\ndave@example.com,100,7\n")
+	executeCmd("config", "set", "max-fee-percent", "0.1")
+
+	stdout, _, err := executeCmd("pay", "batch", file, "--dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "fee ≤ 20 sats") || !strings.Contains(stdout, "fee ≤ 7 sats") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Amounts
+// ---------------------------------------------------------------------------
+
+func writeRates(t *testing.T) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "rates.json")
+	os.WriteFile(p, []byte(`{"USD": 65000, "EUR": 60000}`), 0o600)
+	t.Setenv("LNBOT_RATES_FILE", p)
+}
+
+func TestAmounts_UnitsAndFiat(t *testing.T) {
+	_, w := devAccount(t, 100000)
+	writeRates(t)
+
+	stdout, _, err := executeCmd("invoice", "create", "--amount", "5usd", "--no-wait")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "amount:  7,692 sats (5 USD)") {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	stdout, _, _ = executeCmdWithStdin("n\n", "pay", "carol@example.com", "--amount", "10eur")
+	if !strings.Contains(stdout, "Send 16,667 sats (10 EUR) to carol@example.com?") {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	if _, _, err := executeCmd("pay", "carol@example.com", "--amount", "0.0001btc", "--max-fee", "1k", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+	payments, _ := w.Payments.List(context.Background(), nil)
+	if len(payments) != 1 || payments[0].Amount != 10000 || payments[0].MaxFee != 1000 {
+		t.Errorf("payments = %+v", payments)
+	}
+}
+
+func TestAmounts_Invalid(t *testing.T) {
+	setupConfig(t, testConfig())
+	t.Setenv("LNBOT_RATES_FILE", "")
+	t.Setenv("LNBOT_RATES_URL", "")
+
+	tests := map[string][]string{
+		"sub-sat":  {"pay", "carol@example.com", "--amount", "1500msat", "--yes"},
+		"unit":     {"invoice", "create", "--amount", "5 apples"},
+		"no rates": {"invoice", "create", "--amount", "5usd"},
+		"fee":      {"pay", "carol@example.com", "--amount", "100", "--max-fee", "0.5", "--yes"},
+	}
+	wants := map[string]string{
+		"sub-sat":  "whole sats",
+		"unit":     "unknown unit",
+		"no rates": "no exchange rate source",
+		"fee":      "--max-fee",
+	}
+	for name, args := range tests {
+		_, _, err := executeCmd(args...)
+		if err == nil || !strings.Contains(err.Error(), wants[name]) {
+			t.Errorf("%s: err = %v, want %q", name, err, wants[name])
+		}
+	}
+}
+
+func TestConfig_RatesSource(t *testing.T) {
+	setupConfig(t, testConfig())
+	t.Setenv("LNBOT_RATES_FILE", "")
+	p := filepath.Join(t.TempDir(), "rates.json")
+	os.WriteFile(p, []byte(`{"USD": 50000}`), 0o600)
+
+	if _, _, err := executeCmd("config", "set", "rates-file", p); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, _ := executeCmdWithStdin("n\n", "pay", "carol@example.com", "--amount", "1usd")
+	if !strings.Contains(stdout, "Send 2,000 sats (1 USD)") {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	if _, _, err := executeCmd("config", "set", "rates-file", filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing rates file")
+	}
+	if _, _, err := executeCmd("config", "set", "rates-url", "ftp://example.com/rates"); err == nil {
+		t.Error("expected an error for a non-http rates URL")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Display units
+// ---------------------------------------------------------------------------
+
+func TestBalance_UnitAndFiat(t *testing.T) {
+	devAccount(t, 123456)
+	writeRates(t)
+	t.Cleanup(func() { format.SetDisplay(format.Display{}) })
+
+	stdout, _, err := executeCmd("balance", "--unit", "btc", "--fiat", "usd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "balance:   0.00123456 BTC (80.25 USD)") {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	// JSON stays in sats.
+	stdout, _, _ = executeCmd("balance", "--unit", "btc", "--json")
+	if !strings.Contains(stdout, `"balance":123456`) {
+		t.Errorf("json = %q", stdout)
+	}
+
+	if _, _, err := executeCmd("balance", "--unit", "eth"); err == nil {
+		t.Error("expected an error for an unknown unit")
+	}
+}
+
+func TestLists_ConfiguredUnit(t *testing.T) {
+	_, w := devAccount(t, 100000)
+	writeRates(t)
+	t.Cleanup(func() { format.SetDisplay(format.Display{}) })
+	w.Payments.Create(context.Background(), &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(2500))})
+
+	executeCmd("config", "set", "unit", "bits")
+	executeCmd("config", "set", "fiat", "eur")
+	stdout, _, err := executeCmd("payment", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "25.00 bits  1.50 EUR") {
+		t.Errorf("payment list = %q", stdout)
+	}
+	stdout, _, _ = executeCmd("transactions", "--fiat", "none", "--unit", "msat")
+	if !strings.Contains(stdout, "-2,500,000 msat") || strings.Contains(stdout, "EUR") {
+		t.Errorf("transactions = %q", stdout)
+	}
+
+	// Without a rate source the amounts are shown without fiat.
+	t.Setenv("LNBOT_RATES_FILE", "")
+	stdout, stderr, err := executeCmd("invoice", "list")
+	if err != nil || !strings.Contains(stderr, "not showing EUR amounts") {
+		t.Errorf("invoice list: %v %q %q", err, stdout, stderr)
+	}
+}
+
+func TestOutput_ListFormats(t *testing.T) {
+	_, w := devAccount(t, 100000)
+	ctx := context.Background()
+	w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(2500)), Reference: lnbot.Ptr("inv-7")})
+	w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "dave@example.com", Amount: lnbot.Ptr(int64(100))})
+
+	stdout, _, err := executeCmd("payment", "list", "-o", "csv", "--columns", "number,amount,address,reference")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "number,amount,address,reference\n2,100,dave@example.com,\n1,2500,carol@example.com,inv-7\n"
+	if stdout != want {
+		t.Errorf("csv = %q, want %q", stdout, want)
+	}
+
+	stdout, _, _ = executeCmd("payment", "list", "-o", "wide")
+	if !strings.Contains(stdout, "MAX FEE") || !strings.Contains(stdout, "inv-7") {
+		t.Errorf("wide = %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("payment", "list", "--template", "{{.Number}} {{.Address}}")
+	if stdout != "2 dave@example.com\n1 carol@example.com\n" {
+		t.Errorf("template = %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("transactions", "-o", "ndjson", "--columns", "type,amount")
+	if stdout != `{"type":"debit","amount":100}`+"\n"+`{"type":"debit","amount":2500}`+"\n"+`{"type":"credit","amount":100000}`+"\n" {
+		t.Errorf("ndjson = %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("invoice", "list", "-o", "yaml")
+	if strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("yaml of no invoices = %q", stdout)
+	}
+
+	// --json keeps its full objects.
+	stdout, _, _ = executeCmd("payment", "list", "--json")
+	var payments []lnbot.Payment
+	if err := json.Unmarshal([]byte(stdout), &payments); err != nil || len(payments) != 2 || payments[1].MaxFee == 0 {
+		t.Errorf("json = %q, %v", stdout, err)
+	}
+
+	_, _, err = executeCmd("payment", "list", "--columns", "number,colour")
+	if err == nil || !strings.Contains(err.Error(), `unknown column "colour"`) {
+		t.Errorf("unknown column: err = %v", err)
+	}
+}
+
+func TestOutput_Results(t *testing.T) {
+	devAccount(t, 100000)
+
+	stdout, _, err := executeCmd("balance", "-o", "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "balance: 100000\n") {
+		t.Errorf("yaml = %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("balance", "-o", "json-pretty")
+	if !strings.Contains(stdout, "\n  \"balance\": 100000,\n") {
+		t.Errorf("json-pretty = %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("balance", "--template", "{{.Available}}")
+	if stdout != "100000\n" {
+		t.Errorf("template = %q", stdout)
+	}
+
+	stdout, _, _ = executeCmd("config", "get", "-o", "tsv")
+	if !strings.HasPrefix(stdout, "fiat\tmax-fee\t") {
+		t.Errorf("tsv = %q", stdout)
+	}
+}
+
+func TestOutput_FlagErrors(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"balance", "-o", "xml"}, "unknown output format"},
+		{[]string{"balance", "--json", "-o", "yaml"}, "--json can't be combined"},
+		{[]string{"balance", "-o", "template"}, "needs --template"},
+		{[]string{"balance", "-o", "csv", "--template", "{{.}}"}, "--template can't be combined"},
+		{[]string{"balance", "--template", "{{.Balance"}, "invalid --template"},
+		{[]string{"profile", "list", "-o", "xml"}, "unknown output format"},
+	}
+	for _, tt := range tests {
+		_, _, err := executeCmd(tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: err = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	_, w := devAccount(t, 100000)
+	w.Payments.Create(context.Background(), &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(2500))})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"balance", "--query", "available"}, "97497\n"},
+		{[]string{"tx", "--query", "[?type=='debit'].amount"}, "[2500]\n"},
+		{[]string{"tx", "--query", "[?type=='debit'] | [0].type"}, "debit\n"},
+		{[]string{"payment", "list", "--query", "[0].address"}, "carol@example.com\n"},
+		{[]string{"tx", "--query", "[*].amount", "-o", "yaml"}, "- 2500\n- 100000\n"},
+		{[]string{"tx", "--query", "[*].{type: type}", "-o", "csv"}, "type\ndebit\ncredit\n"},
+		{[]string{"wallet", "list", "--query", "length(@)"}, "1\n"},
+		{[]string{"config", "get", "--query", "unit"}, "null\n"},
+	}
+	for _, tt := range tests {
+		stdout, _, err := executeCmd(tt.args...)
+		if err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if stdout != tt.want {
+			t.Errorf("%v = %q, want %q", tt.args, stdout, tt.want)
+		}
+	}
+
+	_, _, err := executeCmd("balance", "--query", "[?")
+	if err == nil || !strings.Contains(err.Error(), "invalid --query") {
+		t.Errorf("invalid query: err = %v", err)
+	}
+	_, _, err = executeCmd("tx", "--query", "@", "--columns", "number")
+	if err == nil || !strings.Contains(err.Error(), "--columns can't be combined with --query") {
+		t.Errorf("--columns with --query: err = %v", err)
+	}
+}
+
+func TestInvoiceCreate_WaitTimeout(t *testing.T) {
+	devAccount(t, 0)
+
+	stdout, _, err := executeCmd("invoice", "create", "--amount", "100", "--wait-timeout", "50ms")
+	if ExitCode(err) != exitNetwork || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("exit code = %d (%v), want %d", ExitCode(err), err, exitNetwork)
+	}
+	for _, want := range []string{"Stopped waiting — invoice #1 is still pending", "Keep watching with: lnbot invoice watch 1"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+
+	// --timeout bounds the whole command, waits included. Under --json the
+	// pending invoice is the only output.
+	stdout, _, err = executeCmd("invoice", "create", "--amount", "100", "--timeout", "50ms", "--json")
+	var inv lnbot.Invoice
+	if ExitCode(err) != exitNetwork || json.Unmarshal([]byte(stdout), &inv) != nil || inv.Status != "pending" {
+		t.Errorf("--timeout: exit code %d (%v), stdout %q", ExitCode(err), err, stdout)
+	}
+}
+
+func TestInvoiceWatch(t *testing.T) {
+	dev, w := devAccount(t, 0)
+	ctx := context.Background()
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 100, Memo: lnbot.Ptr("coffee")})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		dev.SettleInvoice(inv.Bolt11)
+	}()
+
+	stdout, _, err := executeCmd("invoice", "watch", "#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"memo:    coffee", "Payment received! +100 sats"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+
+	// Already settled: reported straight away.
+	stdout, _, err = executeCmd("invoice", "watch", "1", "--json")
+	var got lnbot.Invoice
+	if err != nil || json.Unmarshal([]byte(stdout), &got) != nil || got.Status != "settled" {
+		t.Errorf("settled --json: %v, stdout %q", err, stdout)
+	}
+
+	w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 200})
+	stdout, _, err = executeCmd("invoice", "watch", "2", "--wait-timeout", "50ms")
+	if ExitCode(err) != exitNetwork || !strings.Contains(stdout, "lnbot invoice watch 2") {
+		t.Errorf("pending: exit code %d (%v), stdout %q", ExitCode(err), err, stdout)
+	}
+
+	for _, tt := range []struct {
+		arg  string
+		code int
+	}{
+		{"99", exitNotFound},
+		{"abc", exitUsage},
+		{"0", exitUsage},
+	} {
+		_, _, err := executeCmd("invoice", "watch", tt.arg)
+		if ExitCode(err) != tt.code {
+			t.Errorf("watch %s: exit code %d (%v), want %d", tt.arg, ExitCode(err), err, tt.code)
+		}
+	}
+}
+
+func TestInvoiceWatch_ReconnectsUntilExpired(t *testing.T) {
+	setupConfig(t, testConfig())
+	old := watchBackoff
+	watchBackoff = 10 * time.Millisecond
+	defer func() { watchBackoff = old }()
+
+	var streams int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/events") {
+			io.WriteString(w, `{"number":5,"status":"pending","amount":50}`)
+			return
+		}
+		streams++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if streams > 1 {
+			io.WriteString(w, "event: expired\ndata: {\"number\":5,\"status\":\"expired\",\"amount\":50}\n\n")
+		}
+	}))
+	defer srv.Close()
+
+	stdout, stderr, err := executeCmd("invoice", "watch", "5", "--json", "--api-url", srv.URL)
+	if ExitCode(err) != exitInvoiceExpired {
+		t.Errorf("exit code = %d (%v), want %d", ExitCode(err), err, exitInvoiceExpired)
+	}
+	if streams != 2 || strings.Count(stderr, "reconnecting") != 1 {
+		t.Errorf("streams = %d, stderr = %q", streams, stderr)
+	}
+	var got lnbot.Invoice
+	if json.Unmarshal([]byte(stdout), &got) != nil || got.Status != "expired" {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestPaymentWatch(t *testing.T) {
+	_, w := devAccount(t, 1000)
+	ctx := context.Background()
+	w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(100))})
+	w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "fail@example.com", Amount: lnbot.Ptr(int64(100))})
+
+	stdout, _, err := executeCmd("payment", "watch", "1")
+	if err != nil || !strings.Contains(stdout, "Payment #1 settled") {
+		t.Errorf("settled: %v, stdout %q", err, stdout)
+	}
+
+	_, stderr, err := executeCmd("payment", "watch", "2")
+	if ExitCode(err) != exitPaymentFailed || !strings.Contains(stderr, "Payment failed: no route found") {
+		t.Errorf("failed: exit code %d (%v), stderr %q", ExitCode(err), err, stderr)
+	}
+
+	stdout, _, err = executeCmd("payment", "watch", "2", "--json")
+	var got lnbot.Payment
+	if ExitCode(err) != exitPaymentFailed || json.Unmarshal([]byte(stdout), &got) != nil || got.Status != "failed" {
+		t.Errorf("failed --json: exit code %d (%v), stdout %q", ExitCode(err), err, stdout)
+	}
+}
+
+func TestPaymentWatch_FollowsOneStream(t *testing.T) {
+	setupConfig(t, testConfig())
+
+	var streams int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/events") {
+			io.WriteString(w, `{"number":7,"status":"pending","amount":50}`)
+			return
+		}
+		streams++
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: processing\ndata: {\"number\":7,\"status\":\"processing\",\"amount\":50}\n\n")
+		io.WriteString(w, "event: settled\ndata: {\"number\":7,\"status\":\"settled\",\"amount\":50}\n\n")
+	}))
+	defer srv.Close()
+
+	stdout, _, err := executeCmd("payment", "watch", "7", "--json", "--api-url", srv.URL)
+	var got lnbot.Payment
+	if err != nil || json.Unmarshal([]byte(stdout), &got) != nil || got.Status != "settled" {
+		t.Errorf("watch: %v, stdout %q", err, stdout)
+	}
+	if streams != 1 {
+		t.Errorf("opened %d streams, want 1", streams)
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	resetState()
+	jsonFlag = true
+	err := interrupted(apiError("sending payment", context.Canceled))
+	if ExitCode(err) != exitInterrupted || err.Error() != "interrupted" {
+		t.Errorf("interrupted API call: %d %v", ExitCode(err), err)
+	}
+	err = interrupted(stoppedWaiting(canceledContext(), "invoice", 4))
+	var ee *exitError
+	if ExitCode(err) != exitInterrupted || !errors.As(err, &ee) || !ee.reported {
+		t.Errorf("interrupted wait: %d %v", ExitCode(err), err)
+	}
+}
+
+func TestConfirm_Cancelled(t *testing.T) {
+	resetState()
+	r, w, _ := os.Pipe()
+	defer w.Close()
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = r, nil
+	defer func() { os.Stdin, os.Stdout = oldStdin, oldStdout }()
+
+	if confirm(canceledContext(), "Pay?") {
+		t.Error("confirm should answer no once the context is cancelled")
+	}
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/amount"
+	"github.com/lnbotdev/cli/internal/fees"
+	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/rates"
+)
+
+// configKeys are the settings 'lnbot config' manages, in display order.
+var configKeys = []string{"max-fee", "max-fee-percent", "max-fee-ppm", "max-fee-floor", "max-fee-ceiling", "rates-file", "rates-url", "unit", "fiat"}
+
+var configCmd = &cobra.Command{
+	Use:   "config <command>",
+	Short: "Show and change settings",
+	Long: `Show and change settings of the current (or --profile) profile.
+
+Settings:
+
+  max-fee          default routing fee limit in sats
+  max-fee-percent  default fee limit as a percentage of the amount
+  max-fee-ppm      default fee limit in parts per million of the amount
+  max-fee-floor    lowest fee limit a percentage or ppm may give, in sats
+  max-fee-ceiling  highest fee limit a percentage or ppm may give, in sats
+  rates-file       JSON file of BTC prices, for fiat amounts such as 5usd
+  rates-url        URL to fetch BTC prices from (cached for 10 minutes)
+  unit             unit balances and lists are shown in: sats, btc, msat or bits
+  fiat             currency to show alongside, e.g. usd (needs a rate source)
+
+max-fee, max-fee-percent and max-fee-ppm replace each other. The 'pay'
+flags of the same names override these defaults for one payment.
+LNBOT_RATES_FILE and LNBOT_RATES_URL override the rate settings, and
+--unit and --fiat the display settings.`,
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Show settings",
+	Long:  `Show every setting, or the value of one. Unset values are shown as "-" (empty with a key).`,
+	Example: `  lnbot config get
+  lnbot config get max-fee-percent
+  lnbot config get --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireConfig(); err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			v, err := configValue(args[0])
+			if err != nil {
+				return err
+			}
+			if jsonFlag {
+				return printOutput(map[string]any{args[0]: v})
+			}
+			if v != nil {
+				fmt.Println(v)
+			}
+			return nil
+		}
+
+		if jsonFlag {
+			out := map[string]any{}
+			for _, k := range configKeys {
+				out[k], _ = configValue(k)
+			}
+			return printOutput(out)
+		}
+		for _, k := range configKeys {
+			v, _ := configValue(k)
+			if v == nil {
+				v = "-"
+			}
+			fmt.Printf("  %-16s %v\n", k, v)
+		}
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Long:  `Change a setting of the current (or --profile) profile. Run 'lnbot config --help' for the list.`,
+	Example: `  lnbot config set max-fee-percent 0.5
+  lnbot config set max-fee-ceiling 2000
+  lnbot config set max-fee 20 --profile staging`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireConfig(); err != nil {
+			return err
+		}
+		if err := setConfigValue(args[0], args[1]); err != nil {
+			return err
+		}
+		return saveConfigValue(args[0], fmt.Sprintf("%s set to %s", args[0], args[1]))
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:     "unset <key>",
+	Short:   "Remove a setting",
+	Example: `  lnbot config unset max-fee-percent`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireConfig(); err != nil {
+			return err
+		}
+		if err := setConfigValue(args[0], ""); err != nil {
+			return err
+		}
+		return saveConfigValue(args[0], args[0]+" unset")
+	},
+}
+
+// configValue returns a setting of the selected profile, or nil if it is
+// unset.
+func configValue(key string) (any, error) {
+	var l fees.Limit
+	if cfg.FeeLimit != nil {
+		l = *cfg.FeeLimit
+	}
+	var v any
+	switch key {
+	case "max-fee":
+		v = l.Sats
+	case "max-fee-percent":
+		v = l.Percent
+	case "max-fee-ppm":
+		v = l.PPM
+	case "max-fee-floor":
+		v = l.Floor
+	case "max-fee-ceiling":
+		v = l.Ceiling
+	case "rates-file":
+		v = cfg.RatesFile
+	case "rates-url":
+		v = cfg.RatesURL
+	case "unit":
+		v = cfg.Unit
+	case "fiat":
+		v = cfg.Fiat
+	default:
+		return nil, fmt.Errorf("unknown setting %q — run 'lnbot config get' for the list", key)
+	}
+	if v == int64(0) || v == float64(0) || v == "" {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// setConfigValue parses and sets a setting; an empty value clears it.
+// Setting a fee limit replaces the other kinds of fee limit.
+func setConfigValue(key, value string) error {
+	if _, err := configValue(key); err != nil {
+		return err
+	}
+
+	switch key {
+	case "rates-file":
+		if value != "" {
+			path, err := filepath.Abs(value)
+			if err != nil {
+				return err
+			}
+			if _, err := (&rates.Source{File: path}).Quote(); err != nil {
+				return err
+			}
+			value = path
+		}
+		cfg.RatesFile = value
+		return nil
+	case "rates-url":
+		if value != "" {
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("invalid rates-url %q: expected an http(s) URL", value)
+			}
+		}
+		cfg.RatesURL = value
+		return nil
+	case "unit":
+		if value != "" {
+			u, err := format.ParseUnit(value)
+			if err != nil {
+				return err
+			}
+			value = string(u)
+		}
+		cfg.Unit = value
+		return nil
+	case "fiat":
+		if value != "" && !isCurrencyCode(value) {
+			return fmt.Errorf("invalid fiat %q: expected a currency code such as usd", value)
+		}
+		cfg.Fiat = strings.ToUpper(value)
+		return nil
+	}
+
+	var l fees.Limit
+	if cfg.FeeLimit != nil {
+		l = *cfg.FeeLimit
+	}
+	switch key {
+	case "max-fee-percent":
+		var pct float64
+		if value != "" {
+			var err error
+			if pct, err = strconv.ParseFloat(value, 64); err != nil || pct < 0 {
+				return fmt.Errorf("invalid %s %q: expected a percentage, e.g. 0.5", key, value)
+			}
+		}
+		if pct > 0 {
+			l.Sats, l.PPM = 0, 0
+		}
+		l.Percent = pct
+	case "max-fee-ppm":
+		var ppm int64
+		if value != "" {
+			var err error
+			if ppm, err = strconv.ParseInt(value, 10, 64); err != nil || ppm < 0 {
+				return fmt.Errorf("invalid %s %q: expected a whole number", key, value)
+			}
+		}
+		if ppm > 0 {
+			l.Sats, l.Percent = 0, 0
+		}
+		l.PPM = ppm
+	default:
+		a, err := amount.Parse(value, nil)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "max-fee":
+			if a.Sats > 0 {
+				l.Percent, l.PPM = 0, 0
+			}
+			l.Sats = a.Sats
+		case "max-fee-floor":
+			l.Floor = a.Sats
+		case "max-fee-ceiling":
+			l.Ceiling = a.Sats
+		}
+	}
+	if err := l.Validate(); err != nil {
+		return err
+	}
+	cfg.FeeLimit = &l
+	if l == (fees.Limit{}) {
+		cfg.FeeLimit = nil
+	}
+	return nil
+}
+
+// saveConfigValue saves the config after key was changed.
+func saveConfigValue(key, msg string) error {
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	v, _ := configValue(key)
+	if jsonFlag {
+		return printOutput(map[string]any{"profile": cfg.Name(), "key": key, "value": v})
+	}
+	printSuccess(msg)
+	if strings.HasPrefix(key, "max-fee") {
+		limit := fees.Limit{}
+		if cfg.FeeLimit != nil {
+			limit = *cfg.FeeLimit
+		}
+		fmt.Printf("  fee limit: %s\n", limit)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/bolt11"
+	"github.com/lnbotdev/cli/internal/format"
+)
+
+var decodeCmd = &cobra.Command{
+	Use:   "decode <invoice>",
+	Short: "Decode a BOLT11 invoice",
+	Long: `Decode a BOLT11 Lightning invoice locally, without contacting the API.
+
+Shows the network, amount, description, payment hash, expiry and route
+hints. The signature is not verified.`,
+	Example: `  lnbot decode lnbc2500u1pvjluez...
+  lnbot decode lightning:lnbc2500u1pvjluez... --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inv, err := bolt11.Decode(args[0])
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		if jsonFlag {
+			out := struct {
+				*bolt11.Invoice
+				Network       string    `json:"network"`
+				AmountSats    *int64    `json:"amountSats"`
+				ExpirySeconds int64     `json:"expirySeconds"`
+				ExpiresAt     time.Time `json:"expiresAt"`
+				Expired       bool      `json:"expired"`
+			}{
+				Invoice:       inv,
+				Network:       inv.Network(),
+				ExpirySeconds: int64(inv.Expiry / time.Second),
+				ExpiresAt:     inv.ExpiresAt(),
+				Expired:       inv.Expired(now),
+			}
+			if sats, ok := inv.AmountSats(); ok {
+				out.AmountSats = &sats
+			}
+			return printOutput(out)
+		}
+
+		fmt.Printf("  network:      %s\n", inv.Network())
+		fmt.Printf("  amount:       %s\n", invoiceAmount(inv))
+		if inv.Description != "" {
+			fmt.Printf("  description:  %s\n", inv.Description)
+		}
+		if inv.DescriptionHash != "" {
+			fmt.Printf("  desc hash:    %s\n", inv.DescriptionHash)
+		}
+		if inv.Payee != "" {
+			fmt.Printf("  payee:        %s\n", inv.Payee)
+		}
+		fmt.Printf("  payment hash: %s\n", inv.PaymentHash)
+		fmt.Printf("  created:      %s\n", inv.Timestamp.Local().Format(time.DateTime))
+		fmt.Printf("  expires:      %s (%s)\n", inv.ExpiresAt().Local().Format(time.DateTime), invoiceExpiry(inv, now))
+		fmt.Printf("  min cltv:     %d\n", inv.MinFinalCLTVExpiry)
+		for i, route := range inv.RouteHints {
+			fmt.Printf("  route hint %d:\n", i+1)
+			for _, h := range route {
+				fmt.Printf("    %s via %s  fee %d msat + %d ppm  cltv Δ%d\n",
+					format.Truncate(h.PubKey, 20), h.ShortChannelID,
+					h.FeeBaseMsat, h.FeeProportionalMillionths, h.CLTVExpiryDelta)
+			}
+		}
+		return nil
+	},
+}
+
+// invoiceAmount formats a decoded invoice amount, keeping sub-sat precision.
+func invoiceAmount(inv *bolt11.Invoice) string {
+	if inv.AmountMsat == nil {
+		return "any (payer chooses)"
+	}
+	msat := *inv.AmountMsat
+	if msat%1000 != 0 {
+		return fmt.Sprintf("%s (%s msat)", format.Sats(msat/1000), format.SatsPlain(msat))
+	}
+	return format.Sats(msat / 1000)
+}
+
+// invoiceExpiry describes whether a decoded invoice is still payable.
+func invoiceExpiry(inv *bolt11.Invoice, now time.Time) string {
+	if inv.Expired(now) {
+		at := inv.ExpiresAt()
+		return "expired " + format.TimeAgo(&at)
+	}
+	return "valid, expires " + format.TimeUntil(inv.ExpiresAt())
+}
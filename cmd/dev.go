@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/devserver"
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev <command>",
+	Short: "Local development tools",
+	Long:  `Tools for building against ln.bot without touching real money.`,
+	// Dev commands never read the config file.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cfg = nil
+		if err := setOutput(); err != nil {
+			return err
+		}
+		setTimeout(cmd)
+		return nil
+	},
+}
+
+func init() {
+	devServerCmd.Flags().String("addr", "127.0.0.1:8080", "address to listen on")
+	devServerCmd.Flags().String("balance", "100k", "starting balance of new wallets, e.g. 100000, 100k or 0.001btc")
+	devServerCmd.Flags().Duration("settle-after", 0, "settle invoices automatically after this delay (default: never)")
+	devServerCmd.Flags().String("domain", "ln.bot", "Lightning address domain")
+	devServerCmd.Flags().Bool("quiet", false, "don't log requests")
+
+	devCmd.AddCommand(devServerCmd)
+}
+
+var devServerCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Run a fake ln.bot API locally",
+	Long: `Run an in-memory implementation of the ln.bot API for offline development.
+
+The server supports accounts, wallets, invoices, payments, transactions,
+addresses, webhooks, events, key rotation and recovery. Nothing is
+persisted — state is lost when the server stops.
+
+Payments between wallets on the server settle instantly. Payments to
+anything else succeed with a small simulated fee, unless the target
+starts with "fail" (e.g. fail@example.com), which makes them fail.
+Invoices stay pending until paid by another wallet, settled with
+--settle-after, or settled by hand:
+
+  curl -X POST http://127.0.0.1:8080/dev/invoices/<bolt11|payment-hash>/settle
+
+Fund a wallet with:
+
+  curl -X POST http://127.0.0.1:8080/dev/wallets/<id>/fund -d '{"amount":50000}'
+
+Point the CLI at the server with --api-url, LNBOT_API_URL or a profile.`,
+	Example: `  lnbot dev server
+  lnbot dev server --addr :9000 --settle-after 2s
+  LNBOT_API_URL=http://127.0.0.1:8080 lnbot init --profile dev`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		balance, err := satsFlag(cmd, "balance")
+		if err != nil {
+			return err
+		}
+		settleAfter, _ := cmd.Flags().GetDuration("settle-after")
+		domain, _ := cmd.Flags().GetString("domain")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+
+		if balance < 0 {
+			return usageErrorf("--balance must not be negative")
+		}
+		if settleAfter < 0 {
+			return usageErrorf("--settle-after must not be negative")
+		}
+
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+
+		opts := devserver.Options{Domain: domain, StartingBalance: balance, SettleAfter: settleAfter}
+		if !quiet {
+			opts.Log = os.Stderr
+		}
+		dev := devserver.New(opts)
+		defer dev.Close()
+
+		url := "http://" + ln.Addr().String()
+
+		if jsonFlag {
+			if err := printOutput(map[string]string{"url": url}); err != nil {
+				ln.Close()
+				return err
+			}
+		} else {
+			printSuccess("Dev server listening on " + url)
+			fmt.Println()
+			fmt.Println("  Use it from another terminal:")
+			fmt.Printf("    export LNBOT_API_URL=%s\n", url)
+			fmt.Println("    lnbot init")
+			fmt.Println()
+			fmt.Println("  Or keep it as a profile:")
+			fmt.Printf("    lnbot profile add dev --api-url %s\n", url)
+			fmt.Println()
+			fmt.Println("  Press Ctrl+C to stop.")
+			fmt.Println()
+		}
+
+		srv := &http.Server{Handler: dev}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.Serve(ln) }()
+
+		select {
+		case err := <-errc:
+			return err
+		case <-cmd.Context().Done():
+		}
+
+		// Close the event streams first so Shutdown doesn't wait on them.
+		dev.Close()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if !jsonFlag {
+			fmt.Println()
+			fmt.Println("Stopped.")
+		}
+		return nil
+	},
+}
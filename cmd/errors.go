@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/output"
+	"github.com/lnbotdev/cli/internal/policy"
+)
+
+// Process exit codes. They are part of the CLI's interface — scripts branch
+// on them — so existing codes must not change meaning.
+const (
+	exitGeneral            = 1
+	exitUsage              = 2
+	exitAuth               = 3
+	exitInsufficientFunds  = 4
+	exitPaymentFailed      = 5
+	exitNotFound           = 6
+	exitNetwork            = 7
+	exitPolicyDenied       = 8
+	exitCancelled          = 9
+	exitSignatureInvalid   = 10
+	exitTimestampTolerance = 11
+	exitInvoiceExpired     = 12
+	exitInterrupted        = 130 // 128 + SIGINT, as shells report it
+)
+
+// errorCodes names each exit code in --json errors.
+var errorCodes = map[int]string{
+	exitGeneral:            "error",
+	exitUsage:              "usage",
+	exitAuth:               "auth",
+	exitInsufficientFunds:  "insufficient_balance",
+	exitPaymentFailed:      "payment_failed",
+	exitNotFound:           "not_found",
+	exitNetwork:            "network",
+	exitPolicyDenied:       "policy_denied",
+	exitCancelled:          "cancelled",
+	exitSignatureInvalid:   "signature_invalid",
+	exitTimestampTolerance: "timestamp_outside_tolerance",
+	exitInvoiceExpired:     "invoice_expired",
+	exitInterrupted:        "interrupted",
+}
+
+// exitError attaches a process exit code to an error.
+type exitError struct {
+	code int
+	err  error
+	// reported is set when the command has already shown the failure, such
+	// as a failed payment's result, so it isn't printed again as an error.
+	reported bool
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// errCancelled is returned when a confirmation prompt is declined, after
+// printing "Cancelled.".
+var errCancelled = &exitError{code: exitCancelled, err: errors.New("cancelled"), reported: true}
+
+// interrupted gives the error of a command stopped by Ctrl+C or SIGTERM
+// the interrupted exit code, keeping whether it has been reported.
+func interrupted(err error) error {
+	var ee *exitError
+	reported := errors.As(err, &ee) && ee.reported
+	if errors.Is(err, context.Canceled) {
+		err = errors.New("interrupted")
+	}
+	return &exitError{code: exitInterrupted, err: err, reported: reported}
+}
+
+// usageErrorf is an error in how the command was invoked, such as an
+// invalid flag value.
+func usageErrorf(format string, a ...any) error {
+	return &exitError{code: exitUsage, err: fmt.Errorf(format, a...)}
+}
+
+// usageErrors marks the usage errors cobra reports as plain errors —
+// unknown commands, wrong argument counts and missing required flags.
+// Flag parsing errors are marked by rootCmd's flag error func.
+func usageErrors(err error) error {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return err
+	}
+	for _, prefix := range []string{"unknown command ", "accepts ", "requires at least ", "invalid argument ", "required flag(s) "} {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return &exitError{code: exitUsage, err: err}
+		}
+	}
+	return err
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+
+	var apiErr *lnbot.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return exitAuth
+		case apiErr.StatusCode == http.StatusNotFound:
+			return exitNotFound
+		case apiErr.StatusCode == http.StatusPaymentRequired || strings.Contains(strings.ToLower(apiErr.Message), "insufficient balance"):
+			return exitInsufficientFunds
+		case apiErr.StatusCode == http.StatusBadGateway || apiErr.StatusCode == http.StatusServiceUnavailable || apiErr.StatusCode == http.StatusGatewayTimeout:
+			return exitNetwork
+		}
+		return exitGeneral
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return exitNetwork
+	}
+	return exitGeneral
+}
+
+// actionError is a failed API request, keeping what was being done for
+// --json errors.
+type actionError struct {
+	action string
+	err    error
+}
+
+func (e *actionError) Error() string {
+	var apiErr *lnbot.APIError
+	if errors.As(e.err, &apiErr) {
+		return e.action + ": " + apiErr.Message
+	}
+	return e.action + ": " + e.err.Error()
+}
+
+func (e *actionError) Unwrap() error { return e.err }
+
+// apiError describes a failed API request, e.g. apiError("creating
+// invoice", err).
+func apiError(action string, err error) error {
+	return &actionError{action: action, err: err}
+}
+
+// errorJSON is the body of an error printed under --json.
+type errorJSON struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Status is the HTTP status of a failed API request.
+	Status *int `json:"status"`
+	// Action is what was being done, e.g. "creating invoice".
+	Action *string `json:"action"`
+
+	// Spending policy denials add the rule that denied the payment.
+	Rule   string `json:"rule,omitempty"`
+	Limit  int64  `json:"limit,omitempty"`
+	Actual int64  `json:"actual,omitempty"`
+}
+
+func newErrorJSON(err error) errorJSON {
+	code := ExitCode(err)
+	e := errorJSON{Code: errorCodes[code], Message: err.Error()}
+	if e.Code == "" {
+		e.Code = errorCodes[exitGeneral]
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		e.Code = "timeout"
+	}
+
+	var apiErr *lnbot.APIError
+	if errors.As(err, &apiErr) {
+		e.Status = &apiErr.StatusCode
+	}
+	var ae *actionError
+	if errors.As(err, &ae) {
+		e.Action = &ae.action
+		e.Message = strings.TrimPrefix(e.Message, ae.action+": ")
+	}
+	var v *policy.Violation
+	if errors.As(err, &v) {
+		e.Rule, e.Limit, e.Actual = v.Rule, v.Limit, v.Actual
+	}
+	return e
+}
+
+// printError shows an error returned by a command: under --json (or any
+// machine-readable --output) as {"error": {...}} on stdout, otherwise as
+// a message on stderr.
+func printError(err error) {
+	var ee *exitError
+	if errors.As(err, &ee) && ee.reported {
+		return
+	}
+	if !machineOutput() {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return
+	}
+	json.NewEncoder(os.Stdout).Encode(map[string]errorJSON{"error": newErrorJSON(err)})
+}
+
+// machineOutput reports whether output is for programs. It reads the flags
+// directly as well, since usage errors can stop a command before
+// setOutput runs.
+func machineOutput() bool {
+	if jsonFlag || queryFlag != "" {
+		return true
+	}
+	f, err := output.ParseFormat(outputFlag)
+	return (err == nil && !f.Human()) || templateFlag != ""
+}
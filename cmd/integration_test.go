@@ -6,20 +6,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/lnbotdev/cli/internal/config"
+	"github.com/lnbotdev/cli/internal/devserver"
 )
 
-// Integration tests — run the CLI commands against the live API.
+// Integration tests — run the CLI commands against a real API.
 //
-// Required environment variables:
+// By default the tests run against an in-process dev server (see
+// internal/devserver) with a freshly funded wallet. To use the live API
+// instead, set:
 //   LNBOT_USER_KEY=uk_...   # user key that owns the prefunded wallet
 //   LNBOT_WALLET_ID=wal_... # prefunded wallet ID
 //
+// Optional:
+//   LNBOT_API_URL=http://... # run against a staging or stand-in API
+//
 // Run:
 //   go test -tags=integration -run TestInteg -v -count=1 -timeout=120s
 
@@ -32,13 +39,49 @@ var (
 func TestMain(m *testing.M) {
 	fundedUserKey = os.Getenv("LNBOT_USER_KEY")
 	fundedWalletID = os.Getenv("LNBOT_WALLET_ID")
+	stop := func() {}
 	if fundedUserKey == "" || fundedWalletID == "" {
-		fmt.Println("SKIP: LNBOT_USER_KEY and LNBOT_WALLET_ID required")
-		os.Exit(0)
+		var err error
+		if stop, err = startDevServer(); err != nil {
+			fmt.Fprintln(os.Stderr, "dev server:", err)
+			os.Exit(1)
+		}
 	}
 
 	os.Setenv("LNBOT_NO_UPDATE_CHECK", "1")
-	os.Exit(m.Run())
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// startDevServer runs the suite against an in-process dev server and
+// creates the funded account the tests expect.
+func startDevServer() (stop func(), err error) {
+	dev := devserver.New(devserver.Options{})
+	srv := httptest.NewServer(dev)
+	stop = func() {
+		dev.Close()
+		srv.Close()
+	}
+	os.Setenv("LNBOT_API_URL", srv.URL)
+
+	ctx := context.Background()
+	account, err := config.NewClient("", srv.URL).Register(ctx)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	wallet, err := config.NewClient(account.PrimaryKey, srv.URL).Wallets.Create(ctx)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	if err := dev.Fund(wallet.WalletID, 1_000_000); err != nil {
+		stop()
+		return nil, err
+	}
+	fundedUserKey, fundedWalletID = account.PrimaryKey, wallet.WalletID
+	return stop, nil
 }
 
 func integSetup(t *testing.T) {
@@ -690,7 +733,7 @@ func TestInteg_MCP_Config(t *testing.T) {
 	if !strings.Contains(stdout, fundedUserKey) {
 		t.Errorf("expected user key in output, got %q", stdout)
 	}
-	expectedURL := fmt.Sprintf("api.ln.bot/v1/wallets/%s/mcp", fundedWalletID)
+	expectedURL := fmt.Sprintf("%s/v1/wallets/%s/mcp", config.ResolveAPIURL(""), fundedWalletID)
 	if !strings.Contains(stdout, expectedURL) {
 		t.Errorf("expected MCP URL %q in output, got %q", expectedURL, stdout)
 	}
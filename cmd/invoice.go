@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 
@@ -11,28 +10,41 @@ import (
 	lnbot "github.com/lnbotdev/go-sdk"
 
 	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/output"
+	"github.com/lnbotdev/cli/internal/store"
 )
 
 var invoiceCmd = &cobra.Command{
 	Use:   "invoice <command>",
-	Short: "Create and list Lightning invoices",
-	Long: `Create invoices to receive sats, and list past invoices.
+	Short: "Create, list and watch Lightning invoices",
+	Long: `Create invoices to receive sats, list past invoices, and wait for one
+to be paid.
 
 When you create an invoice the CLI prints a QR code and waits for
-payment via Server-Sent Events. Press Ctrl+C to stop waiting.`,
+payment via Server-Sent Events. Press Ctrl+C to stop waiting, and pick
+the wait up again later with 'lnbot invoice watch <number>'.`,
 }
 
 func init() {
-	invoiceCreateCmd.Flags().Int64("amount", 0, "amount in sats (required)")
+	invoiceCreateCmd.Flags().String("amount", "", "amount to receive, "+amountUsage+" (required)")
 	invoiceCreateCmd.MarkFlagRequired("amount")
 	invoiceCreateCmd.Flags().String("memo", "", "short description attached to the invoice")
 	invoiceCreateCmd.Flags().Bool("no-wait", false, "return immediately without waiting for payment")
+	addWaitTimeoutFlag(invoiceCreateCmd)
+	addQRFlags(invoiceCreateCmd)
 
 	invoiceListCmd.Flags().Int("limit", 20, "max number of results")
 	invoiceListCmd.Flags().Int("after", 0, "show results after this invoice number (for pagination)")
+	invoiceListCmd.Flags().Bool("offline", false, "read from the local mirror without contacting the API (see 'lnbot sync')")
+	invoiceListCmd.Flags().String("search", "", "search the whole history for text in memos, references, statuses and invoices (needs 'lnbot sync')")
+	addDisplayFlags(invoiceListCmd)
+	addColumnsFlag(invoiceListCmd, invoiceColumns)
+
+	addWaitTimeoutFlag(invoiceWatchCmd)
 
 	invoiceCmd.AddCommand(invoiceCreateCmd)
 	invoiceCmd.AddCommand(invoiceListCmd)
+	invoiceCmd.AddCommand(invoiceWatchCmd)
 }
 
 var invoiceCreateCmd = &cobra.Command{
@@ -40,154 +52,273 @@ var invoiceCreateCmd = &cobra.Command{
 	Short: "Create a Lightning invoice to receive sats",
 	Long: `Create a new Lightning invoice for the given amount.
 
-Prints the BOLT11 string and automatically waits for the payment to
-settle via SSE. Use --no-wait to return immediately. The invoice
-remains valid until it expires.`,
+Prints the BOLT11 string and a QR code, then automatically waits for
+the payment to settle via SSE. Use --no-wait to return immediately, or
+--wait-timeout to stop waiting after a while. The invoice remains valid
+until it expires.
+
+The QR code is only drawn when stdout is a terminal. Use --qr png|svg
+with --out to save it as an image instead (this also works with --json).`,
 	Example: `  lnbot invoice create --amount 1000
-  lnbot invoice create --amount 5000 --memo "for coffee"
+  lnbot invoice create --amount 5k --memo "for coffee"
+  lnbot invoice create --amount 5usd
   lnbot invoice create --amount 100 --no-wait
+  lnbot invoice create --amount 100 --wait-timeout 10m
+  lnbot invoice create --amount 100 --qr png --out invoice.png
   lnbot invoice create --amount 100 --json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		amount, _ := cmd.Flags().GetInt64("amount")
-		if amount <= 0 {
-			return fmt.Errorf("--amount must be a positive integer")
+		amt, err := amountFlag(cmd, "amount")
+		if err != nil {
+			return err
+		}
+		if amt.Sats <= 0 {
+			return usageErrorf("--amount must be a positive integer number of sats")
 		}
 
 		memo, _ := cmd.Flags().GetString("memo")
 
-		w, err := resolveWallet()
+		qrKind, qrPath, err := qrFileFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		params := &lnbot.CreateInvoiceParams{Amount: amount}
+		params := &lnbot.CreateInvoiceParams{Amount: amt.Sats}
 		if memo != "" {
 			params.Memo = lnbot.Ptr(memo)
 		}
 
-		ctx := context.Background()
+		ctx := cmd.Context()
 		invoice, err := w.Invoices.Create(ctx, params)
 		if err != nil {
 			return apiError("creating invoice", err)
 		}
 
+		if qrPath != "" {
+			if err := writeQRFile(lightningURI(invoice.Bolt11), qrKind, qrPath); err != nil {
+				return err
+			}
+		}
+
 		noWait, _ := cmd.Flags().GetBool("no-wait")
 
+		waitCtx, cancel := waitContext(ctx, cmd)
+		defer cancel()
+
 		if jsonFlag {
 			if noWait {
-				return json.NewEncoder(os.Stdout).Encode(invoice)
-			}
-			events, errs := w.Invoices.Watch(ctx, invoice.Number, nil)
-			for {
-				select {
-				case ev, ok := <-events:
-					if !ok {
-						return json.NewEncoder(os.Stdout).Encode(invoice)
-					}
-					return json.NewEncoder(os.Stdout).Encode(ev.Data)
-				case err, ok := <-errs:
-					if ok && err != nil {
-						return json.NewEncoder(os.Stdout).Encode(invoice)
-					}
-					return json.NewEncoder(os.Stdout).Encode(invoice)
-				}
+				return printOutput(invoice)
 			}
+			return awaitInvoice(waitCtx, w, invoice)
 		}
 
-		fmt.Printf("  amount:  %s\n", format.Sats(invoice.Amount))
+		if amt.Fiat != "" {
+			fmt.Printf("  amount:  %s (%s)\n", format.Sats(invoice.Amount), amt.Fiat)
+		} else {
+			fmt.Printf("  amount:  %s\n", format.Sats(invoice.Amount))
+		}
 		fmt.Printf("  status:  %s\n", invoice.Status)
 		fmt.Println("  bolt11:")
 		fmt.Printf("  %s\n", invoice.Bolt11)
+		if qrPath != "" {
+			fmt.Printf("  qr:      saved to %s\n", qrPath)
+		}
 		fmt.Println()
+		printTerminalQR(lightningURI(invoice.Bolt11))
 
 		if noWait {
 			return nil
 		}
 
-		fmt.Print("  Waiting for payment... (Ctrl+C to stop)")
+		return awaitInvoice(waitCtx, w, invoice)
+	},
+}
+
+var invoiceWatchCmd = &cobra.Command{
+	Use:   "watch <number>",
+	Short: "Wait for an invoice to be paid",
+	Long: `Wait for an existing invoice to settle or expire.
+
+An invoice that is already settled or expired is reported straight away.
+The wait reconnects with backoff if the connection drops. Use
+--wait-timeout to give up after a while.
+
+Exits 0 once the invoice is paid, 12 if it expired, and 7 if waiting was
+stopped while it was still pending. With --json the final invoice is
+printed, or the pending one if waiting was stopped.`,
+	Example: `  lnbot invoice watch 12
+  lnbot invoice watch 12 --wait-timeout 10m
+  lnbot invoice watch 12 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		number, err := parseNumber("invoice", args[0])
+		if err != nil {
+			return err
+		}
+
+		w, err := resolveWallet(cmd.Context())
+		if err != nil {
+			return err
+		}
 
-		watchCtx, cancel := context.WithCancel(ctx)
+		ctx, cancel := waitContext(cmd.Context(), cmd)
 		defer cancel()
 
-		events, errs := w.Invoices.Watch(watchCtx, invoice.Number, nil)
-		for {
-			select {
-			case ev, ok := <-events:
-				if !ok {
-					fmt.Println()
-					return nil
-				}
-				switch ev.Event {
-				case "settled":
-					fmt.Println()
-					printSuccess(fmt.Sprintf("Payment received! +%s", format.Sats(invoice.Amount)))
-					return nil
-				case "expired":
-					fmt.Println()
-					fmt.Println("  Invoice expired.")
-					return nil
-				}
-			case err, ok := <-errs:
-				if ok && err != nil {
-					fmt.Println()
-					return err
-				}
-				return nil
+		invoice, err := w.Invoices.Get(ctx, number)
+		if err != nil {
+			return apiError("getting invoice", err)
+		}
+
+		if !jsonFlag {
+			fmt.Printf("  invoice: #%d\n", invoice.Number)
+			fmt.Printf("  amount:  %s\n", format.Sats(invoice.Amount))
+			if invoice.Memo != nil && *invoice.Memo != "" {
+				fmt.Printf("  memo:    %s\n", *invoice.Memo)
 			}
+			fmt.Printf("  status:  %s\n", invoice.Status)
+			fmt.Println()
 		}
+		return awaitInvoice(ctx, w, invoice)
 	},
 }
 
+// awaitInvoice waits for an invoice to settle or expire and prints the
+// outcome. An expired invoice exits with exitInvoiceExpired.
+func awaitInvoice(ctx context.Context, w *lnbot.WalletHandle, invoice *lnbot.Invoice) error {
+	if jsonFlag {
+		inv, err := waitForInvoice(ctx, w, invoice)
+		if err != nil && ctx.Err() == nil {
+			return apiError("watching invoice", err)
+		}
+		if err := printOutput(inv); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return stoppedWaiting(ctx, "invoice", inv.Number)
+		}
+		return invoiceExpired(inv)
+	}
+
+	if !invoiceFinal(invoice) {
+		fmt.Print("  Waiting for payment... (Ctrl+C to stop)")
+		inv, err := waitForInvoice(ctx, w, invoice)
+		fmt.Println()
+		if ctx.Err() != nil {
+			return stoppedWaiting(ctx, "invoice", invoice.Number)
+		}
+		if err != nil {
+			return apiError("watching invoice", err)
+		}
+		invoice = inv
+	}
+	switch invoice.Status {
+	case "settled":
+		printSuccess(fmt.Sprintf("Payment received! +%s", format.Sats(invoice.Amount)))
+	case "expired":
+		fmt.Fprintln(os.Stderr, "✗ Invoice expired unpaid")
+	}
+	return invoiceExpired(invoice)
+}
+
+// invoiceExpired returns an exit error for an invoice whose result has been
+// printed, if it expired.
+func invoiceExpired(invoice *lnbot.Invoice) error {
+	if invoice.Status != "expired" {
+		return nil
+	}
+	return &exitError{code: exitInvoiceExpired, err: fmt.Errorf("invoice #%d expired", invoice.Number), reported: true}
+}
+
 var invoiceListCmd = &cobra.Command{
 	Use:     "list",
 	Short:   "List invoices",
 	Aliases: []string{"ls"},
-	Long:    `Show recent invoices for the active wallet, newest first.`,
+	Long: `Show recent invoices for the active wallet, newest first.
+
+Once the wallet has been synced ('lnbot sync'), results come from the
+local mirror after fetching anything new. --offline skips the network
+and --search searches the whole history.`,
 	Example: `  lnbot invoice list
   lnbot invoice list --limit 5
   lnbot invoice list --after 20
-  lnbot invoice list --json`,
+  lnbot invoice list --json
+  lnbot invoice list --offline --search rent
+  lnbot invoice list -o wide
+  lnbot invoice list -o csv --columns number,status,amount,memo`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		limit, _ := cmd.Flags().GetInt("limit")
 		after, _ := cmd.Flags().GetInt("after")
+		offline, _ := cmd.Flags().GetBool("offline")
+		search, _ := cmd.Flags().GetString("search")
+		if err := applyDisplay(cmd); err != nil {
+			return err
+		}
 
-		w, err := resolveWallet()
+		local, err := localStore(cmd.Context(), offline, search != "")
 		if err != nil {
 			return err
 		}
 
-		params := &lnbot.ListInvoicesParams{Limit: lnbot.Ptr(limit)}
-		if after > 0 {
-			params.After = lnbot.Ptr(after)
-		}
+		var invoices []lnbot.Invoice
+		if local != nil {
+			invoices = local.ListInvoices(store.Query{Limit: limit, After: after, Search: search})
+		} else {
+			w, err := resolveWallet(cmd.Context())
+			if err != nil {
+				return err
+			}
 
-		invoices, err := w.Invoices.List(context.Background(), params)
-		if err != nil {
-			return apiError("listing invoices", err)
+			params := &lnbot.ListInvoicesParams{Limit: lnbot.Ptr(limit)}
+			if after > 0 {
+				params.After = lnbot.Ptr(after)
+			}
+
+			invoices, err = w.Invoices.List(cmd.Context(), params)
+			if err != nil {
+				return apiError("listing invoices", err)
+			}
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(invoices)
+			return printRows(cmd, invoices, invoiceColumns)
 		}
 
 		if len(invoices) == 0 {
-			fmt.Println("No invoices yet.")
+			if search != "" {
+				fmt.Println("No matching invoices.")
+			} else {
+				fmt.Println("No invoices yet.")
+			}
 			return nil
 		}
 
-		for _, inv := range invoices {
-			fmt.Printf("  #%4d  %-8s  %10s sats  %s\n",
-				inv.Number,
-				inv.Status,
-				format.SatsPlain(inv.Amount),
-				format.TimeAgo(inv.CreatedAt),
-			)
+		if err := printRows(cmd, invoices, invoiceColumns); err != nil {
+			return err
 		}
-
 		if len(invoices) == limit {
 			last := invoices[len(invoices)-1].Number
 			fmt.Printf("\n  %d shown — next page: --after %d\n", limit, last)
 		}
+		if offline {
+			printSyncedAt(local)
+		}
 		return nil
 	},
 }
+
+// invoiceColumns are the columns of 'invoice list'.
+var invoiceColumns = []output.Column[lnbot.Invoice]{
+	{Name: "number", Right: true, Text: func(inv lnbot.Invoice) string { return fmt.Sprintf("#%d", inv.Number) }, Value: func(inv lnbot.Invoice) any { return inv.Number }},
+	{Name: "status", Text: func(inv lnbot.Invoice) string { return inv.Status }},
+	amountColumn(func(inv lnbot.Invoice) int64 { return inv.Amount }),
+	fiatColumn(func(inv lnbot.Invoice) int64 { return inv.Amount }),
+	{Name: "created", Text: func(inv lnbot.Invoice) string { return format.TimeAgo(inv.CreatedAt) }, Value: func(inv lnbot.Invoice) any { return inv.CreatedAt }},
+	{Name: "memo", Wide: true, Text: func(inv lnbot.Invoice) string { return optional(inv.Memo) }, Value: func(inv lnbot.Invoice) any { return inv.Memo }},
+	{Name: "reference", Wide: true, Text: func(inv lnbot.Invoice) string { return optional(inv.Reference) }, Value: func(inv lnbot.Invoice) any { return inv.Reference }},
+	{Name: "settled", Wide: true, Text: func(inv lnbot.Invoice) string { return format.TimeAgo(inv.SettledAt) }, Value: func(inv lnbot.Invoice) any { return inv.SettledAt }},
+	{Name: "expires", Wide: true, Text: func(inv lnbot.Invoice) string { return timeUntil(inv.ExpiresAt) }, Value: func(inv lnbot.Invoice) any { return inv.ExpiresAt }},
+}
@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 	"strconv"
 
 	"github.com/spf13/cobra"
@@ -36,7 +33,7 @@ var keyShowCmd = &cobra.Command{
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(map[string]string{
+			return printOutput(map[string]string{
 				"primary_key":   cfg.PrimaryKey,
 				"secondary_key": cfg.SecondaryKey,
 			})
@@ -79,13 +76,13 @@ The new key is printed once — save it. The local config is updated automatical
 		}
 
 		if !yesFlag {
-			if !confirm(fmt.Sprintf("Rotate %s key? The old key will stop working.", slotLabel)) {
+			if !confirm(cmd.Context(), fmt.Sprintf("Rotate %s key? The old key will stop working.", slotLabel)) {
 				fmt.Println("Cancelled.")
-				return nil
+				return errCancelled
 			}
 		}
 
-		rotated, err := cfg.Client().Keys.Rotate(context.Background(), slot)
+		rotated, err := cfg.Client().Keys.Rotate(cmd.Context(), slot)
 		if err != nil {
 			return apiError("rotating key", err)
 		}
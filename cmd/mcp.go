@@ -1,68 +1,90 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/bolt11"
+	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/mcp"
 )
 
 var mcpCmd = &cobra.Command{
 	Use:   "mcp <command>",
-	Short: "MCP server config for AI agents",
-	Long: `Generate configuration for the Model Context Protocol (MCP) server.
+	Short: "MCP server for AI agents",
+	Long: `Run a local Model Context Protocol (MCP) server, or generate the
+configuration for one.
 
 MCP lets AI agents (Claude, Cursor, etc.) use your wallet. The 'config'
-command prints JSON you paste into your MCP client settings.`,
+command prints JSON you paste into your MCP client settings. By default
+it points the client at 'lnbot mcp serve', which keeps your API key in
+the local lnbot config instead of the client config.`,
 }
 
 func init() {
-	mcpConfigCmd.Flags().Bool("remote", false, "generate remote config (required for now)")
+	mcpConfigCmd.Flags().Bool("remote", false, "generate config for the hosted endpoint instead of the local server")
+
+	mcpServeCmd.Flags().Bool("read-only", false, "only expose tools that read the wallet (no create_invoice or pay)")
 
 	mcpCmd.AddCommand(mcpConfigCmd)
 	mcpCmd.AddCommand(mcpServeCmd)
 }
 
 var mcpConfigCmd = &cobra.Command{
-	Use:   "config --remote",
+	Use:   "config",
 	Short: "Print MCP server configuration JSON",
 	Long: `Print the JSON config block to add to your MCP client (Claude Desktop,
 Cursor, etc).
 
-Currently only --remote is supported, which uses the hosted endpoint.
-Local stdio mode is coming soon.`,
-	Example: `  lnbot mcp config --remote`,
+Without flags the config launches 'lnbot mcp serve' over stdio, so the
+client never sees your API key. Use --remote for the hosted endpoint,
+which requires the key in the client config.`,
+	Example: `  lnbot mcp config
+  lnbot mcp config --wallet agent01
+  lnbot mcp config --remote`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		remote, _ := cmd.Flags().GetBool("remote")
 
-		if !remote {
-			fmt.Println("Local MCP server is not available yet.")
-			fmt.Println()
-			fmt.Println("  Use --remote for the hosted endpoint:")
-			fmt.Println("    lnbot mcp config --remote")
-			return nil
-		}
-
-		walletID, err := resolveWalletID()
+		walletID, err := resolveWalletID(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		config := map[string]any{
-			"mcpServers": map[string]any{
-				"lnbot": map[string]any{
-					"type": "url",
-					"url":  fmt.Sprintf("https://api.ln.bot/v1/wallets/%s/mcp", walletID),
-					"headers": map[string]string{
-						"Authorization": "Bearer " + cfg.PrimaryKey,
-					},
+		var server map[string]any
+		if remote {
+			server = map[string]any{
+				"type": "url",
+				"url":  fmt.Sprintf("%s/v1/wallets/%s/mcp", cfg.BaseURL(), walletID),
+				"headers": map[string]string{
+					"Authorization": "Bearer " + cfg.PrimaryKey,
 				},
-			},
+			}
+		} else {
+			args := []string{"mcp", "serve", "--wallet", walletID}
+			if cfg.CustomEndpoint() {
+				args = append(args, "--api-url", cfg.BaseURL())
+			}
+			server = map[string]any{
+				"command": "lnbot",
+				"args":    args,
+			}
 		}
 
-		fmt.Println("Add to your MCP client config:")
-		fmt.Println()
+		config := map[string]any{
+			"mcpServers": map[string]any{"lnbot": server},
+		}
+
+		if !jsonFlag {
+			fmt.Println("Add to your MCP client config:")
+			fmt.Println()
+		}
 
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
@@ -72,13 +94,266 @@ Local stdio mode is coming soon.`,
 
 var mcpServeCmd = &cobra.Command{
 	Use:   "serve",
-	Short: "Start local MCP server (coming soon)",
-	Long:  `Start a local MCP server over stdio. This is not available yet.`,
+	Short: "Start a local MCP server over stdio",
+	Long: `Start a local MCP server that speaks JSON-RPC over stdin/stdout.
+
+The server exposes wallet tools backed by your local config: get_balance,
+create_invoice, pay, list_transactions and list_addresses. Use --wallet
+to pin the server to a specific wallet, --read-only to hide every tool
+that writes (create_invoice and pay), and --json to return raw API
+objects instead of text.
+Payments made through the pay tool work like 'lnbot pay': they are
+subject to the wallet's spending policy ('lnbot policy') and default fee
+limit ('lnbot config'), and an invoice is never paid twice.
+
+This command is meant to be launched by an MCP client — see
+'lnbot mcp config'.`,
+	Example: `  lnbot mcp serve
+  lnbot mcp serve --wallet agent01 --read-only`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fmt.Println("Local MCP server is not available yet.")
-		fmt.Println()
-		fmt.Println("  Use the remote endpoint instead:")
-		fmt.Println("    lnbot mcp config --remote")
-		return nil
+		readOnly, _ := cmd.Flags().GetBool("read-only")
+
+		srv := mcp.NewServer("lnbot", version)
+		registerMCPTools(srv, readOnly)
+
+		return srv.Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
 	},
 }
+
+// registerMCPTools adds the wallet tools to srv. The wallet is resolved on
+// first use so that the server can start (and answer initialize/tools/list)
+// even before the config is usable.
+func registerMCPTools(srv *mcp.Server, readOnly bool) {
+	var handle *lnbot.WalletHandle
+	wallet := func(ctx context.Context) (*lnbot.WalletHandle, error) {
+		if handle != nil {
+			return handle, nil
+		}
+		w, err := resolveWallet(ctx)
+		if err != nil {
+			return nil, err
+		}
+		handle = w
+		return w, nil
+	}
+
+	srv.AddTool(&mcp.Tool{
+		Name:        "get_balance",
+		Description: "Get the wallet balance, available amount and on-hold amount in sats.",
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			w, err := wallet(ctx)
+			if err != nil {
+				return "", err
+			}
+			wal, err := w.Get(ctx)
+			if err != nil {
+				return "", apiError("fetching balance", err)
+			}
+			return mcpText(wal, fmt.Sprintf("balance: %s\navailable: %s\non hold: %s",
+				format.Sats(wal.Balance), format.Sats(wal.Available), format.Sats(wal.OnHold)))
+		},
+	})
+
+	if !readOnly {
+		srv.AddTool(&mcp.Tool{
+			Name:        "create_invoice",
+			Description: "Create a Lightning invoice to receive sats. Returns the BOLT11 string.",
+			InputSchema: mcpSchema([]string{"amount"}, map[string]any{
+				"amount": map[string]any{"type": "integer", "description": "amount in sats"},
+				"memo":   map[string]any{"type": "string", "description": "short description attached to the invoice"},
+			}),
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var in struct {
+					Amount int64  `json:"amount"`
+					Memo   string `json:"memo"`
+				}
+				if err := json.Unmarshal(args, &in); err != nil {
+					return "", fmt.Errorf("invalid arguments: %w", err)
+				}
+				if in.Amount <= 0 {
+					return "", fmt.Errorf("amount must be a positive integer")
+				}
+				w, err := wallet(ctx)
+				if err != nil {
+					return "", err
+				}
+				params := &lnbot.CreateInvoiceParams{Amount: in.Amount}
+				if in.Memo != "" {
+					params.Memo = lnbot.Ptr(in.Memo)
+				}
+				inv, err := w.Invoices.Create(ctx, params)
+				if err != nil {
+					return "", apiError("creating invoice", err)
+				}
+				return mcpText(inv, fmt.Sprintf("invoice #%d for %s\nbolt11: %s",
+					inv.Number, format.Sats(inv.Amount), inv.Bolt11))
+			},
+		})
+
+		srv.AddTool(&mcp.Tool{
+			Name: "pay",
+			Description: "Send sats to a Lightning address (user@domain), LNURL, or BOLT11 invoice. " +
+				"Amount is required for addresses and LNURLs. Waits for the payment to settle.",
+			InputSchema: mcpSchema([]string{"target"}, map[string]any{
+				"target":  map[string]any{"type": "string", "description": "Lightning address, LNURL, or BOLT11 invoice"},
+				"amount":  map[string]any{"type": "integer", "description": "amount in sats"},
+				"max_fee": map[string]any{"type": "integer", "description": "maximum routing fee in sats"},
+			}),
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var in struct {
+					Target string `json:"target"`
+					Amount int64  `json:"amount"`
+					MaxFee int64  `json:"max_fee"`
+				}
+				if err := json.Unmarshal(args, &in); err != nil {
+					return "", fmt.Errorf("invalid arguments: %w", err)
+				}
+				if strings.TrimSpace(in.Target) == "" {
+					return "", fmt.Errorf("target is required")
+				}
+				var inv *bolt11.Invoice
+				if bolt11.IsInvoice(in.Target) {
+					var err error
+					if inv, err = checkInvoice(in.Target); err != nil {
+						return "", err
+					}
+				}
+				params := &lnbot.CreatePaymentParams{Target: in.Target}
+				if in.Amount > 0 {
+					params.Amount = lnbot.Ptr(in.Amount)
+				}
+				w, err := wallet(ctx)
+				if err != nil {
+					return "", err
+				}
+				// Like 'lnbot pay': the profile's default fee limit applies
+				// unless max_fee is given.
+				limit := defaultFeeLimit()
+				if in.MaxFee > 0 {
+					limit.Sats, limit.Percent, limit.PPM = in.MaxFee, 0, 0
+				}
+				if fee, ok := limit.For(paymentAmount(inv, in.Amount)); ok {
+					params.MaxFee = lnbot.Ptr(fee)
+				}
+
+				payment, hooks, err := journalPayment(ctx, w, params, "", inv)
+				if err != nil {
+					return "", err
+				}
+				if payment == nil {
+					if err := enforcePolicy(ctx, w, params); err != nil {
+						return "", err
+					}
+					if payment, _, err = sendPayment(ctx, w, params, retryPlan{}, hooks); err != nil {
+						return "", err
+					}
+				}
+				if pending(payment) {
+					latest, err := waitForPayment(ctx, w, payment)
+					if err != nil {
+						return "", fmt.Errorf("payment #%d is still pending: %w", payment.Number, err)
+					}
+					payment = latest
+				}
+				if payment.Status == "failed" {
+					reason := "unknown"
+					if payment.FailureReason != nil {
+						reason = *payment.FailureReason
+					}
+					return "", fmt.Errorf("payment #%d failed: %s", payment.Number, reason)
+				}
+				return mcpText(payment, fmt.Sprintf("payment #%d %s: %s",
+					payment.Number, payment.Status, format.Sats(payment.Amount)))
+			},
+		})
+	}
+
+	srv.AddTool(&mcp.Tool{
+		Name:        "list_transactions",
+		Description: "List wallet transactions (credits and debits), newest first.",
+		InputSchema: mcpSchema(nil, map[string]any{
+			"limit": map[string]any{"type": "integer", "description": "max number of results (default 20)"},
+			"after": map[string]any{"type": "integer", "description": "only return transactions before this number (for pagination)"},
+		}),
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			var in struct {
+				Limit int `json:"limit"`
+				After int `json:"after"`
+			}
+			if err := json.Unmarshal(args, &in); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			if in.Limit <= 0 {
+				in.Limit = 20
+			}
+			w, err := wallet(ctx)
+			if err != nil {
+				return "", err
+			}
+			params := &lnbot.ListTransactionsParams{Limit: lnbot.Ptr(in.Limit)}
+			if in.After > 0 {
+				params.After = lnbot.Ptr(in.After)
+			}
+			txs, err := w.Transactions.List(ctx, params)
+			if err != nil {
+				return "", apiError("listing transactions", err)
+			}
+			if len(txs) == 0 {
+				return mcpText(txs, "No transactions yet.")
+			}
+			var b strings.Builder
+			for _, tx := range txs {
+				sign := "+"
+				if tx.Type == "debit" {
+					sign = "-"
+				}
+				fmt.Fprintf(&b, "#%d %s %s%s sats (balance %s)\n",
+					tx.Number, tx.Type, sign, format.SatsPlain(tx.Amount), format.SatsPlain(tx.BalanceAfter))
+			}
+			return mcpText(txs, strings.TrimRight(b.String(), "\n"))
+		},
+	})
+
+	srv.AddTool(&mcp.Tool{
+		Name:        "list_addresses",
+		Description: "List the wallet's Lightning addresses.",
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			w, err := wallet(ctx)
+			if err != nil {
+				return "", err
+			}
+			addrs, err := w.Addresses.List(ctx)
+			if err != nil {
+				return "", apiError("listing addresses", err)
+			}
+			if len(addrs) == 0 {
+				return mcpText(addrs, "No addresses yet.")
+			}
+			lines := make([]string, len(addrs))
+			for i, a := range addrs {
+				lines[i] = a.Address
+			}
+			return mcpText(addrs, strings.Join(lines, "\n"))
+		},
+	})
+}
+
+// mcpText returns v as JSON when --json is set, otherwise the human summary.
+func mcpText(v any, human string) (string, error) {
+	if !jsonFlag {
+		return human, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func mcpSchema(required []string, props map[string]any) map[string]any {
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
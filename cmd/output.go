@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/output"
+	"github.com/lnbotdev/cli/internal/query"
+)
+
+var (
+	outputFlag   string
+	templateFlag string
+	queryFlag    string
+
+	// outputFormat is the format chosen with --output, --json, --template
+	// or --query. Every format but table and wide also sets jsonFlag, so
+	// commands skip prompts and progress output for all of them.
+	outputFormat = output.Table
+)
+
+func addOutputFlags(cmd *cobra.Command) {
+	names := make([]string, len(output.Formats))
+	for i, f := range output.Formats {
+		names[i] = string(f)
+	}
+	cmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", "", "output format: "+strings.Join(names, ", ")+" (default: table)")
+	cmd.PersistentFlags().StringVar(&templateFlag, "template", "", "Go template for each result, e.g. '{{.Amount}}' (implies --output template)")
+	cmd.PersistentFlags().StringVar(&queryFlag, "query", "", "JMESPath expression to select from the JSON result, e.g. 'available' (implies --json)")
+}
+
+// setOutput checks --output, --json, --template and --query.
+func setOutput() error {
+	f, err := output.ParseFormat(outputFlag)
+	if err != nil {
+		return usageErrorf("--output: %w", err)
+	}
+	if templateFlag != "" && outputFlag == "" {
+		f = output.Template
+	}
+	switch {
+	case jsonFlag && outputFlag != "" && f != output.JSON:
+		return usageErrorf("--json can't be combined with --output %s", f)
+	case f == output.Template && templateFlag == "":
+		return usageErrorf("--output template needs --template")
+	case templateFlag != "" && f != output.Template:
+		return usageErrorf("--template can't be combined with --output %s", f)
+	}
+	if templateFlag != "" {
+		if err := output.CheckTemplate(templateFlag); err != nil {
+			return &exitError{code: exitUsage, err: err}
+		}
+	}
+	if queryFlag != "" {
+		if _, err := query.Compile(queryFlag); err != nil {
+			return &exitError{code: exitUsage, err: err}
+		}
+	}
+
+	if jsonFlag || (queryFlag != "" && f.Human()) {
+		f = output.JSON
+	}
+	if !f.Human() {
+		jsonFlag = true
+	}
+	outputFormat = f
+	return nil
+}
+
+func outputOptions(columns []string) output.Options {
+	return output.Options{Format: outputFormat, Template: templateFlag, Columns: columns, Query: queryFlag}
+}
+
+// printOutput writes a command's result in the --output format. Commands
+// call it where they would print JSON.
+func printOutput(v any) error {
+	return output.Value(os.Stdout, outputOptions(nil), v)
+}
+
+// printEvent writes one result of a stream, such as 'lnbot watch'. YAML
+// documents are separated with "---"; the other formats write one result
+// per line (or per template execution) already.
+func printEvent(v any) error {
+	if outputFormat == output.YAML {
+		fmt.Println("---")
+	}
+	return printOutput(v)
+}
+
+// addColumnsFlag adds --columns to a list command.
+func addColumnsFlag[T any](cmd *cobra.Command, cols []output.Column[T]) {
+	cmd.Flags().StringSlice("columns", nil, "columns to show, in order: "+strings.Join(output.ColumnNames(cols), ", "))
+}
+
+// printRows writes a listing in the --output format, with the columns
+// chosen with --columns.
+func printRows[T any](cmd *cobra.Command, rows []T, cols []output.Column[T]) error {
+	columns, _ := cmd.Flags().GetStringSlice("columns")
+	return output.Rows(os.Stdout, outputOptions(columns), rows, cols)
+}
+
+// optional is a column's text for an optional string.
+func optional(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// timeUntil is a column's text for an optional time that may be in the
+// future, such as an expiry.
+func timeUntil(t *time.Time) string {
+	if t == nil {
+		return "--"
+	}
+	return format.TimeUntil(*t)
+}
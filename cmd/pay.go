@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -12,7 +12,12 @@ import (
 
 	lnbot "github.com/lnbotdev/go-sdk"
 
+	"github.com/lnbotdev/cli/internal/amount"
+	"github.com/lnbotdev/cli/internal/bolt11"
+	"github.com/lnbotdev/cli/internal/config"
+	"github.com/lnbotdev/cli/internal/fees"
 	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/journal"
 )
 
 var payCmd = &cobra.Command{
@@ -22,9 +27,36 @@ var payCmd = &cobra.Command{
 
   - A Lightning address (user@domain) — requires --amount
   - An LNURL (lnurl1...) — requires --amount
-  - A BOLT11 invoice (starts with lnbc/lntb/lnbs) — amount is encoded
+  - A BOLT11 invoice (starts with lnbc/lntb/lntbs/lnbcrt) — amount is encoded
+
+BOLT11 invoices are decoded locally first: expired invoices are rejected
+with exit code 12 without contacting the API, and the confirmation
+prompt shows the amount, memo and expiry ('lnbot decode' prints the
+full invoice).
+
+Every payment to a BOLT11 invoice carries an idempotency key derived from
+its payment hash; pass --idempotency-key to set one for any target. The
+key and the payment it produced are recorded in a local journal, so
+running the same command again — after a crash or a killed process —
+prints the original payment's status instead of paying twice. A derived
+key whose payment failed may be paid again.
+
+The routing fee limit is --max-fee in sats, or a share of the amount with
+--max-fee-percent or --max-fee-ppm, bounded by --max-fee-floor and
+--max-fee-ceiling. Without these flags the profile's default from 'lnbot
+config' applies, and failing that the API's. The confirmation prompt
+shows the resulting limit.
+
+--retry N tries a payment again when it fails for a reason that may not
+last (no route, a timeout, a fee above the limit), waiting --retry-backoff
+before the first retry and twice as long before each next one. Failures
+that would repeat — an expired invoice, an insufficient balance — are not
+retried. --max-fee-steps raises the fee limit with each attempt. Every
+attempt is reported, and listed under "attempts" with --json.
 
 A confirmation prompt is shown before sending. Use --yes to skip it.
+If the wallet has a spending policy ('lnbot policy'), it is checked
+first and violations exit with code 8.
 The CLI waits for settlement via SSE. Use --no-wait to return immediately.`,
 	Example: `  # Pay a Lightning address
   lnbot pay alice@ln.bot --amount 1000
@@ -39,92 +71,524 @@ The CLI waits for settlement via SSE. Use --no-wait to return immediately.`,
   lnbot pay alice@ln.bot --amount 500 --no-wait
 
   # Skip the confirmation prompt
-  lnbot pay alice@ln.bot --amount 500 --yes`,
+  lnbot pay alice@ln.bot --amount 500 --yes
+
+  # Allow at most 0.5% in fees, but never less than 5 sats
+  lnbot pay alice@ln.bot --amount 20000 --max-fee-percent 0.5 --max-fee-floor 5
+
+  # Retry routing failures, allowing a higher fee each time
+  lnbot pay alice@ln.bot --amount 50000 --max-fee-steps 10,50,200 --retry 3
+
+  # Safe to retry: a second run reports the first payment
+  lnbot pay alice@ln.bot --amount 500 --idempotency-key order-1042`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		target := args[0]
 		params := &lnbot.CreatePaymentParams{Target: target}
 
 		lower := strings.ToLower(target)
-		isBolt11 := strings.HasPrefix(lower, "lnbc") ||
-			strings.HasPrefix(lower, "lntb") ||
-			strings.HasPrefix(lower, "lnbs")
+		isBolt11 := bolt11.IsInvoice(target)
 		isAddress := strings.Contains(target, "@")
 		isLNURL := strings.HasPrefix(lower, "lnurl")
 
-		amount, _ := cmd.Flags().GetInt64("amount")
-		maxFee, _ := cmd.Flags().GetInt64("max-fee")
+		var inv *bolt11.Invoice
+		if isBolt11 {
+			target = strings.TrimPrefix(strings.TrimPrefix(target, "lightning:"), "LIGHTNING:")
+			params.Target = target
+
+			var err error
+			if inv, err = checkInvoice(target); err != nil {
+				return err
+			}
+		}
+
+		amt, err := amountFlag(cmd, "amount")
+		if err != nil {
+			return err
+		}
+		amount := amt.Sats
 
 		if amount > 0 {
 			params.Amount = lnbot.Ptr(amount)
 		} else if isAddress || isLNURL {
-			return fmt.Errorf("--amount is required when paying a Lightning address or LNURL\n\n  lnbot pay %s --amount <sats>", format.Truncate(target, 40))
+			return usageErrorf("--amount is required when paying a Lightning address or LNURL\n\n  lnbot pay %s --amount <sats>", format.Truncate(target, 40))
+		} else if inv != nil && inv.AmountMsat == nil {
+			return usageErrorf("--amount is required when paying an invoice without an amount\n\n  lnbot pay %s --amount <sats>", format.Truncate(target, 40))
 		}
 
-		if maxFee > 0 {
-			params.MaxFee = lnbot.Ptr(maxFee)
+		limit, err := feeLimitFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if fee, ok := limit.For(paymentAmount(inv, amount)); ok {
+			params.MaxFee = lnbot.Ptr(fee)
+		}
+
+		plan, err := retryPlanFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if inv != nil {
+			plan.invoice = target
+		}
+		if len(plan.feeSteps) > 0 {
+			// The policy is checked against the highest fee we may pay.
+			params.MaxFee = lnbot.Ptr(plan.feeSteps[len(plan.feeSteps)-1])
 		}
 
 		if !isBolt11 && !isAddress && !isLNURL {
-			return fmt.Errorf("unrecognized target: %s\n\nTarget must be a Lightning address (user@domain), LNURL (lnurl1...), or BOLT11 invoice (lnbc...)", format.Truncate(target, 40))
+			return usageErrorf("unrecognized target: %s\n\nTarget must be a Lightning address (user@domain), LNURL (lnurl1...), or BOLT11 invoice (lnbc...)", format.Truncate(target, 40))
 		}
 
-		w, err := resolveWallet()
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
+		ctx := cmd.Context()
+		noWait, _ := cmd.Flags().GetBool("no-wait")
+		if noWait && plan.attempts > 1 {
+			return usageErrorf("--retry and --max-fee-steps need to wait for the result — drop --no-wait")
+		}
+
+		key, _ := cmd.Flags().GetString("idempotency-key")
+		sent, hooks, err := journalPayment(ctx, w, params, key, inv)
+		if err != nil {
+			return err
+		}
+		if sent != nil {
+			if !jsonFlag {
+				printWarning(fmt.Sprintf("Already sent as payment #%d — not paying again", sent.Number))
+			}
+			return printPayment(ctx, w, sent, noWait, time.Time{})
+		}
+
+		if err := enforcePolicy(ctx, w, params); err != nil {
+			return err
+		}
+
 		if !yesFlag {
 			desc := format.Truncate(target, 50)
-			if amount > 0 {
-				if !confirm(fmt.Sprintf("Send %s to %s?", format.Sats(amount), desc)) {
+			feeNote := describeMaxFee(params, limit, plan)
+			if inv != nil {
+				printInvoicePreview(inv, amount, amt.Fiat)
+				if feeNote != "" {
+					fmt.Printf("  max fee: %s\n", feeNote)
+				}
+				if !confirm(cmd.Context(), "Pay this invoice?") {
+					fmt.Println("Cancelled.")
+					return errCancelled
+				}
+			} else if amount > 0 {
+				if feeNote != "" {
+					desc += ", max fee " + feeNote
+				}
+				if !confirm(cmd.Context(), fmt.Sprintf("Send %s to %s?", amt, desc)) {
 					fmt.Println("Cancelled.")
-					return nil
+					return errCancelled
 				}
 			} else {
-				if !confirm(fmt.Sprintf("Pay %s?", desc)) {
+				if !confirm(cmd.Context(), fmt.Sprintf("Pay %s?", desc)) {
 					fmt.Println("Cancelled.")
-					return nil
+					return errCancelled
 				}
 			}
 		}
 
-		ctx := context.Background()
+		// --wait-timeout covers sending and every wait for a result.
+		ctx, cancel := waitContext(ctx, cmd)
+		defer cancel()
+
 		start := time.Now()
+		payment, attempts, err := sendPayment(ctx, w, params, plan, hooks)
+		if err != nil && (payment == nil || ctx.Err() == nil) {
+			return err
+		}
+
+		if jsonFlag && plan.attempts > 1 {
+			if err := printOutput(struct {
+				*lnbot.Payment
+				Attempts []payAttempt `json:"attempts"`
+			}{payment, attempts}); err != nil {
+				return err
+			}
+			if err != nil {
+				return stoppedRetrying(ctx, payment)
+			}
+			if ctx.Err() != nil && pending(payment) {
+				return stoppedWaiting(ctx, "payment", payment.Number)
+			}
+			return paymentFailed(payment)
+		}
+		if err != nil {
+			return stoppedRetrying(ctx, payment)
+		}
+		return printPayment(ctx, w, payment, noWait, start)
+	},
+}
+
+// feeLimitFromFlags is the profile's default fee limit ('lnbot config')
+// overridden by the --max-fee flags.
+func feeLimitFromFlags(cmd *cobra.Command) (fees.Limit, error) {
+	limit := defaultFeeLimit()
+
+	flags := cmd.Flags()
+	sats, err := satsFlag(cmd, "max-fee")
+	if err != nil {
+		return limit, err
+	}
+	pct, _ := flags.GetFloat64("max-fee-percent")
+	ppm, _ := flags.GetInt64("max-fee-ppm")
+	set := 0
+	for _, name := range []string{"max-fee", "max-fee-percent", "max-fee-ppm"} {
+		if flags.Changed(name) {
+			set++
+		}
+	}
+	if set > 1 {
+		return limit, usageErrorf("use only one of --max-fee, --max-fee-percent and --max-fee-ppm")
+	}
+	if set == 1 {
+		limit.Sats, limit.Percent, limit.PPM = sats, pct, ppm
+	}
+	if flags.Changed("max-fee-floor") {
+		if limit.Floor, err = satsFlag(cmd, "max-fee-floor"); err != nil {
+			return limit, err
+		}
+	}
+	if flags.Changed("max-fee-ceiling") {
+		if limit.Ceiling, err = satsFlag(cmd, "max-fee-ceiling"); err != nil {
+			return limit, err
+		}
+	}
+	return limit, limit.Validate()
+}
+
+// defaultFeeLimit is the profile's default fee limit ('lnbot config').
+func defaultFeeLimit() fees.Limit {
+	if cfg != nil && cfg.FeeLimit != nil {
+		return *cfg.FeeLimit
+	}
+	return fees.Limit{}
+}
+
+// paymentAmount is the amount in sats being paid: --amount, or the
+// invoice's own amount.
+func paymentAmount(inv *bolt11.Invoice, amount int64) int64 {
+	if amount > 0 || inv == nil {
+		return amount
+	}
+	sats, _ := inv.AmountSats()
+	return sats
+}
+
+// describeMaxFee explains the fee limit for the confirmation prompt, or
+// returns "" if the API default applies.
+func describeMaxFee(params *lnbot.CreatePaymentParams, limit fees.Limit, plan retryPlan) string {
+	switch {
+	case len(plan.feeSteps) > 1:
+		return fmt.Sprintf("%s, rising to %s", format.Sats(plan.feeSteps[0]), format.Sats(*params.MaxFee))
+	case params.MaxFee == nil:
+		return ""
+	case len(plan.feeSteps) == 0 && limit.Sats == 0 && !limit.IsZero():
+		return fmt.Sprintf("%s (%s)", format.Sats(*params.MaxFee), limit)
+	}
+	return format.Sats(*params.MaxFee)
+}
+
+// retryPlan is how many times 'lnbot pay' tries a payment and with which
+// fee limits.
+type retryPlan struct {
+	attempts int
+	feeSteps []int64
+	backoff  time.Duration
+	invoice  string // BOLT11 target, re-checked for expiry before each retry
+}
+
+// payAttempt is one try at a payment, as reported under --json.
+type payAttempt struct {
+	Attempt       int     `json:"attempt"`
+	Number        int     `json:"number"`
+	MaxFee        *int64  `json:"max_fee,omitempty"`
+	Status        string  `json:"status"`
+	FailureReason *string `json:"failure_reason,omitempty"`
+	Retryable     bool    `json:"retryable"`
+}
+
+func retryPlanFromFlags(cmd *cobra.Command) (retryPlan, error) {
+	retries, _ := cmd.Flags().GetInt("retry")
+	backoff, _ := cmd.Flags().GetDuration("retry-backoff")
+	var steps []int64
+	list, _ := cmd.Flags().GetStringSlice("max-fee-steps")
+	for _, v := range list {
+		a, err := amount.Parse(v, rateSource())
+		if err != nil {
+			return retryPlan{}, usageErrorf("--max-fee-steps: %w", err)
+		}
+		steps = append(steps, a.Sats)
+	}
+
+	if retries < 0 {
+		return retryPlan{}, usageErrorf("--retry must not be negative")
+	}
+	if len(steps) > 0 && (cmd.Flags().Changed("max-fee") || cmd.Flags().Changed("max-fee-percent") || cmd.Flags().Changed("max-fee-ppm")) {
+		return retryPlan{}, usageErrorf("use either --max-fee-steps or a single fee limit, not both")
+	}
+	for i, fee := range steps {
+		if fee < 0 || (i > 0 && fee < steps[i-1]) {
+			return retryPlan{}, usageErrorf("--max-fee-steps must be increasing fee limits in sats, e.g. 10,50,200")
+		}
+	}
+	// Each fee step is tried at least once; extra retries reuse the last.
+	return retryPlan{attempts: max(retries+1, len(steps)), feeSteps: steps, backoff: backoff}, nil
+}
+
+// sendPayment creates the payment, retrying retryable failures as planned.
+// With a single attempt the payment is returned as soon as it is created;
+// otherwise each attempt is waited on before deciding whether to retry.
+func sendPayment(ctx context.Context, w *lnbot.WalletHandle, params *lnbot.CreatePaymentParams, plan retryPlan,
+	hooks journalHooks) (*lnbot.Payment, []payAttempt, error) {
+	var attempts []payAttempt
+	backoff := plan.backoff
+	for i := 1; ; i++ {
+		if len(plan.feeSteps) > 0 {
+			params.MaxFee = lnbot.Ptr(plan.feeSteps[min(i, len(plan.feeSteps))-1])
+		}
+		hooks.prepare(i)
+
 		payment, err := w.Payments.Create(ctx, params)
 		if err != nil {
-			return apiError("sending payment", err)
+			return nil, attempts, apiError("sending payment", err)
+		}
+		hooks.record(w.WalletID, payment, i)
+		if plan.attempts <= 1 {
+			return payment, nil, nil
+		}
+		if pending(payment) {
+			payment, _ = waitForPayment(ctx, w, payment)
 		}
 
-		noWait, _ := cmd.Flags().GetBool("no-wait")
+		a := payAttempt{Attempt: i, Number: payment.Number, MaxFee: params.MaxFee, Status: payment.Status, FailureReason: payment.FailureReason}
+		if payment.Status == "failed" && payment.FailureReason != nil {
+			a.Retryable = retryableFailure(*payment.FailureReason)
+		}
+		attempts = append(attempts, a)
+		if payment.Status != "failed" || !a.Retryable || i == plan.attempts {
+			if !jsonFlag && payment.Status == "failed" && !a.Retryable && i < plan.attempts {
+				fmt.Println("  Not retrying: the failure is not a routing problem.")
+			}
+			return payment, attempts, nil
+		}
 
-		if jsonFlag {
-			if !noWait && (payment.Status == "pending" || payment.Status == "processing") {
-				payment, err = waitForPaymentJSON(ctx, w, payment)
-				if err != nil {
-					return json.NewEncoder(os.Stdout).Encode(payment)
-				}
+		if plan.invoice != "" {
+			if _, err := checkInvoice(plan.invoice); err != nil {
+				return payment, attempts, err
 			}
-			return json.NewEncoder(os.Stdout).Encode(payment)
 		}
+		if !jsonFlag {
+			next := ""
+			if len(plan.feeSteps) > 0 {
+				next = fmt.Sprintf(" with max fee %s", format.Sats(plan.feeSteps[min(i+1, len(plan.feeSteps))-1]))
+			}
+			fmt.Printf("  ✗ attempt %d/%d failed: %s — retrying in %s%s\n", i, plan.attempts, *payment.FailureReason, backoff, next)
+		}
+		select {
+		case <-ctx.Done():
+			return payment, attempts, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
 
-		if noWait {
-			fmt.Printf("  status: %s\n", payment.Status)
-			fmt.Printf("  number: %d\n", payment.Number)
-			return nil
+// retryableFailure reports whether a payment that failed for reason may
+// succeed if tried again: routing failures and timeouts, or a fee limit
+// that a later attempt may raise. Anything else — an expired invoice, an
+// insufficient balance — fails the same way every time.
+func retryableFailure(reason string) bool {
+	reason = strings.ToLower(reason)
+	for _, s := range []string{"no route", "route not found", "timeout", "timed out", "temporar", "liquidity", "fee exceeds"} {
+		if strings.Contains(reason, s) {
+			return true
 		}
+	}
+	return false
+}
 
-		return printPaymentResult(ctx, w, payment, start)
-	},
+// journalPath is the payment journal, next to the config file.
+func journalPath() string {
+	return filepath.Join(filepath.Dir(config.Path()), "journal.json")
+}
+
+// journalHooks keep the payment journal up to date as sendPayment makes
+// attempts. The zero value does nothing, for payments without a key.
+type journalHooks struct {
+	jrnl   *journal.Journal
+	key    string
+	entry  journal.Entry
+	params *lnbot.CreatePaymentParams
+}
+
+// prepare gives each attempt its own idempotency key.
+func (h journalHooks) prepare(attempt int) {
+	if h.key != "" {
+		h.params.IdempotencyKey = lnbot.Ptr(attemptKey(h.key, h.entry.Attempt+attempt-1))
+	}
+}
+
+// record journals an attempt once the payment is created.
+func (h journalHooks) record(walletID string, p *lnbot.Payment, attempt int) {
+	if h.jrnl == nil {
+		return
+	}
+	e := h.entry
+	e.Number, e.Attempt = p.Number, h.entry.Attempt+attempt-1
+	if err := h.jrnl.Record(walletID, h.key, e); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ could not record payment #%d in the journal: %s\n", p.Number, err)
+	}
+}
+
+// journalPayment looks up a payment's idempotency key in the journal. Without
+// a key, paying an invoice uses one derived from its payment hash. If the
+// payment was already sent it is returned and must not be sent again —
+// except that a failed invoice payment may be tried again under a new key.
+// Otherwise params gets its idempotency key and the hooks for sendPayment.
+func journalPayment(ctx context.Context, w *lnbot.WalletHandle, params *lnbot.CreatePaymentParams, key string, inv *bolt11.Invoice) (*lnbot.Payment, journalHooks, error) {
+	derived := key == "" && inv != nil
+	if derived {
+		key = "bolt11-" + inv.PaymentHash
+	}
+	if key == "" {
+		return nil, journalHooks{}, nil
+	}
+
+	var amount int64
+	if params.Amount != nil {
+		amount = *params.Amount
+	}
+	hooks := journalHooks{key: key, entry: journal.Entry{Target: params.Target, Amount: amount, Attempt: 1}, params: params}
+	var err error
+	if hooks.jrnl, err = journal.Load(journalPath()); err != nil {
+		return nil, hooks, err
+	}
+	if prev, ok := hooks.jrnl.Lookup(w.WalletID, key); ok {
+		if !prev.Matches(params.Target, amount) {
+			return nil, hooks, fmt.Errorf("idempotency key %q was already used for payment #%d to %s — use a new key", key, prev.Number, format.Truncate(prev.Target, 40))
+		}
+		payment, err := w.Payments.Get(ctx, prev.Number)
+		if err != nil {
+			return nil, hooks, apiError("fetching payment", err)
+		}
+		if !derived || payment.Status != "failed" {
+			return payment, hooks, nil
+		}
+		hooks.entry.Attempt = prev.Attempt + 1
+	}
+	params.IdempotencyKey = lnbot.Ptr(attemptKey(key, hooks.entry.Attempt))
+	return nil, hooks, nil
+}
+
+// attemptKey is the idempotency key sent to the API for a retry of a
+// failed payment, so the API doesn't return the failed one again.
+func attemptKey(key string, attempt int) string {
+	if attempt <= 1 {
+		return key
+	}
+	return fmt.Sprintf("%s-%d", key, attempt)
+}
+
+// printPayment prints a payment, waiting for it to settle unless noWait.
+func printPayment(ctx context.Context, w *lnbot.WalletHandle, payment *lnbot.Payment, noWait bool, start time.Time) error {
+	if jsonFlag {
+		if !noWait && pending(payment) {
+			payment, _ = waitForPayment(ctx, w, payment)
+		}
+		if err := printOutput(payment); err != nil {
+			return err
+		}
+		if !noWait && ctx.Err() != nil && pending(payment) {
+			return stoppedWaiting(ctx, "payment", payment.Number)
+		}
+		return paymentFailed(payment)
+	}
+
+	if noWait {
+		fmt.Printf("  status: %s\n", payment.Status)
+		fmt.Printf("  number: %d\n", payment.Number)
+		return nil
+	}
+
+	return printPaymentResult(ctx, w, payment, start)
+}
+
+// checkInvoice decodes a BOLT11 target and rejects it if it has already
+// expired, so that stale invoices never reach the API.
+func checkInvoice(target string) (*bolt11.Invoice, error) {
+	inv, err := bolt11.Decode(target)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Expired(time.Now()) {
+		at := inv.ExpiresAt()
+		return nil, &exitError{
+			code: exitInvoiceExpired,
+			err:  fmt.Errorf("invoice expired %s — ask the payee for a new one", format.TimeAgo(&at)),
+		}
+	}
+	return inv, nil
 }
 
+// printInvoicePreview shows what is about to be paid before the confirmation
+// prompt. amount overrides the invoice amount for "any amount" invoices;
+// fiat is the fiat amount it was converted from, if any.
+func printInvoicePreview(inv *bolt11.Invoice, amount int64, fiat string) {
+	if amount > 0 && inv.AmountMsat == nil {
+		if fiat != "" {
+			fmt.Printf("  amount:  %s (%s)\n", format.Sats(amount), fiat)
+		} else {
+			fmt.Printf("  amount:  %s\n", format.Sats(amount))
+		}
+	} else {
+		fmt.Printf("  amount:  %s\n", invoiceAmount(inv))
+	}
+	switch {
+	case inv.Description != "":
+		fmt.Printf("  memo:    %s\n", inv.Description)
+	case inv.DescriptionHash != "":
+		fmt.Printf("  memo:    (hashed) %s\n", format.Truncate(inv.DescriptionHash, 20))
+	}
+	if inv.Payee != "" {
+		fmt.Printf("  payee:   %s\n", format.Truncate(inv.Payee, 20))
+	}
+	fmt.Printf("  expires: %s\n", invoiceExpiry(inv, time.Now()))
+}
+
+// printPaymentResult waits for a pending payment to settle or fail and
+// prints the outcome. start is when the payment was sent, or zero if that
+// is not known.
 func printPaymentResult(ctx context.Context, w *lnbot.WalletHandle, payment *lnbot.Payment, start time.Time) error {
+	if pending(payment) {
+		fmt.Print("  Waiting for settlement... (Ctrl+C to stop)")
+		latest, err := waitForPayment(ctx, w, payment)
+		fmt.Println()
+		if ctx.Err() != nil {
+			return stoppedWaiting(ctx, "payment", payment.Number)
+		}
+		if err != nil {
+			return apiError("watching payment", err)
+		}
+		payment = latest
+	}
+
 	switch payment.Status {
 	case "settled":
 		elapsed := time.Since(start)
-		if elapsed < 100*time.Millisecond {
+		switch {
+		case start.IsZero():
+			printSuccess(fmt.Sprintf("Payment #%d settled", payment.Number))
+		case elapsed < 100*time.Millisecond:
 			printSuccess("Sent! Settled instantly")
-		} else {
+		default:
 			printSuccess(fmt.Sprintf("Sent! Settled in %dms", elapsed.Milliseconds()))
 		}
 		fmt.Printf("  amount:  %s\n", format.Sats(payment.Amount))
@@ -142,57 +606,54 @@ func printPaymentResult(ctx context.Context, w *lnbot.WalletHandle, payment *lnb
 		}
 		fmt.Fprintf(os.Stderr, "✗ Payment failed: %s\n", reason)
 		fmt.Fprintln(os.Stderr, "  No sats were deducted.")
-	default:
-		fmt.Print("  Waiting for settlement... (Ctrl+C to stop)")
-
-		watchCtx, cancel := context.WithCancel(ctx)
-		defer cancel()
-
-		events, errs := w.Payments.Watch(watchCtx, payment.Number, nil)
-		for {
-			select {
-			case ev, ok := <-events:
-				if !ok {
-					fmt.Println()
-					return nil
-				}
-				fmt.Println()
-				return printPaymentResult(ctx, w, &ev.Data, start)
-			case err, ok := <-errs:
-				if ok && err != nil {
-					fmt.Println()
-					return err
-				}
-				return nil
-			}
-		}
+		return paymentFailed(payment)
 	}
 	return nil
 }
 
-func waitForPaymentJSON(ctx context.Context, w *lnbot.WalletHandle, payment *lnbot.Payment) (*lnbot.Payment, error) {
-	watchCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+// pending reports whether a payment has yet to settle or fail.
+func pending(payment *lnbot.Payment) bool {
+	return payment.Status == "pending" || payment.Status == "processing"
+}
 
-	events, errs := w.Payments.Watch(watchCtx, payment.Number, nil)
-	for {
-		select {
-		case ev, ok := <-events:
-			if !ok {
-				return payment, nil
-			}
-			return &ev.Data, nil
-		case err, ok := <-errs:
-			if ok && err != nil {
-				return payment, err
-			}
-			return payment, nil
-		}
+// paymentFailed returns an exit error for a payment whose result has been
+// printed, if it failed.
+func paymentFailed(payment *lnbot.Payment) error {
+	if payment.Status != "failed" {
+		return nil
+	}
+	reason := "unknown"
+	if payment.FailureReason != nil {
+		reason = *payment.FailureReason
+	}
+	return &exitError{code: exitPaymentFailed, err: fmt.Errorf("payment #%d failed: %s", payment.Number, reason), reported: true}
+}
+
+// stoppedRetrying is the error for a --retry run whose --wait-timeout ran
+// out, or that was interrupted, while backing off: the last attempt failed
+// and no further one was made.
+func stoppedRetrying(ctx context.Context, payment *lnbot.Payment) error {
+	if !jsonFlag {
+		printWarning(fmt.Sprintf("Stopped retrying — payment #%d failed and was not tried again", payment.Number))
+	}
+	return &exitError{
+		code:     exitNetwork,
+		err:      fmt.Errorf("stopped retrying after payment #%d failed: %w", payment.Number, ctx.Err()),
+		reported: true,
 	}
 }
 
 func init() {
-	payCmd.Flags().Int64("amount", 0, "amount in sats (required for Lightning addresses and LNURLs)")
-	payCmd.Flags().Int64("max-fee", 0, "maximum routing fee in sats")
+	payCmd.Flags().String("amount", "", "amount to send, "+amountUsage+" (required for Lightning addresses and LNURLs)")
+	payCmd.Flags().String("max-fee", "", "maximum routing fee in sats")
+	payCmd.Flags().Float64("max-fee-percent", 0, "maximum routing fee as a percentage of the amount")
+	payCmd.Flags().Int64("max-fee-ppm", 0, "maximum routing fee in parts per million of the amount")
+	payCmd.Flags().String("max-fee-floor", "", "lowest limit --max-fee-percent or --max-fee-ppm may give, in sats")
+	payCmd.Flags().String("max-fee-ceiling", "", "highest limit --max-fee-percent or --max-fee-ppm may give, in sats")
 	payCmd.Flags().Bool("no-wait", false, "return immediately without waiting for settlement")
+	addWaitTimeoutFlag(payCmd)
+	payCmd.Flags().Int("retry", 0, "retry routing failures up to N times")
+	payCmd.Flags().Duration("retry-backoff", time.Second, "delay before the first retry, doubling after each")
+	payCmd.Flags().StringSlice("max-fee-steps", nil, "max fee in sats for each attempt, e.g. 10,50,200")
+	payCmd.Flags().String("idempotency-key", "", "pay at most once for this key (default for invoices: the payment hash)")
 }
@@ -1,86 +1,185 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	lnbot "github.com/lnbotdev/go-sdk"
 
 	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/output"
+	"github.com/lnbotdev/cli/internal/store"
 )
 
 var paymentCmd = &cobra.Command{
 	Use:   "payment <command>",
-	Short: "List outgoing payments",
-	Long:  `View outgoing payments sent from the active wallet.`,
+	Short: "List and watch outgoing payments",
+	Long: `View outgoing payments sent from the active wallet, and wait for a
+pending one to settle.`,
 }
 
 func init() {
 	paymentListCmd.Flags().Int("limit", 20, "max number of results")
 	paymentListCmd.Flags().Int("after", 0, "show results after this payment number (for pagination)")
+	paymentListCmd.Flags().Bool("offline", false, "read from the local mirror without contacting the API (see 'lnbot sync')")
+	paymentListCmd.Flags().String("search", "", "search the whole history for text in addresses, references, statuses and failure reasons (needs 'lnbot sync')")
+	addDisplayFlags(paymentListCmd)
+	addColumnsFlag(paymentListCmd, paymentColumns)
+
+	addWaitTimeoutFlag(paymentWatchCmd)
 
 	paymentCmd.AddCommand(paymentListCmd)
+	paymentCmd.AddCommand(paymentWatchCmd)
 }
 
 var paymentListCmd = &cobra.Command{
 	Use:     "list",
 	Short:   "List outgoing payments",
 	Aliases: []string{"ls"},
-	Long:    `Show recent outgoing payments for the active wallet, newest first.`,
+	Long: `Show recent outgoing payments for the active wallet, newest first.
+
+Once the wallet has been synced ('lnbot sync'), results come from the
+local mirror after fetching anything new. --offline skips the network
+and --search searches the whole history.`,
 	Example: `  lnbot payment list
   lnbot payment list --limit 5
   lnbot payment list --after 20
-  lnbot payment list --json`,
+  lnbot payment list --json
+  lnbot payment list --offline --search carol@
+  lnbot payment list -o wide
+  lnbot payment list -o tsv --columns number,amount,fee`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		limit, _ := cmd.Flags().GetInt("limit")
 		after, _ := cmd.Flags().GetInt("after")
+		offline, _ := cmd.Flags().GetBool("offline")
+		search, _ := cmd.Flags().GetString("search")
+		if err := applyDisplay(cmd); err != nil {
+			return err
+		}
 
-		w, err := resolveWallet()
+		local, err := localStore(cmd.Context(), offline, search != "")
 		if err != nil {
 			return err
 		}
 
-		params := &lnbot.ListPaymentsParams{Limit: lnbot.Ptr(limit)}
-		if after > 0 {
-			params.After = lnbot.Ptr(after)
-		}
+		var payments []lnbot.Payment
+		if local != nil {
+			payments = local.ListPayments(store.Query{Limit: limit, After: after, Search: search})
+		} else {
+			w, err := resolveWallet(cmd.Context())
+			if err != nil {
+				return err
+			}
 
-		payments, err := w.Payments.List(context.Background(), params)
-		if err != nil {
-			return apiError("listing payments", err)
+			params := &lnbot.ListPaymentsParams{Limit: lnbot.Ptr(limit)}
+			if after > 0 {
+				params.After = lnbot.Ptr(after)
+			}
+
+			payments, err = w.Payments.List(cmd.Context(), params)
+			if err != nil {
+				return apiError("listing payments", err)
+			}
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(payments)
+			return printRows(cmd, payments, paymentColumns)
 		}
 
 		if len(payments) == 0 {
-			fmt.Println("No payments yet.")
+			if search != "" {
+				fmt.Println("No matching payments.")
+			} else {
+				fmt.Println("No payments yet.")
+			}
 			return nil
 		}
 
-		for _, p := range payments {
-			addr := p.Address
-			if addr == "" {
-				addr = "--"
-			}
-			fmt.Printf("  #%4d  %-8s  %10s sats  %8s  %s\n",
-				p.Number,
-				p.Status,
-				format.SatsPlain(p.Amount),
-				format.TimeAgo(p.CreatedAt),
-				format.Truncate(addr, 40),
-			)
+		if err := printRows(cmd, payments, paymentColumns); err != nil {
+			return err
 		}
 
 		if len(payments) == limit {
 			last := payments[len(payments)-1].Number
 			fmt.Printf("\n  %d shown — next page: --after %d\n", limit, last)
 		}
+		if offline {
+			printSyncedAt(local)
+		}
 		return nil
 	},
 }
+
+// paymentColumns are the columns of 'payment list'.
+var paymentColumns = []output.Column[lnbot.Payment]{
+	{Name: "number", Right: true, Text: func(p lnbot.Payment) string { return fmt.Sprintf("#%d", p.Number) }, Value: func(p lnbot.Payment) any { return p.Number }},
+	{Name: "status", Text: func(p lnbot.Payment) string { return p.Status }},
+	amountColumn(func(p lnbot.Payment) int64 { return p.Amount }),
+	fiatColumn(func(p lnbot.Payment) int64 { return p.Amount }),
+	{Name: "created", Text: func(p lnbot.Payment) string { return format.TimeAgo(p.CreatedAt) }, Value: func(p lnbot.Payment) any { return p.CreatedAt }},
+	{Name: "address", Text: func(p lnbot.Payment) string {
+		if p.Address == "" {
+			return "--"
+		}
+		return format.Truncate(p.Address, 40)
+	}, Value: func(p lnbot.Payment) any { return p.Address }},
+	{Name: "fee", Wide: true, Right: true, Text: func(p lnbot.Payment) string {
+		if p.ActualFee == nil {
+			return "--"
+		}
+		return format.SatsPlain(*p.ActualFee)
+	}, Value: func(p lnbot.Payment) any { return p.ActualFee }},
+	{Name: "max_fee", Wide: true, Right: true, Text: func(p lnbot.Payment) string { return format.SatsPlain(p.MaxFee) }, Value: func(p lnbot.Payment) any { return p.MaxFee }},
+	{Name: "reference", Wide: true, Text: func(p lnbot.Payment) string { return optional(p.Reference) }, Value: func(p lnbot.Payment) any { return p.Reference }},
+	{Name: "failure", Wide: true, Text: func(p lnbot.Payment) string { return optional(p.FailureReason) }, Value: func(p lnbot.Payment) any { return p.FailureReason }},
+}
+
+var paymentWatchCmd = &cobra.Command{
+	Use:   "watch <number>",
+	Short: "Wait for a payment to settle or fail",
+	Long: `Wait for an outgoing payment to settle or fail.
+
+A payment that has already settled or failed is reported straight away.
+The wait reconnects with backoff if the connection drops. Use
+--wait-timeout to give up after a while.
+
+Exits 0 once the payment settles, 5 if it failed, and 7 if waiting was
+stopped while it was still pending. With --json the final payment is
+printed, or the pending one if waiting was stopped.`,
+	Example: `  lnbot payment watch 7
+  lnbot payment watch 7 --wait-timeout 2m
+  lnbot payment watch 7 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		number, err := parseNumber("payment", args[0])
+		if err != nil {
+			return err
+		}
+
+		w, err := resolveWallet(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := waitContext(cmd.Context(), cmd)
+		defer cancel()
+
+		payment, err := w.Payments.Get(ctx, number)
+		if err != nil {
+			return apiError("getting payment", err)
+		}
+
+		if !jsonFlag {
+			fmt.Printf("  payment: #%d\n", payment.Number)
+			fmt.Printf("  amount:  %s\n", format.Sats(payment.Amount))
+			if payment.Address != "" {
+				fmt.Printf("  to:      %s\n", format.Truncate(payment.Address, 60))
+			}
+			fmt.Printf("  status:  %s\n", payment.Status)
+			fmt.Println()
+		}
+		return printPayment(ctx, w, payment, false, time.Time{})
+	},
+}
@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/bolt11"
+	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/policy"
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy <command>",
+	Short: "Manage local spending policies",
+	Long: `Attach a spending policy file to a wallet. The policy is checked
+locally before every outgoing payment — including 'pay --yes' and the
+MCP pay tool — and violations fail with exit code 8.
+
+A policy is a JSON file; every field is optional:
+
+  {
+    "max_per_payment": 10000,
+    "daily_budget": 50000,
+    "weekly_budget": 200000,
+    "max_fee": 100,
+    "allow": ["ln.bot", "carol@example.com"],
+    "deny": ["mallory@ln.bot"],
+    "allow_bolt11": true
+  }
+
+Budgets are rolling 24-hour and 7-day windows over the wallet's
+outgoing payments. Allow and deny entries are Lightning addresses or
+domains (a domain also matches its subdomains). When an allow list is
+set, BOLT11 invoices are rejected unless allow_bolt11 is true.`,
+}
+
+func init() {
+	policyCheckCmd.Flags().String("amount", "", "amount, "+amountUsage)
+	policyCheckCmd.Flags().String("max-fee", "", "maximum routing fee in sats")
+
+	policyCmd.AddCommand(policyShowCmd)
+	policyCmd.AddCommand(policySetCmd)
+	policyCmd.AddCommand(policyUnsetCmd)
+	policyCmd.AddCommand(policyCheckCmd)
+}
+
+var policyShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the spending policy for a wallet",
+	Long:  `Print the policy file attached to the active (or --wallet) wallet and its limits.`,
+	Example: `  lnbot policy show
+  lnbot policy show --wallet agent01 --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		walletID, err := resolveWalletID(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		path := cfg.PolicyPath(walletID)
+		if path == "" {
+			if jsonFlag {
+				return printOutput(map[string]any{"walletId": walletID, "path": nil, "policy": nil})
+			}
+			fmt.Println("No spending policy set.")
+			fmt.Println()
+			fmt.Println("  Attach one: lnbot policy set <file>")
+			return nil
+		}
+
+		pol, err := policy.Load(path)
+		if err != nil {
+			return err
+		}
+
+		if jsonFlag {
+			return printOutput(map[string]any{"walletId": walletID, "path": path, "policy": pol})
+		}
+
+		fmt.Printf("  file:            %s\n", path)
+		fmt.Printf("  max per payment: %s\n", policyLimit(pol.MaxPerPayment))
+		fmt.Printf("  daily budget:    %s\n", policyLimit(pol.DailyBudget))
+		fmt.Printf("  weekly budget:   %s\n", policyLimit(pol.WeeklyBudget))
+		fmt.Printf("  max fee:         %s\n", policyLimit(pol.MaxFee))
+		if len(pol.Allow) > 0 {
+			fmt.Printf("  allow:           %v\n", pol.Allow)
+			fmt.Printf("  allow bolt11:    %v\n", pol.AllowBolt11)
+		}
+		if len(pol.Deny) > 0 {
+			fmt.Printf("  deny:            %v\n", pol.Deny)
+		}
+		return nil
+	},
+}
+
+var policySetCmd = &cobra.Command{
+	Use:   "set <file>",
+	Short: "Attach a spending policy file to a wallet",
+	Long: `Validate a policy file and attach it to the active (or --wallet) wallet.
+The file is read on every payment, so later edits take effect immediately.`,
+	Example: `  lnbot policy set ~/.config/lnbot/agent-policy.json
+  lnbot policy set policy.json --wallet agent01`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		walletID, err := resolveWalletID(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		path, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := policy.Load(path); err != nil {
+			return err
+		}
+
+		cfg.SetPolicyPath(walletID, path)
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+
+		printSuccess(fmt.Sprintf("Spending policy attached to %s", walletID))
+		fmt.Printf("  file: %s\n", path)
+		return nil
+	},
+}
+
+var policyUnsetCmd = &cobra.Command{
+	Use:     "unset",
+	Short:   "Remove the spending policy from a wallet",
+	Long:    `Detach the policy file from the active (or --wallet) wallet. The file itself is not deleted.`,
+	Example: `  lnbot policy unset --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		walletID, err := resolveWalletID(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if cfg.PolicyPath(walletID) == "" {
+			fmt.Println("No spending policy set.")
+			return nil
+		}
+
+		if !yesFlag {
+			if !confirm(cmd.Context(), fmt.Sprintf("Remove the spending policy from %s?", walletID)) {
+				fmt.Println("Cancelled.")
+				return errCancelled
+			}
+		}
+
+		cfg.SetPolicyPath(walletID, "")
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+
+		printSuccess("Spending policy removed")
+		return nil
+	},
+}
+
+var policyCheckCmd = &cobra.Command{
+	Use:   "check <target>",
+	Short: "Check whether a payment would be allowed",
+	Long: `Evaluate the wallet's spending policy for a payment without sending it.
+Exits with code 8 if the payment would be denied.`,
+	Example: `  lnbot policy check alice@ln.bot --amount 5000
+  lnbot policy check lnbc10u1pj9x... --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		amount, err := satsFlag(cmd, "amount")
+		if err != nil {
+			return err
+		}
+		maxFee, err := satsFlag(cmd, "max-fee")
+		if err != nil {
+			return err
+		}
+
+		w, err := resolveWallet(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		params := &lnbot.CreatePaymentParams{Target: args[0]}
+		if amount > 0 {
+			params.Amount = lnbot.Ptr(amount)
+		}
+		if maxFee > 0 {
+			params.MaxFee = lnbot.Ptr(maxFee)
+		}
+
+		if err := enforcePolicy(cmd.Context(), w, params); err != nil {
+			return err
+		}
+
+		if jsonFlag {
+			return printOutput(map[string]any{"allowed": true})
+		}
+		printSuccess("Allowed by spending policy")
+		return nil
+	},
+}
+
+// enforcePolicy checks params against the wallet's spending policy, if one
+// is attached. Violations are returned as *policy.Violation wrapped in an
+// exit error. When the policy has a fee ceiling and params has no fee limit,
+// the ceiling is applied to params.
+func enforcePolicy(ctx context.Context, w *lnbot.WalletHandle, params *lnbot.CreatePaymentParams) error {
+	path := cfg.PolicyPath(w.WalletID)
+	if path == "" {
+		return nil
+	}
+
+	pol, err := policy.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading spending policy: %w", err)
+	}
+
+	pay := policyPayment(ctx, w, pol, params)
+
+	now := time.Now()
+	var history []policy.Spend
+	if pol.NeedsHistory() && pay.Amount > 0 {
+		history, err = spendHistory(ctx, w, now.Add(-7*24*time.Hour))
+		if err != nil {
+			return apiError("checking spending history", err)
+		}
+	}
+
+	if v := pol.Check(pay, history, now); v != nil {
+		return &exitError{code: exitPolicyDenied, err: v}
+	}
+
+	if pol.MaxFee > 0 && params.MaxFee == nil {
+		params.MaxFee = lnbot.Ptr(pol.MaxFee)
+	}
+	return nil
+}
+
+// enforceBatchPolicy checks payments as if they were sent one after
+// another, so that the budgets count the earlier ones, and applies the fee
+// ceiling like enforcePolicy. It returns the index of the first payment
+// the policy denies.
+func enforceBatchPolicy(ctx context.Context, w *lnbot.WalletHandle, batch []*lnbot.CreatePaymentParams) (int, error) {
+	path := cfg.PolicyPath(w.WalletID)
+	if path == "" {
+		return -1, nil
+	}
+
+	pol, err := policy.Load(path)
+	if err != nil {
+		return -1, fmt.Errorf("loading spending policy: %w", err)
+	}
+
+	now := time.Now()
+	var history []policy.Spend
+	if pol.NeedsHistory() {
+		history, err = spendHistory(ctx, w, now.Add(-7*24*time.Hour))
+		if err != nil {
+			return -1, apiError("checking spending history", err)
+		}
+	}
+
+	for i, params := range batch {
+		pay := policyPayment(ctx, w, pol, params)
+		if v := pol.Check(pay, history, now); v != nil {
+			return i, &exitError{code: exitPolicyDenied, err: v}
+		}
+		history = append(history, policy.Spend{Amount: pay.Amount, At: now})
+		if pol.MaxFee > 0 && params.MaxFee == nil {
+			params.MaxFee = lnbot.Ptr(pol.MaxFee)
+		}
+	}
+	return -1, nil
+}
+
+// policyPayment describes params for a policy check, working out the
+// amount of invoices and, when the policy needs it, of other targets.
+func policyPayment(ctx context.Context, w *lnbot.WalletHandle, pol *policy.Policy, params *lnbot.CreatePaymentParams) policy.Payment {
+	pay := policy.Payment{Target: params.Target}
+	if params.Amount != nil {
+		pay.Amount = *params.Amount
+	}
+	if params.MaxFee != nil {
+		pay.MaxFee = *params.MaxFee
+	}
+
+	if pay.Amount == 0 && bolt11.IsInvoice(params.Target) {
+		if inv, err := bolt11.Decode(params.Target); err == nil {
+			pay.Amount, _ = inv.AmountSats()
+		}
+	}
+	if pay.Amount == 0 && pol.NeedsAmount() {
+		res, err := w.Payments.Resolve(ctx, params.Target)
+		if err == nil && res.Amount != nil {
+			pay.Amount = *res.Amount
+		}
+	}
+	return pay
+}
+
+// spendHistory returns the wallet's non-failed outgoing payments created at
+// or after since, walking pages newest first.
+func spendHistory(ctx context.Context, w *lnbot.WalletHandle, since time.Time) ([]policy.Spend, error) {
+	const pageSize = 100
+
+	var (
+		history []policy.Spend
+		after   *int
+	)
+	for {
+		page, err := w.Payments.List(ctx, &lnbot.ListPaymentsParams{Limit: lnbot.Ptr(pageSize), After: after})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page {
+			at := time.Now()
+			if p.CreatedAt != nil {
+				at = *p.CreatedAt
+			}
+			if at.Before(since) {
+				return history, nil
+			}
+			if p.Status != "failed" {
+				history = append(history, policy.Spend{Amount: p.Amount, At: at})
+			}
+		}
+		if len(page) < pageSize {
+			return history, nil
+		}
+		after = lnbot.Ptr(page[len(page)-1].Number)
+	}
+}
+
+func policyLimit(sats int64) string {
+	if sats <= 0 {
+		return "none"
+	}
+	return format.Sats(sats)
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/config"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile <command>",
+	Short: "Manage accounts and environments",
+	Long: `Keep several accounts (e.g. staging and production) in one config file.
+
+Each profile has its own API keys, active wallet and optional API URL.
+Commands use the profile given by --profile, then the LNBOT_PROFILE env
+var, then the current profile set with 'lnbot profile use'.
+
+Config files from before profiles existed are read as a single profile
+named "default".`,
+	// Profile commands manage the config file itself, so they must work
+	// even when the selected profile does not exist.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setOutput(); err != nil {
+			return err
+		}
+		setTimeout(cmd)
+		config.SetProfile(profileFlag)
+		cfg = nil
+		return setAPIURL()
+	},
+}
+
+func init() {
+	profileAddCmd.Flags().String("key", "", "existing user key (default: register a new account)")
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileRemoveCmd)
+	profileCmd.AddCommand(profileRenameCmd)
+}
+
+var profileListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List profiles",
+	Aliases: []string{"ls"},
+	Long:    `Show all profiles in the config file. The profile in use is marked with a bullet.`,
+	Example: `  lnbot profile list
+  lnbot profile list --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := config.LoadFile()
+		if err != nil {
+			return err
+		}
+
+		names := file.ProfileNames()
+
+		if jsonFlag {
+			out := make([]map[string]any, 0, len(names))
+			for _, name := range names {
+				p := file.Profile(name)
+				out = append(out, map[string]any{
+					"name":             name,
+					"current":          name == file.CurrentProfile,
+					"selected":         name == file.Name(),
+					"active_wallet_id": p.ActiveWalletID,
+					"api_url":          p.APIURL,
+				})
+			}
+			return printOutput(out)
+		}
+
+		if len(names) == 0 {
+			fmt.Println("No profiles yet. Run 'lnbot init' or 'lnbot profile add <name>'.")
+			return nil
+		}
+
+		for _, name := range names {
+			p := file.Profile(name)
+			marker := " "
+			if name == file.Name() {
+				marker = "●"
+			}
+			wallet := p.ActiveWalletID
+			if wallet == "" {
+				wallet = "(no wallet)"
+			}
+			line := fmt.Sprintf("%s %-12s %s", marker, name, wallet)
+			if p.APIURL != "" {
+				line += "  " + p.APIURL
+			}
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch the current profile",
+	Long:  `Make a profile current. Subsequent commands use it unless --profile or LNBOT_PROFILE says otherwise.`,
+	Example: `  lnbot profile use staging
+  lnbot profile use default`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := config.LoadFile()
+		if err != nil {
+			return err
+		}
+		if err := file.UseProfile(args[0]); err != nil {
+			return err
+		}
+		if err := file.Save(); err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Switched to profile %s", args[0]))
+		return nil
+	},
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a profile",
+	Long: `Add a profile for another account or environment.
+
+With --key the profile uses an existing account: pass --wallet wal_...
+to pick its active wallet, or the first wallet on the account is used.
+Without --key a new account and wallet are registered, like 'lnbot init'.
+
+Use --api-url to point the profile at a different API, e.g. staging; the
+URL is saved with the profile.`,
+	Example: `  lnbot profile add staging --key uk_... --api-url https://staging.api.ln.bot
+  lnbot profile add sandbox
+  lnbot profile add ops --key uk_... --wallet wal_7x9kQ2mR`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		key, _ := cmd.Flags().GetString("key")
+
+		file, err := config.LoadFile()
+		if err != nil {
+			return err
+		}
+		if err := config.ValidateProfileName(name); err != nil {
+			return err
+		}
+		if file.Profile(name) != nil {
+			return fmt.Errorf("profile %q already exists", name)
+		}
+
+		ctx := cmd.Context()
+		base := config.ResolveAPIURL(apiURLFlag)
+		// Save the API the key belongs to, whether it came from --api-url
+		// or LNBOT_API_URL.
+		var apiURL string
+		if base != config.DefaultAPIURL {
+			apiURL = base
+		}
+		profile := &config.Profile{PrimaryKey: key, APIURL: apiURL}
+		var passphrase, address string
+
+		if key != "" {
+			if strings.HasPrefix(walletFlag, "wal_") {
+				profile.ActiveWalletID = walletFlag
+			} else {
+				wallets, err := config.NewClient(key, base).Wallets.List(ctx)
+				if err != nil {
+					return apiError("listing wallets", err)
+				}
+				for _, w := range wallets {
+					if walletFlag == "" || w.Name == walletFlag {
+						profile.ActiveWalletID = w.WalletID
+						break
+					}
+				}
+				if walletFlag != "" && profile.ActiveWalletID == "" {
+					return fmt.Errorf("wallet %q not found", walletFlag)
+				}
+			}
+		} else {
+			fmt.Fprint(os.Stderr, "Registering account... ")
+			account, err := config.NewClient("", base).Register(ctx)
+			if err != nil {
+				fmt.Fprintln(os.Stderr)
+				return apiError("registering account", err)
+			}
+			wallet, err := config.NewClient(account.PrimaryKey, base).Wallets.Create(ctx)
+			if err != nil {
+				fmt.Fprintln(os.Stderr)
+				return apiError("creating wallet", err)
+			}
+			fmt.Fprintln(os.Stderr, "done")
+
+			profile.PrimaryKey = account.PrimaryKey
+			profile.SecondaryKey = account.SecondaryKey
+			profile.ActiveWalletID = wallet.WalletID
+			passphrase = account.RecoveryPassphrase
+			address = wallet.Address
+		}
+
+		if err := file.AddProfile(name, profile); err != nil {
+			return err
+		}
+		if err := file.Save(); err != nil {
+			return err
+		}
+
+		if jsonFlag {
+			out := map[string]string{
+				"name":      name,
+				"wallet_id": profile.ActiveWalletID,
+				"api_url":   apiURL,
+			}
+			if passphrase != "" {
+				out["primary_key"] = profile.PrimaryKey
+				out["secondary_key"] = profile.SecondaryKey
+				out["address"] = address
+				out["recovery_passphrase"] = passphrase
+			}
+			return printOutput(out)
+		}
+
+		printSuccess(fmt.Sprintf("Profile %s added", name))
+		if profile.ActiveWalletID != "" {
+			fmt.Printf("  wallet:  %s\n", profile.ActiveWalletID)
+		}
+		if address != "" {
+			fmt.Printf("  address: %s\n", address)
+		}
+		if apiURL != "" {
+			fmt.Printf("  api url: %s\n", apiURL)
+		}
+		if passphrase != "" {
+			fmt.Println()
+			printWarning("Recovery passphrase (save this — shown only once):")
+			fmt.Printf("  %s\n", passphrase)
+		}
+		if file.CurrentProfile != name {
+			fmt.Println()
+			fmt.Printf("  Switch to it: lnbot profile use %s\n", name)
+		}
+		return nil
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Short:   "Remove a profile",
+	Aliases: []string{"rm"},
+	Long: `Delete a profile and its API keys from the config file. The account
+itself is not affected. The current profile cannot be removed.`,
+	Example: `  lnbot profile remove staging
+  lnbot profile remove staging --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		file, err := config.LoadFile()
+		if err != nil {
+			return err
+		}
+		if file.Profile(name) == nil {
+			return &config.ProfileNotFoundError{Name: name}
+		}
+
+		if !yesFlag {
+			if !confirm(cmd.Context(), fmt.Sprintf("Remove profile %s? Its API keys will be deleted from this machine.", name)) {
+				fmt.Println("Cancelled.")
+				return errCancelled
+			}
+		}
+
+		if err := file.RemoveProfile(name); err != nil {
+			return err
+		}
+		if err := file.Save(); err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Profile %s removed", name))
+		return nil
+	},
+}
+
+var profileRenameCmd = &cobra.Command{
+	Use:     "rename <old> <new>",
+	Short:   "Rename a profile",
+	Long:    `Rename a profile. If it is the current profile, it stays current.`,
+	Example: `  lnbot profile rename default production`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := config.LoadFile()
+		if err != nil {
+			return err
+		}
+		if err := file.RenameProfile(args[0], args[1]); err != nil {
+			return err
+		}
+		if err := file.Save(); err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Renamed profile %s to %s", args[0], args[1]))
+		return nil
+	},
+}
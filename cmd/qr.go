@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/qr"
+)
+
+// qrScale is the number of PNG pixels per QR module.
+const qrScale = 8
+
+// addQRFlags registers the --qr and --out flags for commands that can write a
+// QR code image.
+func addQRFlags(c *cobra.Command) {
+	c.Flags().String("qr", "", "write the QR code as an image: png or svg (requires --out)")
+	c.Flags().String("out", "", "file to write the QR code image to (format inferred from .png/.svg)")
+}
+
+// qrFileFlags validates --qr and --out. Both results are empty when no image
+// was requested. Call it before any API request so bad flags fail fast.
+func qrFileFlags(cmd *cobra.Command) (kind, path string, err error) {
+	kind, _ = cmd.Flags().GetString("qr")
+	path, _ = cmd.Flags().GetString("out")
+	kind = strings.ToLower(kind)
+
+	if kind == "" && path != "" {
+		kind = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if kind != "png" && kind != "svg" {
+			return "", "", usageErrorf("cannot infer the QR image format from %s — use --qr png or --qr svg", path)
+		}
+	}
+	switch kind {
+	case "", "png", "svg":
+	default:
+		return "", "", usageErrorf("--qr must be png or svg, got %q", kind)
+	}
+	if kind != "" && path == "" {
+		return "", "", usageErrorf("--out is required with --qr %s", kind)
+	}
+	return kind, path, nil
+}
+
+// writeQRFile encodes text as a QR code and writes it to path.
+func writeQRFile(text, kind, path string) error {
+	code, err := qr.Encode(text, qr.Medium)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if kind == "svg" {
+		_, err = f.WriteString(code.SVG())
+	} else {
+		err = code.WritePNG(f, qrScale)
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// printTerminalQR prints text as a QR code when stdout is a terminal. When
+// output is piped or redirected nothing is printed, so scripts only see the
+// plain-text fields.
+func printTerminalQR(text string) {
+	if !isTerminal(os.Stdout) {
+		return
+	}
+	code, err := qr.Encode(text, qr.Medium)
+	if err != nil {
+		return
+	}
+	fmt.Print(code.Terminal(2))
+}
+
+// lightningURI returns the uppercased lightning: URI for a BOLT11 invoice or
+// LNURL. Uppercase bech32 fits the compact alphanumeric QR mode.
+func lightningURI(s string) string {
+	return strings.ToUpper("lightning:" + s)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 
@@ -58,7 +56,7 @@ This rotates all API keys — old keys stop working immediately.`,
 		phrase, _ := cmd.Flags().GetString("passphrase")
 
 		ln := config.AnonClient()
-		restored, err := ln.Restore.Recovery(context.Background(), &lnbot.RecoveryRestoreParams{
+		restored, err := ln.Restore.Recovery(cmd.Context(), &lnbot.RecoveryRestoreParams{
 			Passphrase: phrase,
 		})
 		if err != nil {
@@ -71,15 +69,14 @@ This rotates all API keys — old keys stop working immediately.`,
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(restored)
+			return printOutput(restored)
 		}
 
 		printSuccess("Account restored")
 		fmt.Printf("  wallet: %s\n", restored.WalletID)
 		fmt.Printf("  name:   %s\n", restored.Name)
 
-		newClient := lnbot.New(restored.PrimaryKey)
-		addrs, err := newClient.Wallet(restored.WalletID).Addresses.List(context.Background())
+		addrs, err := cfg.Client().Wallet(restored.WalletID).Addresses.List(cmd.Context())
 		if err == nil && len(addrs) > 0 {
 			fmt.Printf("  address: %s\n", addrs[0].Address)
 		}
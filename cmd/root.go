@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"text/template"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -18,9 +20,15 @@ import (
 )
 
 var (
-	walletFlag string
-	jsonFlag   bool
-	yesFlag    bool
+	walletFlag  string
+	profileFlag string
+	apiURLFlag  string
+	jsonFlag    bool
+	yesFlag     bool
+	timeoutFlag time.Duration
+
+	// stopTimeout releases the --timeout context once the command is done.
+	stopTimeout context.CancelFunc = func() {}
 
 	cfg *config.Config
 )
@@ -39,8 +47,23 @@ var rootCmd = &cobra.Command{
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setOutput(); err != nil {
+			return err
+		}
+		setTimeout(cmd)
+		config.SetProfile(profileFlag)
+		if err := setAPIURL(); err != nil {
+			return err
+		}
+
 		var err error
 		cfg, err = config.Load()
+
+		// init and restore may create the requested profile.
+		var notFound *config.ProfileNotFoundError
+		if errors.As(err, &notFound) && (cmd == initCmd || cmd.Parent() == restoreCmd) {
+			cfg, err = nil, nil
+		}
 		return err
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
@@ -84,6 +107,11 @@ const leafHelpTmpl = `{{with (or .Long .Short)}}{{. | trimTrailingWhitespaces}}
 {{end}}{{if .Runnable}}Usage:
   {{.UseLine}}
 
+{{end}}{{if .HasAvailableSubCommands}}Commands:
+{{- range .Commands}}{{if .IsAvailableCommand}}
+  {{rpad .Name .NamePadding}} {{.Short}}
+{{- end}}{{end}}
+
 {{end}}{{if .HasAvailableLocalFlags}}Flags:
 {{.LocalFlags.FlagUsages}}
 {{end}}{{if .HasAvailableInheritedFlags}}Global Flags:
@@ -111,8 +139,15 @@ const groupHelpTmpl = `{{with (or .Long .Short)}}{{. | trimTrailingWhitespaces}}
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&walletFlag, "wallet", "w", "", "wallet ID or name (default: active wallet)")
-	rootCmd.PersistentFlags().BoolVar(&jsonFlag, "json", false, "output as JSON")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "config profile to use (default: $LNBOT_PROFILE or the current profile)")
+	rootCmd.PersistentFlags().StringVar(&apiURLFlag, "api-url", "", "API base URL (default: $LNBOT_API_URL, the profile's api_url, or https://api.ln.bot)")
+	rootCmd.PersistentFlags().BoolVar(&jsonFlag, "json", false, "output as JSON (same as --output json)")
+	addOutputFlags(rootCmd)
 	rootCmd.PersistentFlags().BoolVarP(&yesFlag, "yes", "y", false, "skip confirmation prompts")
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0, "give up if the command takes longer than this, e.g. 30s (default: no limit)")
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &exitError{code: exitUsage, err: err}
+	})
 
 	rootCmd.AddGroup(
 		&cobra.Group{ID: "start", Title: "Getting Started:"},
@@ -125,12 +160,16 @@ func init() {
 
 	initCmd.GroupID = "start"
 	walletCmd.GroupID = "start"
+	profileCmd.GroupID = "start"
+	configCmd.GroupID = "start"
 
 	balanceCmd.GroupID = "money"
 	invoiceCmd.GroupID = "money"
 	payCmd.GroupID = "money"
 	paymentCmd.GroupID = "money"
 	transactionsCmd.GroupID = "money"
+	syncCmd.GroupID = "money"
+	decodeCmd.GroupID = "money"
 
 	addressCmd.GroupID = "identity"
 	whoamiCmd.GroupID = "identity"
@@ -139,16 +178,21 @@ func init() {
 	keyCmd.GroupID = "security"
 	backupCmd.GroupID = "security"
 	restoreCmd.GroupID = "security"
+	policyCmd.GroupID = "security"
 
 	webhookCmd.GroupID = "integrations"
 	mcpCmd.GroupID = "integrations"
+	watchCmd.GroupID = "integrations"
 
+	devCmd.GroupID = "other"
 	updateCmd.GroupID = "other"
 	completionCmd.GroupID = "other"
 	versionCmd.GroupID = "other"
 
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(walletCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(balanceCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(whoamiCmd)
@@ -156,12 +200,17 @@ func init() {
 	rootCmd.AddCommand(payCmd)
 	rootCmd.AddCommand(paymentCmd)
 	rootCmd.AddCommand(transactionsCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(decodeCmd)
 	rootCmd.AddCommand(keyCmd)
 	rootCmd.AddCommand(backupCmd)
 	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(policyCmd)
 	rootCmd.AddCommand(addressCmd)
 	rootCmd.AddCommand(webhookCmd)
 	rootCmd.AddCommand(mcpCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(devCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(versionCmd)
 
@@ -179,12 +228,12 @@ func init() {
 
 	rootCmd.SetHelpTemplate(rootHelpTmpl)
 
-	for _, cmd := range []*cobra.Command{walletCmd, invoiceCmd, paymentCmd, addressCmd, keyCmd, backupCmd, restoreCmd, webhookCmd, mcpCmd} {
+	for _, cmd := range []*cobra.Command{walletCmd, profileCmd, configCmd, invoiceCmd, paymentCmd, addressCmd, keyCmd, backupCmd, restoreCmd, policyCmd, webhookCmd, mcpCmd, devCmd} {
 		cmd.SetHelpTemplate(groupHelpTmpl)
 	}
 
 	leafCmds := []*cobra.Command{
-		initCmd, balanceCmd, statusCmd, whoamiCmd, payCmd, transactionsCmd,
+		initCmd, balanceCmd, statusCmd, whoamiCmd, payCmd, transactionsCmd, syncCmd, decodeCmd, watchCmd,
 		updateCmd, completionCmd, versionCmd,
 	}
 	for _, cmd := range leafCmds {
@@ -192,9 +241,21 @@ func init() {
 	}
 }
 
+// Execute runs the command line. Ctrl+C and SIGTERM cancel the command's
+// context, so that API calls and waits stop and the command can say what
+// was left pending.
 func Execute() error {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
+	stopTimeout()
+	if err != nil {
+		err = usageErrors(err)
+		if ctx.Err() != nil {
+			err = interrupted(err)
+		}
+		printError(err)
 		return err
 	}
 	return nil
@@ -227,28 +288,35 @@ with 'lnbot wallet create'.`,
 			fmt.Println("Already initialized. Config at", config.Path())
 			fmt.Println()
 			fmt.Println("  To create another wallet: lnbot wallet create")
+			fmt.Println("  To add another account:  lnbot profile add <name>")
 			return nil
 		}
 
-		fmt.Print("Registering account... ")
+		progress := func(s string) {
+			if !jsonFlag {
+				fmt.Print(s)
+			}
+		}
+
+		progress("Registering account... ")
 
-		ctx := context.Background()
+		ctx := cmd.Context()
 		ln := config.AnonClient()
 		account, err := ln.Register(ctx)
 		if err != nil {
-			fmt.Println()
+			progress("\n")
 			return apiError("registering account", err)
 		}
-		fmt.Println("done")
+		progress("done\n")
 
-		fmt.Print("Creating wallet... ")
-		authed := lnbot.New(account.PrimaryKey)
+		progress("Creating wallet... ")
+		authed := config.NewClient(account.PrimaryKey, config.ResolveAPIURL(""))
 		wallet, err := authed.Wallets.Create(ctx)
 		if err != nil {
-			fmt.Println()
+			progress("\n")
 			return apiError("creating wallet", err)
 		}
-		fmt.Println("done")
+		progress("done\n")
 
 		cfg, err = config.Init(account.PrimaryKey, account.SecondaryKey, wallet.WalletID)
 		if err != nil {
@@ -256,7 +324,7 @@ with 'lnbot wallet create'.`,
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(map[string]string{
+			return printOutput(map[string]string{
 				"primary_key":         account.PrimaryKey,
 				"secondary_key":       account.SecondaryKey,
 				"wallet_id":           wallet.WalletID,
@@ -275,7 +343,11 @@ with 'lnbot wallet create'.`,
 		printWarning("Recovery passphrase (save this — shown only once):")
 		fmt.Printf("  %s\n", account.RecoveryPassphrase)
 		fmt.Println()
-		fmt.Printf("  Config saved to %s\n", config.Path())
+		if cfg.Name() != config.DefaultProfile {
+			fmt.Printf("  Config saved to %s (profile %s)\n", config.Path(), cfg.Name())
+		} else {
+			fmt.Printf("  Config saved to %s\n", config.Path())
+		}
 		return nil
 	},
 }
@@ -284,9 +356,24 @@ with 'lnbot wallet create'.`,
 // Helpers
 // ---------------------------------------------------------------------------
 
+// setAPIURL applies --api-url and checks it, or LNBOT_API_URL, before any
+// client is built.
+func setAPIURL() error {
+	config.SetAPIURL(apiURLFlag)
+	if apiURLFlag != "" {
+		return config.ValidateAPIURL(apiURLFlag)
+	}
+	if env := os.Getenv("LNBOT_API_URL"); env != "" {
+		if err := config.ValidateAPIURL(env); err != nil {
+			return fmt.Errorf("LNBOT_API_URL: %w", err)
+		}
+	}
+	return nil
+}
+
 func requireConfig() error {
 	if cfg == nil {
-		return fmt.Errorf("no config found — run 'lnbot init' first")
+		return &exitError{code: exitAuth, err: fmt.Errorf("no config found — run 'lnbot init' first")}
 	}
 	return nil
 }
@@ -294,7 +381,7 @@ func requireConfig() error {
 // resolveWalletID returns the wallet ID to use, from the --wallet flag or active config.
 // If the flag looks like a wallet ID (wal_...) it is used directly.
 // Otherwise it is treated as a wallet name and resolved via the API.
-func resolveWalletID() (string, error) {
+func resolveWalletID(ctx context.Context) (string, error) {
 	if err := requireConfig(); err != nil {
 		return "", err
 	}
@@ -308,35 +395,56 @@ func resolveWalletID() (string, error) {
 		return walletFlag, nil
 	}
 	// Look up by name from API
-	wallets, err := cfg.Client().Wallets.List(context.Background())
+	wallets, err := cfg.Client().Wallets.List(ctx)
 	if err != nil {
-		return "", fmt.Errorf("looking up wallet: %w", err)
+		return "", apiError("looking up wallet", err)
 	}
 	for _, w := range wallets {
 		if w.Name == walletFlag {
 			return w.WalletID, nil
 		}
 	}
-	return "", fmt.Errorf("wallet %q not found", walletFlag)
+	return "", &exitError{code: exitNotFound, err: fmt.Errorf("wallet %q not found", walletFlag)}
 }
 
 // resolveWallet returns a WalletHandle for the active or --wallet-specified wallet.
-func resolveWallet() (*lnbot.WalletHandle, error) {
-	id, err := resolveWalletID()
+func resolveWallet(ctx context.Context) (*lnbot.WalletHandle, error) {
+	id, err := resolveWalletID(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return cfg.Client().Wallet(id), nil
 }
 
-func confirm(prompt string) bool {
+// setTimeout applies --timeout to the command's context.
+func setTimeout(cmd *cobra.Command) {
+	if timeoutFlag > 0 {
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeoutFlag)
+		cmd.SetContext(ctx)
+		stopTimeout = cancel
+	}
+}
+
+// confirm asks a yes/no question. It answers no if ctx is cancelled, e.g.
+// by Ctrl+C, while waiting.
+func confirm(ctx context.Context, prompt string) bool {
 	if yesFlag {
 		return true
 	}
 	fmt.Printf("%s (y/N) ", prompt)
-	var response string
-	fmt.Scanln(&response)
-	return response == "y" || response == "Y"
+	answer := make(chan string, 1)
+	go func() {
+		var response string
+		fmt.Scanln(&response)
+		answer <- response
+	}()
+	select {
+	case response := <-answer:
+		return response == "y" || response == "Y"
+	case <-ctx.Done():
+		fmt.Println()
+		return false
+	}
 }
 
 func printSuccess(msg string) {
@@ -353,11 +461,3 @@ func truncateKey(key string) string {
 	}
 	return key[:12] + "..." + key[len(key)-4:]
 }
-
-func apiError(action string, err error) error {
-	var apiErr *lnbot.APIError
-	if errors.As(err, &apiErr) {
-		return fmt.Errorf("%s: %s", action, apiErr.Message)
-	}
-	return fmt.Errorf("%s: %w", action, err)
-}
@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -15,17 +12,23 @@ import (
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Wallet status and API health",
-	Long:  `Show wallet details, balance, addresses, and API connectivity.`,
+	Long: `Show wallet details, balance, addresses, and API connectivity.
+
+When the CLI talks to an API other than https://api.ln.bot (via --api-url,
+LNBOT_API_URL or the profile's api_url), the endpoint is flagged.`,
 	Example: `  lnbot status
   lnbot status --wallet wal_abc
   lnbot status --json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		w, err := resolveWallet()
+		if err := applyDisplay(cmd); err != nil {
+			return err
+		}
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		ctx := context.Background()
+		ctx := cmd.Context()
 
 		t0 := time.Now()
 		wal, err := w.Get(ctx)
@@ -41,7 +44,7 @@ var statusCmd = &cobra.Command{
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(map[string]any{
+			return printOutput(map[string]any{
 				"walletId":  wal.WalletID,
 				"name":      wal.Name,
 				"balance":   wal.Balance,
@@ -49,6 +52,8 @@ var statusCmd = &cobra.Command{
 				"onHold":    wal.OnHold,
 				"address":   firstAddr,
 				"latencyMs": latency.Milliseconds(),
+				"apiUrl":    cfg.BaseURL(),
+				"customApi": cfg.CustomEndpoint(),
 			})
 		}
 
@@ -57,10 +62,18 @@ var statusCmd = &cobra.Command{
 		if firstAddr != "" {
 			fmt.Printf("  address:   %s\n", firstAddr)
 		}
-		fmt.Printf("  balance:   %s\n", format.Sats(wal.Balance))
-		fmt.Printf("  available: %s\n", format.Sats(wal.Available))
-		fmt.Printf("  on hold:   %s\n", format.Sats(wal.OnHold))
+		fmt.Printf("  balance:   %s\n", format.Amount(wal.Balance))
+		fmt.Printf("  available: %s\n", format.Amount(wal.Available))
+		fmt.Printf("  on hold:   %s\n", format.Amount(wal.OnHold))
 		fmt.Printf("  api:       ✓ connected (%dms)\n", latency.Milliseconds())
+		if cfg.CustomEndpoint() {
+			fmt.Printf("  endpoint:  %s\n", cfg.BaseURL())
+			printWarning("Using a non-default API endpoint")
+		}
 		return nil
 	},
 }
+
+func init() {
+	addDisplayFlags(statusCmd)
+}
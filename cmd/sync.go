@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/config"
+	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/store"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Mirror wallet history locally",
+	Long: `Download the wallet's transactions, invoices and payments into a local
+mirror under the config directory.
+
+The first sync fetches the full history; later syncs only fetch records
+newer than the ones stored, and refresh invoices and payments that were
+still pending.
+
+Once a wallet has been synced, 'transactions', 'invoice list', 'payment
+list' and 'transactions export' read from the mirror, fetching only new
+records first. Pass --offline to those commands to skip the network
+entirely, and --search to search the whole history.`,
+	Example: `  lnbot sync
+  lnbot sync --all
+  lnbot sync --full --wallet agent01
+  lnbot transactions --offline --search coffee`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		full, _ := cmd.Flags().GetBool("full")
+
+		ids := []string{}
+		if all {
+			if err := requireConfig(); err != nil {
+				return err
+			}
+			wallets, err := cfg.Client().Wallets.List(cmd.Context())
+			if err != nil {
+				return apiError("listing wallets", err)
+			}
+			for _, w := range wallets {
+				ids = append(ids, w.WalletID)
+			}
+		} else {
+			id, err := resolveWalletID(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+
+		var results []map[string]any
+		for i, id := range ids {
+			if full {
+				if err := os.Remove(store.Path(storeDir(), id)); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			s, err := openStore(id)
+			if err != nil {
+				return err
+			}
+			res, err := syncStore(cmd.Context(), s)
+			if err != nil {
+				return err
+			}
+
+			if jsonFlag {
+				results = append(results, map[string]any{
+					"wallet_id":    s.WalletID,
+					"wallet_name":  s.WalletName,
+					"transactions": res.Transactions,
+					"invoices":     res.Invoices,
+					"payments":     res.Payments,
+					"updated":      res.Updated,
+					"synced_at":    s.SyncedAt,
+				})
+				continue
+			}
+
+			if i > 0 {
+				fmt.Println()
+			}
+			printSuccess(fmt.Sprintf("Synced %s (%s)", s.WalletName, s.WalletID))
+			fmt.Printf("  transactions:  %d new, %d total\n", res.Transactions, len(s.Transactions))
+			fmt.Printf("  invoices:      %d new, %d total\n", res.Invoices, len(s.Invoices))
+			fmt.Printf("  payments:      %d new, %d total\n", res.Payments, len(s.Payments))
+			if res.Updated > 0 {
+				fmt.Printf("  updated:       %d\n", res.Updated)
+			}
+		}
+
+		if jsonFlag {
+			if all {
+				return printOutput(results)
+			}
+			return printOutput(results[0])
+		}
+		return nil
+	},
+}
+
+func init() {
+	syncCmd.Flags().Bool("all", false, "sync every wallet on the account")
+	syncCmd.Flags().Bool("full", false, "discard local data and download everything again")
+}
+
+// storeDir is where wallet mirrors live, next to the config file.
+func storeDir() string {
+	return filepath.Join(filepath.Dir(config.Path()), "store")
+}
+
+// openStore returns the wallet's mirror, or a new empty one. A mirror of
+// the same wallet ID taken from a different API endpoint is discarded.
+func openStore(walletID string) (*store.Store, error) {
+	s, err := store.Load(storeDir(), walletID)
+	if errors.Is(err, store.ErrNotSynced) || (err == nil && s.APIURL != cfg.BaseURL()) {
+		return store.New(storeDir(), walletID), nil
+	}
+	return s, err
+}
+
+// syncStore brings a mirror up to date and saves it.
+func syncStore(ctx context.Context, s *store.Store) (store.Result, error) {
+	res, err := s.Sync(ctx, cfg.Client().Wallet(s.WalletID))
+	if err != nil {
+		return res, apiError("syncing", err)
+	}
+	s.APIURL = cfg.BaseURL()
+	return res, s.Save()
+}
+
+// localStore returns the mirror a listing command should read from, or nil
+// to use the API directly. Offline the mirror is used as is and must exist.
+// Online an existing mirror is refreshed first; without one the command
+// talks to the API, except that search needs a mirror.
+func localStore(ctx context.Context, offline, search bool) (*store.Store, error) {
+	if offline {
+		return offlineStore()
+	}
+	id, err := resolveWalletID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var s *store.Store
+	if store.Exists(storeDir(), id) {
+		if s, err = openStore(id); err != nil {
+			return nil, err
+		}
+	}
+	if s == nil || s.SyncedAt == nil {
+		if search {
+			return nil, usageErrorf("--search needs a local copy of the history — run 'lnbot sync' first")
+		}
+		return nil, nil
+	}
+	if _, err := s.Refresh(ctx, cfg.Client().Wallet(s.WalletID)); err != nil {
+		return nil, apiError("syncing", err)
+	}
+	return s, s.Save()
+}
+
+// offlineStore loads the mirror of the --wallet or active wallet without
+// touching the network. Wallet names are matched against synced mirrors.
+func offlineStore() (*store.Store, error) {
+	if err := requireConfig(); err != nil {
+		return nil, err
+	}
+	var (
+		s   *store.Store
+		err error
+	)
+	switch {
+	case walletFlag != "" && !strings.HasPrefix(walletFlag, "wal_"):
+		s, err = store.FindByName(storeDir(), walletFlag)
+	case walletFlag != "":
+		s, err = store.Load(storeDir(), walletFlag)
+	case cfg.ActiveWalletID != "":
+		s, err = store.Load(storeDir(), cfg.ActiveWalletID)
+	default:
+		return nil, fmt.Errorf("no active wallet — run 'lnbot wallet use <id>'")
+	}
+	if errors.Is(err, store.ErrNotSynced) {
+		return nil, fmt.Errorf("no local data for this wallet — run 'lnbot sync' first")
+	}
+	return s, err
+}
+
+// printSyncedAt notes how old offline results are.
+func printSyncedAt(s *store.Store) {
+	fmt.Printf("\n  offline — last synced %s\n", format.TimeAgo(s.SyncedAt))
+}
@@ -2,15 +2,21 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	lnbot "github.com/lnbotdev/go-sdk"
 
+	"github.com/lnbotdev/cli/internal/export"
 	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/output"
+	"github.com/lnbotdev/cli/internal/store"
 )
 
 var transactionsCmd = &cobra.Command{
@@ -18,62 +24,320 @@ var transactionsCmd = &cobra.Command{
 	Short:   "List all transaction history",
 	Aliases: []string{"txns", "tx"},
 	Long: `Show the combined transaction ledger for the active wallet — both
-incoming (credits) and outgoing (debits), newest first.`,
+incoming (credits) and outgoing (debits), newest first.
+
+Use 'lnbot transactions export' to write the full history as CSV, OFX,
+Beancount or Ledger records. After 'lnbot sync', listing reads from the
+local mirror; --offline skips the network and --search searches the
+whole history.`,
 	Example: `  lnbot transactions
   lnbot tx --limit 5
   lnbot transactions --after 20
-  lnbot transactions --json`,
+  lnbot transactions --json
+  lnbot transactions -o csv --columns number,type,amount,note
+  lnbot transactions --offline --search coffee
+  lnbot transactions export --format beancount --out wallet.beancount`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		limit, _ := cmd.Flags().GetInt("limit")
 		after, _ := cmd.Flags().GetInt("after")
+		offline, _ := cmd.Flags().GetBool("offline")
+		search, _ := cmd.Flags().GetString("search")
+		if err := applyDisplay(cmd); err != nil {
+			return err
+		}
 
-		w, err := resolveWallet()
+		local, err := localStore(cmd.Context(), offline, search != "")
 		if err != nil {
 			return err
 		}
 
-		params := &lnbot.ListTransactionsParams{Limit: lnbot.Ptr(limit)}
-		if after > 0 {
-			params.After = lnbot.Ptr(after)
-		}
+		var txs []lnbot.Transaction
+		if local != nil {
+			txs = local.ListTransactions(store.Query{Limit: limit, After: after, Search: search})
+		} else {
+			w, err := resolveWallet(cmd.Context())
+			if err != nil {
+				return err
+			}
 
-		txs, err := w.Transactions.List(context.Background(), params)
-		if err != nil {
-			return apiError("listing transactions", err)
+			params := &lnbot.ListTransactionsParams{Limit: lnbot.Ptr(limit)}
+			if after > 0 {
+				params.After = lnbot.Ptr(after)
+			}
+
+			txs, err = w.Transactions.List(cmd.Context(), params)
+			if err != nil {
+				return apiError("listing transactions", err)
+			}
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(txs)
+			return printRows(cmd, txs, transactionColumns)
 		}
 
 		if len(txs) == 0 {
-			fmt.Println("No transactions yet.")
+			if search != "" {
+				fmt.Println("No matching transactions.")
+			} else {
+				fmt.Println("No transactions yet.")
+			}
 			return nil
 		}
 
-		for _, tx := range txs {
-			sign := "+"
-			if tx.Type == "debit" {
-				sign = "-"
-			}
-			fmt.Printf("  %-6s  %s%10s sats  bal: %10s  %s\n",
-				tx.Type,
-				sign,
-				format.SatsPlain(tx.Amount),
-				format.SatsPlain(tx.BalanceAfter),
-				format.TimeAgo(tx.CreatedAt),
-			)
+		if err := printRows(cmd, txs, transactionColumns); err != nil {
+			return err
 		}
 
 		if len(txs) == limit {
 			last := txs[len(txs)-1].Number
 			fmt.Printf("\n  %d shown — next page: --after %d\n", limit, last)
 		}
+		if offline {
+			printSyncedAt(local)
+		}
 		return nil
 	},
 }
 
+// transactionColumns are the columns of 'transactions'.
+var transactionColumns = []output.Column[lnbot.Transaction]{
+	{Name: "number", Right: true, Text: func(tx lnbot.Transaction) string { return fmt.Sprintf("#%d", tx.Number) }, Value: func(tx lnbot.Transaction) any { return tx.Number }},
+	{Name: "type", Text: func(tx lnbot.Transaction) string { return tx.Type }},
+	{Name: "amount", Right: true, Text: func(tx lnbot.Transaction) string {
+		sign := "+"
+		if tx.Type == "debit" {
+			sign = "-"
+		}
+		return sign + format.AmountPlain(tx.Amount) + " " + format.UnitLabel()
+	}, Value: func(tx lnbot.Transaction) any { return tx.Amount }},
+	fiatColumn(func(tx lnbot.Transaction) int64 { return tx.Amount }),
+	{Name: "balance", Right: true, Text: func(tx lnbot.Transaction) string { return format.AmountPlain(tx.BalanceAfter) }, Value: func(tx lnbot.Transaction) any { return tx.BalanceAfter }},
+	{Name: "created", Text: func(tx lnbot.Transaction) string { return format.TimeAgo(tx.CreatedAt) }, Value: func(tx lnbot.Transaction) any { return tx.CreatedAt }},
+	{Name: "fee", Wide: true, Right: true, Text: func(tx lnbot.Transaction) string { return format.SatsPlain(tx.NetworkFee + tx.ServiceFee) }, Value: func(tx lnbot.Transaction) any { return tx.NetworkFee + tx.ServiceFee }},
+	{Name: "note", Wide: true, Text: func(tx lnbot.Transaction) string { return optional(tx.Note) }, Value: func(tx lnbot.Transaction) any { return tx.Note }},
+	{Name: "reference", Wide: true, Text: func(tx lnbot.Transaction) string { return optional(tx.Reference) }, Value: func(tx lnbot.Transaction) any { return tx.Reference }},
+	{Name: "payment_hash", Wide: true, Text: func(tx lnbot.Transaction) string { return optional(tx.PaymentHash) }, Value: func(tx lnbot.Transaction) any { return tx.PaymentHash }},
+}
+
 func init() {
 	transactionsCmd.Flags().Int("limit", 20, "max number of results")
 	transactionsCmd.Flags().Int("after", 0, "show results after this transaction number (for pagination)")
+	transactionsCmd.Flags().Bool("offline", false, "read from the local mirror without contacting the API (see 'lnbot sync')")
+	transactionsCmd.Flags().String("search", "", "search the whole history for text in notes, references and payment hashes (needs 'lnbot sync')")
+	addDisplayFlags(transactionsCmd)
+	addColumnsFlag(transactionsCmd, transactionColumns)
+
+	transactionsExportCmd.Flags().String("format", export.CSV, "output format: "+strings.Join(export.Formats, ", "))
+	transactionsExportCmd.Flags().String("from", "", "only transactions on or after this date (YYYY-MM-DD or RFC 3339)")
+	transactionsExportCmd.Flags().String("to", "", "only transactions on or before this date (YYYY-MM-DD or RFC 3339)")
+	transactionsExportCmd.Flags().String("out", "", "write to this file instead of stdout")
+	transactionsExportCmd.Flags().String("unit", string(export.Sats), "amount unit: sats or btc")
+	transactionsExportCmd.Flags().String("account", export.DefaultAccount, "journal account for the wallet")
+	transactionsExportCmd.Flags().String("income-account", export.DefaultIncomeAccount, "journal account for incoming payments")
+	transactionsExportCmd.Flags().String("expense-account", export.DefaultExpenseAccount, "journal account for outgoing payments")
+	transactionsExportCmd.Flags().String("fee-account", export.DefaultFeeAccount, "journal account for fees")
+	transactionsExportCmd.Flags().Bool("offline", false, "export from the local mirror without contacting the API (see 'lnbot sync')")
+
+	transactionsCmd.AddCommand(transactionsExportCmd)
+}
+
+// exportPageSize is the page size used to walk the full history.
+const exportPageSize = 100
+
+var transactionsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export transaction history for accounting",
+	Long: `Export the wallet's full transaction history, oldest first, as accounting
+records.
+
+Formats:
+  csv        one row per transaction with signed amounts and running balance
+  ofx        OFX 2.2 bank statement for accounting software
+  beancount  Beancount journal with daily balance assertions
+  ledger     Ledger journal with a balance assertion on every transaction
+
+Every page of history is fetched, or read from the local mirror once the
+wallet has been synced ('lnbot sync'). --from and --to limit the export to a
+date range (inclusive, UTC); an export that starts mid-history books the
+earlier balance as an opening balance so the assertions still hold.
+
+When --format is not given, it is inferred from the --out file extension.`,
+	Example: `  lnbot transactions export > history.csv
+  lnbot transactions export --format ofx --out march.ofx --from 2024-03-01 --to 2024-03-31
+  lnbot transactions export --out books.beancount --account Assets:Lightning:Agent01
+  lnbot transactions export --format ledger --unit btc`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formatName, _ := cmd.Flags().GetString("format")
+		fromStr, _ := cmd.Flags().GetString("from")
+		toStr, _ := cmd.Flags().GetString("to")
+		out, _ := cmd.Flags().GetString("out")
+		unit, _ := cmd.Flags().GetString("unit")
+
+		if !cmd.Flags().Changed("format") && out != "" {
+			if f, ok := exportFormatFor(out); ok {
+				formatName = f
+			}
+		}
+
+		from, err := parseExportDate(fromStr, false)
+		if err != nil {
+			return usageErrorf("--from: %w", err)
+		}
+		to, err := parseExportDate(toStr, true)
+		if err != nil {
+			return usageErrorf("--to: %w", err)
+		}
+		if !from.IsZero() && !to.IsZero() && to.Before(from) {
+			return usageErrorf("--to must not be before --from")
+		}
+
+		opts := export.Options{Unit: export.Unit(strings.ToLower(unit)), From: from, To: to}
+		opts.Account, _ = cmd.Flags().GetString("account")
+		opts.IncomeAccount, _ = cmd.Flags().GetString("income-account")
+		opts.ExpenseAccount, _ = cmd.Flags().GetString("expense-account")
+		opts.FeeAccount, _ = cmd.Flags().GetString("fee-account")
+
+		// Check the format and options before fetching anything.
+		if err := export.Write(io.Discard, formatName, nil, opts); err != nil {
+			return err
+		}
+
+		offline, _ := cmd.Flags().GetBool("offline")
+		local, err := localStore(cmd.Context(), offline, false)
+		if err != nil {
+			return err
+		}
+
+		var txs []lnbot.Transaction
+		if local != nil {
+			opts.WalletID = local.WalletID
+			txs = inRange(local.Transactions, from, to)
+		} else {
+			id, err := resolveWalletID(cmd.Context())
+			if err != nil {
+				return err
+			}
+			opts.WalletID = id
+
+			txs, err = fetchTransactions(cmd.Context(), cfg.Client().Wallet(id), from, to)
+			if err != nil {
+				return apiError("listing transactions", err)
+			}
+		}
+
+		if out == "" {
+			return export.Write(os.Stdout, formatName, txs, opts)
+		}
+
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		if err := export.Write(f, formatName, txs, opts); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+
+		if jsonFlag {
+			return printOutput(map[string]any{
+				"file":         out,
+				"format":       formatName,
+				"transactions": len(txs),
+			})
+		}
+		printSuccess(fmt.Sprintf("Exported %d transactions to %s (%s)", len(txs), out, formatName))
+		return nil
+	},
+}
+
+// fetchTransactions walks every page of history, newest first, and returns
+// the transactions in [from, to] oldest first. Zero bounds are open.
+func fetchTransactions(ctx context.Context, w *lnbot.WalletHandle, from, to time.Time) ([]lnbot.Transaction, error) {
+	var txs []lnbot.Transaction
+	params := &lnbot.ListTransactionsParams{Limit: lnbot.Ptr(exportPageSize)}
+	for {
+		page, err := w.Transactions.List(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		done := len(page) == 0
+		for _, tx := range page {
+			if tx.CreatedAt != nil {
+				if !to.IsZero() && tx.CreatedAt.After(to) {
+					continue
+				}
+				// Pages are newest first, so everything after this is older.
+				if !from.IsZero() && tx.CreatedAt.Before(from) {
+					done = true
+					break
+				}
+			}
+			txs = append(txs, tx)
+		}
+		if done || page[len(page)-1].Number <= 1 {
+			break
+		}
+		params.After = lnbot.Ptr(page[len(page)-1].Number)
+	}
+
+	for i, j := 0, len(txs)-1; i < j; i, j = i+1, j-1 {
+		txs[i], txs[j] = txs[j], txs[i]
+	}
+	return txs, nil
+}
+
+// inRange returns the transactions in [from, to], keeping their order.
+// Zero bounds are open.
+func inRange(txs []lnbot.Transaction, from, to time.Time) []lnbot.Transaction {
+	var out []lnbot.Transaction
+	for _, tx := range txs {
+		if tx.CreatedAt != nil {
+			if !from.IsZero() && tx.CreatedAt.Before(from) {
+				continue
+			}
+			if !to.IsZero() && tx.CreatedAt.After(to) {
+				continue
+			}
+		}
+		out = append(out, tx)
+	}
+	return out
+}
+
+// parseExportDate parses a YYYY-MM-DD date (UTC) or an RFC 3339 time. With
+// endOfDay, a bare date means the last instant of that day, so that --to
+// includes the whole day.
+func parseExportDate(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD or RFC 3339)", s)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// exportFormatFor infers an export format from a file name.
+func exportFormatFor(name string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return export.CSV, true
+	case ".ofx", ".qfx":
+		return export.OFX, true
+	case ".beancount", ".bean":
+		return export.Beancount, true
+	case ".ledger", ".journal", ".dat":
+		return export.Ledger, true
+	}
+	return "", false
 }
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+// addWaitTimeoutFlag registers --wait-timeout on a command that waits for
+// an invoice or payment to settle.
+func addWaitTimeoutFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration("wait-timeout", 0, "stop waiting for settlement after this long, e.g. 5m (default: no limit)")
+}
+
+// waitContext bounds ctx by the command's --wait-timeout.
+func waitContext(ctx context.Context, cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	d, _ := cmd.Flags().GetDuration("wait-timeout")
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// stoppedWaiting is returned when waiting for an invoice or payment to
+// settle is cut short by Ctrl+C, --wait-timeout or --timeout. It says how
+// to pick the wait up again; under --json the pending result has already
+// been printed instead.
+func stoppedWaiting(ctx context.Context, kind string, number int) error {
+	if !jsonFlag {
+		printWarning(fmt.Sprintf("Stopped waiting — %s #%d is still pending", kind, number))
+		fmt.Printf("  Keep watching with: lnbot %s watch %d\n", kind, number)
+	}
+	return &exitError{
+		code:     exitNetwork,
+		err:      fmt.Errorf("%s #%d is still pending: %w", kind, number, ctx.Err()),
+		reported: true,
+	}
+}
+
+// parseNumber parses an invoice or payment number argument, with or
+// without a leading '#'.
+func parseNumber(kind, arg string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil || n <= 0 {
+		return 0, usageErrorf("invalid %s number %q", kind, arg)
+	}
+	return n, nil
+}
+
+// waitForInvoice follows an invoice until it settles or expires. It returns
+// the latest state it saw, with ctx's error if ctx ended first.
+func waitForInvoice(ctx context.Context, w *lnbot.WalletHandle, invoice *lnbot.Invoice) (*lnbot.Invoice, error) {
+	return waitFor(ctx, invoice, invoiceFinal,
+		func(ctx context.Context) (*lnbot.Invoice, bool, error) {
+			ctx, cancel := context.WithCancel(ctx)
+			events, errs := w.Invoices.Watch(ctx, invoice.Number, nil)
+			return readStream(cancel, events, errs, func(ev lnbot.InvoiceEvent) *lnbot.Invoice { return &ev.Data }, invoiceFinal)
+		},
+		func(ctx context.Context) (*lnbot.Invoice, error) {
+			return w.Invoices.Get(ctx, invoice.Number)
+		})
+}
+
+// waitForPayment follows a payment until it settles or fails. It returns
+// the latest state it saw, with ctx's error if ctx ended first.
+func waitForPayment(ctx context.Context, w *lnbot.WalletHandle, payment *lnbot.Payment) (*lnbot.Payment, error) {
+	final := func(p *lnbot.Payment) bool { return !pending(p) }
+	return waitFor(ctx, payment, final,
+		func(ctx context.Context) (*lnbot.Payment, bool, error) {
+			ctx, cancel := context.WithCancel(ctx)
+			events, errs := w.Payments.Watch(ctx, payment.Number, nil)
+			return readStream(cancel, events, errs, func(ev lnbot.PaymentEvent) *lnbot.Payment { return &ev.Data }, final)
+		},
+		func(ctx context.Context) (*lnbot.Payment, error) {
+			return w.Payments.Get(ctx, payment.Number)
+		})
+}
+
+// readStream reads a watch stream until an event with a final state arrives
+// or the stream ends, and returns the latest state with ok set if there was
+// one. It then cancels the stream and drains it, so that neither the
+// connection nor the SDK's reader outlives the call.
+func readStream[E, T any](cancel context.CancelFunc, events <-chan E, errs <-chan error, data func(E) T, final func(T) bool) (latest T, ok bool, err error) {
+	defer func() {
+		cancel()
+		for range events {
+		}
+	}()
+	for ev := range events {
+		latest, ok = data(ev), true
+		if final(latest) {
+			return latest, true, nil
+		}
+	}
+	if ok {
+		return latest, true, nil
+	}
+	return latest, false, <-errs
+}
+
+func invoiceFinal(inv *lnbot.Invoice) bool {
+	return inv.Status != "pending"
+}
+
+// waitFor waits on an SSE stream until current is final. watch returns the
+// latest state the stream reported, or the error it ended with. When the stream drops
+// waitFor reconnects with backoff, fetching the latest state first in case
+// it changed in between. Client errors such as a revoked key end the wait.
+func waitFor[T any](ctx context.Context, current T, final func(T) bool,
+	watch func(context.Context) (T, bool, error), get func(context.Context) (T, error)) (T, error) {
+	backoff := watchBackoff
+	for !final(current) {
+		next, ok, err := watch(ctx)
+		if ok {
+			current, backoff = next, watchBackoff
+			continue
+		}
+		if ctx.Err() != nil {
+			return current, ctx.Err()
+		}
+
+		var apiErr *lnbot.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 &&
+			apiErr.StatusCode != http.StatusRequestTimeout && apiErr.StatusCode != http.StatusTooManyRequests {
+			return current, err
+		}
+		reason := "stream closed"
+		if err != nil {
+			reason = err.Error()
+		}
+		fmt.Fprintf(os.Stderr, "⚠ %s — reconnecting in %s\n", reason, backoff)
+		select {
+		case <-ctx.Done():
+			return current, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+
+		if latest, err := get(ctx); err == nil {
+			current = latest
+		}
+	}
+	return current, nil
+}
@@ -1,14 +1,14 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 
 	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/format"
+	"github.com/lnbotdev/cli/internal/output"
 )
 
 var walletCmd = &cobra.Command{
@@ -22,6 +22,8 @@ different one, or 'wallet use' to switch.`,
 }
 
 func init() {
+	addColumnsFlag(walletListCmd, walletColumns)
+
 	walletCmd.AddCommand(walletCreateCmd)
 	walletCmd.AddCommand(walletListCmd)
 	walletCmd.AddCommand(walletUseCmd)
@@ -42,7 +44,7 @@ accessible via your existing user key.`,
 			return err
 		}
 
-		ctx := context.Background()
+		ctx := cmd.Context()
 		ln := cfg.Client()
 
 		wallet, err := ln.Wallets.Create(ctx)
@@ -59,7 +61,7 @@ accessible via your existing user key.`,
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(wallet)
+			return printOutput(wallet)
 		}
 
 		printSuccess("Wallet created")
@@ -76,19 +78,21 @@ var walletListCmd = &cobra.Command{
 	Aliases: []string{"ls"},
 	Long:    `Show all wallets under your account. The active wallet is marked with a bullet.`,
 	Example: `  lnbot wallet list
-  lnbot wallet list --json`,
+  lnbot wallet list --json
+  lnbot wallet list -o wide
+  lnbot wallet list --template '{{.WalletID}}'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := requireConfig(); err != nil {
 			return err
 		}
 
-		wallets, err := cfg.Client().Wallets.List(context.Background())
+		wallets, err := cfg.Client().Wallets.List(cmd.Context())
 		if err != nil {
 			return apiError("listing wallets", err)
 		}
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(wallets)
+			return printRows(cmd, wallets, walletColumns)
 		}
 
 		if len(wallets) == 0 {
@@ -96,17 +100,23 @@ var walletListCmd = &cobra.Command{
 			return nil
 		}
 
-		for _, w := range wallets {
-			marker := " "
-			if w.WalletID == cfg.ActiveWalletID {
-				marker = "●"
-			}
-			fmt.Printf("%s %s  %s\n", marker, w.Name, w.WalletID)
-		}
-		return nil
+		return printRows(cmd, wallets, walletColumns)
 	},
 }
 
+// walletColumns are the columns of 'wallet list'.
+var walletColumns = []output.Column[lnbot.WalletListItem]{
+	{Name: "active", Text: func(w lnbot.WalletListItem) string {
+		if w.WalletID == cfg.ActiveWalletID {
+			return "●"
+		}
+		return ""
+	}, Value: func(w lnbot.WalletListItem) any { return w.WalletID == cfg.ActiveWalletID }},
+	{Name: "name", Text: func(w lnbot.WalletListItem) string { return w.Name }},
+	{Name: "id", Text: func(w lnbot.WalletListItem) string { return w.WalletID }},
+	{Name: "created", Wide: true, Text: func(w lnbot.WalletListItem) string { return format.TimeAgo(w.CreatedAt) }, Value: func(w lnbot.WalletListItem) any { return w.CreatedAt }},
+}
+
 var walletUseCmd = &cobra.Command{
 	Use:   "use <name|id>",
 	Short: "Switch the active wallet",
@@ -120,7 +130,7 @@ var walletUseCmd = &cobra.Command{
 		}
 		target := args[0]
 
-		wallets, err := cfg.Client().Wallets.List(context.Background())
+		wallets, err := cfg.Client().Wallets.List(cmd.Context())
 		if err != nil {
 			return apiError("listing wallets", err)
 		}
@@ -148,13 +158,13 @@ var walletRenameCmd = &cobra.Command{
   lnbot wallet rename bot-02 --wallet wal_abc`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		w, err := resolveWallet()
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
 		newName := args[0]
-		if _, err := w.Update(context.Background(), &lnbot.UpdateWalletParams{
+		if _, err := w.Update(cmd.Context(), &lnbot.UpdateWalletParams{
 			Name: newName,
 		}); err != nil {
 			return apiError("renaming wallet", err)
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/format"
+)
+
+// Reconnect delays for 'lnbot watch'. The delay doubles after each failed
+// attempt and resets once an event arrives.
+var (
+	watchBackoff    = time.Second
+	watchMaxBackoff = 30 * time.Second
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Follow wallet activity live",
+	Long: `Stream wallet events as they happen: invoices created and settled,
+payments sent, settled and failed.
+
+The stream reconnects with backoff when the connection drops. Events that
+occur while disconnected are not replayed — run 'lnbot sync' to catch up
+on history.
+
+With --json every event is printed as one JSON object per line (NDJSON).
+
+--exec runs a shell command for each event, one at a time. The event is
+written to its stdin as JSON and described in environment variables:
+
+  LNBOT_EVENT         event name, e.g. invoice.settled
+  LNBOT_CREATED_AT    event time (RFC 3339)
+  LNBOT_WALLET_ID     wallet the event belongs to
+  LNBOT_DATA          event data as JSON
+  LNBOT_DATA_<FIELD>  each top-level data field, e.g. LNBOT_DATA_AMOUNT
+
+A failing command is reported and the stream carries on.`,
+	Example: `  lnbot watch
+  lnbot watch --json | jq .
+  lnbot watch --events invoice.settled --exec './on-paid.sh'
+  lnbot watch --events 'payment.*' --once --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		patterns, _ := cmd.Flags().GetStringSlice("events")
+		command, _ := cmd.Flags().GetString("exec")
+		execTimeout, _ := cmd.Flags().GetDuration("exec-timeout")
+		once, _ := cmd.Flags().GetBool("once")
+
+		id, err := resolveWalletID(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := cfg.Client().Wallet(id)
+
+		ctx := cmd.Context()
+
+		if !jsonFlag {
+			fmt.Printf("Watching %s — press Ctrl+C to stop.\n\n", id)
+		}
+
+		return followEvents(ctx, w, func(ev lnbot.WalletEvent) (bool, error) {
+			if !matchEvent(patterns, ev.Event) {
+				return false, nil
+			}
+			if jsonFlag {
+				if err := printEvent(ev); err != nil {
+					return false, err
+				}
+			} else {
+				fmt.Println(describeEvent(ev))
+			}
+			if command != "" {
+				runEventHook(ctx, command, execTimeout, id, ev)
+			}
+			return once, nil
+		})
+	},
+}
+
+func init() {
+	watchCmd.Flags().StringSlice("events", nil, "only these events, e.g. invoice.settled or 'payment.*' (default: all)")
+	watchCmd.Flags().String("exec", "", "shell command to run for each event")
+	watchCmd.Flags().Duration("exec-timeout", time.Minute, "kill --exec commands that run longer than this")
+	watchCmd.Flags().Bool("once", false, "exit after the first matching event")
+}
+
+// followEvents streams a wallet's events into handle until ctx is done or
+// handle asks to stop, reconnecting when the stream drops. Client errors
+// such as a revoked key end the watch.
+func followEvents(ctx context.Context, w *lnbot.WalletHandle, handle func(lnbot.WalletEvent) (stop bool, err error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	backoff := watchBackoff
+	for {
+		events, errs := w.Events.Stream(ctx)
+		for ev := range events {
+			backoff = watchBackoff
+			stop, err := handle(ev)
+			if err != nil || stop {
+				return err
+			}
+		}
+		err := <-errs
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var apiErr *lnbot.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 &&
+			apiErr.StatusCode != http.StatusRequestTimeout && apiErr.StatusCode != http.StatusTooManyRequests {
+			return apiError("watching events", err)
+		}
+
+		reason := "stream closed"
+		if err != nil {
+			reason = err.Error()
+		}
+		fmt.Fprintf(os.Stderr, "⚠ %s — reconnecting in %s\n", reason, backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+	}
+}
+
+// matchEvent reports whether name matches any pattern. A pattern is an
+// event name, a prefix such as "invoice", or a prefix with ".*". No
+// patterns match everything.
+func matchEvent(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		p = strings.TrimSuffix(strings.TrimSpace(p), ".*")
+		if p == name || p == "*" || strings.HasPrefix(name, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// describeEvent is the one-line human summary of an event.
+func describeEvent(ev lnbot.WalletEvent) string {
+	at := ev.CreatedAt
+	if t, err := time.Parse(time.RFC3339Nano, ev.CreatedAt); err == nil {
+		at = t.Local().Format("15:04:05")
+	}
+	line := fmt.Sprintf("  %s  %-16s", at, ev.Event)
+
+	switch {
+	case strings.HasPrefix(ev.Event, "invoice."):
+		var inv lnbot.Invoice
+		if json.Unmarshal(ev.Data, &inv) == nil {
+			line += fmt.Sprintf("  #%-4d  +%s", inv.Number, format.Sats(inv.Amount))
+			if inv.Memo != nil && *inv.Memo != "" {
+				line += "  " + format.Truncate(*inv.Memo, 40)
+			}
+		}
+	case strings.HasPrefix(ev.Event, "payment."):
+		var p lnbot.Payment
+		if json.Unmarshal(ev.Data, &p) == nil {
+			line += fmt.Sprintf("  #%-4d  -%s", p.Number, format.Sats(p.Amount))
+			if p.Address != "" {
+				line += "  " + format.Truncate(p.Address, 40)
+			}
+			if p.FailureReason != nil {
+				line += "  (" + *p.FailureReason + ")"
+			}
+		}
+	}
+	return strings.TrimRight(line, " ")
+}
+
+// runEventHook runs the --exec command for one event. Failures are
+// reported on stderr and do not stop the watch.
+func runEventHook(ctx context.Context, command string, timeout time.Duration, walletID string, ev lnbot.WalletEvent) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	line, _ := json.Marshal(ev)
+	c.Stdin = bytes.NewReader(append(line, '\n'))
+	c.Env = append(os.Environ(), eventEnv(walletID, ev)...)
+	// Keep stdout clean for NDJSON consumers.
+	var out io.Writer = os.Stdout
+	if jsonFlag {
+		out = os.Stderr
+	}
+	c.Stdout = out
+	c.Stderr = os.Stderr
+
+	if err := c.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		fmt.Fprintf(os.Stderr, "⚠ --exec failed for %s: %s\n", ev.Event, err)
+	}
+}
+
+// eventEnv describes an event as environment variables.
+func eventEnv(walletID string, ev lnbot.WalletEvent) []string {
+	env := []string{
+		"LNBOT_EVENT=" + ev.Event,
+		"LNBOT_CREATED_AT=" + ev.CreatedAt,
+		"LNBOT_WALLET_ID=" + walletID,
+		"LNBOT_DATA=" + string(ev.Data),
+	}
+
+	var fields map[string]any
+	if json.Unmarshal(ev.Data, &fields) != nil {
+		return env
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var v string
+		switch val := fields[k].(type) {
+		case nil:
+			continue
+		case string:
+			v = val
+		default:
+			b, _ := json.Marshal(val)
+			v = string(b)
+		}
+		env = append(env, "LNBOT_DATA_"+envName(k)+"="+v)
+	}
+	return env
+}
+
+// envName turns a camelCase JSON field into UPPER_SNAKE_CASE.
+func envName(field string) string {
+	var b strings.Builder
+	for i, r := range field {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -1,12 +1,11 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/lnbotdev/cli/internal/config"
 )
 
 var whoamiCmd = &cobra.Command{
@@ -16,20 +15,24 @@ var whoamiCmd = &cobra.Command{
 	Example: `  lnbot whoami
   lnbot whoami --json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		w, err := resolveWallet()
+		w, err := resolveWallet(cmd.Context())
 		if err != nil {
 			return err
 		}
 
-		ctx := context.Background()
+		ctx := cmd.Context()
 
 		if jsonFlag {
-			return json.NewEncoder(os.Stdout).Encode(map[string]string{
+			return printOutput(map[string]string{
 				"wallet_id": w.WalletID,
 				"api_key":   truncateKey(cfg.PrimaryKey),
+				"profile":   cfg.Name(),
 			})
 		}
 
+		if cfg.Name() != config.DefaultProfile {
+			fmt.Printf("  profile: %s\n", cfg.Name())
+		}
 		fmt.Printf("  wallet:  %s\n", w.WalletID)
 
 		wal, err := w.Get(ctx)
@@ -0,0 +1,138 @@
+// Package amount parses human-friendly amounts into sats.
+//
+// Besides a plain number of sats, an amount may carry a multiplier or a
+// unit: 21k, 1.5M, 0.001btc, 2500msat. Fiat amounts such as 5usd or
+// 10 EUR are converted at the current exchange rate. Amounts that would
+// need a fraction of a sat are rejected, except fiat amounts, which are
+// rounded to the nearest sat.
+package amount
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/lnbotdev/cli/internal/format"
+)
+
+// Rates looks up the price of one bitcoin in a fiat currency.
+type Rates interface {
+	// Price returns the price of 1 BTC in currency, an upper-case ISO
+	// 4217 code such as "USD".
+	Price(currency string) (float64, error)
+}
+
+// Amount is a parsed amount.
+type Amount struct {
+	Sats int64
+	// Fiat is the amount as given, e.g. "5 USD", when it was converted
+	// from a fiat currency.
+	Fiat string
+}
+
+// String is the amount in sats, with the fiat amount it was converted
+// from, e.g. "7,692 sats (5 USD)".
+func (a Amount) String() string {
+	s := format.Sats(a.Sats)
+	if a.Fiat != "" {
+		s += " (" + a.Fiat + ")"
+	}
+	return s
+}
+
+// units maps unit suffixes to their value in sats.
+var units = map[string]*big.Rat{
+	"":      big.NewRat(1, 1),
+	"sat":   big.NewRat(1, 1),
+	"sats":  big.NewRat(1, 1),
+	"k":     big.NewRat(1_000, 1),
+	"m":     big.NewRat(1_000_000, 1),
+	"btc":   big.NewRat(100_000_000, 1),
+	"msat":  big.NewRat(1, 1000),
+	"msats": big.NewRat(1, 1000),
+}
+
+// Parse parses s into sats. An empty s is zero. rates is only used for
+// fiat amounts and may be nil, in which case they are rejected.
+func Parse(s string, rates Rates) (Amount, error) {
+	in := strings.TrimSpace(s)
+	if in == "" {
+		return Amount{}, nil
+	}
+
+	// Split into number and unit: "1.5M", "0.001 btc", "5usd".
+	i := strings.IndexFunc(in, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != ',' && r != '_'
+	})
+	num, unit := in, ""
+	if i >= 0 {
+		num, unit = in[:i], strings.ToLower(strings.TrimSpace(in[i:]))
+	}
+	num = strings.NewReplacer(",", "", "_", "").Replace(num)
+	if strings.HasPrefix(in, "-") {
+		return Amount{}, fmt.Errorf("invalid amount %q: must be a positive integer number of sats", s)
+	}
+	if num == "" {
+		return Amount{}, fmt.Errorf("invalid amount %q: expected e.g. 1000, 21k, 1.5M, 0.001btc, 2500msat or 5usd", s)
+	}
+	value, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid amount %q: %q is not a number", s, num)
+	}
+
+	if mult, ok := units[unit]; ok {
+		sats := new(big.Rat).Mul(value, mult)
+		if !sats.IsInt() {
+			return Amount{}, fmt.Errorf("invalid amount %q: amounts must be whole sats", s)
+		}
+		if !sats.Num().IsInt64() {
+			return Amount{}, fmt.Errorf("invalid amount %q: too large", s)
+		}
+		return Amount{Sats: sats.Num().Int64()}, nil
+	}
+
+	if !isCurrency(unit) {
+		return Amount{}, fmt.Errorf("invalid amount %q: unknown unit %q (use sat, k, M, btc, msat or a currency code such as usd)", s, unit)
+	}
+	currency := strings.ToUpper(unit)
+	if rates == nil {
+		return Amount{}, fmt.Errorf("invalid amount %q: fiat amounts are not accepted here", s)
+	}
+	price, err := rates.Price(currency)
+	if err != nil {
+		return Amount{}, err
+	}
+	if price <= 0 {
+		return Amount{}, fmt.Errorf("invalid exchange rate for %s: %v", currency, price)
+	}
+	rate := new(big.Rat).SetFloat64(price)
+	sats := new(big.Rat).Quo(new(big.Rat).Mul(value, units["btc"]), rate)
+	n, _ := new(big.Float).SetRat(sats).Float64()
+	rounded := int64(n + 0.5)
+	if rounded <= 0 && value.Sign() > 0 {
+		return Amount{}, fmt.Errorf("invalid amount %q: less than 1 sat", s)
+	}
+	return Amount{Sats: rounded, Fiat: value.FloatString(decimals(num)) + " " + currency}, nil
+}
+
+// isCurrency reports whether unit looks like an ISO 4217 currency code.
+func isCurrency(unit string) bool {
+	if len(unit) != 3 {
+		return false
+	}
+	for _, r := range unit {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// decimals is the number of digits after the decimal point in num.
+func decimals(num string) int {
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		return len(num) - i - 1
+	}
+	return 0
+}
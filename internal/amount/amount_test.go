@@ -0,0 +1,87 @@
+package amount
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fixedRates map[string]float64
+
+func (r fixedRates) Price(currency string) (float64, error) {
+	p, ok := r[currency]
+	if !ok {
+		return 0, fmt.Errorf("no rate for %s", currency)
+	}
+	return p, nil
+}
+
+var testRates = fixedRates{"USD": 65000, "EUR": 60000}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		sats int64
+		fiat string
+	}{
+		{"", 0, ""},
+		{"1000", 1000, ""},
+		{"1,000", 1000, ""},
+		{"100 sats", 100, ""},
+		{"1sat", 1, ""},
+		{"21k", 21000, ""},
+		{"21K", 21000, ""},
+		{"1.5M", 1500000, ""},
+		{"0.001btc", 100000, ""},
+		{"0.001 BTC", 100000, ""},
+		{"1btc", 100000000, ""},
+		{"3000msat", 3, ""},
+		{"5usd", 7692, "5 USD"},
+		{"10 EUR", 16667, "10 EUR"},
+		{"0.50usd", 769, "0.50 USD"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, testRates)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got.Sats != tt.sats || got.Fiat != tt.fiat {
+			t.Errorf("Parse(%q) = %+v, want %d sats %q", tt.in, got, tt.sats, tt.fiat)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"abc":            "invalid amount",
+		"-5":             "invalid amount",
+		"1.5":            "whole sats",
+		"0.000000001btc": "whole sats",
+		"2500msat":       "whole sats",
+		"1.2.3k":         "not a number",
+		"5 bananas":      "unknown unit",
+		"5gbp":           "no rate for GBP",
+		"0.0000001usd":   "less than 1 sat",
+		"99999999999btc": "too large",
+	}
+	for in, want := range tests {
+		_, err := Parse(in, testRates)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", in, err, want)
+		}
+	}
+
+	if _, err := Parse("5usd", nil); err == nil || !strings.Contains(err.Error(), "fiat amounts are not accepted") {
+		t.Errorf("fiat without rates: %v", err)
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	if got := (Amount{Sats: 7692, Fiat: "5 USD"}).String(); got != "7,692 sats (5 USD)" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Amount{Sats: 21000}).String(); got != "21,000 sats" {
+		t.Errorf("String() = %q", got)
+	}
+}
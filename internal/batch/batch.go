@@ -0,0 +1,189 @@
+// Package batch reads payout files for 'lnbot pay batch', tracks the status
+// of each row in a state file so that an interrupted batch can be resumed,
+// and writes result reports.
+//
+// A payout file is CSV with a header row, or a JSON array of objects. The
+// columns (or keys) are target, amount, max_fee and memo; only target is
+// required, and amount is required unless the target is a BOLT11 invoice
+// that carries one. Other columns are ignored.
+package batch
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lnbotdev/cli/internal/amount"
+	"github.com/lnbotdev/cli/internal/bolt11"
+)
+
+// Row is one payout.
+type Row struct {
+	// Line is the row's position in the file: the line number for CSV, the
+	// 1-based array index for JSON.
+	Line   int    `json:"line"`
+	Target string `json:"target"`
+	Amount int64  `json:"amount,omitempty"`
+	MaxFee int64  `json:"max_fee,omitempty"`
+	Memo   string `json:"memo,omitempty"`
+
+	// Key identifies the row across runs. It depends on the row's content,
+	// not its position, so rows can be reordered or added between runs.
+	Key string `json:"-"`
+}
+
+// Load reads a payout file. Files ending in .json are read as JSON, others
+// as CSV.
+func Load(path string) ([]Row, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseJSON(f)
+	}
+	return ParseCSV(f)
+}
+
+// ParseCSV reads payouts from CSV with a header row. Header names are
+// matched ignoring case, spaces, dashes and underscores, so "Max Fee" and
+// "max-fee" both work.
+func ParseCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty payout file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		if i == 0 {
+			h = strings.TrimPrefix(h, "\ufeff") // Excel's byte order mark
+		}
+		name := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(h)))
+		cols[name] = i
+	}
+	if _, ok := cols["target"]; !ok {
+		return nil, errors.New(`payout file has no "target" column`)
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var rows []Row
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		row := Row{Line: line, Target: field(rec, "target"), Memo: field(rec, "memo")}
+		if row.Amount, err = parseSats(field(rec, "amount")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if row.MaxFee, err = parseSats(field(rec, "maxfee")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid max_fee: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+	return finish(rows)
+}
+
+// ParseJSON reads payouts from a JSON array.
+func ParseJSON(r io.Reader) ([]Row, error) {
+	var rows []Row
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("invalid payout file: %w", err)
+	}
+	for i := range rows {
+		rows[i].Line = i + 1
+		rows[i].Target = strings.TrimSpace(rows[i].Target)
+	}
+	return finish(rows)
+}
+
+// finish validates rows and assigns their keys.
+func finish(rows []Row) ([]Row, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("payout file has no rows")
+	}
+	seen := map[string]int{}
+	for i := range rows {
+		r := &rows[i]
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.Line, err)
+		}
+		// Identical rows are distinct payouts; number them.
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%s", r.Target, r.Amount, r.MaxFee, r.Memo)))
+		content := hex.EncodeToString(sum[:8])
+		seen[content]++
+		r.Key = fmt.Sprintf("%s-%d", content, seen[content])
+	}
+	return rows, nil
+}
+
+func (r *Row) validate() error {
+	switch {
+	case r.Target == "":
+		return errors.New("target is empty")
+	case r.Amount < 0:
+		return errors.New("amount must be positive")
+	case r.MaxFee < 0:
+		return errors.New("max_fee must not be negative")
+	}
+	if bolt11.IsInvoice(r.Target) {
+		inv, err := bolt11.Decode(r.Target)
+		if err != nil {
+			return err
+		}
+		if r.Amount == 0 && inv.AmountMsat == nil {
+			return errors.New("amount is required for an invoice without an amount")
+		}
+		return nil
+	}
+	lower := strings.ToLower(r.Target)
+	if !strings.Contains(r.Target, "@") && !strings.HasPrefix(lower, "lnurl") {
+		return fmt.Errorf("unrecognized target %q", r.Target)
+	}
+	if r.Amount == 0 {
+		return errors.New("amount is required for a Lightning address or LNURL")
+	}
+	return nil
+}
+
+// parseSats parses an amount such as 1000, 21k or 0.001btc. Negative
+// amounts are parsed, so that validation can report them.
+func parseSats(s string) (int64, error) {
+	neg := strings.HasPrefix(s, "-")
+	a, err := amount.Parse(strings.TrimPrefix(s, "-"), nil)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		return -a.Sats, nil
+	}
+	return a.Sats, nil
+}
@@ -0,0 +1,108 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	in := "\ufeffTarget, Amount ,Max Fee,memo,team\n" +
+		"alice@ln.bot,1000,5,weekly payout,core\n" +
+		"# bob is paid next week\n" +
+		"\n" +
+		"carol@example.com,\"2,500\",,,docs\n" +
+		"alice@ln.bot,1000,5,weekly payout,core\n"
+	rows, err := ParseCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows = %+v", rows)
+	}
+	if r := rows[0]; r.Line != 2 || r.Target != "alice@ln.bot" || r.Amount != 1000 || r.MaxFee != 5 || r.Memo != "weekly payout" {
+		t.Errorf("row 0 = %+v", r)
+	}
+	if r := rows[1]; r.Line != 5 || r.Amount != 2500 || r.MaxFee != 0 || r.Memo != "" {
+		t.Errorf("row 1 = %+v", r)
+	}
+	if rows[0].Key == rows[2].Key {
+		t.Error("identical rows must have different keys")
+	}
+	if !strings.HasSuffix(rows[0].Key, "-1") || !strings.HasSuffix(rows[2].Key, "-2") {
+		t.Errorf("keys = %q, %q", rows[0].Key, rows[2].Key)
+	}
+}
+
+func TestParseCSV_KeysIgnorePosition(t *testing.T) {
+	a, _ := ParseCSV(strings.NewReader("target,amount\nalice@ln.bot,10\nbob@ln.bot,20\n"))
+	b, _ := ParseCSV(strings.NewReader("target,amount\ncarol@ln.bot,30\nbob@ln.bot,20\nalice@ln.bot,10\n"))
+	if a[0].Key != b[2].Key || a[1].Key != b[1].Key {
+		t.Errorf("keys changed with position: %v %v", a, b)
+	}
+}
+
+func TestParseCSV_Errors(t *testing.T) {
+	tests := map[string]string{
+		"":                                   "empty",
+		"amount\n10\n":                       `no "target" column`,
+		"target,amount\n":                    "no rows",
+		"target,amount\nalice@ln.bot,ten\n":  "line 2: invalid amount",
+		"target,amount\nalice@ln.bot,\n":     "line 2: amount is required",
+		"target,amount\nalice@ln.bot,-5\n":   "line 2: amount must be positive",
+		"target,amount\n,10\n":               "line 2: target is empty",
+		"target,amount\nnot-a-target,10\n":   `unrecognized target "not-a-target"`,
+		"target,amount,max_fee\na@b.c,1,x\n": "invalid max_fee",
+		"target,amount\nlnbc1invalid,10\n":   "line 2:",
+	}
+	for in, want := range tests {
+		_, err := ParseCSV(strings.NewReader(in))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseCSV(%q) = %v, want %q", in, err, want)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	rows, err := ParseJSON(strings.NewReader(`[
+		{"target": "alice@ln.bot", "amount": 1000, "max_fee": 5, "memo": "weekly"},
+		{"target": " lnurl1dp68gurn8ghj7um9wfmxjcm99e3k7mf0v9cxj0m385ekvcenxc6r2c35xvukxefcv5mkvv34x5ekzd3ev56nyd3hxqurzepexejxxepnxscrvwfnv9nxzcn9xq6xyefhvgcxxcmyxymnserxfq5fns ", "amount": 21}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 1 || rows[1].Line != 2 || rows[0].MaxFee != 5 || strings.HasPrefix(rows[1].Target, " ") {
+		t.Errorf("rows = %+v", rows)
+	}
+
+	if _, err := ParseJSON(strings.NewReader(`{"target": "alice@ln.bot"}`)); err == nil {
+		t.Error("object instead of array should fail")
+	}
+	if _, err := ParseJSON(strings.NewReader(`[{"target": "alice@ln.bot"}]`)); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("missing amount: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "payouts.csv")
+	jsonPath := filepath.Join(dir, "payouts.JSON")
+	os.WriteFile(csvPath, []byte("target,amount\nalice@ln.bot,10\n"), 0o600)
+	os.WriteFile(jsonPath, []byte(`[{"target":"alice@ln.bot","amount":10}]`), 0o600)
+
+	a, err := Load(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a[0].Key != b[0].Key {
+		t.Errorf("same payout in CSV and JSON has keys %q and %q", a[0].Key, b[0].Key)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("missing file should fail")
+	}
+}
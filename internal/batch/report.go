@@ -0,0 +1,46 @@
+package batch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteReport writes results as CSV or JSON ("csv" or "json").
+func WriteReport(w io.Writer, format string, results []Result) error {
+	switch strings.ToLower(format) {
+	case "json":
+		if results == nil {
+			results = []Result{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"line", "target", "amount", "max_fee", "memo", "status", "payment_number", "fee", "error"})
+		for _, r := range results {
+			number := ""
+			if r.PaymentNumber > 0 {
+				number = strconv.Itoa(r.PaymentNumber)
+			}
+			cw.Write([]string{
+				strconv.Itoa(r.Line),
+				r.Target,
+				strconv.FormatInt(r.Amount, 10),
+				strconv.FormatInt(r.MaxFee, 10),
+				r.Memo,
+				r.Status,
+				number,
+				strconv.FormatInt(r.Fee, 10),
+				r.Error,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown report format %q (use csv or json)", format)
+}
@@ -0,0 +1,46 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteReport(t *testing.T) {
+	results := []Result{
+		{Row: Row{Line: 2, Target: "alice@ln.bot", Amount: 1000, MaxFee: 5, Memo: "weekly, core"}, Status: Settled, PaymentNumber: 7, Fee: 1, Attempts: 1},
+		{Row: Row{Line: 3, Target: "fail@ln.bot", Amount: 50}, Status: Failed, Error: "no route found", Attempts: 2},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, "csv", results); err != nil {
+		t.Fatal(err)
+	}
+	want := "line,target,amount,max_fee,memo,status,payment_number,fee,error\n" +
+		"2,alice@ln.bot,1000,5,\"weekly, core\",settled,7,1,\n" +
+		"3,fail@ln.bot,50,0,,failed,,0,no route found\n"
+	if buf.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteReport(&buf, "JSON", results); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0]["payment_number"] != float64(7) || got[1]["error"] != "no route found" || got[0]["target"] != "alice@ln.bot" {
+		t.Errorf("json = %v", got)
+	}
+
+	buf.Reset()
+	WriteReport(&buf, "json", nil)
+	if buf.String() != "[]\n" {
+		t.Errorf("empty json = %q", buf.String())
+	}
+	if err := WriteReport(&buf, "xml", results); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
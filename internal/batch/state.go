@@ -0,0 +1,152 @@
+package batch
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Row statuses.
+const (
+	Pending = "pending" // not sent
+	Sending = "sending" // sent, outcome not known yet
+	Settled = "settled"
+	Failed  = "failed"
+)
+
+// Result is the state of one row.
+type Result struct {
+	Row
+	Status        string     `json:"status"`
+	PaymentNumber int        `json:"payment_number,omitempty"`
+	Fee           int64      `json:"fee,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	Attempts      int        `json:"attempts,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// State records the progress of a batch. It is saved after every change,
+// so a batch that is interrupted or partly fails can be rerun and only the
+// rows that have not settled are sent again.
+type State struct {
+	// ID is random and unique to the batch. It is part of every payment's
+	// idempotency key, so a row whose outcome was lost is not paid twice
+	// when it is sent again.
+	ID       string             `json:"id"`
+	WalletID string             `json:"wallet_id"`
+	Rows     map[string]*Result `json:"rows"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// StatePath is the default state file for a payout file.
+func StatePath(input string) string {
+	return input + ".state.json"
+}
+
+// LoadState reads a state file, or starts a new batch for walletID if the
+// file does not exist. A state file of another wallet is an error.
+func LoadState(path, walletID string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		b := make([]byte, 8)
+		rand.Read(b)
+		return &State{ID: hex.EncodeToString(b), WalletID: walletID, Rows: map[string]*Result{}, path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &State{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid batch state %s: %w", path, err)
+	}
+	if s.WalletID != walletID {
+		return nil, fmt.Errorf("batch state %s belongs to wallet %s — use that wallet or another --state file", path, s.WalletID)
+	}
+	if s.Rows == nil {
+		s.Rows = map[string]*Result{}
+	}
+	s.path = path
+	return s, nil
+}
+
+// Results returns the state of each row, in file order. Rows not seen
+// before are pending.
+func (s *State) Results(rows []Row) []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Result, len(rows))
+	for i, row := range rows {
+		r, ok := s.Rows[row.Key]
+		if !ok {
+			r = &Result{Status: Pending}
+			s.Rows[row.Key] = r
+		}
+		r.Row = row
+		out[i] = *r
+	}
+	return out
+}
+
+// Update changes a row's state and saves the file.
+func (s *State) Update(key string, change func(*Result)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.Rows[key]
+	if !ok {
+		r = &Result{Status: Pending}
+		s.Rows[key] = r
+	}
+	change(r)
+	now := time.Now().UTC()
+	r.UpdatedAt = &now
+	return s.save()
+}
+
+// IdempotencyKey is the key a row's payment is sent with on the given
+// attempt. A row that is sent again after its outcome was lost must reuse
+// the attempt number; a row that failed gets a new one.
+func (s *State) IdempotencyKey(row Row, attempt int) string {
+	return fmt.Sprintf("batch-%s-%s-%d", s.ID, row.Key, attempt)
+}
+
+// NextAttempt is the attempt number to send a row with: the same as last
+// time if its outcome is unknown, otherwise the next one.
+func (r *Result) NextAttempt() int {
+	if r.Status == Sending && r.Attempts > 0 {
+		return r.Attempts
+	}
+	return r.Attempts + 1
+}
+
+// Path is where the state is saved.
+func (s *State) Path() string {
+	return s.path
+}
+
+// save writes the state atomically. It must be called with the lock held.
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".batch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,96 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testRows(t *testing.T) []Row {
+	t.Helper()
+	rows, err := ParseCSV(strings.NewReader("target,amount\nalice@ln.bot,10\nbob@ln.bot,20\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestState_SaveAndResume(t *testing.T) {
+	path := StatePath(filepath.Join(t.TempDir(), "payouts.csv"))
+	rows := testRows(t)
+
+	s, err := LoadState(path, "wal_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("a new state should not be written until a row changes")
+	}
+	results := s.Results(rows)
+	if results[0].Status != Pending || results[0].Target != "alice@ln.bot" {
+		t.Errorf("results = %+v", results)
+	}
+
+	if err := s.Update(rows[0].Key, func(r *Result) {
+		r.Status = Settled
+		r.PaymentNumber = 7
+		r.Attempts = 1
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := LoadState(path, "wal_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != s.ID {
+		t.Errorf("ID = %q, want %q", again.ID, s.ID)
+	}
+	results = again.Results(rows)
+	if r := results[0]; r.Status != Settled || r.PaymentNumber != 7 || r.Line != 2 || r.UpdatedAt == nil {
+		t.Errorf("row 0 = %+v", r)
+	}
+	if results[1].Status != Pending {
+		t.Errorf("row 1 = %+v", results[1])
+	}
+
+	if _, err := LoadState(path, "wal_b"); err == nil || !strings.Contains(err.Error(), "wal_a") {
+		t.Errorf("other wallet: %v", err)
+	}
+	os.WriteFile(path, []byte("{"), 0o600)
+	if _, err := LoadState(path, "wal_a"); err == nil {
+		t.Error("corrupt state should fail")
+	}
+}
+
+func TestState_IdempotencyKeys(t *testing.T) {
+	rows := testRows(t)
+	a, _ := LoadState(filepath.Join(t.TempDir(), "a"), "wal_a")
+	b, _ := LoadState(filepath.Join(t.TempDir(), "b"), "wal_a")
+
+	if a.IdempotencyKey(rows[0], 1) == b.IdempotencyKey(rows[0], 1) {
+		t.Error("batches should not share keys")
+	}
+	if a.IdempotencyKey(rows[0], 1) == a.IdempotencyKey(rows[1], 1) {
+		t.Error("rows should not share keys")
+	}
+	if a.IdempotencyKey(rows[0], 1) == a.IdempotencyKey(rows[0], 2) {
+		t.Error("attempts should not share keys")
+	}
+
+	tests := []struct {
+		r    Result
+		want int
+	}{
+		{Result{Status: Pending}, 1},
+		{Result{Status: Sending, Attempts: 1}, 1},
+		{Result{Status: Failed, Attempts: 1}, 2},
+		{Result{Status: Sending, Attempts: 3}, 3},
+	}
+	for _, tt := range tests {
+		if got := tt.r.NextAttempt(); got != tt.want {
+			t.Errorf("NextAttempt(%s, %d) = %d, want %d", tt.r.Status, tt.r.Attempts, got, tt.want)
+		}
+	}
+}
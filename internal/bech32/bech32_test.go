@@ -0,0 +1,94 @@
+package bech32
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecode_ValidVectors(t *testing.T) {
+	// BIP-173 test vectors.
+	valid := []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	}
+	for _, s := range valid {
+		if _, _, err := Decode(s); err != nil {
+			t.Errorf("Decode(%q) error = %v", s, err)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	invalid := []string{
+		"a12uel5m",      // bad checksum
+		"A12uEL5L",      // mixed case
+		"pzry9x0s0muk",  // no separator
+		"1pzry9x0s0muk", // empty hrp
+		"abc1b",         // too short checksum
+		"x1b4n0q5v",     // invalid character 'b'
+	}
+	for _, s := range invalid {
+		if _, _, err := Decode(s); err == nil {
+			t.Errorf("Decode(%q) expected error", s)
+		}
+	}
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	payload := []byte("https://service.com/api?q=3fc3645b439ce8e7f2553a69e5267081d96dcd340693afabe04be7b0ccd178df")
+	words, err := ConvertBits(payload, 8, 5, true)
+	if err != nil {
+		t.Fatalf("ConvertBits() error = %v", err)
+	}
+	s, err := Encode("lnurl", words)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if len(s) <= 90 {
+		t.Fatalf("test payload should exceed the BIP-173 length limit, got %d chars", len(s))
+	}
+
+	hrp, data, err := Decode(s)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if hrp != "lnurl" {
+		t.Errorf("hrp = %q, want lnurl", hrp)
+	}
+	got, err := ConvertBits(data, 5, 8, false)
+	if err != nil {
+		t.Fatalf("ConvertBits() error = %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("round trip = %q, want %q", got, payload)
+	}
+}
+
+func TestDecode_LNURL(t *testing.T) {
+	// LUD-01 example.
+	lnurl := "LNURL1DP68GURN8GHJ7UM9WFMXJCM99E3K7MF0V9CXJ0M385EKVCENXC6R2C35XVUKXEFCV5MKVV34X5EKZD3EV56NYD3HXQURZEPEXEJXXEPNXSCRVWFNV9NXZCN9XQ6XYEFHVGCXXCMYXYMNSERXFQ5FNS"
+	hrp, data, err := Decode(lnurl)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if hrp != "lnurl" {
+		t.Errorf("hrp = %q, want lnurl", hrp)
+	}
+	got, err := ConvertBits(data, 5, 8, false)
+	if err != nil {
+		t.Fatalf("ConvertBits() error = %v", err)
+	}
+	want := "https://service.com/api?q=3fc3645b439ce8e7f2553a69e5267081d96dcd340693afabe04be7b0ccd178df"
+	if string(got) != want {
+		t.Errorf("decoded = %q, want %q", got, want)
+	}
+}
+
+func TestConvertBits_InvalidPadding(t *testing.T) {
+	// 5 bits of non-zero padding can't be dropped.
+	if _, err := ConvertBits([]byte{31}, 5, 8, false); err == nil {
+		t.Error("expected padding error")
+	}
+}
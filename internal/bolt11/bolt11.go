@@ -0,0 +1,341 @@
+// Package bolt11 decodes (and, for tests and the dev server, encodes)
+// BOLT11 Lightning invoices without any network access.
+//
+// The signature is carried through but not verified, and the payee is only
+// known when the invoice includes an explicit 'n' field.
+package bolt11
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lnbotdev/cli/internal/bech32"
+)
+
+// DefaultExpiry applies when an invoice has no 'x' field.
+const DefaultExpiry = time.Hour
+
+// DefaultMinFinalCLTVExpiry applies when an invoice has no 'c' field.
+const DefaultMinFinalCLTVExpiry = 18
+
+// Tagged field types (the bech32 character of each tag).
+const (
+	tagPaymentHash     = 1  // p
+	tagRouteHint       = 3  // r
+	tagFeatures        = 5  // 9
+	tagExpiry          = 6  // x
+	tagFallback        = 9  // f
+	tagDescription     = 13 // d
+	tagPaymentSecret   = 16 // s
+	tagPayee           = 19 // n
+	tagDescriptionHash = 23 // h
+	tagMinFinalCLTV    = 24 // c
+	tagMetadata        = 27 // m
+)
+
+const (
+	timestampWords = 7
+	signatureWords = 104
+)
+
+// Networks maps invoice prefixes to network names.
+var Networks = map[string]string{
+	"bc":   "mainnet",
+	"tb":   "testnet",
+	"tbs":  "signet",
+	"bcrt": "regtest",
+	"sb":   "simnet",
+}
+
+// Invoice is a decoded BOLT11 payment request.
+type Invoice struct {
+	// Prefix is the network prefix after "ln" (bc, tb, bcrt, ...).
+	Prefix string `json:"prefix"`
+	// AmountMsat is nil for "any amount" invoices.
+	AmountMsat         *int64        `json:"amountMsat"`
+	Timestamp          time.Time     `json:"timestamp"`
+	Expiry             time.Duration `json:"-"`
+	PaymentHash        string        `json:"paymentHash"`
+	PaymentSecret      string        `json:"paymentSecret,omitempty"`
+	Description        string        `json:"description,omitempty"`
+	DescriptionHash    string        `json:"descriptionHash,omitempty"`
+	Payee              string        `json:"payee,omitempty"`
+	MinFinalCLTVExpiry int           `json:"minFinalCltvExpiry"`
+	Metadata           string        `json:"metadata,omitempty"`
+	Features           []int         `json:"features,omitempty"`
+	Fallbacks          []string      `json:"fallbacks,omitempty"`
+	RouteHints         [][]HopHint   `json:"routeHints,omitempty"`
+	Signature          string        `json:"signature"`
+}
+
+// HopHint is one hop of a private route hint ('r' field).
+type HopHint struct {
+	PubKey                    string `json:"pubkey"`
+	ShortChannelID            string `json:"shortChannelId"`
+	FeeBaseMsat               uint32 `json:"feeBaseMsat"`
+	FeeProportionalMillionths uint32 `json:"feeProportionalMillionths"`
+	CLTVExpiryDelta           uint16 `json:"cltvExpiryDelta"`
+}
+
+// Network returns the human network name for the invoice prefix.
+func (inv *Invoice) Network() string {
+	if n, ok := Networks[inv.Prefix]; ok {
+		return n
+	}
+	return inv.Prefix
+}
+
+// AmountSats returns the amount rounded up to whole sats, and false for
+// "any amount" invoices.
+func (inv *Invoice) AmountSats() (int64, bool) {
+	if inv.AmountMsat == nil {
+		return 0, false
+	}
+	return (*inv.AmountMsat + 999) / 1000, true
+}
+
+// ExpiresAt returns the time after which the invoice can no longer be paid.
+func (inv *Invoice) ExpiresAt() time.Time {
+	return inv.Timestamp.Add(inv.Expiry)
+}
+
+// Expired reports whether the invoice has expired at now.
+func (inv *Invoice) Expired(now time.Time) bool {
+	return !now.Before(inv.ExpiresAt())
+}
+
+// IsInvoice reports whether s looks like a BOLT11 invoice (optionally with a
+// lightning: URI prefix). It does not validate the checksum.
+func IsInvoice(s string) bool {
+	s = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(s, "lightning:"), "LIGHTNING:"))
+	if !strings.HasPrefix(s, "ln") || strings.HasPrefix(s, "lnurl") {
+		return false
+	}
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 4 {
+		return false
+	}
+	prefix, _ := splitHRP(s[2:sep])
+	_, ok := Networks[prefix]
+	return ok
+}
+
+// Decode parses a BOLT11 invoice. A leading "lightning:" URI scheme is
+// accepted.
+func Decode(s string) (*Invoice, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 10 && strings.EqualFold(s[:10], "lightning:") {
+		s = s[10:]
+	}
+
+	hrp, words, err := bech32.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice: %w", err)
+	}
+	if !strings.HasPrefix(hrp, "ln") {
+		return nil, fmt.Errorf("invalid invoice: prefix %q does not start with ln", hrp)
+	}
+
+	prefix, amountStr := splitHRP(hrp[2:])
+	if _, ok := Networks[prefix]; !ok {
+		return nil, fmt.Errorf("invalid invoice: unknown network prefix %q", prefix)
+	}
+
+	inv := &Invoice{
+		Prefix:             prefix,
+		Expiry:             DefaultExpiry,
+		MinFinalCLTVExpiry: DefaultMinFinalCLTVExpiry,
+	}
+
+	if amountStr != "" {
+		msat, err := parseAmount(amountStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid invoice: %w", err)
+		}
+		inv.AmountMsat = &msat
+	}
+
+	if len(words) < timestampWords+signatureWords {
+		return nil, fmt.Errorf("invalid invoice: too short")
+	}
+
+	inv.Timestamp = time.Unix(int64(wordsToUint(words[:timestampWords])), 0).UTC()
+
+	sigWords := words[len(words)-signatureWords:]
+	sig, err := bech32.ConvertBits(sigWords, 5, 8, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice signature: %w", err)
+	}
+	inv.Signature = hex.EncodeToString(sig)
+
+	tagged := words[timestampWords : len(words)-signatureWords]
+	for len(tagged) > 0 {
+		if len(tagged) < 3 {
+			return nil, fmt.Errorf("invalid invoice: truncated tagged field")
+		}
+		tag := tagged[0]
+		n := int(tagged[1])<<5 | int(tagged[2])
+		if len(tagged) < 3+n {
+			return nil, fmt.Errorf("invalid invoice: tagged field overruns data")
+		}
+		data := tagged[3 : 3+n]
+		tagged = tagged[3+n:]
+
+		if err := inv.parseField(tag, data); err != nil {
+			return nil, fmt.Errorf("invalid invoice: %w", err)
+		}
+	}
+
+	if inv.PaymentHash == "" {
+		return nil, fmt.Errorf("invalid invoice: missing payment hash")
+	}
+	return inv, nil
+}
+
+func (inv *Invoice) parseField(tag byte, data []byte) error {
+	switch tag {
+	case tagPaymentHash:
+		// Fields with an unexpected length are skipped, per BOLT11.
+		if len(data) == 52 {
+			inv.PaymentHash = wordsToHex(data)
+		}
+	case tagPaymentSecret:
+		if len(data) == 52 {
+			inv.PaymentSecret = wordsToHex(data)
+		}
+	case tagDescriptionHash:
+		if len(data) == 52 {
+			inv.DescriptionHash = wordsToHex(data)
+		}
+	case tagPayee:
+		if len(data) == 53 {
+			inv.Payee = wordsToHex(data)
+		}
+	case tagDescription:
+		b, err := bech32.ConvertBits(data, 5, 8, false)
+		if err != nil {
+			return fmt.Errorf("description: %w", err)
+		}
+		inv.Description = string(b)
+	case tagMetadata:
+		inv.Metadata = wordsToHex(data)
+	case tagExpiry:
+		inv.Expiry = time.Duration(wordsToUint(data)) * time.Second
+	case tagMinFinalCLTV:
+		inv.MinFinalCLTVExpiry = int(wordsToUint(data))
+	case tagFeatures:
+		for i := range data {
+			w := data[len(data)-1-i]
+			for bit := 0; bit < 5; bit++ {
+				if w&(1<<uint(bit)) != 0 {
+					inv.Features = append(inv.Features, i*5+bit)
+				}
+			}
+		}
+	case tagFallback:
+		if len(data) > 0 {
+			b, _ := bech32.ConvertBits(data[1:], 5, 8, false)
+			inv.Fallbacks = append(inv.Fallbacks, fmt.Sprintf("%d:%x", data[0], b))
+		}
+	case tagRouteHint:
+		b, err := bech32.ConvertBits(data, 5, 8, false)
+		if err != nil {
+			return fmt.Errorf("route hint: %w", err)
+		}
+		const hopLen = 33 + 8 + 4 + 4 + 2
+		if len(b)%hopLen != 0 {
+			return fmt.Errorf("route hint: invalid length %d", len(b))
+		}
+		var route []HopHint
+		for ; len(b) > 0; b = b[hopLen:] {
+			route = append(route, HopHint{
+				PubKey:                    hex.EncodeToString(b[:33]),
+				ShortChannelID:            formatSCID(b[33:41]),
+				FeeBaseMsat:               be32(b[41:45]),
+				FeeProportionalMillionths: be32(b[45:49]),
+				CLTVExpiryDelta:           uint16(b[49])<<8 | uint16(b[50]),
+			})
+		}
+		inv.RouteHints = append(inv.RouteHints, route)
+	}
+	return nil
+}
+
+// splitHRP splits "bc2500u" into ("bc", "2500u").
+func splitHRP(s string) (prefix, amount string) {
+	i := strings.IndexFunc(s, func(r rune) bool { return r >= '0' && r <= '9' })
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// msatPerUnit is the msat value of one unit for each amount multiplier.
+// 'p' is a tenth of a msat and handled separately.
+var msatPerUnit = map[byte]int64{
+	'm': 100_000_000,
+	'u': 100_000,
+	'n': 100,
+}
+
+const msatPerBTC = 100_000_000_000
+
+func parseAmount(s string) (int64, error) {
+	mult := s[len(s)-1]
+	digits := s
+	if mult < '0' || mult > '9' {
+		digits = s[:len(s)-1]
+	} else {
+		mult = 0
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	switch mult {
+	case 0:
+		return n * msatPerBTC, nil
+	case 'p':
+		if n%10 != 0 {
+			return 0, fmt.Errorf("invalid amount %q: sub-millisatoshi precision", s)
+		}
+		return n / 10, nil
+	default:
+		per, ok := msatPerUnit[mult]
+		if !ok {
+			return 0, fmt.Errorf("invalid amount multiplier %q", string(mult))
+		}
+		return n * per, nil
+	}
+}
+
+func wordsToUint(words []byte) uint64 {
+	var v uint64
+	for _, w := range words {
+		v = v<<5 | uint64(w)
+	}
+	return v
+}
+
+func wordsToHex(words []byte) string {
+	b, _ := bech32.ConvertBits(words, 5, 8, false)
+	return hex.EncodeToString(b)
+}
+
+func be32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// formatSCID renders a short channel ID as block x tx x output.
+func formatSCID(b []byte) string {
+	v := uint64(0)
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return fmt.Sprintf("%dx%dx%d", v>>40, v>>16&0xffffff, v&0xffff)
+}
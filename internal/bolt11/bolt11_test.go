@@ -0,0 +1,139 @@
+package bolt11
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from the BOLT11 specification.
+const (
+	donation = "lnbc1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpl2pkx2ctnv5sxxmmwwd5kgetjypeh2ursdae8g6twvus8g6rfwvs8qun0dfjkxaq9qrsgq357wnc5r2ueh7ck6q93dj32dlqnls087fxdwk8qakdyafkq3yap9us6v52vjjsrvywa6rt52cm9r9zqt8r2t7mlcwspyetp5h2tztugp9lfyql"
+	coffee   = "lnbc2500u1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpu9qrsgquk0rl77nj30yxdy8j9vdx85fkpmdla2087ne0xh8nhedh8w27kyke0lp53ut353s06fv3qfegext0eh0ymjpf39tuven09sam30g4vgpfna3rh"
+)
+
+const specHash = "0001020304050607080900010203040506070809000102030405060708090102"
+
+func TestDecode_NoAmount(t *testing.T) {
+	inv, err := Decode(donation)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if inv.Network() != "mainnet" {
+		t.Errorf("Network() = %q, want mainnet", inv.Network())
+	}
+	if inv.AmountMsat != nil {
+		t.Errorf("AmountMsat = %d, want nil", *inv.AmountMsat)
+	}
+	if _, ok := inv.AmountSats(); ok {
+		t.Error("AmountSats() ok = true for an any-amount invoice")
+	}
+	if inv.PaymentHash != specHash {
+		t.Errorf("PaymentHash = %s", inv.PaymentHash)
+	}
+	if inv.PaymentSecret != strings.Repeat("11", 32) {
+		t.Errorf("PaymentSecret = %s", inv.PaymentSecret)
+	}
+	if inv.Description != "Please consider supporting this project" {
+		t.Errorf("Description = %q", inv.Description)
+	}
+	if !inv.Timestamp.Equal(time.Unix(1496314658, 0)) {
+		t.Errorf("Timestamp = %v", inv.Timestamp)
+	}
+	if inv.Expiry != DefaultExpiry || inv.MinFinalCLTVExpiry != DefaultMinFinalCLTVExpiry {
+		t.Errorf("defaults: expiry %v, cltv %d", inv.Expiry, inv.MinFinalCLTVExpiry)
+	}
+	if len(inv.Features) != 2 || inv.Features[0] != 8 || inv.Features[1] != 14 {
+		t.Errorf("Features = %v, want [8 14]", inv.Features)
+	}
+	if len(inv.Signature) != 130 {
+		t.Errorf("Signature length = %d, want 130 hex chars", len(inv.Signature))
+	}
+}
+
+func TestDecode_AmountAndExpiry(t *testing.T) {
+	inv, err := Decode(coffee)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if inv.AmountMsat == nil || *inv.AmountMsat != 250_000_000 {
+		t.Fatalf("AmountMsat = %v, want 250000000", inv.AmountMsat)
+	}
+	if sats, _ := inv.AmountSats(); sats != 250_000 {
+		t.Errorf("AmountSats() = %d, want 250000", sats)
+	}
+	if inv.Description != "1 cup coffee" {
+		t.Errorf("Description = %q", inv.Description)
+	}
+	if inv.Expiry != time.Minute {
+		t.Errorf("Expiry = %v, want 1m", inv.Expiry)
+	}
+	if !inv.Expired(time.Now()) {
+		t.Error("a 2017 invoice should be expired")
+	}
+	if inv.Expired(inv.Timestamp.Add(59 * time.Second)) {
+		t.Error("invoice should not be expired before its expiry")
+	}
+}
+
+func TestDecode_LightningURI(t *testing.T) {
+	if _, err := Decode("lightning:" + strings.ToUpper(coffee)); err != nil {
+		t.Errorf("Decode() error = %v", err)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"bad checksum":  coffee[:len(coffee)-1] + "q",
+		"not lightning": "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		"unknown net":   "lnxx1qqqqqqqqqqqqqqqqqqq",
+		"garbage":       "hello",
+	}
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(s); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		msat int64
+	}{
+		{"1", 100_000_000_000},
+		{"2m", 200_000_000},
+		{"2500u", 250_000_000},
+		{"10n", 1_000},
+		{"10p", 1},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in)
+		if err != nil || got != tt.msat {
+			t.Errorf("parseAmount(%q) = %d, %v; want %d", tt.in, got, err, tt.msat)
+		}
+	}
+
+	for _, in := range []string{"1p", "0u", "5x", "u"} {
+		if _, err := parseAmount(in); err == nil {
+			t.Errorf("parseAmount(%q) should fail", in)
+		}
+	}
+}
+
+func TestIsInvoice(t *testing.T) {
+	yes := []string{coffee, donation, "LIGHTNING:" + strings.ToUpper(coffee), "lntb10u1abcdef", "lnbcrt1abcdef"}
+	for _, s := range yes {
+		if !IsInvoice(s) {
+			t.Errorf("IsInvoice(%q) = false", s)
+		}
+	}
+	no := []string{"alice@ln.bot", "lnurl1dp68gurn8ghj7", "lnxx10u1abcdef", "ln1"}
+	for _, s := range no {
+		if IsInvoice(s) {
+			t.Errorf("IsInvoice(%q) = true", s)
+		}
+	}
+}
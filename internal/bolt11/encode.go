@@ -0,0 +1,152 @@
+package bolt11
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lnbotdev/cli/internal/bech32"
+)
+
+// Encode serializes inv as a BOLT11 string. It does not sign: Signature
+// (65 bytes hex) is copied as-is, or zero-filled when empty, so the result
+// only decodes — no node will accept it. It exists for tests and the local
+// dev server.
+//
+// Encoded fields: amount, timestamp, payment hash, payment secret,
+// description or description hash, payee, expiry, min_final_cltv_expiry
+// and route hints.
+func Encode(inv *Invoice) (string, error) {
+	if _, ok := Networks[inv.Prefix]; !ok {
+		return "", fmt.Errorf("unknown network prefix %q", inv.Prefix)
+	}
+
+	hrp := "ln" + inv.Prefix
+	if inv.AmountMsat != nil {
+		if *inv.AmountMsat <= 0 {
+			return "", fmt.Errorf("amount must be positive")
+		}
+		hrp += formatAmount(*inv.AmountMsat)
+	}
+
+	words := uintToWords(uint64(inv.Timestamp.Unix()), timestampWords)
+
+	addBytes := func(tag byte, hexStr string) error {
+		if hexStr == "" {
+			return nil
+		}
+		b, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return fmt.Errorf("tag %d: %w", tag, err)
+		}
+		data, _ := bech32.ConvertBits(b, 8, 5, true)
+		words = appendField(words, tag, data)
+		return nil
+	}
+
+	if inv.PaymentHash == "" {
+		return "", fmt.Errorf("payment hash is required")
+	}
+	if err := addBytes(tagPaymentHash, inv.PaymentHash); err != nil {
+		return "", err
+	}
+	if err := addBytes(tagPaymentSecret, inv.PaymentSecret); err != nil {
+		return "", err
+	}
+	if inv.Description != "" {
+		data, _ := bech32.ConvertBits([]byte(inv.Description), 8, 5, true)
+		words = appendField(words, tagDescription, data)
+	}
+	if err := addBytes(tagDescriptionHash, inv.DescriptionHash); err != nil {
+		return "", err
+	}
+	if err := addBytes(tagPayee, inv.Payee); err != nil {
+		return "", err
+	}
+	if inv.Expiry != 0 && inv.Expiry != DefaultExpiry {
+		words = appendField(words, tagExpiry, uintToWords(uint64(inv.Expiry/time.Second), 0))
+	}
+	if inv.MinFinalCLTVExpiry != 0 && inv.MinFinalCLTVExpiry != DefaultMinFinalCLTVExpiry {
+		words = appendField(words, tagMinFinalCLTV, uintToWords(uint64(inv.MinFinalCLTVExpiry), 0))
+	}
+	for _, route := range inv.RouteHints {
+		var b []byte
+		for _, h := range route {
+			pk, err := hex.DecodeString(h.PubKey)
+			if err != nil || len(pk) != 33 {
+				return "", fmt.Errorf("route hint: invalid pubkey %q", h.PubKey)
+			}
+			scid, err := parseSCID(h.ShortChannelID)
+			if err != nil {
+				return "", err
+			}
+			b = append(b, pk...)
+			b = append(b, byte(scid>>56), byte(scid>>48), byte(scid>>40), byte(scid>>32),
+				byte(scid>>24), byte(scid>>16), byte(scid>>8), byte(scid))
+			b = append(b, byte(h.FeeBaseMsat>>24), byte(h.FeeBaseMsat>>16), byte(h.FeeBaseMsat>>8), byte(h.FeeBaseMsat))
+			b = append(b, byte(h.FeeProportionalMillionths>>24), byte(h.FeeProportionalMillionths>>16),
+				byte(h.FeeProportionalMillionths>>8), byte(h.FeeProportionalMillionths))
+			b = append(b, byte(h.CLTVExpiryDelta>>8), byte(h.CLTVExpiryDelta))
+		}
+		data, _ := bech32.ConvertBits(b, 8, 5, true)
+		words = appendField(words, tagRouteHint, data)
+	}
+
+	sig := make([]byte, 65)
+	if inv.Signature != "" {
+		b, err := hex.DecodeString(inv.Signature)
+		if err != nil || len(b) != 65 {
+			return "", fmt.Errorf("signature must be 65 bytes of hex")
+		}
+		sig = b
+	}
+	sigWords, _ := bech32.ConvertBits(sig, 8, 5, true)
+	words = append(words, sigWords...)
+
+	return bech32.Encode(hrp, words)
+}
+
+func appendField(words []byte, tag byte, data []byte) []byte {
+	return append(append(words, tag, byte(len(data)>>5), byte(len(data)&31)), data...)
+}
+
+// uintToWords encodes v as big-endian 5-bit words. With n == 0 the minimal
+// number of words is used.
+func uintToWords(v uint64, n int) []byte {
+	if n == 0 {
+		for x := v; x > 0; x >>= 5 {
+			n++
+		}
+		if n == 0 {
+			n = 1
+		}
+	}
+	out := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		out[i] = byte(v & 31)
+		v >>= 5
+	}
+	return out
+}
+
+// formatAmount picks the largest multiplier that represents msat exactly.
+func formatAmount(msat int64) string {
+	if msat%msatPerBTC == 0 {
+		return strconv.FormatInt(msat/msatPerBTC, 10)
+	}
+	for _, m := range []byte{'m', 'u', 'n'} {
+		if msat%msatPerUnit[m] == 0 {
+			return strconv.FormatInt(msat/msatPerUnit[m], 10) + string(m)
+		}
+	}
+	return strconv.FormatInt(msat*10, 10) + "p"
+}
+
+func parseSCID(s string) (uint64, error) {
+	var block, tx, out uint64
+	if _, err := fmt.Sscanf(s, "%dx%dx%d", &block, &tx, &out); err != nil {
+		return 0, fmt.Errorf("route hint: invalid short channel id %q", s)
+	}
+	return block<<40 | tx<<16 | out, nil
+}
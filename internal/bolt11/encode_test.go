@@ -0,0 +1,79 @@
+package bolt11
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode_RoundTrip(t *testing.T) {
+	amount := int64(1_500_000)
+	in := &Invoice{
+		Prefix:             "bcrt",
+		AmountMsat:         &amount,
+		Timestamp:          time.Unix(1_700_000_000, 0).UTC(),
+		Expiry:             10 * time.Minute,
+		PaymentHash:        specHash,
+		PaymentSecret:      strings.Repeat("22", 32),
+		Description:        "coffee ☕",
+		Payee:              "03" + strings.Repeat("ab", 32),
+		MinFinalCLTVExpiry: 40,
+		RouteHints: [][]HopHint{{{
+			PubKey:                    "02" + strings.Repeat("cd", 32),
+			ShortChannelID:            "800000x1234x1",
+			FeeBaseMsat:               1000,
+			FeeProportionalMillionths: 250,
+			CLTVExpiryDelta:           144,
+		}}},
+		Signature: strings.Repeat("00", 65),
+	}
+
+	s, err := Encode(in)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !strings.HasPrefix(s, "lnbcrt15u1") {
+		t.Errorf("Encode() = %s, want lnbcrt15u1 prefix", s)
+	}
+
+	out, err := Decode(s)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", out, in)
+	}
+}
+
+func TestEncode_Amounts(t *testing.T) {
+	tests := map[int64]string{
+		100_000_000_000: "lnbc1",
+		200_000_000:     "lnbc2m",
+		1_000:           "lnbc10n",
+		1:               "lnbc10p",
+	}
+	for msat, prefix := range tests {
+		m := msat
+		s, err := Encode(&Invoice{Prefix: "bc", AmountMsat: &m, PaymentHash: specHash})
+		if err != nil {
+			t.Fatalf("Encode(%d) error = %v", msat, err)
+		}
+		if !strings.HasPrefix(s, prefix+"1") {
+			t.Errorf("Encode(%d) = %s, want prefix %s", msat, s, prefix)
+		}
+		inv, err := Decode(s)
+		if err != nil || *inv.AmountMsat != msat {
+			t.Errorf("Decode(Encode(%d)) = %v, %v", msat, inv.AmountMsat, err)
+		}
+	}
+}
+
+func TestEncode_Invalid(t *testing.T) {
+	if _, err := Encode(&Invoice{Prefix: "xx", PaymentHash: specHash}); err == nil {
+		t.Error("expected error for unknown prefix")
+	}
+	if _, err := Encode(&Invoice{Prefix: "bc"}); err == nil {
+		t.Error("expected error for missing payment hash")
+	}
+}
@@ -3,19 +3,339 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/fees"
 )
 
+// DefaultAPIURL is the production API, used unless a profile, LNBOT_API_URL
+// or --api-url says otherwise.
+const DefaultAPIURL = "https://api.ln.bot"
+
+// DefaultProfile is the profile used when none is selected, and the one
+// that single-account config files are migrated into.
+const DefaultProfile = "default"
+
+// Profile is one account: its keys, active wallet and API endpoint.
+type Profile struct {
+	PrimaryKey     string `json:"primary_key"`
+	SecondaryKey   string `json:"secondary_key,omitempty"`
+	ActiveWalletID string `json:"active_wallet_id,omitempty"`
+	APIURL         string `json:"api_url,omitempty"`
+
+	// Policies maps wallet IDs to spending policy files.
+	Policies map[string]string `json:"policies,omitempty"`
+
+	// FeeLimit is the default routing fee limit for payments.
+	FeeLimit *fees.Limit `json:"fee_limit,omitempty"`
+
+	// RatesFile and RatesURL are where exchange rates for fiat amounts
+	// come from.
+	RatesFile string `json:"rates_file,omitempty"`
+	RatesURL  string `json:"rates_url,omitempty"`
+
+	// Unit and Fiat are how amounts are displayed by default.
+	Unit string `json:"unit,omitempty"`
+	Fiat string `json:"fiat,omitempty"`
+}
+
 // Config stores the CLI authentication state.
 // Only the user key and active wallet ID are persisted locally.
 // Wallet listing comes from the API.
+//
+// The config file holds any number of named profiles. The flat fields are
+// the selected profile; Save writes them back into Profiles.
 type Config struct {
-	PrimaryKey     string `json:"primary_key"`
-	SecondaryKey   string `json:"secondary_key,omitempty"`
-	ActiveWalletID string `json:"active_wallet_id,omitempty"`
+	PrimaryKey     string
+	SecondaryKey   string
+	ActiveWalletID string
+	APIURL         string
+
+	// Policies maps wallet IDs to spending policy files.
+	Policies map[string]string
+
+	// FeeLimit is the default routing fee limit for payments, or nil.
+	FeeLimit *fees.Limit
+
+	// RatesFile and RatesURL are where exchange rates for fiat amounts
+	// come from.
+	RatesFile string
+	RatesURL  string
+
+	// Unit and Fiat are how amounts are displayed by default.
+	Unit string
+	Fiat string
+
+	// ProfileName is the selected profile ("default" when empty).
+	ProfileName string
+	// CurrentProfile is the profile used when none is selected with
+	// --profile or LNBOT_PROFILE.
+	CurrentProfile string
+	// Profiles holds every profile in the file. The selected profile's
+	// entry is stale until Save.
+	Profiles map[string]*Profile
+}
+
+// file is the on-disk layout. The flat legacy fields are only read, to
+// migrate single-account configs into the default profile.
+type file struct {
+	CurrentProfile string              `json:"current_profile,omitempty"`
+	Profiles       map[string]*Profile `json:"profiles,omitempty"`
+
+	PrimaryKey     string            `json:"primary_key,omitempty"`
+	SecondaryKey   string            `json:"secondary_key,omitempty"`
+	ActiveWalletID string            `json:"active_wallet_id,omitempty"`
+	Policies       map[string]string `json:"policies,omitempty"`
+}
+
+// MarshalJSON writes the profile layout, folding the flat fields into the
+// selected profile.
+func (c *Config) MarshalJSON() ([]byte, error) {
+	name := c.Name()
+	f := file{CurrentProfile: c.CurrentProfile, Profiles: map[string]*Profile{}}
+	if f.CurrentProfile == "" {
+		f.CurrentProfile = name
+	}
+	for n, p := range c.Profiles {
+		f.Profiles[n] = p
+	}
+	if _, ok := f.Profiles[name]; ok || c.PrimaryKey != "" {
+		f.Profiles[name] = c.current()
+	}
+	if len(f.Profiles) == 0 {
+		f.CurrentProfile = ""
+	}
+	return json.Marshal(f)
+}
+
+// UnmarshalJSON reads both the profile layout and the legacy single-account
+// layout, and selects the current profile.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	if len(f.Profiles) == 0 && (f.PrimaryKey != "" || f.ActiveWalletID != "") {
+		f.Profiles = map[string]*Profile{DefaultProfile: {
+			PrimaryKey:     f.PrimaryKey,
+			SecondaryKey:   f.SecondaryKey,
+			ActiveWalletID: f.ActiveWalletID,
+			Policies:       f.Policies,
+		}}
+	}
+	*c = Config{CurrentProfile: f.CurrentProfile, Profiles: f.Profiles}
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+	c.Select(c.CurrentProfile)
+	return nil
+}
+
+// Name returns the selected profile name.
+func (c *Config) Name() string {
+	if c.ProfileName == "" {
+		return DefaultProfile
+	}
+	return c.ProfileName
+}
+
+// Select makes name the selected profile, loading its fields (or clearing
+// them if the profile does not exist yet). Unsaved changes to the previously
+// selected profile are kept in Profiles.
+func (c *Config) Select(name string) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	if c.ProfileName != "" {
+		c.flush()
+	}
+	c.ProfileName = name
+	p := c.Profiles[name]
+	if p == nil {
+		p = &Profile{}
+	}
+	c.PrimaryKey = p.PrimaryKey
+	c.SecondaryKey = p.SecondaryKey
+	c.ActiveWalletID = p.ActiveWalletID
+	c.APIURL = p.APIURL
+	c.Policies = p.Policies
+	c.FeeLimit = p.FeeLimit
+	c.RatesFile = p.RatesFile
+	c.RatesURL = p.RatesURL
+	c.Unit = p.Unit
+	c.Fiat = p.Fiat
+}
+
+// AddProfile stores a new profile. The first profile in a file becomes the
+// current one.
+func (c *Config) AddProfile(name string, p *Profile) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if c.Profile(name) != nil {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	c.flush()
+	c.Profiles[name] = p
+	if c.CurrentProfile == "" || c.Profiles[c.CurrentProfile] == nil {
+		c.CurrentProfile = name
+	}
+	if name == c.Name() {
+		c.reselect(name)
+	}
+	return nil
+}
+
+// UseProfile makes name the current profile.
+func (c *Config) UseProfile(name string) error {
+	if c.Profile(name) == nil {
+		return &ProfileNotFoundError{Name: name}
+	}
+	c.CurrentProfile = name
+	return nil
+}
+
+// RemoveProfile deletes a profile. The current profile cannot be removed.
+func (c *Config) RemoveProfile(name string) error {
+	if c.Profile(name) == nil {
+		return &ProfileNotFoundError{Name: name}
+	}
+	if name == c.CurrentProfile {
+		return fmt.Errorf("cannot remove the current profile — switch with 'lnbot profile use <name>' first")
+	}
+	c.flush()
+	delete(c.Profiles, name)
+	c.reselect(c.CurrentProfile)
+	return nil
+}
+
+// RenameProfile renames a profile, following it if it is current.
+func (c *Config) RenameProfile(oldName, newName string) error {
+	if err := ValidateProfileName(newName); err != nil {
+		return err
+	}
+	if c.Profile(oldName) == nil {
+		return &ProfileNotFoundError{Name: oldName}
+	}
+	if c.Profile(newName) != nil {
+		return fmt.Errorf("profile %q already exists", newName)
+	}
+	c.flush()
+	c.Profiles[newName] = c.Profiles[oldName]
+	delete(c.Profiles, oldName)
+	if c.CurrentProfile == oldName {
+		c.CurrentProfile = newName
+	}
+	selected := c.Name()
+	if selected == oldName {
+		selected = newName
+	}
+	c.reselect(selected)
+	return nil
+}
+
+// flush writes the selected profile's fields back into Profiles.
+func (c *Config) flush() {
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+	if _, ok := c.Profiles[c.Name()]; ok || c.PrimaryKey != "" {
+		c.Profiles[c.Name()] = c.current()
+	}
+}
+
+// reselect selects name without writing the previous selection back.
+func (c *Config) reselect(name string) {
+	c.ProfileName = ""
+	c.PrimaryKey = ""
+	c.Select(name)
+}
+
+func (c *Config) current() *Profile {
+	return &Profile{
+		PrimaryKey:     c.PrimaryKey,
+		SecondaryKey:   c.SecondaryKey,
+		ActiveWalletID: c.ActiveWalletID,
+		APIURL:         c.APIURL,
+		Policies:       c.Policies,
+		FeeLimit:       c.FeeLimit,
+		RatesFile:      c.RatesFile,
+		RatesURL:       c.RatesURL,
+		Unit:           c.Unit,
+		Fiat:           c.Fiat,
+	}
+}
+
+// ProfileNames returns the names of all profiles, including the selected
+// one, sorted.
+func (c *Config) ProfileNames() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for n := range c.Profiles {
+		names = append(names, n)
+		seen[n] = true
+	}
+	if c.PrimaryKey != "" && !seen[c.Name()] {
+		names = append(names, c.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns a copy of the named profile, with unsaved changes for the
+// selected one, or nil if it does not exist.
+func (c *Config) Profile(name string) *Profile {
+	if name == c.Name() && c.PrimaryKey != "" {
+		return c.current()
+	}
+	return c.Profiles[name]
+}
+
+// ProfileNotFoundError is returned by Load when the requested profile is
+// not in the config file.
+type ProfileNotFoundError struct {
+	Name string
+}
+
+func (e *ProfileNotFoundError) Error() string {
+	return fmt.Sprintf("profile %q not found — run 'lnbot profile list'", e.Name)
+}
+
+var profileOverride string
+
+// SetProfile selects the profile used by Load and Init, taking precedence
+// over LNBOT_PROFILE and the file's current profile. Used for --profile.
+func SetProfile(name string) {
+	profileOverride = name
+}
+
+// selectedProfile returns the explicitly requested profile (--profile or
+// LNBOT_PROFILE), or "" if none was requested.
+func selectedProfile() string {
+	if profileOverride != "" {
+		return profileOverride
+	}
+	return os.Getenv("LNBOT_PROFILE")
+}
+
+// ValidateProfileName checks that a profile name is usable as a map key and
+// on the command line.
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name must not be empty")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return fmt.Errorf("invalid profile name %q: use letters, digits, '-', '_' or '.'", name)
+		}
+	}
+	return nil
 }
 
 func Path() string {
@@ -26,22 +346,49 @@ func Path() string {
 	return filepath.Join(home, ".config", "lnbot", "config.json")
 }
 
-func Load() (*Config, error) {
+// LoadFile reads the whole config file with the requested (or current)
+// profile selected. Unlike Load it returns an empty Config rather than nil
+// when there is no file or the profile has no key.
+func LoadFile() (*Config, error) {
+	cfg := &Config{Profiles: map[string]*Profile{}}
 	data, err := os.ReadFile(Path())
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			cfg.Select(selectedProfile())
+			return cfg, nil
 		}
 		return nil, err
 	}
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	if name := selectedProfile(); name != "" {
+		cfg.Select(name)
+	}
+	return cfg, nil
+}
+
+// Load returns the selected profile, or nil if there is no config or the
+// profile has no key. The profile is chosen by --profile (SetProfile), then
+// LNBOT_PROFILE, then the file's current profile, then "default". Asking
+// for a profile that does not exist is an error.
+func Load() (*Config, error) {
+	cfg, err := LoadFile()
+	if err != nil {
+		return nil, err
+	}
+	if name := selectedProfile(); name != "" && cfg.Profile(name) == nil && len(cfg.Profiles) > 0 {
+		return nil, &ProfileNotFoundError{Name: name}
+	}
 	if cfg.PrimaryKey == "" {
 		return nil, nil
 	}
-	return &cfg, nil
+	if cfg.APIURL != "" {
+		if err := ValidateAPIURL(cfg.APIURL); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", cfg.Name(), err)
+		}
+	}
+	return cfg, nil
 }
 
 func (c *Config) Save() error {
@@ -56,21 +403,105 @@ func (c *Config) Save() error {
 	return os.WriteFile(p, append(data, '\n'), 0o600)
 }
 
+// Init stores a new account in the selected profile, keeping any other
+// profiles in the file. A non-default API URL is saved with the keys, since
+// they only work against the API that issued them.
 func Init(primaryKey, secondaryKey, walletID string) (*Config, error) {
-	cfg := &Config{
-		PrimaryKey:     primaryKey,
-		SecondaryKey:   secondaryKey,
-		ActiveWalletID: walletID,
+	cfg, err := LoadFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg.PrimaryKey = primaryKey
+	cfg.SecondaryKey = secondaryKey
+	cfg.ActiveWalletID = walletID
+	if base := cfg.BaseURL(); base != DefaultAPIURL {
+		cfg.APIURL = base
+	}
+	if cfg.CurrentProfile == "" {
+		cfg.CurrentProfile = cfg.Name()
 	}
 	return cfg, cfg.Save()
 }
 
-// Client returns an authenticated API client using the user key.
+// PolicyPath returns the spending policy file for a wallet, or "" if none is set.
+func (c *Config) PolicyPath(walletID string) string {
+	return c.Policies[walletID]
+}
+
+// SetPolicyPath attaches a spending policy file to a wallet. An empty path
+// removes it.
+func (c *Config) SetPolicyPath(walletID, path string) {
+	if path == "" {
+		delete(c.Policies, walletID)
+		return
+	}
+	if c.Policies == nil {
+		c.Policies = map[string]string{}
+	}
+	c.Policies[walletID] = path
+}
+
+var apiURLOverride string
+
+// SetAPIURL overrides the API base URL for every profile, taking precedence
+// over LNBOT_API_URL. Used for --api-url.
+func SetAPIURL(u string) {
+	apiURLOverride = u
+}
+
+// ResolveAPIURL returns the API base URL to use: --api-url (SetAPIURL), then
+// LNBOT_API_URL, then profileURL, then DefaultAPIURL.
+func ResolveAPIURL(profileURL string) string {
+	for _, u := range []string{apiURLOverride, os.Getenv("LNBOT_API_URL"), profileURL} {
+		if u != "" {
+			return strings.TrimRight(u, "/")
+		}
+	}
+	return DefaultAPIURL
+}
+
+// ValidateAPIURL checks that u is an absolute http(s) URL without a query or
+// fragment. Plain http is accepted so a local stand-in server can be used.
+func ValidateAPIURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid API URL %q: expected e.g. https://api.ln.bot", u)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return fmt.Errorf("invalid API URL %q: scheme must be http or https", u)
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return fmt.Errorf("invalid API URL %q: must not have a query or fragment", u)
+	}
+	return nil
+}
+
+// BaseURL returns the API base URL for this profile. It is safe to call on
+// a nil Config.
+func (c *Config) BaseURL() string {
+	if c == nil {
+		return ResolveAPIURL("")
+	}
+	return ResolveAPIURL(c.APIURL)
+}
+
+// CustomEndpoint reports whether the API base URL is not the production API.
+func (c *Config) CustomEndpoint() bool {
+	return c.BaseURL() != DefaultAPIURL
+}
+
+// NewClient returns an API client for key, talking to baseURL.
+func NewClient(key, baseURL string) *lnbot.Client {
+	return lnbot.New(key, lnbot.WithBaseURL(baseURL))
+}
+
+// Client returns an authenticated API client using the user key and the
+// profile's API endpoint.
 func (c *Config) Client() *lnbot.Client {
-	return lnbot.New(c.PrimaryKey)
+	return NewClient(c.PrimaryKey, c.BaseURL())
 }
 
 // AnonClient returns an unauthenticated API client.
 func AnonClient() *lnbot.Client {
-	return lnbot.New("")
+	return NewClient("", ResolveAPIURL(""))
 }
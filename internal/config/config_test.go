@@ -2,9 +2,13 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/lnbotdev/cli/internal/fees"
 )
 
 func TestPath_Default(t *testing.T) {
@@ -188,3 +192,270 @@ func TestLoad_OldConfigFormat(t *testing.T) {
 		t.Errorf("Load() should return nil for old config format, got %+v", cfg)
 	}
 }
+
+func TestPolicyPath(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "config.json")
+	t.Setenv("LNBOT_CONFIG", p)
+
+	cfg := &Config{PrimaryKey: "uk_test", ActiveWalletID: "wal_abc"}
+	if got := cfg.PolicyPath("wal_abc"); got != "" {
+		t.Errorf("PolicyPath() = %q, want empty", got)
+	}
+
+	cfg.SetPolicyPath("wal_abc", "/etc/lnbot/policy.json")
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := loaded.PolicyPath("wal_abc"); got != "/etc/lnbot/policy.json" {
+		t.Errorf("PolicyPath() = %q, want /etc/lnbot/policy.json", got)
+	}
+	if got := loaded.PolicyPath("wal_other"); got != "" {
+		t.Errorf("PolicyPath() for other wallet = %q, want empty", got)
+	}
+
+	loaded.SetPolicyPath("wal_abc", "")
+	if got := loaded.PolicyPath("wal_abc"); got != "" {
+		t.Errorf("PolicyPath() after removal = %q, want empty", got)
+	}
+}
+
+func TestFeeLimit_PerProfile(t *testing.T) {
+	writeConfig(t, profilesJSON)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.FeeLimit = &fees.Limit{Percent: 0.5, Ceiling: 500}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := Load()
+	if loaded.FeeLimit == nil || *loaded.FeeLimit != (fees.Limit{Percent: 0.5, Ceiling: 500}) {
+		t.Errorf("FeeLimit = %+v", loaded.FeeLimit)
+	}
+	SetProfile("staging")
+	t.Cleanup(func() { SetProfile("") })
+	if other, _ := Load(); other.FeeLimit != nil {
+		t.Errorf("staging FeeLimit = %+v, want nil", other.FeeLimit)
+	}
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(p, []byte(data), 0o600)
+	t.Setenv("LNBOT_CONFIG", p)
+	t.Setenv("LNBOT_PROFILE", "")
+	t.Cleanup(func() { SetProfile("") })
+	return p
+}
+
+const profilesJSON = `{
+  "current_profile": "prod",
+  "profiles": {
+    "prod": {"primary_key": "uk_prod", "active_wallet_id": "wal_prod"},
+    "staging": {"primary_key": "uk_stg", "active_wallet_id": "wal_stg", "api_url": "https://staging.example.com"}
+  }
+}`
+
+func TestLoad_MigratesLegacyFormat(t *testing.T) {
+	p := writeConfig(t, `{"primary_key":"uk_abc","active_wallet_id":"wal_xyz","policies":{"wal_xyz":"/p.json"}}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Name() != DefaultProfile || cfg.PrimaryKey != "uk_abc" || cfg.PolicyPath("wal_xyz") != "/p.json" {
+		t.Errorf("Load() = %+v", cfg)
+	}
+
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	var saved map[string]any
+	data, _ := os.ReadFile(p)
+	json.Unmarshal(data, &saved)
+	if _, ok := saved["primary_key"]; ok {
+		t.Errorf("saved config still has legacy fields: %s", data)
+	}
+	if saved["current_profile"] != DefaultProfile {
+		t.Errorf("current_profile = %v, want default", saved["current_profile"])
+	}
+	profiles, _ := saved["profiles"].(map[string]any)
+	if def, _ := profiles[DefaultProfile].(map[string]any); def["primary_key"] != "uk_abc" {
+		t.Errorf("default profile = %v", profiles[DefaultProfile])
+	}
+}
+
+func TestLoad_ProfileSelection(t *testing.T) {
+	writeConfig(t, profilesJSON)
+
+	cfg, _ := Load()
+	if cfg.Name() != "prod" || cfg.PrimaryKey != "uk_prod" {
+		t.Errorf("current profile: got %s/%s", cfg.Name(), cfg.PrimaryKey)
+	}
+
+	t.Setenv("LNBOT_PROFILE", "staging")
+	cfg, _ = Load()
+	if cfg.Name() != "staging" || cfg.APIURL != "https://staging.example.com" {
+		t.Errorf("LNBOT_PROFILE: got %s/%s", cfg.Name(), cfg.APIURL)
+	}
+
+	SetProfile("prod")
+	cfg, _ = Load()
+	if cfg.Name() != "prod" {
+		t.Errorf("SetProfile should beat LNBOT_PROFILE, got %s", cfg.Name())
+	}
+}
+
+func TestLoad_UnknownProfile(t *testing.T) {
+	writeConfig(t, profilesJSON)
+	SetProfile("nope")
+
+	_, err := Load()
+	var nf *ProfileNotFoundError
+	if !errors.As(err, &nf) || nf.Name != "nope" {
+		t.Errorf("Load() error = %v, want ProfileNotFoundError", err)
+	}
+}
+
+func TestSave_KeepsOtherProfiles(t *testing.T) {
+	writeConfig(t, profilesJSON)
+	SetProfile("staging")
+
+	cfg, _ := Load()
+	cfg.ActiveWalletID = "wal_new"
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	SetProfile("")
+	cfg, _ = Load()
+	if cfg.Name() != "prod" || cfg.ActiveWalletID != "wal_prod" {
+		t.Errorf("prod profile changed: %+v", cfg)
+	}
+	if p := cfg.Profile("staging"); p == nil || p.ActiveWalletID != "wal_new" {
+		t.Errorf("staging profile = %+v, want wal_new", p)
+	}
+}
+
+func TestInit_NewProfile(t *testing.T) {
+	writeConfig(t, profilesJSON)
+	SetProfile("sandbox")
+
+	if _, err := Init("uk_sb", "", "wal_sb"); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	cfg, _ := Load()
+	if cfg.PrimaryKey != "uk_sb" || cfg.CurrentProfile != "prod" {
+		t.Errorf("Load() = %+v", cfg)
+	}
+	if names := cfg.ProfileNames(); len(names) != 3 {
+		t.Errorf("ProfileNames() = %v, want 3 profiles", names)
+	}
+}
+
+func TestProfileManagement(t *testing.T) {
+	writeConfig(t, profilesJSON)
+
+	cfg, _ := LoadFile()
+	if err := cfg.AddProfile("prod", &Profile{PrimaryKey: "x"}); err == nil {
+		t.Error("AddProfile() should reject duplicates")
+	}
+	if err := cfg.AddProfile("bad name", &Profile{PrimaryKey: "x"}); err == nil {
+		t.Error("AddProfile() should reject invalid names")
+	}
+	if err := cfg.AddProfile("dev", &Profile{PrimaryKey: "uk_dev"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.RemoveProfile("prod"); err == nil {
+		t.Error("RemoveProfile() should refuse the current profile")
+	}
+	if err := cfg.UseProfile("missing"); err == nil {
+		t.Error("UseProfile() should reject unknown profiles")
+	}
+
+	if err := cfg.RenameProfile("prod", "production"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CurrentProfile != "production" || cfg.Name() != "production" || cfg.PrimaryKey != "uk_prod" {
+		t.Errorf("after rename: current %s, selected %s, key %s", cfg.CurrentProfile, cfg.Name(), cfg.PrimaryKey)
+	}
+	if err := cfg.RemoveProfile("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := LoadFile()
+	names := loaded.ProfileNames()
+	if len(names) != 2 || names[0] != "dev" || names[1] != "production" {
+		t.Errorf("ProfileNames() = %v, want [dev production]", names)
+	}
+}
+
+func TestResolveAPIURL(t *testing.T) {
+	t.Setenv("LNBOT_API_URL", "")
+	t.Cleanup(func() { SetAPIURL("") })
+
+	if got := ResolveAPIURL(""); got != DefaultAPIURL {
+		t.Errorf("default = %q, want %q", got, DefaultAPIURL)
+	}
+	if got := ResolveAPIURL("https://staging.example.com/"); got != "https://staging.example.com" {
+		t.Errorf("profile URL = %q, want trailing slash trimmed", got)
+	}
+
+	t.Setenv("LNBOT_API_URL", "http://localhost:8080")
+	if got := ResolveAPIURL("https://staging.example.com"); got != "http://localhost:8080" {
+		t.Errorf("LNBOT_API_URL should win over the profile, got %q", got)
+	}
+
+	SetAPIURL("http://127.0.0.1:9000")
+	if got := ResolveAPIURL("https://staging.example.com"); got != "http://127.0.0.1:9000" {
+		t.Errorf("SetAPIURL should win over LNBOT_API_URL, got %q", got)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	t.Setenv("LNBOT_API_URL", "")
+
+	var none *Config
+	if none.BaseURL() != DefaultAPIURL || none.CustomEndpoint() {
+		t.Errorf("nil Config should use the default endpoint")
+	}
+	cfg := &Config{PrimaryKey: "uk_x", APIURL: "http://localhost:8080"}
+	if cfg.BaseURL() != "http://localhost:8080" || !cfg.CustomEndpoint() {
+		t.Errorf("BaseURL() = %q, want profile URL", cfg.BaseURL())
+	}
+}
+
+func TestValidateAPIURL(t *testing.T) {
+	for _, u := range []string{"https://api.ln.bot", "http://localhost:8080", "https://example.com/lnbot"} {
+		if err := ValidateAPIURL(u); err != nil {
+			t.Errorf("ValidateAPIURL(%q) = %v", u, err)
+		}
+	}
+	for _, u := range []string{"", "api.ln.bot", "ftp://api.ln.bot", "https://api.ln.bot?x=1", "https://api.ln.bot/#frag", "://bad"} {
+		if err := ValidateAPIURL(u); err == nil {
+			t.Errorf("ValidateAPIURL(%q) should fail", u)
+		}
+	}
+}
+
+func TestLoad_InvalidProfileAPIURL(t *testing.T) {
+	writeConfig(t, `{"profiles":{"default":{"primary_key":"uk_x","api_url":"api.ln.bot"}}}`)
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "invalid API URL") {
+		t.Errorf("expected invalid API URL error, got %v", err)
+	}
+}
@@ -0,0 +1,801 @@
+// Package devserver is an in-memory stand-in for the ln.bot API, used by
+// 'lnbot dev server' and the integration tests.
+//
+// It implements the REST and SSE endpoints the CLI and SDK use: accounts,
+// wallets, keys, invoices, payments, addresses, transactions, webhooks,
+// the wallet event stream, backup and restore. Payments between wallets on
+// the same server settle instantly; payments to anything else are simulated
+// as successful external payments, except targets starting with "fail"
+// (fail@example.com, or an invoice whose description starts with "fail"),
+// which fail with "no route found".
+//
+// Two unauthenticated endpoints drive the simulation:
+//
+//	POST /dev/wallets/{id}/fund      {"amount": 5000}
+//	POST /dev/invoices/{ref}/settle  ref is a payment hash or BOLT11 string
+//
+// State lives in memory and is lost when the process exits.
+package devserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/bolt11"
+)
+
+// Options configure a Server. The zero value is usable.
+type Options struct {
+	// Domain is the Lightning address domain (default "ln.bot").
+	Domain string
+	// Network is the BOLT11 prefix for issued invoices (default "bc").
+	Network string
+	// StartingBalance is credited to every new wallet, in sats.
+	StartingBalance int64
+	// SettleAfter settles pending invoices automatically after this delay,
+	// as if paid by an outside node. Zero disables it.
+	SettleAfter time.Duration
+	// InvoiceExpiry is the lifetime of new invoices (default 1h).
+	InvoiceExpiry time.Duration
+	// Log receives one line per request when set.
+	Log io.Writer
+}
+
+// Server is an in-memory ln.bot API. It implements http.Handler.
+type Server struct {
+	opts Options
+
+	mu         sync.Mutex
+	userKeys   map[string]*account
+	walletKeys map[string]*wallet
+	wallets    map[string]*wallet
+	addresses  map[string]*wallet  // local part → owner
+	invoices   map[string]*invoice // payment hash → invoice
+	accounts   []*account
+	subs       map[*subscriber]struct{}
+	timers     []*time.Timer
+	closed     bool
+
+	webhookClient *http.Client
+}
+
+type account struct {
+	id         string
+	keys       [2]string
+	passphrase string
+	wallets    []*wallet
+}
+
+type wallet struct {
+	id        string
+	name      string
+	account   *account
+	createdAt time.Time
+	balance   int64
+
+	key        string
+	keyCreated *time.Time
+	keyUsed    *time.Time
+
+	invoices     []*invoice
+	payments     []*payment
+	transactions []*lnbot.Transaction
+	addresses    []*lnbot.Address
+	webhooks     []*webhook
+	idempotency  map[string]*payment
+}
+
+// New returns a Server with defaults applied to opts.
+func New(opts Options) *Server {
+	if opts.Domain == "" {
+		opts.Domain = "ln.bot"
+	}
+	if opts.Network == "" {
+		opts.Network = "bc"
+	}
+	if opts.InvoiceExpiry == 0 {
+		opts.InvoiceExpiry = bolt11.DefaultExpiry
+	}
+	return &Server{
+		opts:          opts,
+		userKeys:      map[string]*account{},
+		walletKeys:    map[string]*wallet{},
+		wallets:       map[string]*wallet{},
+		addresses:     map[string]*wallet{},
+		invoices:      map[string]*invoice{},
+		subs:          map[*subscriber]struct{}{},
+		webhookClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Close stops pending settlement and expiry timers and ends open event
+// streams. The Server must not be used afterwards.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, t := range s.timers {
+		t.Stop()
+	}
+	for sub := range s.subs {
+		close(sub.done)
+		delete(s.subs, sub)
+	}
+}
+
+// Fund credits sats to a wallet, as if received from outside.
+func (s *Server) Fund(walletID string, sats int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := s.wallets[walletID]
+	if w == nil {
+		return fmt.Errorf("wallet %s not found", walletID)
+	}
+	if sats <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	s.credit(w, sats, nil, nil, "dev server funding")
+	return nil
+}
+
+// SettleInvoice marks a pending invoice as paid by an outside node. ref is
+// the payment hash or the BOLT11 string.
+func (s *Server) SettleInvoice(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv := s.invoiceByRef(ref)
+	if inv == nil {
+		return fmt.Errorf("invoice not found")
+	}
+	if inv.Status != "pending" {
+		return fmt.Errorf("invoice is %s", inv.Status)
+	}
+	s.settleInvoice(inv, inv.Amount)
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Routing
+// ---------------------------------------------------------------------------
+
+// apiError is returned by handlers and written as {"message": ...}.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func errorf(status int, format string, args ...any) *apiError {
+	return &apiError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+var (
+	errNotFound       = errorf(http.StatusNotFound, "not found")
+	errUnauthorized   = errorf(http.StatusUnauthorized, "invalid or missing API key")
+	errNotImplemented = errorf(http.StatusNotImplemented, "not supported by the dev server")
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	if err := s.route(rec, r); err != nil {
+		writeError(rec, err)
+	}
+
+	if s.opts.Log != nil {
+		fmt.Fprintf(s.opts.Log, "%s  %-6s %s  %d  %s\n",
+			start.Format("15:04:05"), r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Microsecond))
+	}
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) error {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		return writeJSON(w, http.StatusOK, map[string]string{"name": "ln.bot dev server", "status": "ok"})
+	}
+	parts := strings.Split(path, "/")
+
+	switch {
+	case parts[0] == "dev":
+		return s.routeDev(w, r, parts[1:])
+	case parts[0] != "v1" || len(parts) < 2:
+		return errNotFound
+	}
+
+	p := parts[1:]
+	switch p[0] {
+	case "register":
+		if err := allow(r, http.MethodPost); err != nil {
+			return err
+		}
+		return s.handleRegister(w)
+	case "me":
+		return s.handleMe(w, r)
+	case "keys":
+		if len(p) == 3 && p[2] == "rotate" {
+			if err := allow(r, http.MethodPost); err != nil {
+				return err
+			}
+			return s.handleRotateKey(w, r, p[1])
+		}
+	case "backup":
+		if len(p) == 2 && p[1] == "recovery" {
+			if err := allow(r, http.MethodPost); err != nil {
+				return err
+			}
+			return s.handleBackup(w, r)
+		}
+		return errNotImplemented
+	case "restore":
+		if len(p) == 2 && p[1] == "recovery" {
+			if err := allow(r, http.MethodPost); err != nil {
+				return err
+			}
+			return s.handleRestore(w, r)
+		}
+		return errNotImplemented
+	case "invoices":
+		if len(p) == 2 && (p[1] == "for-wallet" || p[1] == "for-address") {
+			if err := allow(r, http.MethodPost); err != nil {
+				return err
+			}
+			return s.handlePublicInvoice(w, r, p[1])
+		}
+	case "wallets":
+		if len(p) == 1 {
+			return s.handleWallets(w, r)
+		}
+		return s.routeWallet(w, r, p[1], p[2:])
+	}
+	return errNotFound
+}
+
+func (s *Server) routeWallet(w http.ResponseWriter, r *http.Request, id string, p []string) error {
+	wal, err := s.authWallet(r, id)
+	if err != nil {
+		return err
+	}
+	if len(p) == 0 {
+		return s.handleWallet(w, r, wal)
+	}
+
+	switch p[0] {
+	case "key":
+		if len(p) == 2 && p[1] == "rotate" {
+			if err := allow(r, http.MethodPost); err != nil {
+				return err
+			}
+			return s.handleWalletKeyRotate(w, wal)
+		}
+		if len(p) == 1 {
+			return s.handleWalletKey(w, r, wal)
+		}
+	case "invoices":
+		switch len(p) {
+		case 1:
+			return s.handleInvoices(w, r, wal)
+		case 2:
+			if err := allow(r, http.MethodGet); err != nil {
+				return err
+			}
+			return s.handleInvoice(w, wal, p[1])
+		case 3:
+			if p[2] == "events" {
+				return s.watchInvoice(w, r, wal, p[1])
+			}
+		}
+	case "payments":
+		switch {
+		case len(p) == 1:
+			return s.handlePayments(w, r, wal)
+		case len(p) == 2 && p[1] == "resolve":
+			return s.handleResolve(w, r, wal)
+		case len(p) == 2:
+			if err := allow(r, http.MethodGet); err != nil {
+				return err
+			}
+			return s.handlePayment(w, wal, p[1])
+		case len(p) == 3 && p[2] == "events":
+			return s.watchPayment(w, r, wal, p[1])
+		}
+	case "addresses":
+		switch {
+		case len(p) == 1:
+			return s.handleAddresses(w, r, wal)
+		case len(p) == 2:
+			if err := allow(r, http.MethodDelete); err != nil {
+				return err
+			}
+			return s.handleDeleteAddress(w, wal, p[1])
+		case len(p) == 3 && p[2] == "transfer":
+			if err := allow(r, http.MethodPost); err != nil {
+				return err
+			}
+			return s.handleTransferAddress(w, r, wal, p[1])
+		}
+	case "transactions":
+		if len(p) == 1 {
+			return s.handleTransactions(w, r, wal)
+		}
+	case "webhooks":
+		switch len(p) {
+		case 1:
+			return s.handleWebhooks(w, r, wal)
+		case 2:
+			if err := allow(r, http.MethodDelete); err != nil {
+				return err
+			}
+			return s.handleDeleteWebhook(w, wal, p[1])
+		}
+	case "events":
+		if len(p) == 1 {
+			return s.streamEvents(w, r, wal)
+		}
+	case "l402":
+		return errNotImplemented
+	}
+	return errNotFound
+}
+
+func (s *Server) routeDev(w http.ResponseWriter, r *http.Request, p []string) error {
+	if err := allow(r, http.MethodPost); err != nil {
+		return err
+	}
+	switch {
+	case len(p) == 3 && p[0] == "wallets" && p[2] == "fund":
+		var body struct {
+			Amount int64 `json:"amount"`
+		}
+		if err := decodeBody(r, &body); err != nil {
+			return err
+		}
+		if err := s.Fund(p[1], body.Amount); err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return writeJSON(w, http.StatusOK, s.walletView(s.wallets[p[1]]))
+	case len(p) == 3 && p[0] == "invoices" && p[2] == "settle":
+		if err := s.SettleInvoice(p[1]); err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return writeJSON(w, http.StatusOK, s.invoiceByRef(p[1]).Invoice)
+	}
+	return errNotFound
+}
+
+// ---------------------------------------------------------------------------
+// Auth
+// ---------------------------------------------------------------------------
+
+func bearer(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(h[len("Bearer "):])
+}
+
+// authUser returns the account for a user key.
+func (s *Server) authUser(r *http.Request) (*account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acct := s.userKeys[bearer(r)]
+	if acct == nil {
+		return nil, errUnauthorized
+	}
+	return acct, nil
+}
+
+// authWallet checks that the request's key (user key or wallet key) may
+// access wallet id.
+func (s *Server) authWallet(r *http.Request, id string) (*wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := bearer(r)
+	if wal := s.walletKeys[key]; wal != nil {
+		if wal.id != id {
+			return nil, errorf(http.StatusForbidden, "wallet key is not valid for %s", id)
+		}
+		now := time.Now().UTC()
+		wal.keyUsed = &now
+		return wal, nil
+	}
+	acct := s.userKeys[key]
+	if acct == nil {
+		return nil, errUnauthorized
+	}
+	wal := s.wallets[id]
+	if wal == nil || wal.account != acct {
+		return nil, errorf(http.StatusNotFound, "wallet not found")
+	}
+	return wal, nil
+}
+
+// ---------------------------------------------------------------------------
+// Accounts, wallets and keys
+// ---------------------------------------------------------------------------
+
+func (s *Server) handleRegister(w http.ResponseWriter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acct := &account{
+		id:         randomID("usr_", 12),
+		keys:       [2]string{randomID("uk_", 32), randomID("uk_", 32)},
+		passphrase: passphrase(),
+	}
+	s.accounts = append(s.accounts, acct)
+	s.userKeys[acct.keys[0]] = acct
+	s.userKeys[acct.keys[1]] = acct
+	return writeJSON(w, http.StatusCreated, lnbot.RegisterResponse{
+		UserID:             acct.id,
+		PrimaryKey:         acct.keys[0],
+		SecondaryKey:       acct.keys[1],
+		RecoveryPassphrase: acct.passphrase,
+	})
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) error {
+	if err := allow(r, http.MethodGet); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := bearer(r)
+	if wal := s.walletKeys[key]; wal != nil {
+		return writeJSON(w, http.StatusOK, lnbot.MeResponse{WalletID: wal.id})
+	}
+	acct := s.userKeys[key]
+	if acct == nil {
+		return errUnauthorized
+	}
+	me := lnbot.MeResponse{}
+	if len(acct.wallets) > 0 {
+		me.WalletID = acct.wallets[0].id
+	}
+	return writeJSON(w, http.StatusOK, me)
+}
+
+func (s *Server) handleWallets(w http.ResponseWriter, r *http.Request) error {
+	acct, err := s.authUser(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		out := []lnbot.WalletListItem{}
+		for _, wal := range acct.wallets {
+			created := wal.createdAt
+			out = append(out, lnbot.WalletListItem{WalletID: wal.id, Name: wal.name, CreatedAt: &created})
+		}
+		return writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		wal := s.createWallet(acct)
+		return writeJSON(w, http.StatusCreated, lnbot.CreateWalletResponse{
+			WalletID: wal.id,
+			Name:     wal.name,
+			Address:  wal.addresses[0].Address,
+		})
+	}
+	return errMethod
+}
+
+// createWallet adds a wallet with a generated Lightning address and the
+// starting balance.
+func (s *Server) createWallet(acct *account) *wallet {
+	wal := &wallet{
+		id:          randomID("wal_", 12),
+		name:        fmt.Sprintf("wallet-%d", len(acct.wallets)+1),
+		account:     acct,
+		createdAt:   time.Now().UTC(),
+		idempotency: map[string]*payment{},
+	}
+	acct.wallets = append(acct.wallets, wal)
+	s.wallets[wal.id] = wal
+	s.addAddress(wal, strings.ToLower(randomID("", 10)), true)
+	if s.opts.StartingBalance > 0 {
+		s.credit(wal, s.opts.StartingBalance, nil, nil, "dev server starting balance")
+	}
+	return wal
+}
+
+func (s *Server) walletView(wal *wallet) lnbot.Wallet {
+	return lnbot.Wallet{WalletID: wal.id, Name: wal.name, Balance: wal.balance, Available: wal.balance}
+}
+
+func (s *Server) handleWallet(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return writeJSON(w, http.StatusOK, s.walletView(wal))
+	case http.MethodPatch:
+		var params lnbot.UpdateWalletParams
+		if err := decodeBody(r, &params); err != nil {
+			return err
+		}
+		name := strings.TrimSpace(params.Name)
+		if name == "" {
+			return errorf(http.StatusBadRequest, "name is required")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		wal.name = name
+		return writeJSON(w, http.StatusOK, s.walletView(wal))
+	}
+	return errMethod
+}
+
+func (s *Server) handleRotateKey(w http.ResponseWriter, r *http.Request, slotStr string) error {
+	acct, err := s.authUser(r)
+	if err != nil {
+		return err
+	}
+	slot, err := strconv.Atoi(slotStr)
+	if err != nil || (slot != 0 && slot != 1) {
+		return errorf(http.StatusBadRequest, "slot must be 0 or 1")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.userKeys, acct.keys[slot])
+	acct.keys[slot] = randomID("uk_", 32)
+	s.userKeys[acct.keys[slot]] = acct
+	name := "primary"
+	if slot == 1 {
+		name = "secondary"
+	}
+	return writeJSON(w, http.StatusOK, lnbot.RotatedAPIKey{Key: acct.keys[slot], Name: name})
+}
+
+func (s *Server) handleWalletKey(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		if wal.key == "" {
+			return errorf(http.StatusNotFound, "wallet has no key")
+		}
+		return writeJSON(w, http.StatusOK, lnbot.WalletKeyInfoResponse{
+			Hint:       keyHint(wal.key),
+			CreatedAt:  wal.keyCreated,
+			LastUsedAt: wal.keyUsed,
+		})
+	case http.MethodPost:
+		if wal.key != "" {
+			return errorf(http.StatusConflict, "wallet already has a key — rotate it instead")
+		}
+		return writeJSON(w, http.StatusCreated, s.newWalletKey(wal))
+	case http.MethodDelete:
+		if wal.key == "" {
+			return errorf(http.StatusNotFound, "wallet has no key")
+		}
+		delete(s.walletKeys, wal.key)
+		wal.key, wal.keyCreated, wal.keyUsed = "", nil, nil
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return errMethod
+}
+
+func (s *Server) handleWalletKeyRotate(w http.ResponseWriter, wal *wallet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if wal.key == "" {
+		return errorf(http.StatusNotFound, "wallet has no key")
+	}
+	return writeJSON(w, http.StatusOK, s.newWalletKey(wal))
+}
+
+func (s *Server) newWalletKey(wal *wallet) lnbot.WalletKeyResponse {
+	delete(s.walletKeys, wal.key)
+	now := time.Now().UTC()
+	wal.key = randomID("wk_", 32)
+	wal.keyCreated, wal.keyUsed = &now, nil
+	s.walletKeys[wal.key] = wal
+	return lnbot.WalletKeyResponse{Key: wal.key, Hint: keyHint(wal.key)}
+}
+
+func keyHint(key string) string {
+	return key[:7] + "..." + key[len(key)-4:]
+}
+
+// ---------------------------------------------------------------------------
+// Backup and restore
+// ---------------------------------------------------------------------------
+
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) error {
+	acct, err := s.authUser(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acct.passphrase = passphrase()
+	return writeJSON(w, http.StatusOK, lnbot.RecoveryPassphrase{Passphrase: acct.passphrase})
+}
+
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) error {
+	var params lnbot.RecoveryRestoreParams
+	if err := decodeBody(r, &params); err != nil {
+		return err
+	}
+	phrase := strings.Join(strings.Fields(strings.ToLower(params.Passphrase)), " ")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var acct *account
+	for _, a := range s.accounts {
+		if a.passphrase == phrase {
+			acct = a
+			break
+		}
+	}
+	if acct == nil || phrase == "" {
+		return errorf(http.StatusBadRequest, "invalid recovery passphrase")
+	}
+
+	// Restoring revokes both keys.
+	for i, k := range acct.keys {
+		delete(s.userKeys, k)
+		acct.keys[i] = randomID("uk_", 32)
+		s.userKeys[acct.keys[i]] = acct
+	}
+	out := lnbot.RestoredWallet{PrimaryKey: acct.keys[0], SecondaryKey: acct.keys[1]}
+	if len(acct.wallets) > 0 {
+		out.WalletID, out.Name = acct.wallets[0].id, acct.wallets[0].name
+	}
+	return writeJSON(w, http.StatusOK, out)
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+var errMethod = errorf(http.StatusMethodNotAllowed, "method not allowed")
+
+func allow(r *http.Request, method string) error {
+	if r.Method != method {
+		return errMethod
+	}
+	return nil
+}
+
+func decodeBody(r *http.Request, v any) error {
+	if r.Body == nil {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return errorf(http.StatusBadRequest, "reading body: %s", err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errorf(http.StatusBadRequest, "invalid JSON body: %s", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	ae, ok := err.(*apiError)
+	if !ok {
+		ae = &apiError{status: http.StatusInternalServerError, msg: err.Error()}
+	}
+	writeJSON(w, ae.status, map[string]string{"message": ae.msg})
+}
+
+// listParams reads ?limit and ?after. Lists are newest first and after is
+// an exclusive upper bound on the item number.
+func listParams(r *http.Request) (limit, after int, err error) {
+	q := r.URL.Query()
+	limit = 20
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			return 0, 0, errorf(http.StatusBadRequest, "invalid limit %q", v)
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		if after, err = strconv.Atoi(v); err != nil || after < 0 {
+			return 0, 0, errorf(http.StatusBadRequest, "invalid after %q", v)
+		}
+	}
+	return limit, after, nil
+}
+
+// page returns up to limit items with number below after (if set), newest
+// first. number(i) is the 1-based number of items[i].
+func page[T any](items []T, limit, after int) []T {
+	out := []T{}
+	for i := len(items) - 1; i >= 0 && len(out) < limit; i-- {
+		if after > 0 && i+1 >= after {
+			continue
+		}
+		out = append(out, items[i])
+	}
+	return out
+}
+
+const idAlphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+func randomID(prefix string, n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	for i := range b {
+		b[i] = idAlphabet[int(b[i])%len(idAlphabet)]
+	}
+	return prefix + string(b)
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+func randomHex(n int) string {
+	return hex.EncodeToString(randomBytes(n))
+}
+
+var words = strings.Fields(`
+	acid amber anchor apple arrow atlas bacon badge bamboo banner barrel basket
+	beacon berry bishop blanket bonus border bottle bridge bronze bubble budget
+	cactus candle canvas carbon castle cement cherry circle cobalt comet copper
+	coral cotton crystal dagger delta desert dinner dolphin donkey dragon eagle
+	echo elbow ember engine falcon fabric feather fiber galaxy garden ginger
+	glacier hammer harbor helmet honey island ivory jacket jungle kernel kettle
+	ladder lemon lizard magnet mango marble meadow mirror monkey nectar needle
+	ocean olive orbit oyster paddle panda parrot pepper pilot planet pocket
+	puzzle quartz rabbit radar ribbon rocket saddle salmon signal silver socket
+	spider spiral summit sunset tablet tiger timber tunnel velvet violin walnut
+	window winter wizard yellow zebra zipper`)
+
+// passphrase returns 12 random words.
+func passphrase() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	out := make([]string, len(b))
+	for i, v := range b {
+		out[i] = words[int(v)%len(words)]
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,218 @@
+package devserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+// start runs a Server behind httptest and returns it with its URL.
+func start(t *testing.T, opts Options) (*Server, string) {
+	t.Helper()
+	s := New(opts)
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		s.Close()
+		ts.Close()
+	})
+	return s, ts.URL
+}
+
+// newAccount registers an account and creates its first wallet.
+func newAccount(t *testing.T, url string) (*lnbot.Client, *lnbot.RegisterResponse, *lnbot.CreateWalletResponse) {
+	t.Helper()
+	ctx := context.Background()
+	acct, err := lnbot.New("", lnbot.WithBaseURL(url)).Register(ctx)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	c := lnbot.New(acct.PrimaryKey, lnbot.WithBaseURL(url))
+	wal, err := c.Wallets.Create(ctx)
+	if err != nil {
+		t.Fatalf("Wallets.Create: %v", err)
+	}
+	return c, acct, wal
+}
+
+func TestRegisterAndWallets(t *testing.T) {
+	_, url := start(t, Options{StartingBalance: 1000})
+	ctx := context.Background()
+	c, acct, wal := newAccount(t, url)
+
+	if !strings.HasPrefix(acct.PrimaryKey, "uk_") || !strings.HasPrefix(acct.SecondaryKey, "uk_") {
+		t.Errorf("keys = %q, %q, want uk_ prefix", acct.PrimaryKey, acct.SecondaryKey)
+	}
+	if n := len(strings.Fields(acct.RecoveryPassphrase)); n != 12 {
+		t.Errorf("passphrase has %d words, want 12", n)
+	}
+	if !strings.HasPrefix(wal.WalletID, "wal_") || !strings.HasSuffix(wal.Address, "@ln.bot") {
+		t.Errorf("wallet = %+v", wal)
+	}
+
+	got, err := c.Wallet(wal.WalletID).Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != 1000 || got.Available != 1000 {
+		t.Errorf("balance = %d/%d, want starting balance 1000", got.Balance, got.Available)
+	}
+
+	c.Wallets.Create(ctx)
+	list, err := c.Wallets.List(ctx)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %v, %v; want 2 wallets", list, err)
+	}
+
+	renamed, err := c.Wallet(wal.WalletID).Update(ctx, &lnbot.UpdateWalletParams{Name: "agent01"})
+	if err != nil || renamed.Name != "agent01" {
+		t.Errorf("Update = %+v, %v", renamed, err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	_, _, wal := newAccount(t, url)
+	other, _, _ := newAccount(t, url)
+
+	_, err := lnbot.New("uk_bogus", lnbot.WithBaseURL(url)).Wallets.List(ctx)
+	var unauthorized *lnbot.UnauthorizedError
+	if !errors.As(err, &unauthorized) {
+		t.Errorf("bad key: err = %v, want 401", err)
+	}
+
+	_, err = other.Wallet(wal.WalletID).Get(ctx)
+	var notFound *lnbot.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("other account's wallet: err = %v, want 404", err)
+	}
+}
+
+func TestWalletKey(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	key, err := w.Key.Create(ctx)
+	if err != nil || !strings.HasPrefix(key.Key, "wk_") {
+		t.Fatalf("Key.Create = %+v, %v", key, err)
+	}
+	if _, err := w.Key.Create(ctx); err == nil {
+		t.Error("second Key.Create should conflict")
+	}
+
+	scoped := lnbot.New(key.Key, lnbot.WithBaseURL(url)).Wallet(wal.WalletID)
+	if _, err := scoped.Get(ctx); err != nil {
+		t.Errorf("wallet key should access its wallet: %v", err)
+	}
+	info, err := w.Key.Get(ctx)
+	if err != nil || info.Hint != key.Hint || info.LastUsedAt == nil {
+		t.Errorf("Key.Get = %+v, %v", info, err)
+	}
+
+	rotated, err := w.Key.Rotate(ctx)
+	if err != nil || rotated.Key == key.Key {
+		t.Fatalf("Key.Rotate = %+v, %v", rotated, err)
+	}
+	if _, err := scoped.Get(ctx); err == nil {
+		t.Error("old wallet key should stop working after rotation")
+	}
+}
+
+func TestRotateKey(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, acct, _ := newAccount(t, url)
+
+	rotated, err := c.Keys.Rotate(ctx, 1)
+	if err != nil || rotated.Name != "secondary" || rotated.Key == acct.SecondaryKey {
+		t.Fatalf("Rotate = %+v, %v", rotated, err)
+	}
+	if _, err := lnbot.New(acct.SecondaryKey, lnbot.WithBaseURL(url)).Wallets.List(ctx); err == nil {
+		t.Error("old secondary key should be revoked")
+	}
+	if _, err := lnbot.New(rotated.Key, lnbot.WithBaseURL(url)).Wallets.List(ctx); err != nil {
+		t.Errorf("new key should work: %v", err)
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, acct, wal := newAccount(t, url)
+
+	backup, err := c.Backup.Recovery(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := lnbot.New("", lnbot.WithBaseURL(url)).Restore.Recovery(ctx, &lnbot.RecoveryRestoreParams{
+		Passphrase: "  " + strings.ToUpper(backup.Passphrase),
+	})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.WalletID != wal.WalletID || restored.PrimaryKey == acct.PrimaryKey {
+		t.Errorf("restored = %+v", restored)
+	}
+	if _, err := c.Wallets.List(ctx); err == nil {
+		t.Error("keys should be rotated by restore")
+	}
+
+	_, err = lnbot.New("", lnbot.WithBaseURL(url)).Restore.Recovery(ctx, &lnbot.RecoveryRestoreParams{Passphrase: "wrong words"})
+	if err == nil {
+		t.Error("restore with a wrong passphrase should fail")
+	}
+	if _, err := lnbot.New("", lnbot.WithBaseURL(url)).Restore.PasskeyBegin(ctx); err == nil {
+		t.Error("passkey restore should not be supported")
+	}
+}
+
+func TestDevEndpoints(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	resp, err := http.Post(url+"/dev/wallets/"+wal.WalletID+"/fund", "application/json", strings.NewReader(`{"amount":2500}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("fund status = %d", resp.StatusCode)
+	}
+
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 500})
+	resp, err = http.Post(url+"/dev/invoices/"+inv.Bolt11+"/settle", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("settle status = %d", resp.StatusCode)
+	}
+
+	got, _ := w.Get(ctx)
+	if got.Balance != 3000 {
+		t.Errorf("balance = %d, want 3000", got.Balance)
+	}
+	inv, _ = w.Invoices.Get(ctx, inv.Number)
+	if inv.Status != "settled" || inv.Preimage == nil {
+		t.Errorf("invoice = %+v, want settled with preimage", inv)
+	}
+}
+
+func TestLog(t *testing.T) {
+	var log strings.Builder
+	_, url := start(t, Options{Log: &log})
+	http.Get(url + "/v1/nope")
+	if !strings.Contains(log.String(), "GET    /v1/nope  404") {
+		t.Errorf("log = %q", log.String())
+	}
+}
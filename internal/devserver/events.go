@@ -0,0 +1,330 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	lnwebhook "github.com/lnbotdev/cli/pkg/webhook"
+)
+
+// keepAlive is how often idle event streams get a comment line, so proxies
+// and clients don't time them out.
+const keepAlive = 15 * time.Second
+
+type event struct {
+	name      string
+	data      any
+	createdAt time.Time
+}
+
+// wire is the JSON form of an event, shared by the wallet event stream and
+// webhook deliveries.
+func (e event) wire() []byte {
+	data, _ := json.Marshal(e.data)
+	b, _ := json.Marshal(lnbot.WalletEvent{
+		Event:     e.name,
+		CreatedAt: e.createdAt.Format(time.RFC3339Nano),
+		Data:      data,
+	})
+	return b
+}
+
+type subscriber struct {
+	wallet *wallet
+	ch     chan event
+	done   chan struct{}
+}
+
+type webhook struct {
+	lnbot.Webhook
+	secret string
+}
+
+// subscribe must be called with the lock held.
+func (s *Server) subscribe(wal *wallet) *subscriber {
+	sub := &subscriber{wallet: wal, ch: make(chan event, 64), done: make(chan struct{})}
+	s.subs[sub] = struct{}{}
+	return sub
+}
+
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, sub)
+}
+
+// publish sends an event to the wallet's streams and webhooks. It must be
+// called with the lock held. Slow stream readers miss events rather than
+// block the server.
+func (s *Server) publish(wal *wallet, name string, data any) {
+	ev := event{name: name, data: data, createdAt: time.Now().UTC()}
+	for sub := range s.subs {
+		if sub.wallet != wal {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+		}
+	}
+
+	var hooks []webhook
+	for _, h := range wal.webhooks {
+		if h.Active {
+			hooks = append(hooks, *h)
+		}
+	}
+	if len(hooks) > 0 {
+		go s.deliver(hooks, ev)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// SSE
+// ---------------------------------------------------------------------------
+
+func startSSE(w http.ResponseWriter) (http.Flusher, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errorf(http.StatusInternalServerError, "streaming not supported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+	return f, nil
+}
+
+func writeSSE(w http.ResponseWriter, f http.Flusher, name string, data any) {
+	b, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+	f.Flush()
+}
+
+// timeoutParam returns a channel that fires after ?timeout seconds, or nil
+// (never) when unset.
+func timeoutParam(r *http.Request) (<-chan time.Time, error) {
+	v := r.URL.Query().Get("timeout")
+	if v == "" {
+		return nil, nil
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs < 1 {
+		return nil, errorf(http.StatusBadRequest, "invalid timeout %q", v)
+	}
+	return time.After(time.Duration(secs) * time.Second), nil
+}
+
+// watchInvoice streams a single "settled" or "expired" event for an
+// invoice, then ends.
+func (s *Server) watchInvoice(w http.ResponseWriter, r *http.Request, wal *wallet, ref string) error {
+	if err := allow(r, http.MethodGet); err != nil {
+		return err
+	}
+	timeout, err := timeoutParam(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	inv := walletInvoice(wal, ref)
+	if inv == nil {
+		s.mu.Unlock()
+		return errorf(http.StatusNotFound, "invoice not found")
+	}
+	sub := s.subscribe(wal)
+	current := inv.Invoice
+	s.mu.Unlock()
+	defer s.unsubscribe(sub)
+
+	f, err := startSSE(w)
+	if err != nil {
+		return err
+	}
+	if current.Status != "pending" {
+		writeSSE(w, f, current.Status, current)
+		return nil
+	}
+	for {
+		select {
+		case ev := <-sub.ch:
+			data, ok := ev.data.(lnbot.Invoice)
+			if !ok || data.Number != current.Number || data.Status == "pending" {
+				continue
+			}
+			writeSSE(w, f, data.Status, data)
+			return nil
+		case <-timeout:
+			return nil
+		case <-sub.done:
+			return nil
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// watchPayment streams a single "settled" or "failed" event for a payment,
+// then ends.
+func (s *Server) watchPayment(w http.ResponseWriter, r *http.Request, wal *wallet, ref string) error {
+	if err := allow(r, http.MethodGet); err != nil {
+		return err
+	}
+	timeout, err := timeoutParam(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	p := walletPayment(wal, ref)
+	if p == nil {
+		s.mu.Unlock()
+		return errorf(http.StatusNotFound, "payment not found")
+	}
+	sub := s.subscribe(wal)
+	current := p.Payment
+	s.mu.Unlock()
+	defer s.unsubscribe(sub)
+
+	f, err := startSSE(w)
+	if err != nil {
+		return err
+	}
+	if current.Status == "settled" || current.Status == "failed" {
+		writeSSE(w, f, current.Status, current)
+		return nil
+	}
+	for {
+		select {
+		case ev := <-sub.ch:
+			data, ok := ev.data.(lnbot.Payment)
+			if !ok || data.Number != current.Number || (data.Status != "settled" && data.Status != "failed") {
+				continue
+			}
+			writeSSE(w, f, data.Status, data)
+			return nil
+		case <-timeout:
+			return nil
+		case <-sub.done:
+			return nil
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// streamEvents streams every event for a wallet until the client
+// disconnects.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	if err := allow(r, http.MethodGet); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	sub := s.subscribe(wal)
+	s.mu.Unlock()
+	defer s.unsubscribe(sub)
+
+	f, err := startSSE(w)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev := <-sub.ch:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.wire())
+			f.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			f.Flush()
+		case <-sub.done:
+			return nil
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Webhooks
+// ---------------------------------------------------------------------------
+
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		out := []lnbot.Webhook{}
+		for _, h := range wal.webhooks {
+			out = append(out, h.Webhook)
+		}
+		return writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		var params lnbot.CreateWebhookParams
+		if err := decodeBody(r, &params); err != nil {
+			return err
+		}
+		u, err := url.Parse(params.URL)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			return errorf(http.StatusBadRequest, "invalid webhook URL %q", params.URL)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := time.Now().UTC()
+		h := &webhook{
+			Webhook: lnbot.Webhook{ID: randomID("whk_", 12), URL: params.URL, Active: true, CreatedAt: &now},
+			secret:  lnwebhook.NewSecret(),
+		}
+		wal.webhooks = append(wal.webhooks, h)
+		return writeJSON(w, http.StatusCreated, lnbot.WebhookWithSecret{
+			ID: h.ID, URL: h.URL, Secret: h.secret, CreatedAt: h.CreatedAt,
+		})
+	}
+	return errMethod
+}
+
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, wal *wallet, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, h := range wal.webhooks {
+		if h.ID == id {
+			wal.webhooks = append(wal.webhooks[:i], wal.webhooks[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+	return errorf(http.StatusNotFound, "webhook not found")
+}
+
+// deliver POSTs an event to each webhook, signed as described in
+// package webhook.
+func (s *Server) deliver(hooks []webhook, ev event) {
+	body := ev.wire()
+	for _, h := range hooks {
+		req, err := lnwebhook.NewRequest(context.Background(), h.URL, h.secret, ev.name, body, time.Now())
+		if err != nil {
+			continue
+		}
+		req.Header.Set("User-Agent", "lnbot-devserver")
+
+		var status string
+		resp, err := s.webhookClient.Do(req)
+		if err != nil {
+			status = err.Error()
+		} else {
+			status = strconv.Itoa(resp.StatusCode)
+			resp.Body.Close()
+		}
+		if s.opts.Log != nil {
+			fmt.Fprintf(s.opts.Log, "%s  webhook %s → %s  %s\n", time.Now().Format("15:04:05"), ev.name, h.URL, status)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package devserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+func TestWatchInvoice_Settled(t *testing.T) {
+	_, url := start(t, Options{SettleAfter: 50 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 100})
+	events, errs := w.Invoices.Watch(ctx, inv.Number, nil)
+	select {
+	case ev := <-events:
+		if ev.Event != "settled" || ev.Data.Status != "settled" || ev.Data.Number != inv.Number {
+			t.Errorf("event = %+v", ev)
+		}
+	case err := <-errs:
+		t.Fatalf("watch error: %v", err)
+	}
+
+	// Watching a settled invoice reports its final state at once.
+	events, _ = w.Invoices.Watch(ctx, inv.Number, nil)
+	if ev := <-events; ev.Event != "settled" {
+		t.Errorf("replay event = %+v", ev)
+	}
+}
+
+func TestWatchInvoice_Expired(t *testing.T) {
+	_, url := start(t, Options{InvoiceExpiry: 50 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 100})
+	events, _ := w.Invoices.Watch(ctx, inv.Number, nil)
+	if ev := <-events; ev.Event != "expired" {
+		t.Errorf("event = %+v, want expired", ev)
+	}
+}
+
+func TestWatchInvoice_Timeout(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 100})
+	events, _ := w.Invoices.Watch(ctx, inv.Number, lnbot.Ptr(1))
+	if ev, ok := <-events; ok {
+		t.Errorf("got %+v, want the stream to close on timeout", ev)
+	}
+}
+
+func TestWatchPayment(t *testing.T) {
+	_, url := start(t, Options{StartingBalance: 1000})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	p, _ := w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "fail@example.com", Amount: lnbot.Ptr(int64(10))})
+	events, _ := w.Payments.Watch(ctx, p.Number, nil)
+	if ev := <-events; ev.Event != "failed" || ev.Data.FailureReason == nil {
+		t.Errorf("event = %+v, want failed", ev)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	_, url := start(t, Options{StartingBalance: 1000})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	events, errs := w.Events.Stream(ctx)
+	// The stream subscribes asynchronously; wait until it is connected.
+	time.Sleep(100 * time.Millisecond)
+
+	w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(10))})
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case ev := <-events:
+			got = append(got, ev.Event)
+			if ev.CreatedAt == "" || len(ev.Data) == 0 {
+				t.Errorf("incomplete event %+v", ev)
+			}
+		case err := <-errs:
+			t.Fatalf("stream error: %v", err)
+		case <-ctx.Done():
+			t.Fatalf("timed out, got %v", got)
+		}
+	}
+	if got[0] != "payment.created" || got[1] != "payment.settled" {
+		t.Errorf("events = %v", got)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	deliveries := make(chan delivery, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{r.Header, body}
+	}))
+	defer receiver.Close()
+
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	hook, err := w.Webhooks.Create(ctx, &lnbot.CreateWebhookParams{URL: receiver.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 5})
+
+	select {
+	case d := <-deliveries:
+		if d.header.Get("X-LnBot-Event") != "invoice.created" {
+			t.Errorf("event header = %q", d.header.Get("X-LnBot-Event"))
+		}
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write([]byte(d.header.Get("X-LnBot-Timestamp") + "."))
+		mac.Write(d.body)
+		if want := "v1=" + hex.EncodeToString(mac.Sum(nil)); d.header.Get("X-LnBot-Signature") != want {
+			t.Errorf("signature = %q, want %q", d.header.Get("X-LnBot-Signature"), want)
+		}
+		var ev lnbot.WalletEvent
+		if err := json.Unmarshal(d.body, &ev); err != nil || ev.Event != "invoice.created" {
+			t.Errorf("body = %s", d.body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivery")
+	}
+
+	hooks, _ := w.Webhooks.List(ctx)
+	if len(hooks) != 1 || !hooks[0].Active {
+		t.Errorf("List = %+v", hooks)
+	}
+	if err := w.Webhooks.Delete(ctx, hook.ID); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	if err := w.Webhooks.Delete(ctx, hook.ID); err == nil {
+		t.Error("deleting twice should fail")
+	}
+}
@@ -0,0 +1,671 @@
+package devserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/bech32"
+	"github.com/lnbotdev/cli/internal/bolt11"
+)
+
+type invoice struct {
+	lnbot.Invoice
+	wallet   *wallet
+	hash     string
+	preimage string
+}
+
+type payment struct {
+	lnbot.Payment
+	hash string
+}
+
+// maxAddressAmount is the largest amount a local Lightning address accepts.
+const maxAddressAmount = 10_000_000
+
+// ---------------------------------------------------------------------------
+// Ledger
+// ---------------------------------------------------------------------------
+
+// credit adds sats to a wallet and records the transaction.
+func (s *Server) credit(wal *wallet, sats int64, hash, preimage *string, note string) *lnbot.Transaction {
+	wal.balance += sats
+	return s.record(wal, "credit", sats, 0, hash, preimage, nil, note)
+}
+
+func (s *Server) record(wal *wallet, typ string, amount, fee int64, hash, preimage, reference *string, note string) *lnbot.Transaction {
+	now := time.Now().UTC()
+	tx := &lnbot.Transaction{
+		Number:       len(wal.transactions) + 1,
+		Type:         typ,
+		Amount:       amount,
+		BalanceAfter: wal.balance,
+		NetworkFee:   fee,
+		PaymentHash:  hash,
+		Preimage:     preimage,
+		Reference:    reference,
+		CreatedAt:    &now,
+	}
+	if note != "" {
+		tx.Note = &note
+	}
+	wal.transactions = append(wal.transactions, tx)
+	return tx
+}
+
+// createInvoice issues a BOLT11 invoice for wal. amount 0 makes an
+// "any amount" invoice.
+func (s *Server) createInvoice(wal *wallet, amount int64, memo, reference *string) (*invoice, error) {
+	preimage := randomBytes(32)
+	hash := sha256.Sum256(preimage)
+	now := time.Now().UTC().Truncate(time.Second)
+	expires := now.Add(s.opts.InvoiceExpiry)
+
+	b := &bolt11.Invoice{
+		Prefix:        s.opts.Network,
+		Timestamp:     now,
+		PaymentHash:   hex.EncodeToString(hash[:]),
+		PaymentSecret: randomHex(32),
+		Expiry:        s.opts.InvoiceExpiry,
+	}
+	if amount > 0 {
+		msat := amount * 1000
+		b.AmountMsat = &msat
+	}
+	if memo != nil {
+		b.Description = *memo
+	}
+	encoded, err := bolt11.Encode(b)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &invoice{
+		Invoice: lnbot.Invoice{
+			Number:    len(wal.invoices) + 1,
+			Status:    "pending",
+			Amount:    amount,
+			Bolt11:    encoded,
+			Reference: reference,
+			Memo:      memo,
+			CreatedAt: &now,
+			ExpiresAt: &expires,
+		},
+		wallet:   wal,
+		hash:     b.PaymentHash,
+		preimage: hex.EncodeToString(preimage),
+	}
+	wal.invoices = append(wal.invoices, inv)
+	s.invoices[inv.hash] = inv
+	s.publish(wal, "invoice.created", inv.Invoice)
+
+	s.after(s.opts.InvoiceExpiry, func() { s.expireInvoice(inv) })
+	if s.opts.SettleAfter > 0 {
+		s.after(s.opts.SettleAfter, func() {
+			if inv.Status == "pending" {
+				s.settleInvoice(inv, inv.Amount)
+			}
+		})
+	}
+	return inv, nil
+}
+
+// after runs fn with the lock held once d has passed, unless the server
+// has been closed.
+func (s *Server) after(d time.Duration, fn func()) {
+	t := time.AfterFunc(d, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.closed {
+			fn()
+		}
+	})
+	s.timers = append(s.timers, t)
+}
+
+// settleInvoice credits the invoice's wallet. amount is used for "any
+// amount" invoices.
+func (s *Server) settleInvoice(inv *invoice, amount int64) {
+	now := time.Now().UTC()
+	var note string
+	if inv.Memo != nil {
+		note = *inv.Memo
+	}
+	tx := s.credit(inv.wallet, amount, &inv.hash, &inv.preimage, note)
+	inv.Status = "settled"
+	inv.Amount = amount
+	inv.SettledAt = &now
+	inv.Preimage = &inv.preimage
+	inv.TxNumber = &tx.Number
+	s.publish(inv.wallet, "invoice.settled", inv.Invoice)
+}
+
+func (s *Server) expireInvoice(inv *invoice) {
+	if inv.Status != "pending" {
+		return
+	}
+	inv.Status = "expired"
+	s.publish(inv.wallet, "invoice.expired", inv.Invoice)
+}
+
+// invoiceByRef finds an invoice by payment hash or BOLT11 string.
+func (s *Server) invoiceByRef(ref string) *invoice {
+	if inv := s.invoices[strings.ToLower(ref)]; inv != nil {
+		return inv
+	}
+	if decoded, err := bolt11.Decode(ref); err == nil {
+		return s.invoices[decoded.PaymentHash]
+	}
+	return nil
+}
+
+// walletInvoice finds one of wal's invoices by number or payment hash.
+func walletInvoice(wal *wallet, ref string) *invoice {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n >= 1 && n <= len(wal.invoices) {
+			return wal.invoices[n-1]
+		}
+		return nil
+	}
+	for _, inv := range wal.invoices {
+		if inv.hash == strings.ToLower(ref) {
+			return inv
+		}
+	}
+	return nil
+}
+
+func walletPayment(wal *wallet, ref string) *payment {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n >= 1 && n <= len(wal.payments) {
+			return wal.payments[n-1]
+		}
+		return nil
+	}
+	for _, p := range wal.payments {
+		if p.hash == strings.ToLower(ref) {
+			return p
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Invoices
+// ---------------------------------------------------------------------------
+
+func (s *Server) handleInvoices(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	switch r.Method {
+	case http.MethodGet:
+		limit, after, err := listParams(r)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		out := []lnbot.Invoice{}
+		for _, inv := range page(wal.invoices, limit, after) {
+			out = append(out, inv.Invoice)
+		}
+		return writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		var params lnbot.CreateInvoiceParams
+		if err := decodeBody(r, &params); err != nil {
+			return err
+		}
+		if params.Amount <= 0 {
+			return errorf(http.StatusBadRequest, "amount must be positive")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		inv, err := s.createInvoice(wal, params.Amount, params.Memo, params.Reference)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusCreated, inv.Invoice)
+	}
+	return errMethod
+}
+
+func (s *Server) handleInvoice(w http.ResponseWriter, wal *wallet, ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv := walletInvoice(wal, ref)
+	if inv == nil {
+		return errorf(http.StatusNotFound, "invoice not found")
+	}
+	return writeJSON(w, http.StatusOK, inv.Invoice)
+}
+
+func (s *Server) handlePublicInvoice(w http.ResponseWriter, r *http.Request, kind string) error {
+	var params struct {
+		WalletID string  `json:"walletId"`
+		Address  string  `json:"address"`
+		Amount   int64   `json:"amount"`
+		Comment  *string `json:"comment"`
+	}
+	if err := decodeBody(r, &params); err != nil {
+		return err
+	}
+	if params.Amount <= 0 {
+		return errorf(http.StatusBadRequest, "amount must be positive")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var wal *wallet
+	if kind == "for-wallet" {
+		wal = s.wallets[params.WalletID]
+	} else {
+		wal = s.localAddress(params.Address)
+	}
+	if wal == nil {
+		return errorf(http.StatusNotFound, "recipient not found")
+	}
+	inv, err := s.createInvoice(wal, params.Amount, params.Comment, nil)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, lnbot.AddressInvoice{
+		Bolt11:    inv.Bolt11,
+		Amount:    inv.Amount,
+		ExpiresAt: inv.ExpiresAt,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Payments
+// ---------------------------------------------------------------------------
+
+// destination is a resolved payment target.
+type destination struct {
+	kind    string // "bolt11", "lnaddress" or "lnurl"
+	address string // user@domain for addresses and LNURLs
+	invoice *bolt11.Invoice
+	local   *invoice // invoice issued by this server
+	wallet  *wallet  // local recipient of an address
+}
+
+func (d *destination) fails() bool {
+	if d.invoice != nil {
+		return strings.HasPrefix(d.invoice.Description, "fail")
+	}
+	return strings.HasPrefix(d.address, "fail")
+}
+
+// resolve parses a payment target. It must be called with the lock held.
+func (s *Server) resolve(target string) (*destination, error) {
+	target = strings.TrimSpace(target)
+	if len(target) > 10 && strings.EqualFold(target[:10], "lightning:") {
+		target = target[10:]
+	}
+
+	switch {
+	case bolt11.IsInvoice(target):
+		inv, err := bolt11.Decode(target)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid invoice: %s", err)
+		}
+		return &destination{kind: "bolt11", invoice: inv, local: s.invoices[inv.PaymentHash]}, nil
+
+	case strings.HasPrefix(strings.ToLower(target), "lnurl1"):
+		hrp, data, err := bech32.Decode(target)
+		if err != nil || hrp != "lnurl" {
+			return nil, errorf(http.StatusBadRequest, "invalid LNURL")
+		}
+		raw, err := bech32.ConvertBits(data, 5, 8, false)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid LNURL")
+		}
+		u, err := url.Parse(string(raw))
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid LNURL")
+		}
+		d := &destination{kind: "lnurl", address: "lnurl@" + u.Host}
+		if user, ok := strings.CutPrefix(u.Path, "/.well-known/lnurlp/"); ok {
+			d.address = user + "@" + u.Host
+		}
+		d.wallet = s.localAddress(d.address)
+		return d, nil
+
+	case strings.Contains(target, "@"):
+		user, domain, _ := strings.Cut(strings.ToLower(target), "@")
+		if user == "" || !strings.Contains(domain, ".") {
+			return nil, errorf(http.StatusBadRequest, "invalid Lightning address %q", target)
+		}
+		d := &destination{kind: "lnaddress", address: user + "@" + domain}
+		if domain == s.opts.Domain {
+			if d.wallet = s.localAddress(d.address); d.wallet == nil {
+				return nil, errorf(http.StatusNotFound, "address %s not found", d.address)
+			}
+		}
+		return d, nil
+	}
+	return nil, errorf(http.StatusBadRequest, "unsupported payment target — use a Lightning address, LNURL or BOLT11 invoice")
+}
+
+func (s *Server) handleResolve(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	if err := allow(r, http.MethodGet); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, err := s.resolve(r.URL.Query().Get("target"))
+	if err != nil {
+		return err
+	}
+	out := lnbot.ResolveTargetResponse{Type: d.kind}
+	if d.invoice != nil {
+		sats, ok := d.invoice.AmountSats()
+		out.Fixed = lnbot.Ptr(ok)
+		if ok {
+			out.Amount = &sats
+		}
+	} else {
+		out.Fixed = lnbot.Ptr(false)
+		out.Min = lnbot.Ptr(int64(1))
+		out.Max = lnbot.Ptr(int64(maxAddressAmount))
+	}
+	return writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handlePayments(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	switch r.Method {
+	case http.MethodGet:
+		limit, after, err := listParams(r)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		out := []lnbot.Payment{}
+		for _, p := range page(wal.payments, limit, after) {
+			out = append(out, p.Payment)
+		}
+		return writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		var params lnbot.CreatePaymentParams
+		if err := decodeBody(r, &params); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		p, err := s.pay(wal, &params)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusCreated, p.Payment)
+	}
+	return errMethod
+}
+
+func (s *Server) handlePayment(w http.ResponseWriter, wal *wallet, ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := walletPayment(wal, ref)
+	if p == nil {
+		return errorf(http.StatusNotFound, "payment not found")
+	}
+	return writeJSON(w, http.StatusOK, p.Payment)
+}
+
+// pay sends a payment from wal. Payments settle (or fail) before it
+// returns. It must be called with the lock held.
+func (s *Server) pay(wal *wallet, params *lnbot.CreatePaymentParams) (*payment, error) {
+	if params.IdempotencyKey != nil && *params.IdempotencyKey != "" {
+		if p := wal.idempotency[*params.IdempotencyKey]; p != nil {
+			return p, nil
+		}
+	}
+	if params.Target == "" {
+		return nil, errorf(http.StatusBadRequest, "target is required")
+	}
+	d, err := s.resolve(params.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	var amount int64
+	if params.Amount != nil {
+		amount = *params.Amount
+	}
+	hash := randomHex(32)
+	if d.invoice != nil {
+		if d.invoice.Expired(time.Now()) {
+			return nil, errorf(http.StatusBadRequest, "invoice expired")
+		}
+		if sats, ok := d.invoice.AmountSats(); ok {
+			if amount != 0 && amount != sats {
+				return nil, errorf(http.StatusBadRequest, "amount does not match the invoice amount (%d sats)", sats)
+			}
+			amount = sats
+		} else if amount == 0 {
+			return nil, errorf(http.StatusBadRequest, "amount is required for invoices without an amount")
+		}
+		hash = d.invoice.PaymentHash
+		if d.local != nil && d.local.Status != "pending" {
+			return nil, errorf(http.StatusBadRequest, "invoice is already %s", d.local.Status)
+		}
+	} else if amount == 0 {
+		return nil, errorf(http.StatusBadRequest, "amount is required")
+	}
+	if amount <= 0 {
+		return nil, errorf(http.StatusBadRequest, "amount must be positive")
+	}
+	if d.wallet != nil && amount > maxAddressAmount {
+		return nil, errorf(http.StatusBadRequest, "amount exceeds the maximum of %d sats", maxAddressAmount)
+	}
+
+	// Payments within the server are free; external ones are charged a
+	// simulated routing fee.
+	local := d.local != nil || d.wallet != nil
+	var fee int64
+	if !local {
+		fee = 1 + amount/1000
+	}
+	maxFee := max(10, amount/100)
+	if params.MaxFee != nil {
+		maxFee = *params.MaxFee
+	}
+	if amount+fee > wal.balance {
+		return nil, errorf(http.StatusBadRequest, "insufficient balance: %d sats available", wal.balance)
+	}
+
+	now := time.Now().UTC()
+	p := &payment{
+		Payment: lnbot.Payment{
+			Number:    len(wal.payments) + 1,
+			Status:    "pending",
+			Amount:    amount,
+			MaxFee:    maxFee,
+			Address:   strings.TrimSpace(params.Target),
+			Reference: params.Reference,
+			CreatedAt: &now,
+		},
+		hash: hash,
+	}
+	wal.payments = append(wal.payments, p)
+	if params.IdempotencyKey != nil && *params.IdempotencyKey != "" {
+		wal.idempotency[*params.IdempotencyKey] = p
+	}
+	s.publish(wal, "payment.created", p.Payment)
+
+	var reason string
+	switch {
+	case fee > maxFee:
+		reason = "fee exceeds max fee"
+	case !local && d.fails():
+		reason = "no route found"
+	}
+	if reason != "" {
+		p.Status = "failed"
+		p.FailureReason = &reason
+		s.publish(wal, "payment.failed", p.Payment)
+		return p, nil
+	}
+
+	preimage := randomHex(32)
+	switch {
+	case d.local != nil:
+		preimage = d.local.preimage
+	case d.wallet != nil:
+		memo := "Payment to " + d.address
+		inv, err := s.createInvoice(d.wallet, amount, &memo, nil)
+		if err != nil {
+			return nil, err
+		}
+		hash, preimage = inv.hash, inv.preimage
+		p.hash = hash
+	}
+
+	wal.balance -= amount + fee
+	tx := s.record(wal, "debit", amount, fee, &hash, &preimage, params.Reference, p.Address)
+	settled := time.Now().UTC()
+	p.Status = "settled"
+	p.ActualFee = &fee
+	p.Preimage = &preimage
+	p.TxNumber = &tx.Number
+	p.SettledAt = &settled
+
+	if inv := s.invoices[hash]; inv != nil {
+		s.settleInvoice(inv, amount)
+	}
+	s.publish(wal, "payment.settled", p.Payment)
+	return p, nil
+}
+
+// ---------------------------------------------------------------------------
+// Transactions
+// ---------------------------------------------------------------------------
+
+func (s *Server) handleTransactions(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	if err := allow(r, http.MethodGet); err != nil {
+		return err
+	}
+	limit, after, err := listParams(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeJSON(w, http.StatusOK, page(wal.transactions, limit, after))
+}
+
+// ---------------------------------------------------------------------------
+// Addresses
+// ---------------------------------------------------------------------------
+
+var addressName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// localAddress returns the wallet owning a Lightning address on this
+// server. Plus-addressing (alice+tag@) resolves to alice.
+func (s *Server) localAddress(addr string) *wallet {
+	user, domain, ok := strings.Cut(strings.ToLower(addr), "@")
+	if ok && domain != s.opts.Domain {
+		return nil
+	}
+	user, _, _ = strings.Cut(user, "+")
+	return s.addresses[user]
+}
+
+func (s *Server) addAddress(wal *wallet, name string, generated bool) *lnbot.Address {
+	now := time.Now().UTC()
+	a := &lnbot.Address{Address: name + "@" + s.opts.Domain, Generated: generated, CreatedAt: &now}
+	wal.addresses = append(wal.addresses, a)
+	s.addresses[name] = wal
+	return a
+}
+
+func (s *Server) handleAddresses(w http.ResponseWriter, r *http.Request, wal *wallet) error {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		out := []lnbot.Address{}
+		for _, a := range wal.addresses {
+			out = append(out, *a)
+		}
+		return writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		var params lnbot.CreateAddressParams
+		if err := decodeBody(r, &params); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if params.Address == nil || *params.Address == "" {
+			return writeJSON(w, http.StatusCreated, s.addAddress(wal, strings.ToLower(randomID("", 10)), true))
+		}
+		name, _, _ := strings.Cut(strings.ToLower(*params.Address), "@")
+		if !addressName.MatchString(name) {
+			return errorf(http.StatusBadRequest, "invalid address %q: use lowercase letters, digits, '.', '_' or '-'", name)
+		}
+		if s.addresses[name] != nil {
+			return errorf(http.StatusConflict, "address %s@%s is already taken", name, s.opts.Domain)
+		}
+		return writeJSON(w, http.StatusCreated, s.addAddress(wal, name, false))
+	}
+	return errMethod
+}
+
+// ownAddress finds one of wal's addresses by full address or local part.
+func (s *Server) ownAddress(wal *wallet, ref string) (int, string) {
+	name, _, _ := strings.Cut(strings.ToLower(ref), "@")
+	for i, a := range wal.addresses {
+		if a.Address == name+"@"+s.opts.Domain {
+			return i, name
+		}
+	}
+	return -1, name
+}
+
+func (s *Server) handleDeleteAddress(w http.ResponseWriter, wal *wallet, ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, name := s.ownAddress(wal, ref)
+	if i < 0 {
+		return errorf(http.StatusNotFound, "address not found")
+	}
+	wal.addresses = append(wal.addresses[:i], wal.addresses[i+1:]...)
+	delete(s.addresses, name)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) handleTransferAddress(w http.ResponseWriter, r *http.Request, wal *wallet, ref string) error {
+	var params lnbot.TransferAddressParams
+	if err := decodeBody(r, &params); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, name := s.ownAddress(wal, ref)
+	if i < 0 {
+		return errorf(http.StatusNotFound, "address not found")
+	}
+	if wal.addresses[i].Generated {
+		return errorf(http.StatusBadRequest, "generated addresses cannot be transferred")
+	}
+
+	target := s.walletKeys[params.TargetWalletKey]
+	if acct := s.userKeys[params.TargetWalletKey]; acct != nil && len(acct.wallets) > 0 {
+		target = acct.wallets[0]
+	}
+	if target == nil {
+		return errorf(http.StatusBadRequest, "invalid target wallet key")
+	}
+
+	a := wal.addresses[i]
+	wal.addresses = append(wal.addresses[:i], wal.addresses[i+1:]...)
+	target.addresses = append(target.addresses, a)
+	s.addresses[name] = target
+	return writeJSON(w, http.StatusOK, lnbot.AddressTransfer{Address: a.Address, TransferredTo: target.id})
+}
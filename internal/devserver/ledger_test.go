@@ -0,0 +1,290 @@
+package devserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/bolt11"
+)
+
+func TestInvoices(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	inv, err := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 250, Memo: lnbot.Ptr("coffee")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Number != 1 || inv.Status != "pending" || !strings.HasPrefix(inv.Bolt11, "lnbc2500n1") {
+		t.Errorf("invoice = %+v", inv)
+	}
+
+	decoded, err := bolt11.Decode(inv.Bolt11)
+	if err != nil {
+		t.Fatalf("issued invoice does not decode: %v", err)
+	}
+	if sats, _ := decoded.AmountSats(); sats != 250 || decoded.Description != "coffee" {
+		t.Errorf("decoded = %d sats %q", sats, decoded.Description)
+	}
+
+	byHash, err := w.Invoices.GetByHash(ctx, decoded.PaymentHash)
+	if err != nil || byHash.Number != 1 {
+		t.Errorf("GetByHash = %+v, %v", byHash, err)
+	}
+
+	if _, err := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 0}); err == nil {
+		t.Error("zero amount should be rejected")
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+	for i := 0; i < 5; i++ {
+		w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 10})
+	}
+
+	list, _ := w.Invoices.List(ctx, &lnbot.ListInvoicesParams{Limit: lnbot.Ptr(2)})
+	if len(list) != 2 || list[0].Number != 5 || list[1].Number != 4 {
+		t.Errorf("first page = %v", numbers(list))
+	}
+	list, _ = w.Invoices.List(ctx, &lnbot.ListInvoicesParams{Limit: lnbot.Ptr(10), After: lnbot.Ptr(3)})
+	if len(list) != 2 || list[0].Number != 2 {
+		t.Errorf("after 3 = %v, want [2 1]", numbers(list))
+	}
+}
+
+func numbers(invs []lnbot.Invoice) []int {
+	var out []int
+	for _, inv := range invs {
+		out = append(out, inv.Number)
+	}
+	return out
+}
+
+func TestPay_BetweenWallets(t *testing.T) {
+	_, url := start(t, Options{StartingBalance: 1000})
+	ctx := context.Background()
+	alice, _, aliceWal := newAccount(t, url)
+	bob, _, bobWal := newAccount(t, url)
+	a, b := alice.Wallet(aliceWal.WalletID), bob.Wallet(bobWal.WalletID)
+
+	// To a Lightning address, with plus-addressing.
+	user, domain, _ := strings.Cut(bobWal.Address, "@")
+	p, err := a.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: user + "+tip@" + domain, Amount: lnbot.Ptr(int64(300))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != "settled" || p.ActualFee == nil || *p.ActualFee != 0 || p.Preimage == nil {
+		t.Errorf("payment = %+v", p)
+	}
+
+	// To an invoice.
+	inv, _ := b.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 200})
+	if _, err := a.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "lightning:" + inv.Bolt11}); err != nil {
+		t.Fatal(err)
+	}
+	inv, _ = b.Invoices.Get(ctx, inv.Number)
+	if inv.Status != "settled" {
+		t.Errorf("invoice status = %s, want settled", inv.Status)
+	}
+	if _, err := a.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: inv.Bolt11}); err == nil {
+		t.Error("paying a settled invoice twice should fail")
+	}
+
+	aw, _ := a.Get(ctx)
+	bw, _ := b.Get(ctx)
+	if aw.Balance != 500 || bw.Balance != 1500 {
+		t.Errorf("balances = %d, %d, want 500, 1500", aw.Balance, bw.Balance)
+	}
+
+	txs, _ := b.Transactions.List(ctx, nil)
+	if len(txs) != 3 || txs[0].Type != "credit" || txs[0].BalanceAfter != 1500 {
+		t.Errorf("receiver transactions = %+v", txs)
+	}
+	txs, _ = a.Transactions.List(ctx, nil)
+	if txs[0].Type != "debit" || txs[0].Amount != 200 {
+		t.Errorf("sender transactions = %+v", txs)
+	}
+}
+
+func TestPay_External(t *testing.T) {
+	_, url := start(t, Options{StartingBalance: 10000})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	p, err := w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(2000))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != "settled" || *p.ActualFee != 3 {
+		t.Errorf("external payment = %+v, want settled with fee 3", p)
+	}
+
+	p, _ = w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "fail@example.com", Amount: lnbot.Ptr(int64(100))})
+	if p.Status != "failed" || p.FailureReason == nil || *p.FailureReason != "no route found" {
+		t.Errorf("fail@ payment = %+v", p)
+	}
+
+	p, _ = w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(5000)), MaxFee: lnbot.Ptr(int64(1))})
+	if p.Status != "failed" || *p.FailureReason != "fee exceeds max fee" {
+		t.Errorf("max fee payment = %+v", p)
+	}
+
+	got, _ := w.Get(ctx)
+	if got.Balance != 10000-2003 {
+		t.Errorf("balance = %d, failed payments must not be debited", got.Balance)
+	}
+
+	_, err = w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(1_000_000))})
+	var bad *lnbot.BadRequestError
+	if !errors.As(err, &bad) || !strings.Contains(err.Error(), "insufficient balance") {
+		t.Errorf("err = %v, want insufficient balance", err)
+	}
+}
+
+func TestPay_Validation(t *testing.T) {
+	s, url := start(t, Options{StartingBalance: 1000})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	tests := []struct {
+		name   string
+		params lnbot.CreatePaymentParams
+		want   string
+	}{
+		{"no amount", lnbot.CreatePaymentParams{Target: "carol@example.com"}, "amount is required"},
+		{"unknown local", lnbot.CreatePaymentParams{Target: "nobody@ln.bot", Amount: lnbot.Ptr(int64(1))}, "not found"},
+		{"garbage", lnbot.CreatePaymentParams{Target: "hello", Amount: lnbot.Ptr(int64(1))}, "unsupported payment target"},
+		{"negative", lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(-5))}, "must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := w.Payments.Create(ctx, &tt.params)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Expired invoices are rejected.
+	s.mu.Lock()
+	inv, _ := s.createInvoice(s.wallets[wal.WalletID], 10, nil, nil)
+	s.mu.Unlock()
+	decoded, _ := bolt11.Decode(inv.Bolt11)
+	decoded.Timestamp = time.Now().Add(-2 * time.Hour)
+	stale, _ := bolt11.Encode(decoded)
+	if _, err := w.Payments.Create(ctx, &lnbot.CreatePaymentParams{Target: stale}); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("err = %v, want invoice expired", err)
+	}
+}
+
+func TestPay_Idempotency(t *testing.T) {
+	_, url := start(t, Options{StartingBalance: 1000})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	params := &lnbot.CreatePaymentParams{Target: "carol@example.com", Amount: lnbot.Ptr(int64(100)), IdempotencyKey: lnbot.Ptr("k1")}
+	first, _ := w.Payments.Create(ctx, params)
+	second, _ := w.Payments.Create(ctx, params)
+	if first.Number != second.Number {
+		t.Errorf("same idempotency key created payments %d and %d", first.Number, second.Number)
+	}
+	list, _ := w.Payments.List(ctx, nil)
+	if len(list) != 1 {
+		t.Errorf("%d payments, want 1", len(list))
+	}
+}
+
+func TestResolve(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	res, err := w.Payments.Resolve(ctx, wal.Address)
+	if err != nil || res.Type != "lnaddress" || res.Min == nil || *res.Min != 1 {
+		t.Errorf("address = %+v, %v", res, err)
+	}
+
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 42})
+	res, err = w.Payments.Resolve(ctx, inv.Bolt11)
+	if err != nil || res.Type != "bolt11" || !*res.Fixed || *res.Amount != 42 {
+		t.Errorf("invoice = %+v, %v", res, err)
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	c, _, wal := newAccount(t, url)
+	w := c.Wallet(wal.WalletID)
+
+	addr, err := w.Addresses.Create(ctx, &lnbot.CreateAddressParams{Address: lnbot.Ptr("alice")})
+	if err != nil || addr.Address != "alice@ln.bot" || addr.Generated {
+		t.Fatalf("Create = %+v, %v", addr, err)
+	}
+	if _, err := w.Addresses.Create(ctx, &lnbot.CreateAddressParams{Address: lnbot.Ptr("alice")}); err == nil {
+		t.Error("duplicate address should conflict")
+	}
+	if _, err := w.Addresses.Create(ctx, &lnbot.CreateAddressParams{Address: lnbot.Ptr("Bad Name")}); err == nil {
+		t.Error("invalid address should be rejected")
+	}
+
+	list, _ := w.Addresses.List(ctx)
+	if len(list) != 2 || !list[0].Generated {
+		t.Errorf("List = %+v", list)
+	}
+
+	// Transfer to another account's wallet via its wallet key.
+	other, _, otherWal := newAccount(t, url)
+	key, _ := other.Wallet(otherWal.WalletID).Key.Create(ctx)
+	if _, err := w.Addresses.Transfer(ctx, list[0].Address, &lnbot.TransferAddressParams{TargetWalletKey: key.Key}); err == nil {
+		t.Error("generated addresses should not transfer")
+	}
+	tr, err := w.Addresses.Transfer(ctx, "alice@ln.bot", &lnbot.TransferAddressParams{TargetWalletKey: key.Key})
+	if err != nil || tr.TransferredTo != otherWal.WalletID {
+		t.Fatalf("Transfer = %+v, %v", tr, err)
+	}
+	otherList, _ := other.Wallet(otherWal.WalletID).Addresses.List(ctx)
+	if len(otherList) != 2 {
+		t.Errorf("target wallet addresses = %+v", otherList)
+	}
+
+	if err := other.Wallet(otherWal.WalletID).Addresses.Delete(ctx, "alice@ln.bot"); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	if err := w.Addresses.Delete(ctx, "alice"); err == nil {
+		t.Error("deleting a foreign address should fail")
+	}
+}
+
+func TestPublicInvoices(t *testing.T) {
+	_, url := start(t, Options{})
+	ctx := context.Background()
+	_, _, wal := newAccount(t, url)
+	anon := lnbot.New("", lnbot.WithBaseURL(url))
+
+	inv, err := anon.Invoices.CreateForAddress(ctx, &lnbot.CreateInvoiceForAddressParams{Address: wal.Address, Amount: 21})
+	if err != nil || inv.Amount != 21 || !strings.HasPrefix(inv.Bolt11, "lnbc") {
+		t.Errorf("CreateForAddress = %+v, %v", inv, err)
+	}
+	if _, err := anon.Invoices.CreateForWallet(ctx, &lnbot.CreateInvoiceForWalletParams{WalletID: wal.WalletID, Amount: 21}); err != nil {
+		t.Errorf("CreateForWallet: %v", err)
+	}
+	if _, err := anon.Invoices.CreateForWallet(ctx, &lnbot.CreateInvoiceForWalletParams{WalletID: "wal_nope", Amount: 21}); err == nil {
+		t.Error("unknown wallet should fail")
+	}
+}
@@ -0,0 +1,189 @@
+// Package export writes wallet transactions as accounting records: CSV,
+// OFX bank statements, and Beancount or Ledger journals.
+//
+// Transactions are expected oldest first. Every format carries the wallet
+// balance from Transaction.BalanceAfter, either as a column, a ledger
+// balance, or balance assertions, so imports can be checked against the
+// wallet.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+// Supported formats.
+const (
+	CSV       = "csv"
+	OFX       = "ofx"
+	Beancount = "beancount"
+	Ledger    = "ledger"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{CSV, OFX, Beancount, Ledger}
+
+// Unit is the unit amounts are written in.
+type Unit string
+
+const (
+	Sats Unit = "sats"
+	BTC  Unit = "btc"
+)
+
+// Default account names for the journal formats.
+const (
+	DefaultAccount        = "Assets:Lightning"
+	DefaultIncomeAccount  = "Income:Lightning"
+	DefaultExpenseAccount = "Expenses:Lightning"
+	DefaultFeeAccount     = "Expenses:Fees:Lightning"
+	OpeningAccount        = "Equity:Opening-Balances"
+)
+
+// Options control how transactions are written. Zero values fall back to
+// the defaults above.
+type Options struct {
+	Unit Unit
+	// WalletID identifies the account in OFX statements and journal
+	// metadata.
+	WalletID string
+
+	Account        string // the wallet itself
+	IncomeAccount  string // counterpart of credits
+	ExpenseAccount string // counterpart of debits
+	FeeAccount     string // network and service fees
+
+	// From and To bound the statement period in OFX. Zero values use the
+	// first and last transaction.
+	From, To time.Time
+}
+
+func (o *Options) defaults() {
+	if o.Unit == "" {
+		o.Unit = Sats
+	}
+	if o.Account == "" {
+		o.Account = DefaultAccount
+	}
+	if o.IncomeAccount == "" {
+		o.IncomeAccount = DefaultIncomeAccount
+	}
+	if o.ExpenseAccount == "" {
+		o.ExpenseAccount = DefaultExpenseAccount
+	}
+	if o.FeeAccount == "" {
+		o.FeeAccount = DefaultFeeAccount
+	}
+}
+
+// Write writes txs to w in the given format.
+func Write(w io.Writer, format string, txs []lnbot.Transaction, opts Options) error {
+	opts.defaults()
+	if opts.Unit != Sats && opts.Unit != BTC {
+		return fmt.Errorf("unknown unit %q (want sats or btc)", opts.Unit)
+	}
+	switch format {
+	case CSV:
+		return writeCSV(w, txs, opts)
+	case OFX:
+		return writeOFX(w, txs, opts)
+	case Beancount:
+		return writeBeancount(w, txs, opts)
+	case Ledger:
+		return writeLedger(w, txs, opts)
+	}
+	return fmt.Errorf("unknown format %q (want %s)", format, strings.Join(Formats, ", "))
+}
+
+// Amount formats sats in the unit: a plain integer for sats, eight
+// decimals for BTC.
+func Amount(sats int64, unit Unit) string {
+	if unit != BTC {
+		return strconv.FormatInt(sats, 10)
+	}
+	sign := ""
+	if sats < 0 {
+		sign = "-"
+		sats = -sats
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, sats/100_000_000, sats%100_000_000)
+}
+
+// fee is the total fee charged on a transaction.
+func fee(tx lnbot.Transaction) int64 {
+	return tx.NetworkFee + tx.ServiceFee
+}
+
+// delta is the change a transaction made to the wallet balance. Debits
+// spend the amount plus fees; fees on credits are taken from the amount
+// received.
+func delta(tx lnbot.Transaction) int64 {
+	if tx.Type == "debit" {
+		return -(tx.Amount + fee(tx))
+	}
+	return tx.Amount - fee(tx)
+}
+
+func postedAt(tx lnbot.Transaction) time.Time {
+	if tx.CreatedAt == nil {
+		return time.Time{}
+	}
+	return tx.CreatedAt.UTC()
+}
+
+// description is a one-line summary of a transaction.
+func description(tx lnbot.Transaction) string {
+	if tx.Note != nil && *tx.Note != "" {
+		return *tx.Note
+	}
+	if tx.Type == "debit" {
+		return "Lightning payment"
+	}
+	return "Lightning receipt"
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func writeCSV(w io.Writer, txs []lnbot.Transaction, opts Options) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"number", "date", "type", "amount", "fee", "balance_after", "unit",
+		"payment_hash", "reference", "note",
+	})
+	for _, tx := range txs {
+		// Amounts are signed from the wallet's point of view.
+		amount := tx.Amount
+		if tx.Type == "debit" {
+			amount = -amount
+		}
+		var date string
+		if tx.CreatedAt != nil {
+			date = postedAt(tx).Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			strconv.Itoa(tx.Number),
+			date,
+			tx.Type,
+			Amount(amount, opts.Unit),
+			Amount(fee(tx), opts.Unit),
+			Amount(tx.BalanceAfter, opts.Unit),
+			string(opts.Unit),
+			deref(tx.PaymentHash),
+			deref(tx.Reference),
+			deref(tx.Note),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
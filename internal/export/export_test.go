@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+func at(s string) *time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return &t
+}
+
+// fixture is a short history, oldest first: a deposit, a payment with a
+// fee, and a receipt on the next day.
+func fixture() []lnbot.Transaction {
+	return []lnbot.Transaction{
+		{Number: 1, Type: "credit", Amount: 50000, BalanceAfter: 50000, CreatedAt: at("2024-03-01T09:00:00Z")},
+		{Number: 2, Type: "debit", Amount: 1000, NetworkFee: 2, ServiceFee: 1, BalanceAfter: 48997,
+			PaymentHash: lnbot.Ptr("abc123"), Reference: lnbot.Ptr("order-7"), Note: lnbot.Ptr("carol@example.com"),
+			CreatedAt: at("2024-03-01T12:30:00Z")},
+		{Number: 3, Type: "credit", Amount: 250, BalanceAfter: 49247, Note: lnbot.Ptr(`tip "thanks"`),
+			CreatedAt: at("2024-03-02T08:00:00Z")},
+	}
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		sats int64
+		unit Unit
+		want string
+	}{
+		{1234, Sats, "1234"},
+		{-1234, Sats, "-1234"},
+		{0, BTC, "0.00000000"},
+		{1, BTC, "0.00000001"},
+		{123456789, BTC, "1.23456789"},
+		{-1003, BTC, "-0.00001003"},
+	}
+	for _, tt := range tests {
+		if got := Amount(tt.sats, tt.unit); got != tt.want {
+			t.Errorf("Amount(%d, %s) = %q, want %q", tt.sats, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestDelta(t *testing.T) {
+	txs := fixture()
+	balance := int64(0)
+	for _, tx := range txs {
+		balance += delta(tx)
+		if balance != tx.BalanceAfter {
+			t.Errorf("tx %d: running balance %d, want %d", tx.Number, balance, tx.BalanceAfter)
+		}
+	}
+}
+
+func TestWrite_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, CSV, fixture(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want header + 3", len(rows))
+	}
+	want := []string{"2", "2024-03-01T12:30:00Z", "debit", "-1000", "3", "48997", "sats", "abc123", "order-7", "carol@example.com"}
+	if strings.Join(rows[2], "|") != strings.Join(want, "|") {
+		t.Errorf("row = %q, want %q", rows[2], want)
+	}
+	if rows[3][9] != `tip "thanks"` {
+		t.Errorf("note = %q", rows[3][9])
+	}
+}
+
+func TestWrite_CSVInBTC(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, CSV, fixture(), Options{Unit: BTC})
+	if !strings.Contains(buf.String(), "-0.00001000,0.00000003,0.00048997,btc") {
+		t.Errorf("output = %s", buf.String())
+	}
+}
+
+func TestWrite_Invalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "xlsx", nil, Options{}); err == nil || !strings.Contains(err.Error(), "csv, ofx, beancount, ledger") {
+		t.Errorf("unknown format: err = %v", err)
+	}
+	if err := Write(&buf, CSV, nil, Options{Unit: "msat"}); err == nil {
+		t.Error("unknown unit should fail")
+	}
+}
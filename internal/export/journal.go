@@ -0,0 +1,188 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+type posting struct {
+	account string
+	amount  int64
+}
+
+// postings splits a transaction into balanced double-entry postings, the
+// wallet account first.
+func postings(tx lnbot.Transaction, opts Options) []posting {
+	out := []posting{{opts.Account, delta(tx)}}
+	if tx.Type == "debit" {
+		out = append(out, posting{opts.ExpenseAccount, tx.Amount})
+	} else {
+		out = append(out, posting{opts.IncomeAccount, -tx.Amount})
+	}
+	if f := fee(tx); f != 0 {
+		out = append(out, posting{opts.FeeAccount, f})
+	}
+	return out
+}
+
+// opening is the wallet balance before the first transaction. A non-zero
+// opening balance (an export that starts mid-history) is booked against
+// OpeningAccount so that the balance assertions hold.
+func opening(txs []lnbot.Transaction) int64 {
+	if len(txs) == 0 {
+		return 0
+	}
+	return txs[0].BalanceAfter - delta(txs[0])
+}
+
+func commodity(u Unit) string {
+	if u == BTC {
+		return "BTC"
+	}
+	return "SATS"
+}
+
+func accounts(opts Options) []string {
+	return []string{opts.Account, opts.IncomeAccount, opts.ExpenseAccount, opts.FeeAccount}
+}
+
+var beancountRoots = map[string]bool{
+	"Assets": true, "Liabilities": true, "Equity": true, "Income": true, "Expenses": true,
+}
+
+// checkBeancountAccount reports account names Beancount would reject.
+func checkBeancountAccount(name string) error {
+	parts := strings.Split(name, ":")
+	if len(parts) < 2 || !beancountRoots[parts[0]] {
+		return fmt.Errorf("invalid Beancount account %q: must start with Assets, Liabilities, Equity, Income or Expenses", name)
+	}
+	for _, p := range parts[1:] {
+		r := []rune(p)
+		if len(r) == 0 || !(unicode.IsUpper(r[0]) || unicode.IsDigit(r[0])) {
+			return fmt.Errorf("invalid Beancount account %q: each component must start with a capital letter or digit", name)
+		}
+		for _, c := range r {
+			if !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '-') {
+				return fmt.Errorf("invalid Beancount account %q: unexpected character %q", name, c)
+			}
+		}
+	}
+	return nil
+}
+
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", " ") + `"`
+}
+
+// writeBeancount writes a Beancount journal. A balance assertion follows
+// the last transaction of each day; Beancount checks balances at the start
+// of a day, so it is dated the day after.
+func writeBeancount(w io.Writer, txs []lnbot.Transaction, opts Options) error {
+	names := accounts(opts)
+	if open := opening(txs); open != 0 {
+		names = append(names, OpeningAccount)
+	}
+	for _, name := range names {
+		if err := checkBeancountAccount(name); err != nil {
+			return err
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	unit := commodity(opts.Unit)
+	amount := func(sats int64) string { return Amount(sats, opts.Unit) + " " + unit }
+	day := func(tx lnbot.Transaction) string { return postedAt(tx).Format("2006-01-02") }
+
+	if opts.WalletID != "" {
+		fmt.Fprintf(bw, "; ln.bot wallet %s\n\n", opts.WalletID)
+	}
+	if len(txs) == 0 {
+		return bw.Flush()
+	}
+
+	first := day(txs[0])
+	for _, name := range names {
+		fmt.Fprintf(bw, "%s open %s %s\n", first, name, unit)
+	}
+	fmt.Fprintln(bw)
+
+	if open := opening(txs); open != 0 {
+		fmt.Fprintf(bw, "%s * %s\n", first, quote("Opening balance"))
+		fmt.Fprintf(bw, "  %-40s %20s\n", opts.Account, amount(open))
+		fmt.Fprintf(bw, "  %-40s %20s\n\n", OpeningAccount, amount(-open))
+	}
+
+	for i, tx := range txs {
+		fmt.Fprintf(bw, "%s * %s\n", day(tx), quote(description(tx)))
+		fmt.Fprintf(bw, "  number: %d\n", tx.Number)
+		if tx.PaymentHash != nil {
+			fmt.Fprintf(bw, "  payment_hash: %s\n", quote(*tx.PaymentHash))
+		}
+		if tx.Reference != nil {
+			fmt.Fprintf(bw, "  reference: %s\n", quote(*tx.Reference))
+		}
+		for _, p := range postings(tx, opts) {
+			fmt.Fprintf(bw, "  %-40s %20s\n", p.account, amount(p.amount))
+		}
+		fmt.Fprintln(bw)
+
+		if i == len(txs)-1 || day(txs[i+1]) != day(tx) {
+			next := postedAt(tx).AddDate(0, 0, 1).Format("2006-01-02")
+			fmt.Fprintf(bw, "%s balance %-40s %20s\n\n", next, opts.Account, amount(tx.BalanceAfter))
+		}
+	}
+	return bw.Flush()
+}
+
+// writeLedger writes a Ledger journal. The wallet posting of every
+// transaction carries a balance assertion.
+func writeLedger(w io.Writer, txs []lnbot.Transaction, opts Options) error {
+	bw := bufio.NewWriter(w)
+	unit := "sats"
+	if opts.Unit == BTC {
+		unit = "BTC"
+	}
+	amount := func(sats int64) string { return Amount(sats, opts.Unit) + " " + unit }
+	day := func(t time.Time) string { return t.Format("2006/01/02") }
+
+	if opts.WalletID != "" {
+		fmt.Fprintf(bw, "; ln.bot wallet %s\n\n", opts.WalletID)
+	}
+	if len(txs) == 0 {
+		return bw.Flush()
+	}
+
+	if open := opening(txs); open != 0 {
+		fmt.Fprintf(bw, "%s * Opening balance\n", day(postedAt(txs[0])))
+		fmt.Fprintf(bw, "    %-40s %20s\n", opts.Account, amount(open))
+		fmt.Fprintf(bw, "    %s\n\n", OpeningAccount)
+	}
+
+	for _, tx := range txs {
+		payee := strings.ReplaceAll(description(tx), "\n", " ")
+		fmt.Fprintf(bw, "%s * (%d) %s\n", day(postedAt(tx)), tx.Number, payee)
+		if tx.PaymentHash != nil {
+			fmt.Fprintf(bw, "    ; payment_hash: %s\n", *tx.PaymentHash)
+		}
+		if tx.Reference != nil {
+			fmt.Fprintf(bw, "    ; reference: %s\n", *tx.Reference)
+		}
+		for i, p := range postings(tx, opts) {
+			if i == 0 {
+				fmt.Fprintf(bw, "    %-40s %20s = %s\n", p.account, amount(p.amount), amount(tx.BalanceAfter))
+				continue
+			}
+			fmt.Fprintf(bw, "    %-40s %20s\n", p.account, amount(p.amount))
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrite_Beancount(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Beancount, fixture(), Options{WalletID: "wal_abc"}); err != nil {
+		t.Fatal(err)
+	}
+	out := squash(buf.String())
+
+	for _, want := range []string{
+		"2024-03-01 open Assets:Lightning SATS",
+		"2024-03-01 open Expenses:Fees:Lightning SATS",
+		`2024-03-01 * "carol@example.com"`,
+		`payment_hash: "abc123"`,
+		"Assets:Lightning -1003 SATS",
+		"Expenses:Lightning 1000 SATS",
+		"Expenses:Fees:Lightning 3 SATS",
+		`2024-03-02 * "tip \"thanks\""`,
+		"2024-03-03 balance Assets:Lightning 49247 SATS",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	// One assertion per day, after that day's last transaction.
+	if n := strings.Count(out, " balance "); n != 2 {
+		t.Errorf("%d balance assertions, want 2", n)
+	}
+	if !strings.Contains(out, "2024-03-02 balance Assets:Lightning 48997 SATS") {
+		t.Errorf("missing end-of-day assertion for 2024-03-01:\n%s", out)
+	}
+	if strings.Contains(out, "Opening") {
+		t.Errorf("full history should not need an opening balance:\n%s", out)
+	}
+}
+
+func TestWrite_BeancountOpeningBalance(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, Beancount, fixture()[1:], Options{})
+	out := squash(buf.String())
+	if !strings.Contains(out, "2024-03-01 open Equity:Opening-Balances SATS") ||
+		!strings.Contains(out, "Assets:Lightning 50000 SATS") {
+		t.Errorf("partial history should book an opening balance:\n%s", out)
+	}
+}
+
+func TestWrite_BeancountAccounts(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, Beancount, fixture(), Options{Account: "Assets:Lightning:Agent01", FeeAccount: "Expenses:Fees"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Assets:Lightning:Agent01") {
+		t.Errorf("custom account not used:\n%s", buf.String())
+	}
+
+	for _, bad := range []string{"Lightning", "Assets:lightning", "Cash:Lightning", "Assets:Light ning"} {
+		if err := Write(&buf, Beancount, fixture(), Options{Account: bad}); err == nil {
+			t.Errorf("account %q should be rejected", bad)
+		}
+	}
+}
+
+func TestWrite_Ledger(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Ledger, fixture(), Options{Unit: BTC}); err != nil {
+		t.Fatal(err)
+	}
+	out := squash(buf.String())
+
+	for _, want := range []string{
+		"2024/03/01 * (2) carol@example.com",
+		"; payment_hash: abc123",
+		"; reference: order-7",
+		"Assets:Lightning -0.00001003 BTC = 0.00048997 BTC",
+		"Expenses:Lightning 0.00001000 BTC",
+		"Income:Lightning -0.00000250 BTC",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	Write(&buf, Ledger, fixture()[2:], Options{})
+	if out := squash(buf.String()); !strings.Contains(out, "2024/03/02 * Opening balance") || !strings.Contains(out, "Equity:Opening-Balances") {
+		t.Errorf("partial history should book an opening balance:\n%s", buf.String())
+	}
+}
+
+// squash collapses runs of spaces so tests don't depend on column widths.
+func squash(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.Join(strings.Fields(l), " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+// ofxCurrency is the CURDEF of a statement. OFX wants an ISO 4217 code;
+// XBT is the customary code for bitcoin. There is no code for sats, so
+// sats statements use SAT and may need the currency mapped on import.
+func ofxCurrency(u Unit) string {
+	if u == BTC {
+		return "XBT"
+	}
+	return "SAT"
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+func ofxText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeOFX writes an OFX 2.2 bank statement. Each transaction's amount is
+// its net effect on the balance, fees included.
+func writeOFX(w io.Writer, txs []lnbot.Transaction, opts Options) error {
+	bw := bufio.NewWriter(w)
+
+	from, to := opts.From, opts.To
+	if len(txs) > 0 {
+		if from.IsZero() {
+			from = postedAt(txs[0])
+		}
+		if to.IsZero() {
+			to = postedAt(txs[len(txs)-1])
+		}
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to
+	}
+
+	var balance int64
+	asOf := to
+	if len(txs) > 0 {
+		last := txs[len(txs)-1]
+		balance = last.BalanceAfter
+		asOf = postedAt(last)
+	}
+
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`)
+	fmt.Fprintln(bw, `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`)
+	fmt.Fprintln(bw, "<OFX>")
+	fmt.Fprintln(bw, "  <SIGNONMSGSRSV1><SONRS>")
+	fmt.Fprintln(bw, "    <STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>")
+	fmt.Fprintf(bw, "    <DTSERVER>%s</DTSERVER>\n", ofxTime(time.Now()))
+	fmt.Fprintln(bw, "    <LANGUAGE>ENG</LANGUAGE>")
+	fmt.Fprintln(bw, "  </SONRS></SIGNONMSGSRSV1>")
+	fmt.Fprintln(bw, "  <BANKMSGSRSV1><STMTTRNRS>")
+	fmt.Fprintln(bw, "    <TRNUID>0</TRNUID>")
+	fmt.Fprintln(bw, "    <STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>")
+	fmt.Fprintln(bw, "    <STMTRS>")
+	fmt.Fprintf(bw, "      <CURDEF>%s</CURDEF>\n", ofxCurrency(opts.Unit))
+	fmt.Fprintln(bw, "      <BANKACCTFROM>")
+	fmt.Fprintln(bw, "        <BANKID>ln.bot</BANKID>")
+	fmt.Fprintf(bw, "        <ACCTID>%s</ACCTID>\n", ofxText(opts.WalletID))
+	fmt.Fprintln(bw, "        <ACCTTYPE>CHECKING</ACCTTYPE>")
+	fmt.Fprintln(bw, "      </BANKACCTFROM>")
+	fmt.Fprintln(bw, "      <BANKTRANLIST>")
+	fmt.Fprintf(bw, "        <DTSTART>%s</DTSTART>\n", ofxTime(from))
+	fmt.Fprintf(bw, "        <DTEND>%s</DTEND>\n", ofxTime(to))
+	for _, tx := range txs {
+		typ := "CREDIT"
+		if tx.Type == "debit" {
+			typ = "DEBIT"
+		}
+		fmt.Fprintln(bw, "        <STMTTRN>")
+		fmt.Fprintf(bw, "          <TRNTYPE>%s</TRNTYPE>\n", typ)
+		fmt.Fprintf(bw, "          <DTPOSTED>%s</DTPOSTED>\n", ofxTime(postedAt(tx)))
+		fmt.Fprintf(bw, "          <TRNAMT>%s</TRNAMT>\n", Amount(delta(tx), opts.Unit))
+		fmt.Fprintf(bw, "          <FITID>%s</FITID>\n", ofxText(fitID(tx, opts.WalletID)))
+		fmt.Fprintf(bw, "          <NAME>%s</NAME>\n", ofxText(truncate(description(tx), 32)))
+		if memo := ofxMemo(tx, opts.Unit); memo != "" {
+			fmt.Fprintf(bw, "          <MEMO>%s</MEMO>\n", ofxText(memo))
+		}
+		fmt.Fprintln(bw, "        </STMTTRN>")
+	}
+	fmt.Fprintln(bw, "      </BANKTRANLIST>")
+	fmt.Fprintln(bw, "      <LEDGERBAL>")
+	fmt.Fprintf(bw, "        <BALAMT>%s</BALAMT>\n", Amount(balance, opts.Unit))
+	fmt.Fprintf(bw, "        <DTASOF>%s</DTASOF>\n", ofxTime(asOf))
+	fmt.Fprintln(bw, "      </LEDGERBAL>")
+	fmt.Fprintln(bw, "    </STMTRS>")
+	fmt.Fprintln(bw, "  </STMTTRNRS></BANKMSGSRSV1>")
+	fmt.Fprintln(bw, "</OFX>")
+	return bw.Flush()
+}
+
+// fitID is the transaction's unique ID within the account. Importers use
+// it to skip transactions they have already seen.
+func fitID(tx lnbot.Transaction, walletID string) string {
+	id := strconv.Itoa(tx.Number)
+	if walletID != "" {
+		id = walletID + "-" + id
+	}
+	return id
+}
+
+func ofxMemo(tx lnbot.Transaction, unit Unit) string {
+	var parts []string
+	if f := fee(tx); f != 0 {
+		parts = append(parts, "fee "+Amount(f, unit)+" "+string(unit))
+	}
+	if tx.Reference != nil && *tx.Reference != "" {
+		parts = append(parts, "ref "+*tx.Reference)
+	}
+	if tx.PaymentHash != nil && *tx.PaymentHash != "" {
+		parts = append(parts, "hash "+*tx.PaymentHash)
+	}
+	return truncate(strings.Join(parts, "; "), 255)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWrite_OFX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, OFX, fixture(), Options{WalletID: "wal_abc", Unit: BTC}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`<?OFX OFXHEADER="200" VERSION="220"`,
+		"<CURDEF>XBT</CURDEF>",
+		"<ACCTID>wal_abc</ACCTID>",
+		"<DTSTART>20240301090000[0:GMT]</DTSTART>",
+		"<DTEND>20240302080000[0:GMT]</DTEND>",
+		"<TRNAMT>-0.00001003</TRNAMT>",
+		"<FITID>wal_abc-2</FITID>",
+		"<MEMO>fee 0.00000003 btc; ref order-7; hash abc123</MEMO>",
+		"<NAME>tip &#34;thanks&#34;</NAME>",
+		"<BALAMT>0.00049247</BALAMT>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	// OFX 2.x is XML; the document must be well formed.
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err.Error() != "EOF" {
+				t.Errorf("not well-formed XML: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestWrite_OFXEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, OFX, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<BALAMT>0</BALAMT>") || strings.Contains(buf.String(), "<STMTTRN>") {
+		t.Errorf("output = %s", buf.String())
+	}
+}
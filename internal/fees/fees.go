@@ -0,0 +1,99 @@
+// Package fees computes routing fee limits for outgoing payments.
+//
+// A fixed limit in sats is too loose for a 100-sat payment and too tight
+// for a million-sat one, so a limit can instead be a share of the amount —
+// a percentage or parts per million — kept between a floor and a ceiling.
+package fees
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Limit is a routing fee limit. At most one of Sats, Percent and PPM is
+// set; Floor and Ceiling bound the proportional limits (0 means none).
+type Limit struct {
+	Sats    int64   `json:"sats,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+	PPM     int64   `json:"ppm,omitempty"`
+	Floor   int64   `json:"floor,omitempty"`
+	Ceiling int64   `json:"ceiling,omitempty"`
+}
+
+// IsZero reports whether no limit is set.
+func (l Limit) IsZero() bool {
+	return l.Sats == 0 && l.Percent == 0 && l.PPM == 0
+}
+
+// Validate checks that the limit is well formed.
+func (l Limit) Validate() error {
+	set := 0
+	for _, on := range []bool{l.Sats != 0, l.Percent != 0, l.PPM != 0} {
+		if on {
+			set++
+		}
+	}
+	switch {
+	case set > 1:
+		return fmt.Errorf("use only one of a fee limit in sats, percent or ppm")
+	case l.Sats < 0 || l.Percent < 0 || l.PPM < 0 || l.Floor < 0 || l.Ceiling < 0:
+		return fmt.Errorf("fee limits must not be negative")
+	case l.Percent > 100:
+		return fmt.Errorf("fee limit of %g%% is more than the payment itself", l.Percent)
+	case l.PPM > 1_000_000:
+		return fmt.Errorf("fee limit of %d ppm is more than the payment itself", l.PPM)
+	case l.Ceiling > 0 && l.Floor > l.Ceiling:
+		return fmt.Errorf("fee floor (%d sats) is above the ceiling (%d sats)", l.Floor, l.Ceiling)
+	}
+	return nil
+}
+
+// For returns the fee limit in sats for a payment of amount sats, and false
+// if no limit is set. Proportional limits round up, so that a small payment
+// is never limited to zero unless the floor says so.
+func (l Limit) For(amount int64) (int64, bool) {
+	var fee int64
+	switch {
+	case l.Sats > 0:
+		return l.Sats, true
+	case l.Percent > 0:
+		fee = int64(math.Ceil(float64(amount) * l.Percent / 100))
+	case l.PPM > 0:
+		fee = int64(math.Ceil(float64(amount) * float64(l.PPM) / 1e6))
+	default:
+		return 0, false
+	}
+	fee = max(fee, l.Floor)
+	if l.Ceiling > 0 {
+		fee = min(fee, l.Ceiling)
+	}
+	return fee, true
+}
+
+// String describes the limit, e.g. "0.5%, min 1, max 500 sats".
+func (l Limit) String() string {
+	var s string
+	switch {
+	case l.Sats > 0:
+		return fmt.Sprintf("%d sats", l.Sats)
+	case l.Percent > 0:
+		s = strconv.FormatFloat(l.Percent, 'f', -1, 64) + "%"
+	case l.PPM > 0:
+		s = fmt.Sprintf("%d ppm", l.PPM)
+	default:
+		return "none"
+	}
+	var bounds []string
+	if l.Floor > 0 {
+		bounds = append(bounds, fmt.Sprintf("min %d", l.Floor))
+	}
+	if l.Ceiling > 0 {
+		bounds = append(bounds, fmt.Sprintf("max %d", l.Ceiling))
+	}
+	if len(bounds) > 0 {
+		s += ", " + strings.Join(bounds, ", ") + " sats"
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package fees
+
+import "testing"
+
+func TestLimit_For(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  Limit
+		amount int64
+		want   int64
+		ok     bool
+	}{
+		{"none", Limit{}, 1000, 0, false},
+		{"sats", Limit{Sats: 20}, 1_000_000, 20, true},
+		{"percent", Limit{Percent: 0.5}, 100_000, 500, true},
+		{"percent rounds up", Limit{Percent: 0.5}, 150, 1, true},
+		{"ppm", Limit{PPM: 2000}, 50_000, 100, true},
+		{"floor", Limit{Percent: 1, Floor: 10}, 100, 10, true},
+		{"ceiling", Limit{Percent: 1, Ceiling: 500}, 1_000_000, 500, true},
+		{"sats ignores bounds", Limit{Sats: 5, Floor: 10}, 100, 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.limit.For(tt.amount)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("For(%d) = %d, %v; want %d, %v", tt.amount, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLimit_Validate(t *testing.T) {
+	valid := []Limit{{}, {Sats: 10}, {Percent: 0.5, Floor: 1, Ceiling: 100}, {PPM: 5000}}
+	for _, l := range valid {
+		if err := l.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", l, err)
+		}
+	}
+	invalid := []Limit{
+		{Sats: 10, Percent: 1},
+		{Percent: 1, PPM: 100},
+		{Percent: -1},
+		{Percent: 101},
+		{PPM: 2_000_000},
+		{Percent: 1, Floor: 100, Ceiling: 10},
+	}
+	for _, l := range invalid {
+		if err := l.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", l)
+		}
+	}
+}
+
+func TestLimit_String(t *testing.T) {
+	tests := map[string]Limit{
+		"none":                      {},
+		"20 sats":                   {Sats: 20},
+		"0.5%":                      {Percent: 0.5},
+		"0.5%, min 1, max 500 sats": {Percent: 0.5, Floor: 1, Ceiling: 500},
+		"2000 ppm, max 50 sats":     {PPM: 2000, Ceiling: 50},
+	}
+	for want, l := range tests {
+		if got := l.String(); got != want {
+			t.Errorf("String(%+v) = %q, want %q", l, got, want)
+		}
+	}
+}
@@ -73,6 +73,24 @@ func TimeAgo(t *time.Time) string {
 	}
 }
 
+// TimeUntil formats a future time relative to now ("in 45m"). Past times
+// are formatted like TimeAgo.
+func TimeUntil(t time.Time) string {
+	d := time.Until(t)
+	switch {
+	case d <= 0:
+		return TimeAgo(&t)
+	case d < time.Minute:
+		return fmt.Sprintf("in %ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("in %dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("in %dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("in %dd", int(d.Hours()/24))
+	}
+}
+
 func Truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
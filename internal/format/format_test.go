@@ -102,6 +102,29 @@ func TestTimeAgo(t *testing.T) {
 	}
 }
 
+func TestTimeUntil(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{"seconds", now.Add(30*time.Second + 500*time.Millisecond), "in 30s"},
+		{"minutes", now.Add(45*time.Minute + time.Second), "in 45m"},
+		{"hours", now.Add(5*time.Hour + time.Second), "in 5h"},
+		{"days", now.Add(72*time.Hour + time.Second), "in 3d"},
+		{"past", now.Add(-5 * time.Minute), "5m ago"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TimeUntil(tt.t); got != tt.want {
+				t.Errorf("TimeUntil() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	tests := []struct {
 		name string
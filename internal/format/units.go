@@ -0,0 +1,111 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Unit is a unit amounts can be displayed in.
+type Unit string
+
+const (
+	UnitSats Unit = "sats"
+	UnitBTC  Unit = "btc"
+	UnitMsat Unit = "msat"
+	UnitBits Unit = "bits"
+)
+
+// Units lists the display units.
+var Units = []Unit{UnitSats, UnitBTC, UnitMsat, UnitBits}
+
+// ParseUnit reads a display unit name. An empty name is sats.
+func ParseUnit(s string) (Unit, error) {
+	switch u := Unit(strings.ToLower(strings.TrimSpace(s))); u {
+	case "", "sat":
+		return UnitSats, nil
+	case UnitSats, UnitBTC, UnitMsat, UnitBits:
+		return u, nil
+	case "bit":
+		return UnitBits, nil
+	}
+	return "", fmt.Errorf("unknown unit %q: use sats, btc, msat or bits", s)
+}
+
+// Display is how Amount shows amounts: a unit, and optionally the value in
+// a fiat currency at Price per BTC.
+type Display struct {
+	Unit  Unit
+	Fiat  string
+	Price float64
+}
+
+var display = Display{Unit: UnitSats}
+
+// SetDisplay changes how Amount, AmountPlain and FiatValue show amounts.
+func SetDisplay(d Display) {
+	if d.Unit == "" {
+		d.Unit = UnitSats
+	}
+	if d.Price <= 0 {
+		d.Fiat = ""
+	}
+	display = d
+}
+
+// Amount formats sats in the display unit, followed by the fiat value if
+// one is set: "0.00012345 BTC (8.02 USD)". Unlike Sats it follows the
+// --unit and --fiat settings, so it is meant for reports, not prompts.
+func Amount(sats int64) string {
+	s := AmountPlain(sats) + " " + UnitLabel()
+	if fiat := FiatValue(sats); fiat != "" {
+		s += " (" + fiat + ")"
+	}
+	return s
+}
+
+// AmountPlain is sats in the display unit, without the unit, for tables.
+func AmountPlain(sats int64) string {
+	switch display.Unit {
+	case UnitBTC:
+		return decimal(sats, 8)
+	case UnitMsat:
+		return commafy(sats * 1000)
+	case UnitBits:
+		return decimal(sats, 2)
+	}
+	return commafy(sats)
+}
+
+// UnitLabel names the display unit: "sats", "BTC", "msat" or "bits".
+func UnitLabel() string {
+	if display.Unit == UnitBTC {
+		return "BTC"
+	}
+	return string(display.Unit)
+}
+
+// FiatValue is sats in the fiat display currency, e.g. "8.02 USD", or ""
+// if none is set.
+func FiatValue(sats int64) string {
+	if display.Fiat == "" {
+		return ""
+	}
+	cents := int64(math.Round(float64(sats) * display.Price / 1e6))
+	return decimal(cents, 2) + " " + strings.ToUpper(display.Fiat)
+}
+
+// decimal formats n shifted right by places digits, with thousands
+// separators: decimal(123456789, 8) is "1.23456789".
+func decimal(n int64, places int) string {
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+	scale := int64(math.Pow10(places))
+	s := fmt.Sprintf("%s.%0*d", commafy(n/scale), places, n%scale)
+	if neg {
+		return "-" + s
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package format
+
+import "testing"
+
+func TestParseUnit(t *testing.T) {
+	for in, want := range map[string]Unit{"": UnitSats, "sat": UnitSats, "BTC": UnitBTC, "msat": UnitMsat, "bit": UnitBits} {
+		if got, err := ParseUnit(in); err != nil || got != want {
+			t.Errorf("ParseUnit(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseUnit("eth"); err == nil {
+		t.Error("ParseUnit(eth) should fail")
+	}
+}
+
+func TestAmount(t *testing.T) {
+	t.Cleanup(func() { SetDisplay(Display{}) })
+
+	tests := []struct {
+		display Display
+		sats    int64
+		want    string
+	}{
+		{Display{}, 1234, "1,234 sats"},
+		{Display{Unit: UnitBTC}, 12345, "0.00012345 BTC"},
+		{Display{Unit: UnitBTC}, 123456789012, "1,234.56789012 BTC"},
+		{Display{Unit: UnitBTC}, -5, "-0.00000005 BTC"},
+		{Display{Unit: UnitMsat}, 21, "21,000 msat"},
+		{Display{Unit: UnitBits}, 1234, "12.34 bits"},
+		{Display{Unit: UnitBTC, Fiat: "usd", Price: 65000}, 12345, "0.00012345 BTC (8.02 USD)"},
+		{Display{Fiat: "EUR", Price: 60000}, 100_000_000, "100,000,000 sats (60,000.00 EUR)"},
+		{Display{Fiat: "EUR"}, 1000, "1,000 sats"},
+	}
+	for _, tt := range tests {
+		SetDisplay(tt.display)
+		if got := Amount(tt.sats); got != tt.want {
+			t.Errorf("%+v: Amount(%d) = %q, want %q", tt.display, tt.sats, got, tt.want)
+		}
+	}
+}
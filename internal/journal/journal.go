@@ -0,0 +1,111 @@
+// Package journal records which payment each idempotency key produced, so
+// that a payment command that is run again — after a crash, a timeout or a
+// killed process — reports the original payment instead of paying twice.
+//
+// The journal complements the API's own idempotency keys: it answers
+// without a network round trip and catches key reuse for a different
+// payment.
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MaxAge is how long entries are kept.
+const MaxAge = 90 * 24 * time.Hour
+
+// Entry is the payment a key produced. Attempt counts new payments made
+// under the same key after earlier ones failed.
+type Entry struct {
+	Number    int       `json:"number"`
+	Target    string    `json:"target"`
+	Amount    int64     `json:"amount,omitempty"`
+	Attempt   int       `json:"attempt,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Journal maps wallet ID and idempotency key to a payment.
+type Journal struct {
+	Entries map[string]Entry `json:"entries"`
+
+	path string
+}
+
+// Load reads the journal at path. A missing file is an empty journal.
+func Load(path string) (*Journal, error) {
+	j := &Journal{Entries: map[string]Entry{}, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("invalid payment journal %s: %w", path, err)
+	}
+	if j.Entries == nil {
+		j.Entries = map[string]Entry{}
+	}
+	return j, nil
+}
+
+// Lookup returns the payment recorded for key in a wallet.
+func (j *Journal) Lookup(walletID, key string) (Entry, bool) {
+	e, ok := j.Entries[walletID+"/"+key]
+	return e, ok
+}
+
+// Record stores the payment a key produced, drops expired entries, and
+// saves the journal.
+func (j *Journal) Record(walletID, key string, e Entry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	j.Entries[walletID+"/"+key] = e
+
+	cutoff := time.Now().Add(-MaxAge)
+	for k, old := range j.Entries {
+		if old.CreatedAt.Before(cutoff) {
+			delete(j.Entries, k)
+		}
+	}
+	return j.save()
+}
+
+// Matches reports whether e was made for the same target and amount. An
+// amount of zero (taken from an invoice) matches any amount.
+func (e Entry) Matches(target string, amount int64) bool {
+	return e.Target == target && (amount == 0 || e.Amount == 0 || e.Amount == amount)
+}
+
+func (j *Journal) save() error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), ".journal-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
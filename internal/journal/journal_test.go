@@ -0,0 +1,78 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lnbot", "journal.json")
+	j, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := j.Lookup("wal_a", "k1"); ok {
+		t.Error("empty journal should have no entries")
+	}
+
+	if err := j.Record("wal_a", "k1", Entry{Number: 3, Target: "alice@ln.bot", Amount: 100}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("journal file: %v, %v", info, err)
+	}
+
+	j, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := j.Lookup("wal_a", "k1")
+	if !ok || e.Number != 3 || e.CreatedAt.IsZero() {
+		t.Errorf("Lookup = %+v, %v", e, ok)
+	}
+	if _, ok := j.Lookup("wal_b", "k1"); ok {
+		t.Error("keys are per wallet")
+	}
+}
+
+func TestJournal_PrunesOldEntries(t *testing.T) {
+	j, _ := Load(filepath.Join(t.TempDir(), "journal.json"))
+	j.Record("wal_a", "old", Entry{Number: 1, CreatedAt: time.Now().Add(-MaxAge - time.Hour)})
+	j.Record("wal_a", "new", Entry{Number: 2})
+	if _, ok := j.Lookup("wal_a", "old"); ok {
+		t.Error("old entry should be pruned")
+	}
+	if _, ok := j.Lookup("wal_a", "new"); !ok {
+		t.Error("new entry should be kept")
+	}
+}
+
+func TestJournal_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	os.WriteFile(path, []byte("not json"), 0o600)
+	if _, err := Load(path); err == nil {
+		t.Error("invalid journal should fail to load")
+	}
+}
+
+func TestEntry_Matches(t *testing.T) {
+	e := Entry{Target: "alice@ln.bot", Amount: 100}
+	tests := []struct {
+		target string
+		amount int64
+		want   bool
+	}{
+		{"alice@ln.bot", 100, true},
+		{"alice@ln.bot", 0, true},
+		{"alice@ln.bot", 200, false},
+		{"bob@ln.bot", 100, false},
+	}
+	for _, tt := range tests {
+		if got := e.Matches(tt.target, tt.amount); got != tt.want {
+			t.Errorf("Matches(%q, %d) = %v, want %v", tt.target, tt.amount, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package jsontree
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecode_KeepsKeyOrder(t *testing.T) {
+	const doc = `{"z":1,"a":{"y":[true,null],"b":"x"},"n":1.50}`
+	tree, err := Decode([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := tree.(Object)
+	if o[0].Key != "z" || o[1].Key != "a" || o[2].Value != json.Number("1.50") {
+		t.Errorf("tree = %#v", tree)
+	}
+	if v, ok := o[1].Value.(Object).Get("b"); !ok || v != "x" {
+		t.Errorf(`Get("b") = %v, %v`, v, ok)
+	}
+
+	b, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != doc {
+		t.Errorf("round trip = %s", b)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, doc := range []string{``, `{"a":}`, `[1] 2`} {
+		if _, err := Decode([]byte(doc)); err == nil {
+			t.Errorf("Decode(%q) should fail", doc)
+		}
+	}
+}
+
+func TestFrom(t *testing.T) {
+	tree, err := From(struct {
+		B int    `json:"b"`
+		A string `json:"a"`
+	}{2, "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := tree.(Object); len(o) != 2 || o[0].Key != "b" || o[0].Value != json.Number("2") {
+		t.Errorf("tree = %#v", tree)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tree, _ := Decode([]byte(`[{"a":{"b":[{"c":1}]}}]`))
+	m, ok := Plain(tree).([]any)[0].(map[string]any)
+	if !ok {
+		t.Fatalf("Plain = %#v", Plain(tree))
+	}
+	inner := m["a"].(map[string]any)["b"].([]any)[0].(map[string]any)
+	if inner["c"] != json.Number("1") {
+		t.Errorf("inner = %#v", inner)
+	}
+}
@@ -0,0 +1,195 @@
+// Package mcp implements a minimal Model Context Protocol server that speaks
+// newline-delimited JSON-RPC 2.0 over a reader/writer pair (stdio transport).
+//
+// Only the tools capability is supported: initialize, ping, tools/list and
+// tools/call. Notifications are accepted and ignored.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ProtocolVersion is the MCP revision this server implements.
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+)
+
+// Handler runs a tool call. args is the raw "arguments" object from the
+// request (never nil — an empty object is passed when omitted). The returned
+// string is sent to the client as a single text content block. A non-nil
+// error is reported to the client as a tool error, not a protocol error.
+type Handler func(ctx context.Context, args json.RawMessage) (string, error)
+
+// Tool describes a callable tool.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+	Handler     Handler        `json:"-"`
+}
+
+// Server dispatches JSON-RPC requests to registered tools.
+type Server struct {
+	name    string
+	version string
+	tools   []*Tool
+	byName  map[string]*Tool
+	out     *json.Encoder
+}
+
+// NewServer returns a server that reports the given name and version in
+// its initialize response.
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version, byName: map[string]*Tool{}}
+}
+
+// AddTool registers a tool. Tools are listed in registration order.
+func (s *Server) AddTool(t *Tool) {
+	if t.InputSchema == nil {
+		t.InputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	s.tools = append(s.tools, t)
+	s.byName[t.Name] = t
+}
+
+// Tools returns the registered tools.
+func (s *Server) Tools() []*Tool {
+	return s.tools
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads one JSON-RPC message per line from r and writes responses to
+// w until r is exhausted or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.out = json.NewEncoder(w)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		s.handle(ctx, line)
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handle(ctx context.Context, line []byte) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.reply(json.RawMessage("null"), nil, &rpcError{CodeParseError, "parse error"})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.reply(idOrNull(req.ID), nil, &rpcError{CodeInvalidRequest, "invalid request"})
+		return
+	}
+
+	// Notifications carry no id and never get a response.
+	if len(req.ID) == 0 {
+		return
+	}
+
+	result, rerr := s.dispatch(ctx, &req)
+	s.reply(req.ID, result, rerr)
+}
+
+func (s *Server) dispatch(ctx context.Context, req *request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities": map[string]any{
+				"tools": map[string]any{},
+			},
+			"serverInfo": map[string]string{
+				"name":    s.name,
+				"version": s.version,
+			},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.tools}, nil
+	case "tools/call":
+		return s.callTool(ctx, req.Params)
+	default:
+		return nil, &rpcError{CodeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method)}
+	}
+}
+
+func (s *Server) callTool(ctx context.Context, raw json.RawMessage) (any, *rpcError) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if len(raw) == 0 || json.Unmarshal(raw, &params) != nil || params.Name == "" {
+		return nil, &rpcError{CodeInvalidParams, "tools/call requires a tool name"}
+	}
+
+	tool, ok := s.byName[params.Name]
+	if !ok {
+		return nil, &rpcError{CodeInvalidParams, fmt.Sprintf("unknown tool: %s", params.Name)}
+	}
+
+	args := params.Arguments
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+
+	text, err := tool.Handler(ctx, args)
+	if err != nil {
+		return toolResult(err.Error(), true), nil
+	}
+	return toolResult(text, false), nil
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rerr *rpcError) {
+	resp := map[string]any{"jsonrpc": "2.0", "id": id}
+	if rerr != nil {
+		resp["error"] = rerr
+	} else {
+		resp["result"] = result
+	}
+	s.out.Encode(resp)
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func serve(t *testing.T, s *Server, input string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	var resps []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var m map[string]any
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		resps = append(resps, m)
+	}
+	return resps
+}
+
+func testServer() *Server {
+	s := NewServer("test", "0.0.1")
+	s.AddTool(&Tool{
+		Name:        "echo",
+		Description: "Echo the message argument",
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			var in struct {
+				Message string `json:"message"`
+			}
+			json.Unmarshal(args, &in)
+			return in.Message, nil
+		},
+	})
+	s.AddTool(&Tool{
+		Name: "fail",
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			return "", errors.New("boom")
+		},
+	})
+	return s
+}
+
+func TestServe_Initialize(t *testing.T) {
+	resps := serve(t, testServer(), `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`+"\n")
+	if len(resps) != 1 {
+		t.Fatalf("got %d responses, want 1", len(resps))
+	}
+	result := resps[0]["result"].(map[string]any)
+	if result["protocolVersion"] != ProtocolVersion {
+		t.Errorf("protocolVersion = %v, want %s", result["protocolVersion"], ProtocolVersion)
+	}
+	info := result["serverInfo"].(map[string]any)
+	if info["name"] != "test" || info["version"] != "0.0.1" {
+		t.Errorf("serverInfo = %v", info)
+	}
+	if resps[0]["id"] != float64(1) {
+		t.Errorf("id = %v, want 1", resps[0]["id"])
+	}
+}
+
+func TestServe_NotificationNoResponse(t *testing.T) {
+	resps := serve(t, testServer(), `{"jsonrpc":"2.0","method":"notifications/initialized"}`+"\n")
+	if len(resps) != 0 {
+		t.Errorf("got %d responses for a notification, want 0", len(resps))
+	}
+}
+
+func TestServe_Ping(t *testing.T) {
+	resps := serve(t, testServer(), `{"jsonrpc":"2.0","id":"a","method":"ping"}`+"\n")
+	if _, ok := resps[0]["result"]; !ok {
+		t.Errorf("ping response missing result: %v", resps[0])
+	}
+	if resps[0]["id"] != "a" {
+		t.Errorf("id = %v, want \"a\"", resps[0]["id"])
+	}
+}
+
+func TestServe_ToolsList(t *testing.T) {
+	resps := serve(t, testServer(), `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`+"\n")
+	tools := resps[0]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 2 {
+		t.Fatalf("got %d tools, want 2", len(tools))
+	}
+	first := tools[0].(map[string]any)
+	if first["name"] != "echo" {
+		t.Errorf("first tool = %v, want echo", first["name"])
+	}
+	if _, ok := first["inputSchema"].(map[string]any); !ok {
+		t.Error("tool should have a default inputSchema")
+	}
+}
+
+func TestServe_ToolsCall(t *testing.T) {
+	resps := serve(t, testServer(), `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`+"\n")
+	result := resps[0]["result"].(map[string]any)
+	if result["isError"] != false {
+		t.Errorf("isError = %v, want false", result["isError"])
+	}
+	content := result["content"].([]any)[0].(map[string]any)
+	if content["type"] != "text" || content["text"] != "hi" {
+		t.Errorf("content = %v", content)
+	}
+}
+
+func TestServe_ToolError(t *testing.T) {
+	resps := serve(t, testServer(), `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"fail"}}`+"\n")
+	result := resps[0]["result"].(map[string]any)
+	if result["isError"] != true {
+		t.Errorf("isError = %v, want true", result["isError"])
+	}
+	content := result["content"].([]any)[0].(map[string]any)
+	if content["text"] != "boom" {
+		t.Errorf("text = %v, want boom", content["text"])
+	}
+}
+
+func TestServe_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		code  float64
+	}{
+		{"parse error", `{not json`, CodeParseError},
+		{"invalid request", `{"id":1,"method":"ping"}`, CodeInvalidRequest},
+		{"unknown method", `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`, CodeMethodNotFound},
+		{"unknown tool", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"nope"}}`, CodeInvalidParams},
+		{"missing tool name", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{}}`, CodeInvalidParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resps := serve(t, testServer(), tt.input+"\n")
+			if len(resps) != 1 {
+				t.Fatalf("got %d responses, want 1", len(resps))
+			}
+			rerr, ok := resps[0]["error"].(map[string]any)
+			if !ok {
+				t.Fatalf("expected error response, got %v", resps[0])
+			}
+			if rerr["code"] != tt.code {
+				t.Errorf("code = %v, want %v", rerr["code"], tt.code)
+			}
+		})
+	}
+}
+
+func TestServe_MultipleFrames(t *testing.T) {
+	input := `{"jsonrpc":"2.0","id":1,"method":"ping"}
+
+{"jsonrpc":"2.0","id":2,"method":"ping"}
+`
+	resps := serve(t, testServer(), input)
+	if len(resps) != 2 {
+		t.Fatalf("got %d responses, want 2", len(resps))
+	}
+}
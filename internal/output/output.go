@@ -0,0 +1,264 @@
+// Package output writes command results in the format chosen with
+// --output: aligned tables for people, or JSON, NDJSON, YAML, CSV, TSV or
+// a Go template for scripts.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/lnbotdev/cli/internal/jsontree"
+	"github.com/lnbotdev/cli/internal/query"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	Table      Format = "table"
+	Wide       Format = "wide"
+	JSON       Format = "json"
+	JSONPretty Format = "json-pretty"
+	NDJSON     Format = "ndjson"
+	YAML       Format = "yaml"
+	CSV        Format = "csv"
+	TSV        Format = "tsv"
+	Template   Format = "template"
+)
+
+// Formats lists the output formats.
+var Formats = []Format{Table, Wide, JSON, JSONPretty, NDJSON, YAML, CSV, TSV, Template}
+
+// ParseFormat reads an output format name. An empty name is Table.
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	if f == "" {
+		return Table, nil
+	}
+	for _, known := range Formats {
+		if f == known {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, known := range Formats {
+		names[i] = string(known)
+	}
+	return "", fmt.Errorf("unknown output format %q: use %s", s, strings.Join(names, ", "))
+}
+
+// Human reports whether f is meant for people rather than programs.
+func (f Format) Human() bool {
+	return f == "" || f == Table || f == Wide
+}
+
+// Options are the output settings of a command.
+type Options struct {
+	Format Format
+	// Template is the Go template for the Template format.
+	Template string
+	// Columns selects and orders the columns of a listing.
+	Columns []string
+	// Query is a --query expression (see package query) applied to the
+	// result before it is written.
+	Query string
+}
+
+// CheckTemplate reports whether s is a valid template, so that a mistake
+// is found before a command does anything.
+func CheckTemplate(s string) error {
+	_, err := parseTemplate(s)
+	return err
+}
+
+// Value writes v, a command's result, in opts.Format. Tables are written
+// as JSON, since only listings have columns; see Rows. Templates are
+// executed once for each element of a slice, and once for anything else.
+// With a query, its result is written instead, and scalar results are
+// written raw: strings without quotes, numbers and booleans as in JSON.
+func Value(w io.Writer, opts Options, v any) error {
+	if opts.Query != "" {
+		res, err := search(opts.Query, v)
+		if err != nil {
+			return err
+		}
+		// Scalars are written raw, so that scripts can use them as is.
+		switch res := res.(type) {
+		case string:
+			_, err := fmt.Fprintln(w, res)
+			return err
+		case nil, bool, json.Number:
+			b, _ := json.Marshal(res)
+			_, err := fmt.Fprintln(w, string(b))
+			return err
+		}
+		v = res
+		if opts.Format == Template {
+			v = jsontree.Plain(res)
+		}
+		opts.Query = ""
+	}
+
+	switch opts.Format {
+	case JSONPretty:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case NDJSON:
+		enc := json.NewEncoder(w)
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+			for i := 0; i < rv.Len(); i++ {
+				if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return enc.Encode(v)
+	case YAML:
+		tree, err := jsontree.From(v)
+		if err != nil {
+			return err
+		}
+		return writeYAML(w, tree)
+	case CSV, TSV:
+		tree, err := jsontree.From(v)
+		if err != nil {
+			return err
+		}
+		header, records, err := flatten(tree)
+		if err != nil {
+			return fmt.Errorf("%s output: %w", opts.Format, err)
+		}
+		return writeCSV(w, opts.Format, header, records)
+	case Template:
+		t, err := parseTemplate(opts.Template)
+		if err != nil {
+			return err
+		}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+			for i := 0; i < rv.Len(); i++ {
+				if err := execute(w, t, rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return execute(w, t, v)
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+func search(expr string, v any) (any, error) {
+	q, err := query.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := jsontree.From(v)
+	if err != nil {
+		return nil, err
+	}
+	return q.Search(tree)
+}
+
+func parseTemplate(s string) (*template.Template, error) {
+	t, err := template.New("output").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return t, nil
+}
+
+// execute runs t for one value, ending the output with a newline.
+func execute(w io.Writer, t *template.Template, v any) error {
+	var b bytes.Buffer
+	if err := t.Execute(&b, v); err != nil {
+		return fmt.Errorf("--template: %w", err)
+	}
+	if !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// flatten turns a tree into CSV records: one per element of a list of
+// objects, or one for an object. Nested values are written as JSON.
+func flatten(tree any) ([]string, [][]string, error) {
+	var objects []jsontree.Object
+	switch t := tree.(type) {
+	case jsontree.Object:
+		objects = []jsontree.Object{t}
+	case []any:
+		for _, e := range t {
+			o, ok := e.(jsontree.Object)
+			if !ok {
+				return nil, nil, fmt.Errorf("needs a list of objects")
+			}
+			objects = append(objects, o)
+		}
+	default:
+		return nil, nil, fmt.Errorf("needs a list or an object")
+	}
+
+	var header []string
+	index := map[string]int{}
+	for _, o := range objects {
+		for _, f := range o {
+			if _, ok := index[f.Key]; !ok {
+				index[f.Key] = len(header)
+				header = append(header, f.Key)
+			}
+		}
+	}
+	records := make([][]string, len(objects))
+	for i, o := range objects {
+		rec := make([]string, len(header))
+		for _, f := range o {
+			rec[index[f.Key]] = scalarText(f.Value)
+		}
+		records[i] = rec
+	}
+	return header, records, nil
+}
+
+// scalarText is a tree value as a CSV field.
+func scalarText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func writeCSV(w io.Writer, f Format, header []string, records [][]string) error {
+	cw := csv.NewWriter(w)
+	if f == TSV {
+		cw.Comma = '\t'
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,97 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": Table, "YAML": YAML, " json-pretty ": JSONPretty, "tsv": TSV} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil || !strings.Contains(err.Error(), "ndjson") {
+		t.Errorf("ParseFormat(xml) error = %v", err)
+	}
+}
+
+type result struct {
+	Number int     `json:"number"`
+	Status string  `json:"status"`
+	Memo   *string `json:"memo"`
+}
+
+func TestValue(t *testing.T) {
+	memo := "coffee: large"
+	list := []result{{1, "settled", &memo}, {2, "pending", nil}}
+
+	tests := []struct {
+		opts Options
+		v    any
+		want string
+	}{
+		{Options{Format: JSON}, list[0], `{"number":1,"status":"settled","memo":"coffee: large"}` + "\n"},
+		{Options{Format: Table}, map[string]int{"b": 2, "a": 1}, `{"a":1,"b":2}` + "\n"},
+		{Options{Format: JSONPretty}, list[1], "{\n  \"number\": 2,\n  \"status\": \"pending\",\n  \"memo\": null\n}\n"},
+		{Options{Format: NDJSON}, list, `{"number":1,"status":"settled","memo":"coffee: large"}` + "\n" + `{"number":2,"status":"pending","memo":null}` + "\n"},
+		{Options{Format: YAML}, list, "- number: 1\n  status: settled\n  memo: \"coffee: large\"\n- number: 2\n  status: pending\n  memo: null\n"},
+		{Options{Format: CSV}, list, "number,status,memo\n1,settled,coffee: large\n2,pending,\n"},
+		{Options{Format: TSV}, list[0], "number\tstatus\tmemo\n1\tsettled\tcoffee: large\n"},
+		{Options{Format: Template, Template: "{{.Number}} {{.Status}}"}, list, "1 settled\n2 pending\n"},
+		{Options{Format: Template, Template: "{{json .}}\n"}, map[string]bool{"ok": true}, `{"ok":true}` + "\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := Value(&b, tt.opts, tt.v); err != nil {
+			t.Errorf("%s: %v", tt.opts.Format, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.opts.Format, b.String(), tt.want)
+		}
+	}
+}
+
+func TestValue_Query(t *testing.T) {
+	memo := "coffee"
+	list := []result{{1, "settled", &memo}, {2, "pending", nil}}
+
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Format: JSON, Query: "[0].memo"}, "coffee\n"},
+		{Options{Format: JSON, Query: "[1].memo"}, "null\n"},
+		{Options{Format: YAML, Query: "length(@)"}, "2\n"},
+		{Options{Format: JSON, Query: "[?status=='pending'].number"}, "[2]\n"},
+		{Options{Format: NDJSON, Query: "[*].{n: number}"}, `{"n":1}` + "\n" + `{"n":2}` + "\n"},
+		{Options{Format: Template, Template: "{{.n}}", Query: "[*].{n: number}"}, "1\n2\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := Value(&b, tt.opts, list); err != nil {
+			t.Errorf("%s %q: %v", tt.opts.Format, tt.opts.Query, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s %q = %q, want %q", tt.opts.Format, tt.opts.Query, b.String(), tt.want)
+		}
+	}
+}
+
+func TestValue_Errors(t *testing.T) {
+	if err := Value(&bytes.Buffer{}, Options{Format: CSV}, "text"); err == nil {
+		t.Error("CSV of a string should fail")
+	}
+	if err := Value(&bytes.Buffer{}, Options{Format: Template, Template: "{{.Missing}}"}, result{}); err == nil {
+		t.Error("a template using a missing field should fail")
+	}
+	if err := Value(&bytes.Buffer{}, Options{Format: JSON, Query: "[?"}, result{}); err == nil {
+		t.Error("an invalid query should fail")
+	}
+	if err := CheckTemplate("{{.Amount"); err == nil {
+		t.Error("CheckTemplate should reject an unclosed action")
+	}
+}
@@ -0,0 +1,213 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lnbotdev/cli/internal/jsontree"
+)
+
+// Column is a column of a listing of T.
+type Column[T any] struct {
+	// Name is how --columns selects the column, and its key in JSON, YAML
+	// and CSV.
+	Name string
+	// Wide columns are only shown by the Wide table, CSV and TSV, unless
+	// they are selected.
+	Wide bool
+	// Optional columns are left out when they are empty in every row,
+	// unless they are selected.
+	Optional bool
+	// Right aligns the column to the right in tables.
+	Right bool
+	// Text is the column's table cell.
+	Text func(T) string
+	// Value is the column's value for the machine formats. When nil, Text
+	// is used.
+	Value func(T) any
+}
+
+func (c Column[T]) value(row T) any {
+	if c.Value != nil {
+		return c.Value(row)
+	}
+	return c.Text(row)
+}
+
+// Select returns the columns named in names, in that order, or the
+// default columns when names is empty: those that aren't Wide, or all of
+// them with wide.
+func Select[T any](cols []Column[T], names []string, wide bool) ([]Column[T], error) {
+	if len(names) == 0 {
+		var sel []Column[T]
+		for _, c := range cols {
+			if !c.Wide || wide {
+				sel = append(sel, c)
+			}
+		}
+		return sel, nil
+	}
+
+	sel := make([]Column[T], 0, len(names))
+	for _, name := range names {
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+		found := false
+		for _, c := range cols {
+			if c.Name == key {
+				c.Optional = false
+				sel = append(sel, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q: use %s", name, strings.Join(ColumnNames(cols), ", "))
+		}
+	}
+	return sel, nil
+}
+
+// ColumnNames lists the names of cols.
+func ColumnNames[T any](cols []Column[T]) []string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Rows writes a listing in opts.Format. JSON, NDJSON and YAML write the
+// rows themselves unless columns are selected; tables, CSV and TSV write
+// the columns, CSV and TSV all of them by default. Templates are executed
+// for each row. A query applies to the rows, as for Value.
+func Rows[T any](w io.Writer, opts Options, rows []T, cols []Column[T]) error {
+	if opts.Query != "" {
+		if len(opts.Columns) > 0 {
+			return fmt.Errorf("--columns can't be combined with --query")
+		}
+		return Value(w, opts, rows)
+	}
+
+	switch opts.Format {
+	case Template:
+		return Value(w, opts, rows)
+	case JSON, JSONPretty, NDJSON, YAML:
+		if len(opts.Columns) == 0 {
+			return Value(w, opts, rows)
+		}
+		sel, err := Select(cols, opts.Columns, true)
+		if err != nil {
+			return err
+		}
+		objects := make([]jsontree.Object, len(rows))
+		for i, row := range rows {
+			o := make(jsontree.Object, len(sel))
+			for j, c := range sel {
+				o[j] = jsontree.Field{Key: c.Name, Value: c.value(row)}
+			}
+			objects[i] = o
+		}
+		return Value(w, opts, objects)
+	}
+
+	machine := opts.Format == CSV || opts.Format == TSV
+	sel, err := Select(cols, opts.Columns, machine || opts.Format == Wide)
+	if err != nil {
+		return err
+	}
+	sel = dropEmpty(sel, rows, machine)
+
+	if machine {
+		header := ColumnNames(sel)
+		records := make([][]string, len(rows))
+		for i, row := range rows {
+			rec := make([]string, len(sel))
+			for j, c := range sel {
+				rec[j] = cell(c.value(row))
+			}
+			records[i] = rec
+		}
+		return writeCSV(w, opts.Format, header, records)
+	}
+
+	header := make([]string, len(sel))
+	for i, c := range sel {
+		header[i] = strings.ToUpper(strings.ReplaceAll(c.Name, "_", " "))
+	}
+	table := [][]string{header}
+	for _, row := range rows {
+		line := make([]string, len(sel))
+		for j, c := range sel {
+			line[j] = c.Text(row)
+		}
+		table = append(table, line)
+	}
+	return writeTable(w, table, sel)
+}
+
+// dropEmpty leaves out the Optional columns that are empty in every row.
+func dropEmpty[T any](cols []Column[T], rows []T, machine bool) []Column[T] {
+	var kept []Column[T]
+	for _, c := range cols {
+		if !c.Optional {
+			kept = append(kept, c)
+			continue
+		}
+		for _, row := range rows {
+			if (machine && cell(c.value(row)) != "") || (!machine && c.Text(row) != "") {
+				kept = append(kept, c)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// cell is a column value as a CSV field.
+func cell(v any) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return ""
+		}
+		v = rv.Elem().Interface()
+	}
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+// writeTable writes rows aligned in columns, indented by two spaces like
+// the rest of the CLI's output.
+func writeTable[T any](w io.Writer, rows [][]string, cols []Column[T]) error {
+	widths := make([]int, len(cols))
+	for _, row := range rows {
+		for i, s := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(s))
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, s := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(s))
+			if cols[i].Right {
+				cells[i] = pad + s
+			} else {
+				cells[i] = s + pad
+			}
+		}
+		b.WriteString(strings.TrimRight("  "+strings.Join(cells, "  "), " ") + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,71 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type row struct {
+	Number int
+	Amount int64
+	Note   string
+}
+
+var rowColumns = []Column[row]{
+	{Name: "number", Right: true, Text: func(r row) string { return fmt.Sprintf("#%d", r.Number) }, Value: func(r row) any { return r.Number }},
+	{Name: "amount", Right: true, Text: func(r row) string { return fmt.Sprintf("%d sats", r.Amount) }, Value: func(r row) any { return r.Amount }},
+	{Name: "fiat", Optional: true, Text: func(r row) string { return "" }},
+	{Name: "note", Wide: true, Text: func(r row) string { return r.Note }},
+}
+
+var rows = []row{{1, 500, "tip"}, {12, 21000, "rent, march"}}
+
+func TestRows(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Format: Table}, "  NUMBER      AMOUNT\n      #1    500 sats\n     #12  21000 sats\n"},
+		{Options{Format: Wide}, "  NUMBER      AMOUNT  NOTE\n      #1    500 sats  tip\n     #12  21000 sats  rent, march\n"},
+		{Options{Format: Table, Columns: []string{"note", "number"}}, "  NOTE         NUMBER\n  tip              #1\n  rent, march     #12\n"},
+		{Options{Format: CSV}, "number,amount,note\n1,500,tip\n12,21000,\"rent, march\"\n"},
+		{Options{Format: TSV, Columns: []string{"Amount"}}, "amount\n500\n21000\n"},
+		{Options{Format: JSON}, `[{"Number":1,"Amount":500,"Note":"tip"},{"Number":12,"Amount":21000,"Note":"rent, march"}]` + "\n"},
+		{Options{Format: JSON, Columns: []string{"amount", "number"}}, `[{"amount":500,"number":1},{"amount":21000,"number":12}]` + "\n"},
+		{Options{Format: NDJSON, Columns: []string{"number"}}, `{"number":1}` + "\n" + `{"number":12}` + "\n"},
+		{Options{Format: YAML, Columns: []string{"number", "fiat"}}, "- number: 1\n  fiat: \"\"\n- number: 12\n  fiat: \"\"\n"},
+		{Options{Format: Template, Template: "{{.Number}}={{.Amount}}"}, "1=500\n12=21000\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := Rows(&b, tt.opts, rows, rowColumns); err != nil {
+			t.Errorf("%s %v: %v", tt.opts.Format, tt.opts.Columns, err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s %v:\ngot  %q\nwant %q", tt.opts.Format, tt.opts.Columns, b.String(), tt.want)
+		}
+	}
+}
+
+func TestRows_Query(t *testing.T) {
+	var b bytes.Buffer
+	if err := Rows(&b, Options{Format: Table, Query: "[?Amount > `1000`].Note | [0]"}, rows, rowColumns); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "rent, march\n" {
+		t.Errorf("query = %q", b.String())
+	}
+	if err := Rows(&b, Options{Format: JSON, Query: "@", Columns: []string{"number"}}, rows, rowColumns); err == nil {
+		t.Error("--columns with --query should fail")
+	}
+}
+
+func TestRows_UnknownColumn(t *testing.T) {
+	err := Rows(&bytes.Buffer{}, Options{Format: Table, Columns: []string{"status"}}, rows, rowColumns)
+	if err == nil || !strings.Contains(err.Error(), "number, amount, fiat, note") {
+		t.Errorf("err = %v", err)
+	}
+}
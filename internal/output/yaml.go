@@ -0,0 +1,122 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lnbotdev/cli/internal/jsontree"
+)
+
+// writeYAML writes a tree (see package jsontree) as a YAML document.
+func writeYAML(w io.Writer, tree any) error {
+	var b bytes.Buffer
+	encodeYAML(&b, tree, 0)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func encodeYAML(b *bytes.Buffer, v any, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case jsontree.Object:
+		if len(v) == 0 {
+			b.WriteString(pad + "{}\n")
+			return
+		}
+		for _, f := range v {
+			b.WriteString(pad + yamlString(f.Key) + ":")
+			if isCollection(f.Value) {
+				b.WriteByte('\n')
+				encodeYAML(b, f.Value, indent+1)
+			} else {
+				b.WriteString(" " + yamlScalar(f.Value) + "\n")
+			}
+		}
+	case []any:
+		if len(v) == 0 {
+			b.WriteString(pad + "[]\n")
+			return
+		}
+		for _, e := range v {
+			// Write the item one level deeper, then put the dash in the
+			// first line's indentation: "- key: value".
+			var item bytes.Buffer
+			encodeYAML(&item, e, indent+1)
+			b.WriteString(pad + "- " + strings.TrimPrefix(item.String(), pad+"  "))
+		}
+	default:
+		b.WriteString(pad + yamlScalar(v) + "\n")
+	}
+}
+
+// isCollection reports whether v is a non-empty object or list, which is
+// written on the lines after its key.
+func isCollection(v any) bool {
+	switch v := v.(type) {
+	case jsontree.Object:
+		return len(v) > 0
+	case []any:
+		return len(v) > 0
+	}
+	return false
+}
+
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	case jsontree.Object:
+		return "{}"
+	case []any:
+		return "[]"
+	}
+	return yamlString(fmt.Sprint(v))
+}
+
+// yamlString writes s plain when YAML would read it back as the same
+// string, and double-quoted otherwise.
+func yamlString(s string) string {
+	if plainSafe(s) {
+		return s
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func plainSafe(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteYAML(t *testing.T) {
+	v := map[string]any{
+		"policy": map[string]any{"allow": []string{"ln.bot", "@example.com"}, "deny": []string{}},
+		"nested": [][]int{{1, 2}},
+		"path":   nil,
+		"empty":  map[string]any{},
+		"quoted": []string{"", "true", "123", "a: b", "two\nlines", " padded"},
+		"plain":  "2026-10-17T10:00:00Z",
+	}
+	want := `empty: {}
+nested:
+  - - 1
+    - 2
+path: null
+plain: 2026-10-17T10:00:00Z
+policy:
+  allow:
+    - ln.bot
+    - "@example.com"
+  deny: []
+quoted:
+  - ""
+  - "true"
+  - "123"
+  - "a: b"
+  - "two\nlines"
+  - " padded"
+`
+	var b bytes.Buffer
+	if err := Value(&b, Options{Format: YAML}, v); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
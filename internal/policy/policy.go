@@ -0,0 +1,266 @@
+// Package policy evaluates local spending policies before a payment is sent.
+//
+// A policy is a JSON file referenced per wallet from the CLI config. Every
+// limit is optional; a zero value means "no limit".
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lnbotdev/cli/internal/bech32"
+)
+
+// Rule names reported in violations.
+const (
+	RuleMaxPerPayment = "max_per_payment"
+	RuleDailyBudget   = "daily_budget"
+	RuleWeeklyBudget  = "weekly_budget"
+	RuleMaxFee        = "max_fee"
+	RuleDeny          = "deny"
+	RuleAllow         = "allow"
+	RuleAmountUnknown = "amount_unknown"
+)
+
+// Policy is a per-wallet spending policy.
+//
+// Allow and Deny entries are either Lightning addresses (alice@ln.bot),
+// matched ignoring plus-address tags, or domains (ln.bot), which match the
+// domain and all of its subdomains. LNURL targets are matched by the domain
+// they point to.
+// BOLT11 invoices carry no domain, so they are rejected when an allow list
+// is set unless AllowBolt11 is true.
+type Policy struct {
+	MaxPerPayment int64    `json:"max_per_payment,omitempty"`
+	DailyBudget   int64    `json:"daily_budget,omitempty"`
+	WeeklyBudget  int64    `json:"weekly_budget,omitempty"`
+	MaxFee        int64    `json:"max_fee,omitempty"`
+	Allow         []string `json:"allow,omitempty"`
+	Deny          []string `json:"deny,omitempty"`
+	AllowBolt11   bool     `json:"allow_bolt11,omitempty"`
+}
+
+// Payment is an outgoing payment to be checked. Amount is zero when it is
+// not known; MaxFee is zero when no fee limit was requested.
+type Payment struct {
+	Target string
+	Amount int64
+	MaxFee int64
+}
+
+// Spend is a past outgoing payment counted against the rolling budgets.
+type Spend struct {
+	Amount int64
+	At     time.Time
+}
+
+// Violation describes why a payment was denied.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Limit   int64  `json:"limit,omitempty"`
+	Actual  int64  `json:"actual,omitempty"`
+}
+
+func (v *Violation) Error() string {
+	return "denied by spending policy: " + v.Message
+}
+
+// Load reads a policy file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Validate checks that limits are non-negative and list entries are well formed.
+func (p *Policy) Validate() error {
+	for name, v := range map[string]int64{
+		RuleMaxPerPayment: p.MaxPerPayment,
+		RuleDailyBudget:   p.DailyBudget,
+		RuleWeeklyBudget:  p.WeeklyBudget,
+		RuleMaxFee:        p.MaxFee,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	for _, e := range append(append([]string{}, p.Allow...), p.Deny...) {
+		if strings.TrimSpace(e) == "" || strings.Count(e, "@") > 1 {
+			return fmt.Errorf("invalid destination %q", e)
+		}
+	}
+	return nil
+}
+
+// NeedsAmount reports whether the policy has rules that depend on the
+// payment amount.
+func (p *Policy) NeedsAmount() bool {
+	return p.MaxPerPayment > 0 || p.NeedsHistory()
+}
+
+// NeedsHistory reports whether the policy has rolling budgets.
+func (p *Policy) NeedsHistory() bool {
+	return p.DailyBudget > 0 || p.WeeklyBudget > 0
+}
+
+// Check evaluates pay against the policy. history holds previous outgoing
+// payments (only those within the last week matter). It returns nil when
+// the payment is allowed.
+func (p *Policy) Check(pay Payment, history []Spend, now time.Time) *Violation {
+	if v := p.checkDestination(pay.Target); v != nil {
+		return v
+	}
+
+	if p.MaxFee > 0 && pay.MaxFee > p.MaxFee {
+		return &Violation{
+			Rule:    RuleMaxFee,
+			Message: fmt.Sprintf("max fee %d sats exceeds the %d sat ceiling", pay.MaxFee, p.MaxFee),
+			Limit:   p.MaxFee,
+			Actual:  pay.MaxFee,
+		}
+	}
+
+	if !p.NeedsAmount() {
+		return nil
+	}
+	if pay.Amount <= 0 {
+		return &Violation{
+			Rule:    RuleAmountUnknown,
+			Message: "payment amount could not be determined",
+		}
+	}
+
+	if p.MaxPerPayment > 0 && pay.Amount > p.MaxPerPayment {
+		return &Violation{
+			Rule:    RuleMaxPerPayment,
+			Message: fmt.Sprintf("%d sats exceeds the %d sat per-payment limit", pay.Amount, p.MaxPerPayment),
+			Limit:   p.MaxPerPayment,
+			Actual:  pay.Amount,
+		}
+	}
+
+	budgets := []struct {
+		rule   string
+		label  string
+		limit  int64
+		window time.Duration
+	}{
+		{RuleDailyBudget, "daily", p.DailyBudget, 24 * time.Hour},
+		{RuleWeeklyBudget, "weekly", p.WeeklyBudget, 7 * 24 * time.Hour},
+	}
+	for _, b := range budgets {
+		if b.limit <= 0 {
+			continue
+		}
+		spent := Spent(history, now.Add(-b.window))
+		if spent+pay.Amount > b.limit {
+			return &Violation{
+				Rule: b.rule,
+				Message: fmt.Sprintf("%d sats would bring %s spending to %d sats (budget %d, already spent %d)",
+					pay.Amount, b.label, spent+pay.Amount, b.limit, spent),
+				Limit:  b.limit,
+				Actual: spent + pay.Amount,
+			}
+		}
+	}
+	return nil
+}
+
+// Spent sums the history entries at or after since.
+func Spent(history []Spend, since time.Time) int64 {
+	var total int64
+	for _, s := range history {
+		if !s.At.Before(since) {
+			total += s.Amount
+		}
+	}
+	return total
+}
+
+func (p *Policy) checkDestination(target string) *Violation {
+	address, domain := Destination(target)
+
+	for _, e := range p.Deny {
+		if matches(e, address, domain) {
+			return &Violation{Rule: RuleDeny, Message: fmt.Sprintf("destination matches deny entry %q", e)}
+		}
+	}
+
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	if address == "" && domain == "" {
+		if p.AllowBolt11 {
+			return nil
+		}
+		return &Violation{Rule: RuleAllow, Message: "destination has no address or domain to check against the allow list (set allow_bolt11 to permit invoices)"}
+	}
+	for _, e := range p.Allow {
+		if matches(e, address, domain) {
+			return nil
+		}
+	}
+	return &Violation{Rule: RuleAllow, Message: "destination is not on the allow list"}
+}
+
+// Destination extracts the Lightning address and domain a target points to.
+// Lightning addresses yield both; LNURLs yield the domain of the encoded
+// URL; anything else (BOLT11 invoices) yields neither.
+func Destination(target string) (address, domain string) {
+	target = strings.TrimSpace(target)
+	lower := strings.ToLower(target)
+
+	if at := strings.LastIndex(lower, "@"); at > 0 {
+		return lower, lower[at+1:]
+	}
+
+	if strings.HasPrefix(lower, "lnurl") {
+		hrp, data, err := bech32.Decode(target)
+		if err != nil || hrp != "lnurl" {
+			return "", ""
+		}
+		raw, err := bech32.ConvertBits(data, 5, 8, false)
+		if err != nil {
+			return "", ""
+		}
+		u, err := url.Parse(string(raw))
+		if err != nil {
+			return "", ""
+		}
+		return "", strings.ToLower(u.Hostname())
+	}
+
+	return "", ""
+}
+
+func matches(entry, address, domain string) bool {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if strings.Contains(entry, "@") {
+		return address != "" && baseAddress(entry) == baseAddress(address)
+	}
+	entry = strings.TrimPrefix(entry, "*.")
+	return domain != "" && (domain == entry || strings.HasSuffix(domain, "."+entry))
+}
+
+// baseAddress drops a plus-address tag: alice+tips@ln.bot → alice@ln.bot.
+func baseAddress(address string) string {
+	at := strings.LastIndex(address, "@")
+	if plus := strings.Index(address[:at], "+"); plus >= 0 {
+		return address[:plus] + address[at:]
+	}
+	return address
+}
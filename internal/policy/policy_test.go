@@ -0,0 +1,193 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const lnurl = "LNURL1DP68GURN8GHJ7UM9WFMXJCM99E3K7MF0V9CXJ0M385EKVCENXC6R2C35XVUKXEFCV5MKVV34X5EKZD3EV56NYD3HXQURZEPEXEJXXEPNXSCRVWFNV9NXZCN9XQ6XYEFHVGCXXCMYXYMNSERXFQ5FNS"
+
+var now = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func TestDestination(t *testing.T) {
+	tests := []struct {
+		target  string
+		address string
+		domain  string
+	}{
+		{"Alice@LN.bot", "alice@ln.bot", "ln.bot"},
+		{lnurl, "", "service.com"},
+		{"lnbc10u1pj9x", "", ""},
+		{"lnurl1invalid", "", ""},
+	}
+	for _, tt := range tests {
+		address, domain := Destination(tt.target)
+		if address != tt.address || domain != tt.domain {
+			t.Errorf("Destination(%q) = (%q, %q), want (%q, %q)", tt.target, address, domain, tt.address, tt.domain)
+		}
+	}
+}
+
+func TestCheck_NoLimits(t *testing.T) {
+	p := &Policy{}
+	if v := p.Check(Payment{Target: "lnbc1xyz"}, nil, now); v != nil {
+		t.Errorf("empty policy should allow everything, got %v", v)
+	}
+}
+
+func TestCheck_MaxPerPayment(t *testing.T) {
+	p := &Policy{MaxPerPayment: 1000}
+	if v := p.Check(Payment{Target: "a@ln.bot", Amount: 1000}, nil, now); v != nil {
+		t.Errorf("payment at the limit should pass, got %v", v)
+	}
+	v := p.Check(Payment{Target: "a@ln.bot", Amount: 1001}, nil, now)
+	if v == nil || v.Rule != RuleMaxPerPayment {
+		t.Fatalf("got %v, want %s violation", v, RuleMaxPerPayment)
+	}
+	if v.Limit != 1000 || v.Actual != 1001 {
+		t.Errorf("limit/actual = %d/%d, want 1000/1001", v.Limit, v.Actual)
+	}
+}
+
+func TestCheck_AmountUnknown(t *testing.T) {
+	p := &Policy{MaxPerPayment: 1000}
+	v := p.Check(Payment{Target: "lnbc1xyz"}, nil, now)
+	if v == nil || v.Rule != RuleAmountUnknown {
+		t.Errorf("got %v, want %s violation", v, RuleAmountUnknown)
+	}
+}
+
+func TestCheck_RollingBudgets(t *testing.T) {
+	history := []Spend{
+		{Amount: 400, At: now.Add(-1 * time.Hour)},
+		{Amount: 300, At: now.Add(-30 * time.Hour)},
+		{Amount: 5000, At: now.Add(-8 * 24 * time.Hour)}, // outside both windows
+	}
+
+	p := &Policy{DailyBudget: 1000, WeeklyBudget: 1500}
+
+	if v := p.Check(Payment{Target: "a@ln.bot", Amount: 600}, history, now); v != nil {
+		t.Errorf("400 + 600 fits the daily budget, got %v", v)
+	}
+
+	v := p.Check(Payment{Target: "a@ln.bot", Amount: 601}, history, now)
+	if v == nil || v.Rule != RuleDailyBudget {
+		t.Fatalf("got %v, want %s violation", v, RuleDailyBudget)
+	}
+	if v.Actual != 1001 {
+		t.Errorf("actual = %d, want 1001", v.Actual)
+	}
+
+	p = &Policy{WeeklyBudget: 1000}
+	v = p.Check(Payment{Target: "a@ln.bot", Amount: 301}, history, now)
+	if v == nil || v.Rule != RuleWeeklyBudget {
+		t.Fatalf("got %v, want %s violation", v, RuleWeeklyBudget)
+	}
+}
+
+func TestCheck_MaxFee(t *testing.T) {
+	p := &Policy{MaxFee: 50}
+	if v := p.Check(Payment{Target: "a@ln.bot", Amount: 10, MaxFee: 50}, nil, now); v != nil {
+		t.Errorf("fee at the ceiling should pass, got %v", v)
+	}
+	v := p.Check(Payment{Target: "a@ln.bot", Amount: 10, MaxFee: 51}, nil, now)
+	if v == nil || v.Rule != RuleMaxFee {
+		t.Errorf("got %v, want %s violation", v, RuleMaxFee)
+	}
+}
+
+func TestCheck_Deny(t *testing.T) {
+	p := &Policy{Deny: []string{"evil.com", "mallory@ln.bot"}}
+
+	denied := []string{"bob@evil.com", "bob@pay.evil.com", "mallory@ln.bot", "Mallory+tips@ln.bot"}
+	for _, target := range denied {
+		v := p.Check(Payment{Target: target}, nil, now)
+		if v == nil || v.Rule != RuleDeny {
+			t.Errorf("Check(%q) = %v, want %s violation", target, v, RuleDeny)
+		}
+	}
+
+	allowed := []string{"alice@ln.bot", "bob@notevil.com", "lnbc1xyz"}
+	for _, target := range allowed {
+		if v := p.Check(Payment{Target: target}, nil, now); v != nil {
+			t.Errorf("Check(%q) = %v, want allowed", target, v)
+		}
+	}
+}
+
+func TestCheck_Allow(t *testing.T) {
+	p := &Policy{Allow: []string{"ln.bot", "carol@example.com", "service.com"}}
+
+	allowed := []string{"alice@ln.bot", "carol@example.com", "carol+x@example.com", lnurl}
+	for _, target := range allowed {
+		if v := p.Check(Payment{Target: target}, nil, now); v != nil {
+			t.Errorf("Check(%q) = %v, want allowed", target, v)
+		}
+	}
+
+	denied := []string{"dave@example.com", "eve@other.org", "lnbc1xyz"}
+	for _, target := range denied {
+		v := p.Check(Payment{Target: target}, nil, now)
+		if v == nil || v.Rule != RuleAllow {
+			t.Errorf("Check(%q) = %v, want %s violation", target, v, RuleAllow)
+		}
+	}
+
+	p.AllowBolt11 = true
+	if v := p.Check(Payment{Target: "lnbc1xyz"}, nil, now); v != nil {
+		t.Errorf("allow_bolt11 should permit invoices, got %v", v)
+	}
+}
+
+func TestCheck_DenyBeatsAllow(t *testing.T) {
+	p := &Policy{Allow: []string{"ln.bot"}, Deny: []string{"mallory@ln.bot"}}
+	v := p.Check(Payment{Target: "mallory@ln.bot"}, nil, now)
+	if v == nil || v.Rule != RuleDeny {
+		t.Errorf("got %v, want %s violation", v, RuleDeny)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "policy.json")
+	os.WriteFile(p, []byte(`{"max_per_payment":5000,"daily_budget":20000,"allow":["ln.bot"]}`), 0o600)
+
+	pol, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if pol.MaxPerPayment != 5000 || pol.DailyBudget != 20000 {
+		t.Errorf("Load() = %+v", pol)
+	}
+	if len(pol.Allow) != 1 || pol.Allow[0] != "ln.bot" {
+		t.Errorf("Allow = %v", pol.Allow)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"bad json":    `{bad`,
+		"negative":    `{"daily_budget":-1}`,
+		"empty entry": `{"deny":[""]}`,
+		"double at":   `{"allow":["a@b@c"]}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := filepath.Join(dir, name+".json")
+			os.WriteFile(p, []byte(data), 0o600)
+			if _, err := Load(p); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "nope.json"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Load() error = %v, want not-exist", err)
+	}
+}
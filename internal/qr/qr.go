@@ -0,0 +1,430 @@
+// Package qr is a small QR code encoder (ISO/IEC 18004) for rendering
+// invoices and Lightning addresses without external tools.
+//
+// It supports byte and alphanumeric mode, all 40 versions and the four
+// error correction levels. Uppercased BOLT11 strings and LNURLs fit the
+// alphanumeric character set, which yields noticeably smaller codes.
+package qr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the error correction level.
+type Level int
+
+// Error correction levels, from lowest to highest redundancy.
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+// formatBits are the two-bit level indicators used in the format info.
+var formatBits = [4]int{Low: 1, Medium: 0, Quartile: 3, High: 2}
+
+// Code is an encoded QR symbol. Modules are indexed [y][x]; true is dark.
+type Code struct {
+	Size    int
+	Version int
+	modules [][]bool
+	isFunc  [][]bool
+}
+
+// Dark reports whether the module at column x, row y is dark. Coordinates
+// outside the symbol (the quiet zone) are light.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+const alphanumericCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// Encode builds the smallest QR code that holds text at the given level.
+func Encode(text string, level Level) (*Code, error) {
+	alnum := isAlphanumeric(text)
+
+	for ver := 1; ver <= 40; ver++ {
+		capacity := numDataCodewords(ver, level) * 8
+		bits := segmentBits(text, alnum, ver)
+		if bits < 0 || bits > capacity {
+			continue
+		}
+		data := encodeData(text, alnum, ver, capacity)
+		return build(ver, level, data), nil
+	}
+	return nil, fmt.Errorf("data too long for a QR code (%d characters)", len(text))
+}
+
+func isAlphanumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(alphanumericCharset, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func charCountBits(alnum bool, ver int) int {
+	switch {
+	case ver <= 9:
+		if alnum {
+			return 9
+		}
+		return 8
+	case ver <= 26:
+		if alnum {
+			return 11
+		}
+		return 16
+	default:
+		if alnum {
+			return 13
+		}
+		return 16
+	}
+}
+
+// segmentBits returns the segment length in bits for text at ver, or -1 if
+// the character count does not fit the count field.
+func segmentBits(text string, alnum bool, ver int) int {
+	cc := charCountBits(alnum, ver)
+	if len(text) >= 1<<uint(cc) {
+		return -1
+	}
+	n := 4 + cc
+	if alnum {
+		n += len(text)/2*11 + len(text)%2*6
+	} else {
+		n += len(text) * 8
+	}
+	return n
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (v>>uint(i))&1 != 0)
+	}
+}
+
+// encodeData builds the padded data codewords for a single segment.
+func encodeData(text string, alnum bool, ver, capacity int) []byte {
+	var bb bitBuffer
+	if alnum {
+		bb.append(0x2, 4)
+		bb.append(len(text), charCountBits(true, ver))
+		for i := 0; i+1 < len(text); i += 2 {
+			a := strings.IndexByte(alphanumericCharset, text[i])
+			b := strings.IndexByte(alphanumericCharset, text[i+1])
+			bb.append(a*45+b, 11)
+		}
+		if len(text)%2 == 1 {
+			bb.append(strings.IndexByte(alphanumericCharset, text[len(text)-1]), 6)
+		}
+	} else {
+		bb.append(0x4, 4)
+		bb.append(len(text), charCountBits(false, ver))
+		for i := 0; i < len(text); i++ {
+			bb.append(int(text[i]), 8)
+		}
+	}
+
+	// Terminator, byte alignment, then alternating pad bytes.
+	term := capacity - len(bb)
+	if term > 4 {
+		term = 4
+	}
+	bb.append(0, term)
+	bb.append(0, (8-len(bb)%8)%8)
+	for pad := 0xEC; len(bb) < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	data := make([]byte, len(bb)/8)
+	for i, bit := range bb {
+		if bit {
+			data[i>>3] |= 1 << uint(7-i&7)
+		}
+	}
+	return data
+}
+
+func build(ver int, level Level, data []byte) *Code {
+	size := ver*4 + 17
+	c := &Code{Size: size, Version: ver}
+	c.modules = make([][]bool, size)
+	c.isFunc = make([][]bool, size)
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.isFunc[i] = make([]bool, size)
+	}
+
+	c.drawFunctionPatterns()
+	c.drawCodewords(addECCAndInterleave(data, ver, level))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(level, mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // XOR again to undo
+	}
+	c.applyMask(best)
+	c.drawFormatBits(level, best)
+
+	c.isFunc = nil
+	return c
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunc[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	pos := alignmentPositions(c.Version)
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(pos[i]+dx, pos[j]+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas; real bits are drawn once the mask is chosen.
+	c.drawFormatBits(Medium, 0)
+
+	if c.Version >= 7 {
+		bits := versionInfo(c.Version)
+		for i := 0; i < 18; i++ {
+			dark := (bits>>uint(i))&1 != 0
+			a, b := c.Size-11+i%3, i/3
+			c.set(a, b, dark)
+			c.set(b, a, dark)
+		}
+	}
+}
+
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.set(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+// formatInfo returns the 15-bit BCH-protected, masked format information.
+func formatInfo(level Level, mask int) int {
+	data := formatBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionInfo returns the 18-bit BCH-protected version information.
+func versionInfo(ver int) int {
+	rem := ver
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return ver<<12 | rem
+}
+
+func (c *Code) drawFormatBits(level Level, mask int) {
+	bits := formatInfo(level, mask)
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true)
+}
+
+// drawCodewords places the data in the zigzag pattern, skipping function
+// modules.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.isFunc[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.modules[y][x] = (data[i>>3]>>uint(7-i&7))&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.isFunc[y][x] && maskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol with the four rules from the spec; lower is
+// easier to scan.
+func (c *Code) penalty() int {
+	n := c.Size
+	score := 0
+	at := func(x, y int, horizontal bool) bool {
+		if horizontal {
+			return c.modules[y][x]
+		}
+		return c.modules[x][y]
+	}
+
+	finder := []bool{true, false, true, true, true, false, true}
+	for _, horizontal := range []bool{true, false} {
+		for y := 0; y < n; y++ {
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, horizontal) == at(x-1, y, horizontal) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+
+			// 1:1:3:1:1 finder-like pattern with four light modules on
+			// either side (outside the symbol counts as light).
+			for x := 0; x+7 <= n; x++ {
+				match := true
+				for k, want := range finder {
+					if at(x+k, y, horizontal) != want {
+						match = false
+						break
+					}
+				}
+				if !match {
+					continue
+				}
+				if c.lightRun(x-4, x, y, horizontal) || c.lightRun(x+7, x+11, y, horizontal) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				v := c.modules[y][x]
+				if v == c.modules[y][x+1] && v == c.modules[y+1][x] && v == c.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+	// Dark/light balance: 10 points per 5% deviation from 50%.
+	total := n * n
+	if k := (abs(dark*20-total*10)+total-1)/total - 1; k > 0 {
+		score += k * 10
+	}
+	return score
+}
+
+func (c *Code) lightRun(from, to, line int, horizontal bool) bool {
+	for i := from; i < to; i++ {
+		x, y := i, line
+		if !horizontal {
+			x, y = line, i
+		}
+		if c.Dark(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,256 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+)
+
+// readBack decodes a symbol produced by Encode: it recovers the format
+// information, removes the mask, reads the codewords in placement order,
+// checks every block's Reed-Solomon codewords and parses the single data
+// segment.
+func readBack(t *testing.T, c *Code) (Level, string) {
+	t.Helper()
+
+	// Format information, first copy, in the bit order drawFormatBits uses.
+	var coords [15][2]int
+	for i := 0; i <= 5; i++ {
+		coords[i] = [2]int{8, i}
+	}
+	coords[6], coords[7], coords[8] = [2]int{8, 7}, [2]int{8, 8}, [2]int{7, 8}
+	for i := 9; i < 15; i++ {
+		coords[i] = [2]int{14 - i, 8}
+	}
+	format := 0
+	for i, xy := range coords {
+		if c.Dark(xy[0], xy[1]) {
+			format |= 1 << uint(i)
+		}
+	}
+	level, mask := Level(-1), -1
+	for l := Low; l <= High; l++ {
+		for m := 0; m < 8; m++ {
+			if formatInfo(l, m) == format {
+				level, mask = l, m
+			}
+		}
+	}
+	if level < 0 {
+		t.Fatalf("format bits %015b do not match any level/mask", format)
+	}
+
+	// Rebuild the function pattern map for this version.
+	fn := &Code{Size: c.Size, Version: c.Version}
+	fn.modules = make([][]bool, c.Size)
+	fn.isFunc = make([][]bool, c.Size)
+	for i := range fn.modules {
+		fn.modules[i] = make([]bool, c.Size)
+		fn.isFunc[i] = make([]bool, c.Size)
+	}
+	fn.drawFunctionPatterns()
+
+	var bits []bool
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if !fn.isFunc[y][x] {
+					bits = append(bits, c.Dark(x, y) != maskBit(mask, x, y))
+				}
+			}
+		}
+	}
+	raw := numRawDataModules(c.Version) / 8
+	codewords := make([]byte, raw)
+	for i := 0; i < raw*8; i++ {
+		if bits[i] {
+			codewords[i>>3] |= 1 << uint(7-i&7)
+		}
+	}
+
+	// De-interleave.
+	numBlocks := eccBlocks[level][c.Version]
+	eccLen := eccPerBlock[level][c.Version]
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortLen; i++ {
+		for j := range blocks {
+			if i == shortLen-eccLen && j < numShort {
+				continue
+			}
+			blocks[j] = append(blocks[j], codewords[k])
+			k++
+		}
+	}
+
+	var data []byte
+	divisor := rsDivisor(eccLen)
+	for i, b := range blocks {
+		dat, ecc := b[:len(b)-eccLen], b[len(b)-eccLen:]
+		if got := rsRemainder(dat, divisor); string(got) != string(ecc) {
+			t.Fatalf("block %d: error correction mismatch", i)
+		}
+		data = append(data, dat...)
+	}
+	if len(data) != numDataCodewords(c.Version, level) {
+		t.Fatalf("data codewords = %d, want %d", len(data), numDataCodewords(c.Version, level))
+	}
+
+	pos := 0
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(data[pos>>3]>>uint(7-pos&7)&1)
+			pos++
+		}
+		return v
+	}
+	var text strings.Builder
+	switch read(4) {
+	case 0x2:
+		count := read(charCountBits(true, c.Version))
+		for ; count >= 2; count -= 2 {
+			v := read(11)
+			text.WriteByte(alphanumericCharset[v/45])
+			text.WriteByte(alphanumericCharset[v%45])
+		}
+		if count == 1 {
+			text.WriteByte(alphanumericCharset[read(6)])
+		}
+	case 0x4:
+		count := read(charCountBits(false, c.Version))
+		for i := 0; i < count; i++ {
+			text.WriteByte(byte(read(8)))
+		}
+	default:
+		t.Fatal("unexpected segment mode")
+	}
+	return level, text.String()
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	invoice := "LIGHTNING:LNBC2500U1PVJLUEZSP5ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYG3ZYGSPP5QQQSYQCYQ5RQWZQFQQQSYQCYQ5RQWZQFQQQSYQCYQ5RQWZQFQYPQDQ5XYSXXATSYP3K7ENXV4JSXQZPU9QRSGQUK0RL77NJ30YXDY8J9VDX85FKPMDLA2087NE0XH8NHEDH8W27KYKE0LP53UT353S06FV3QFEGEXT0EH0YMJPF39TUVEN09SAM30G4VGPFNA3RH"
+
+	tests := []struct {
+		name  string
+		text  string
+		level Level
+	}{
+		{"short byte", "hello", Medium},
+		{"alphanumeric", "HELLO WORLD", Quartile},
+		{"address", "alice@ln.bot", Low},
+		{"invoice", invoice, Medium},
+		{"long byte", strings.Repeat("lightning ", 120), High},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode(tt.text, tt.level)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if c.Size != c.Version*4+17 {
+				t.Errorf("Size = %d for version %d", c.Size, c.Version)
+			}
+			level, text := readBack(t, c)
+			if level != tt.level {
+				t.Errorf("level = %d, want %d", level, tt.level)
+			}
+			if text != tt.text {
+				t.Errorf("decoded %q, want %q", text, tt.text)
+			}
+		})
+	}
+}
+
+func TestEncode_SmallestVersion(t *testing.T) {
+	// Version 1-M holds 14 bytes or 20 alphanumeric characters.
+	for text, want := range map[string]int{
+		strings.Repeat("a", 14): 1,
+		strings.Repeat("a", 15): 2,
+		strings.Repeat("A", 20): 1,
+		strings.Repeat("A", 21): 2,
+	} {
+		c, err := Encode(text, Medium)
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		if c.Version != want {
+			t.Errorf("len %d: version = %d, want %d", len(text), c.Version, want)
+		}
+	}
+}
+
+func TestEncode_TooLong(t *testing.T) {
+	if _, err := Encode(strings.Repeat("x", 3000), Low); err == nil {
+		t.Error("expected error for oversized input")
+	}
+}
+
+func TestCapacityTables(t *testing.T) {
+	tests := []struct {
+		ver   int
+		level Level
+		want  int
+	}{
+		{1, Low, 19}, {1, Medium, 16}, {1, Quartile, 13}, {1, High, 9},
+		{5, Quartile, 62}, {10, Medium, 216}, {40, Low, 2956}, {40, High, 1276},
+	}
+	for _, tt := range tests {
+		if got := numDataCodewords(tt.ver, tt.level); got != tt.want {
+			t.Errorf("numDataCodewords(%d, %d) = %d, want %d", tt.ver, tt.level, got, tt.want)
+		}
+	}
+	for ver := 1; ver <= 40; ver++ {
+		for l := Low; l <= High; l++ {
+			raw := numRawDataModules(ver) / 8
+			if raw/eccBlocks[l][ver] <= eccPerBlock[l][ver] {
+				t.Errorf("version %d level %d: blocks leave no room for data", ver, l)
+			}
+		}
+	}
+}
+
+func TestFormatAndVersionInfo(t *testing.T) {
+	if got := formatInfo(Low, 0); got != 0x77C4 {
+		t.Errorf("formatInfo(L, 0) = %#x, want 0x77c4", got)
+	}
+	if got := formatInfo(Medium, 0); got != 0x5412 {
+		t.Errorf("formatInfo(M, 0) = %#x, want 0x5412", got)
+	}
+	if got := versionInfo(7); got != 0x07C94 {
+		t.Errorf("versionInfo(7) = %#x, want 0x07c94", got)
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	tests := map[int][]int{
+		1:  nil,
+		2:  {6, 18},
+		7:  {6, 22, 38},
+		32: {6, 34, 60, 86, 112, 138},
+		40: {6, 30, 58, 86, 114, 142, 170},
+	}
+	for ver, want := range tests {
+		got := alignmentPositions(ver)
+		if len(got) != len(want) {
+			t.Errorf("version %d: got %v, want %v", ver, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("version %d: got %v, want %v", ver, got, want)
+				break
+			}
+		}
+	}
+}
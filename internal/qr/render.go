@@ -0,0 +1,83 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// QuietZone is the light border, in modules, required around a symbol.
+const QuietZone = 4
+
+// Terminal renders the code with Unicode half blocks, two module rows per
+// line. Colours are set explicitly with ANSI escapes (black on white) so the
+// code scans on both light and dark terminal themes. quiet is the border
+// width in modules; terminals usually get away with less than QuietZone.
+func (c *Code) Terminal(quiet int) string {
+	var b strings.Builder
+	for y := -quiet; y < c.Size+quiet; y += 2 {
+		b.WriteString("\x1b[30;107m")
+		for x := -quiet; x < c.Size+quiet; x++ {
+			top, bottom := c.Dark(x, y), c.Dark(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
+
+// Image returns the code as a grayscale image with scale pixels per module
+// and the standard quiet zone.
+func (c *Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	n := (c.Size + 2*QuietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, n, n))
+	for py := 0; py < n; py++ {
+		for px := 0; px < n; px++ {
+			v := color.Gray{Y: 0xff}
+			if c.Dark(px/scale-QuietZone, py/scale-QuietZone) {
+				v.Y = 0
+			}
+			img.SetGray(px, py, v)
+		}
+	}
+	return img
+}
+
+// WritePNG encodes the code as a PNG with scale pixels per module.
+func (c *Code) WritePNG(w io.Writer, scale int) error {
+	return png.Encode(w, c.Image(scale))
+}
+
+// SVG returns the code as a standalone SVG document, one unit per module.
+func (c *Code) SVG() string {
+	n := c.Size + 2*QuietZone
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+QuietZone, y+QuietZone)
+			}
+		}
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">
+<rect width="100%%" height="100%%" fill="#fff"/>
+<path d="%s" fill="#000"/>
+</svg>
+`, n, n, path.String())
+}
@@ -0,0 +1,72 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestTerminal(t *testing.T) {
+	c, err := Encode("alice@ln.bot", Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := c.Terminal(2)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if want := (c.Size + 4 + 1) / 2; len(lines) != want {
+		t.Errorf("got %d lines, want %d", len(lines), want)
+	}
+	// Top quiet-zone line is blank; the next one crosses the finder patterns.
+	if strings.ContainsAny(lines[0], "█▀▄") {
+		t.Errorf("first line should be quiet zone: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "█") {
+		t.Errorf("second line should contain finder modules: %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[0], "\x1b[30;107m") || !strings.HasSuffix(lines[0], "\x1b[0m") {
+		t.Errorf("lines should set and reset colours: %q", lines[0])
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	c, err := Encode("alice@ln.bot", Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.WritePNG(&buf, 4); err != nil {
+		t.Fatalf("WritePNG() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	want := (c.Size + 2*QuietZone) * 4
+	if b := img.Bounds(); b.Dx() != want || b.Dy() != want {
+		t.Errorf("image is %dx%d, want %dx%d", b.Dx(), b.Dy(), want, want)
+	}
+	// Top-left finder corner is dark, quiet zone is light.
+	if r, _, _, _ := img.At(QuietZone*4, QuietZone*4).RGBA(); r != 0 {
+		t.Error("finder corner should be dark")
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("quiet zone should be light")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, err := Encode("alice@ln.bot", Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := c.SVG()
+	n := c.Size + 2*QuietZone
+	if !strings.Contains(svg, fmt.Sprintf(`viewBox="0 0 %d %d"`, n, n)) {
+		t.Errorf("unexpected viewBox in %s", svg[:200])
+	}
+	if !strings.Contains(svg, "M4,4h1v1h-1z") {
+		t.Error("SVG should contain the top-left finder module")
+	}
+}
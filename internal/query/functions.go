@@ -0,0 +1,360 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lnbotdev/cli/internal/jsontree"
+)
+
+// functions are the built-in functions, a subset of JMESPath's. They are
+// set in init because some evaluate expressions, which call functions.
+var functions map[string]func(args []any) (any, error)
+
+func init() {
+	functions = map[string]func(args []any) (any, error){
+		"length": func(args []any) (any, error) {
+			switch v := args[0].(type) {
+			case string:
+				return numberValue(float64(len([]rune(v)))), nil
+			case []any:
+				return numberValue(float64(len(v))), nil
+			case jsontree.Object:
+				return numberValue(float64(len(v))), nil
+			}
+			return nil, typeError("length", "a string, array or object", args[0])
+		},
+		"keys": func(args []any) (any, error) {
+			o, ok := args[0].(jsontree.Object)
+			if !ok {
+				return nil, typeError("keys", "an object", args[0])
+			}
+			out := make([]any, len(o))
+			for i, f := range o {
+				out[i] = f.Key
+			}
+			return out, nil
+		},
+		"values": func(args []any) (any, error) {
+			o, ok := args[0].(jsontree.Object)
+			if !ok {
+				return nil, typeError("values", "an object", args[0])
+			}
+			out := make([]any, len(o))
+			for i, f := range o {
+				out[i] = f.Value
+			}
+			return out, nil
+		},
+		"sum": func(args []any) (any, error) {
+			nums, err := numbers("sum", args[0])
+			if err != nil {
+				return nil, err
+			}
+			total := 0.0
+			for _, n := range nums {
+				total += n
+			}
+			return numberValue(total), nil
+		},
+		"avg": func(args []any) (any, error) {
+			nums, err := numbers("avg", args[0])
+			if err != nil || len(nums) == 0 {
+				return nil, err
+			}
+			total := 0.0
+			for _, n := range nums {
+				total += n
+			}
+			return numberValue(total / float64(len(nums))), nil
+		},
+		"min": func(args []any) (any, error) { return extreme("min", args[0], nil, -1) },
+		"max": func(args []any) (any, error) { return extreme("max", args[0], nil, 1) },
+		"min_by": func(args []any) (any, error) {
+			ref, err := expref("min_by", args[1])
+			if err != nil {
+				return nil, err
+			}
+			return extreme("min_by", args[0], &ref, -1)
+		},
+		"max_by": func(args []any) (any, error) {
+			ref, err := expref("max_by", args[1])
+			if err != nil {
+				return nil, err
+			}
+			return extreme("max_by", args[0], &ref, 1)
+		},
+		"sort": func(args []any) (any, error) {
+			l, ok := args[0].([]any)
+			if !ok {
+				return nil, typeError("sort", "an array", args[0])
+			}
+			return sortValues(l, func(v any) any { return v })
+		},
+		"sort_by": func(args []any) (any, error) {
+			l, ok := args[0].([]any)
+			if !ok {
+				return nil, typeError("sort_by", "an array", args[0])
+			}
+			ref, err := expref("sort_by", args[1])
+			if err != nil {
+				return nil, err
+			}
+			var evalErr error
+			out, err := sortValues(l, func(v any) any {
+				k, err := eval(ref.n, v)
+				if err != nil {
+					evalErr = err
+				}
+				return k
+			})
+			if evalErr != nil {
+				return nil, evalErr
+			}
+			return out, err
+		},
+		"reverse": func(args []any) (any, error) {
+			switch v := args[0].(type) {
+			case string:
+				r := []rune(v)
+				for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+					r[i], r[j] = r[j], r[i]
+				}
+				return string(r), nil
+			case []any:
+				out := make([]any, len(v))
+				for i, e := range v {
+					out[len(v)-1-i] = e
+				}
+				return out, nil
+			}
+			return nil, typeError("reverse", "a string or array", args[0])
+		},
+		"map": func(args []any) (any, error) {
+			ref, err := expref("map", args[0])
+			if err != nil {
+				return nil, err
+			}
+			l, ok := args[1].([]any)
+			if !ok {
+				return nil, typeError("map", "an array", args[1])
+			}
+			out := make([]any, len(l))
+			for i, e := range l {
+				if out[i], err = eval(ref.n, e); err != nil {
+					return nil, err
+				}
+			}
+			return out, nil
+		},
+		"contains": func(args []any) (any, error) {
+			switch v := args[0].(type) {
+			case string:
+				s, ok := args[1].(string)
+				return ok && strings.Contains(v, s), nil
+			case []any:
+				for _, e := range v {
+					if equal(e, args[1]) {
+						return true, nil
+					}
+				}
+				return false, nil
+			}
+			return nil, typeError("contains", "a string or array", args[0])
+		},
+		"starts_with": func(args []any) (any, error) {
+			s, prefix, err := twoStrings("starts_with", args)
+			return err == nil && strings.HasPrefix(s, prefix), err
+		},
+		"ends_with": func(args []any) (any, error) {
+			s, suffix, err := twoStrings("ends_with", args)
+			return err == nil && strings.HasSuffix(s, suffix), err
+		},
+		"join": func(args []any) (any, error) {
+			sep, ok := args[0].(string)
+			if !ok {
+				return nil, typeError("join", "a string separator", args[0])
+			}
+			l, ok := args[1].([]any)
+			if !ok {
+				return nil, typeError("join", "an array of strings", args[1])
+			}
+			parts := make([]string, len(l))
+			for i, e := range l {
+				if parts[i], ok = e.(string); !ok {
+					return nil, typeError("join", "an array of strings", e)
+				}
+			}
+			return strings.Join(parts, sep), nil
+		},
+		"to_string": func(args []any) (any, error) {
+			if s, ok := args[0].(string); ok {
+				return s, nil
+			}
+			b, err := json.Marshal(args[0])
+			return string(b), err
+		},
+		"to_number": func(args []any) (any, error) {
+			switch v := args[0].(type) {
+			case json.Number:
+				return v, nil
+			case string:
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil, nil
+				}
+				return numberValue(f), nil
+			}
+			return nil, nil
+		},
+		"abs":   func(args []any) (any, error) { return mathFunc("abs", args[0], math.Abs) },
+		"ceil":  func(args []any) (any, error) { return mathFunc("ceil", args[0], math.Ceil) },
+		"floor": func(args []any) (any, error) { return mathFunc("floor", args[0], math.Floor) },
+		"type": func(args []any) (any, error) {
+			return typeName(args[0]), nil
+		},
+		"not_null": func(args []any) (any, error) {
+			for _, a := range args {
+				if a != nil {
+					return a, nil
+				}
+			}
+			return nil, nil
+		},
+	}
+}
+
+// arity is the number of arguments each function takes; -1 is one or
+// more.
+var arity = map[string]int{
+	"length": 1, "keys": 1, "values": 1, "sum": 1, "avg": 1, "min": 1, "max": 1,
+	"min_by": 2, "max_by": 2, "sort": 1, "sort_by": 2, "reverse": 1, "map": 2,
+	"contains": 2, "starts_with": 2, "ends_with": 2, "join": 2, "to_string": 1,
+	"to_number": 1, "abs": 1, "ceil": 1, "floor": 1, "type": 1, "not_null": -1,
+}
+
+// checkArity reports whether name is a function that takes n arguments.
+func checkArity(name string, n int) error {
+	want, ok := arity[name]
+	switch {
+	case !ok:
+		return fmt.Errorf("unknown function %s()", name)
+	case want < 0 && n == 0:
+		return fmt.Errorf("%s() takes at least 1 argument", name)
+	case want == 1 && n != 1:
+		return fmt.Errorf("%s() takes 1 argument, got %d", name, n)
+	case want >= 0 && n != want:
+		return fmt.Errorf("%s() takes %d arguments, got %d", name, want, n)
+	}
+	return nil
+}
+
+// call runs a function whose arity the parser has checked.
+func call(name string, args []any) (any, error) {
+	return functions[name](args)
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number, float64:
+		return "number"
+	case []any:
+		return "array"
+	case jsontree.Object:
+		return "object"
+	case exprRef:
+		return "expression"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func typeError(fn, want string, got any) error {
+	return fmt.Errorf("%s() expects %s, got %s", fn, want, typeName(got))
+}
+
+func expref(fn string, v any) (exprRef, error) {
+	ref, ok := v.(exprRef)
+	if !ok {
+		return exprRef{}, typeError(fn, "an &expression", v)
+	}
+	return ref, nil
+}
+
+func numbers(fn string, v any) ([]float64, error) {
+	l, ok := v.([]any)
+	if !ok {
+		return nil, typeError(fn, "an array of numbers", v)
+	}
+	out := make([]float64, len(l))
+	for i, e := range l {
+		n, ok := number(e)
+		if !ok {
+			return nil, typeError(fn, "an array of numbers", e)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+// extreme returns the smallest (sign -1) or largest (sign 1) element of
+// an array of numbers or strings, comparing ref's value when it is set.
+func extreme(fn string, v any, ref *exprRef, sign int) (any, error) {
+	l, ok := v.([]any)
+	if !ok {
+		return nil, typeError(fn, "an array", v)
+	}
+	key := func(e any) any { return e }
+	var evalErr error
+	if ref != nil {
+		key = func(e any) any {
+			k, err := eval(ref.n, e)
+			if err != nil {
+				evalErr = err
+			}
+			return k
+		}
+	}
+	sorted, err := sortValues(l, key)
+	if evalErr != nil {
+		return nil, evalErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", fn, err)
+	}
+	if len(sorted) == 0 {
+		return nil, nil
+	}
+	if sign < 0 {
+		return sorted[0], nil
+	}
+	return sorted[len(sorted)-1], nil
+}
+
+func twoStrings(fn string, args []any) (string, string, error) {
+	a, ok := args[0].(string)
+	if !ok {
+		return "", "", typeError(fn, "strings", args[0])
+	}
+	b, ok := args[1].(string)
+	if !ok {
+		return "", "", typeError(fn, "strings", args[1])
+	}
+	return a, b, nil
+}
+
+func mathFunc(fn string, v any, f func(float64) float64) (any, error) {
+	n, ok := number(v)
+	if !ok {
+		return nil, typeError(fn, "a number", v)
+	}
+	return numberValue(f(n)), nil
+}
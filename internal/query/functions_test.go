@@ -0,0 +1,63 @@
+package query
+
+import "testing"
+
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		expr, doc, want string
+	}{
+		{"length(name)", `{"name": "café"}`, `4`},
+		{"length(@)", `{"a": 1, "b": 2}`, `2`},
+		{"keys(@)", `{"b": 1, "a": 2}`, `["b","a"]`},
+		{"values(@)", `{"b": 1, "a": 2}`, `[1,2]`},
+		{"sum(@)", `[1, 2.5]`, `3.5`},
+		{"avg(@)", `[1, 2]`, `1.5`},
+		{"avg(@)", `[]`, `null`},
+		{"min(@)", `[3, 1, 2]`, `1`},
+		{"max(@)", `["b", "c", "a"]`, `"c"`},
+		{"max_by(@, &amount).n", `[{"n": 1, "amount": 5}, {"n": 2, "amount": 9}]`, `2`},
+		{"min_by(@, &amount).n", `[{"n": 1, "amount": 5}, {"n": 2, "amount": 9}]`, `1`},
+		{"sort(@)", `[3, 1, 2]`, `[1,2,3]`},
+		{"sort_by(@, &amount)[*].n", `[{"n": 1, "amount": 5}, {"n": 2, "amount": 1}]`, `[2,1]`},
+		{"reverse(@)", `[1, 2]`, `[2,1]`},
+		{"reverse(@)", `"abc"`, `"cba"`},
+		{"map(&n, @)", `[{"n": 1}, {}]`, `[1,null]`},
+		{"contains(@, 'ln.bot')", `"alice@ln.bot"`, `true`},
+		{"contains(@, `2`)", `[1, 2]`, `true`},
+		{"starts_with(@, 'lnbc')", `"lnbc10u1..."`, `true`},
+		{"ends_with(@, '.bot')", `"ln.bot"`, `true`},
+		{"join(', ', @)", `["a", "b"]`, `"a, b"`},
+		{"to_string(@)", `[1]`, `"[1]"`},
+		{"to_number(@)", `"21.5"`, `21.5`},
+		{"to_number(@)", `"nope"`, `null`},
+		{"abs(@)", `-3`, `3`},
+		{"ceil(@)", `1.2`, `2`},
+		{"floor(@)", `1.8`, `1`},
+		{"type(@)", `{}`, `"object"`},
+		{"not_null(a, b, `0`)", `{"b": "x"}`, `"x"`},
+	}
+	for _, tt := range tests {
+		if got := search(t, tt.expr, tt.doc); got != tt.want {
+			t.Errorf("%s on %s = %s, want %s", tt.expr, tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestFunctions_TypeErrors(t *testing.T) {
+	for _, tt := range []struct{ expr, doc string }{
+		{"length(@)", `1`},
+		{"keys(@)", `[]`},
+		{"sort(@)", `[1, "a"]`},
+		{"join(',', @)", `[1]`},
+		{"sort_by(@, 'amount')", `[]`},
+		{"abs(@)", `"x"`},
+	} {
+		q, err := Compile(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := q.Search(mustDecode(t, tt.doc)); err == nil {
+			t.Errorf("%s on %s should fail", tt.expr, tt.doc)
+		}
+	}
+}
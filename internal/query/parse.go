@@ -0,0 +1,558 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lnbotdev/cli/internal/jsontree"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tIdent
+	tQuoted
+	tRawString
+	tLiteral
+	tNumber
+	tDot
+	tStar
+	tLbracket
+	tRbracket
+	tFilter
+	tFlatten
+	tLbrace
+	tRbrace
+	tLparen
+	tRparen
+	tComma
+	tColon
+	tPipe
+	tOr
+	tAnd
+	tNot
+	tEQ
+	tNE
+	tLT
+	tLE
+	tGT
+	tGE
+	tCurrent
+	tExpref
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value any
+	pos   int
+}
+
+// bindingPowers orders the operators from loosest to tightest, as in the
+// JMESPath reference parser. Tokens below 10 end a projection.
+var bindingPowers = map[tokenKind]int{
+	tPipe:     1,
+	tOr:       2,
+	tAnd:      3,
+	tEQ:       5,
+	tNE:       5,
+	tLT:       5,
+	tLE:       5,
+	tGT:       5,
+	tGE:       5,
+	tFlatten:  9,
+	tStar:     20,
+	tFilter:   21,
+	tDot:      40,
+	tNot:      45,
+	tLbrace:   50,
+	tLbracket: 55,
+	tLparen:   60,
+}
+
+type syntaxError struct {
+	msg string
+	pos int
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.msg, e.pos+1)
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	simple := map[byte]tokenKind{'.': tDot, '*': tStar, ']': tRbracket, '{': tLbrace, '}': tRbrace, '(': tLparen, ')': tRparen, ',': tComma, ':': tColon, '@': tCurrent}
+	for i := 0; i < len(s); {
+		c := s[i]
+		start := i
+		two := ""
+		if i+1 < len(s) {
+			two = s[i : i+2]
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case isIdentStart(c):
+			for i < len(s) && (isIdentStart(s[i]) || (s[i] >= '0' && s[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tIdent, text: s[start:i], pos: start})
+			continue
+		case c == '-' || (c >= '0' && c <= '9'):
+			i++
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			n, err := strconv.Atoi(s[start:i])
+			if err != nil {
+				return nil, &syntaxError{fmt.Sprintf("invalid number %q", s[start:i]), start}
+			}
+			tokens = append(tokens, token{kind: tNumber, text: s[start:i], value: n, pos: start})
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			end := closing(s, i)
+			if end < 0 {
+				return nil, &syntaxError{fmt.Sprintf("unterminated %c", c), start}
+			}
+			body := s[i+1 : end]
+			i = end + 1
+			t := token{text: s[start:i], pos: start}
+			switch c {
+			case '"':
+				var v string
+				if err := json.Unmarshal([]byte(s[start:i]), &v); err != nil {
+					return nil, &syntaxError{"invalid quoted identifier", start}
+				}
+				t.kind, t.value = tQuoted, v
+			case '\'':
+				t.kind, t.value = tRawString, strings.ReplaceAll(body, `\'`, `'`)
+			case '`':
+				// A literal that isn't valid JSON is a string, as in
+				// JMESPath: `debit` is the same as `"debit"`.
+				body = strings.ReplaceAll(body, "\\`", "`")
+				var v any = body
+				if parsed, err := jsontree.Decode([]byte(body)); err == nil {
+					v = parsed
+				}
+				t.kind, t.value = tLiteral, v
+			}
+			tokens = append(tokens, t)
+			continue
+		case two == "[?":
+			tokens = append(tokens, token{kind: tFilter, text: two, pos: start})
+		case two == "[]":
+			tokens = append(tokens, token{kind: tFlatten, text: two, pos: start})
+		case two == "||":
+			tokens = append(tokens, token{kind: tOr, text: two, pos: start})
+		case two == "&&":
+			tokens = append(tokens, token{kind: tAnd, text: two, pos: start})
+		case two == "==":
+			tokens = append(tokens, token{kind: tEQ, text: two, pos: start})
+		case two == "!=":
+			tokens = append(tokens, token{kind: tNE, text: two, pos: start})
+		case two == "<=":
+			tokens = append(tokens, token{kind: tLE, text: two, pos: start})
+		case two == ">=":
+			tokens = append(tokens, token{kind: tGE, text: two, pos: start})
+		default:
+			kind, ok := simple[c]
+			switch {
+			case ok:
+			case c == '[':
+				kind = tLbracket
+			case c == '|':
+				kind = tPipe
+			case c == '&':
+				kind = tExpref
+			case c == '!':
+				kind = tNot
+			case c == '<':
+				kind = tLT
+			case c == '>':
+				kind = tGT
+			default:
+				return nil, &syntaxError{fmt.Sprintf("unexpected character %q", c), start}
+			}
+			tokens = append(tokens, token{kind: kind, text: s[i : i+1], pos: start})
+			i++
+			continue
+		}
+		i += 2
+	}
+	return append(tokens, token{kind: tEOF, pos: len(s)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// closing returns the index of the quote that closes the one at s[i],
+// skipping backslash escapes, or -1.
+func closing(s string, i int) int {
+	q := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case q:
+			return j
+		}
+	}
+	return -1
+}
+
+type nodeKind int
+
+const (
+	nCurrent nodeKind = iota
+	nField
+	nLiteral
+	nIndex
+	nSlice
+	nSubexpr
+	nProjection
+	nValueProjection
+	nFilterProjection
+	nFlatten
+	nPipe
+	nOr
+	nAnd
+	nNot
+	nCompare
+	nMultiList
+	nMultiHash
+	nFunction
+	nExpref
+)
+
+type node struct {
+	kind     nodeKind
+	value    any
+	children []node
+}
+
+var current = node{kind: nCurrent}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func parse(s string) (node, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return node{}, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.expression(0)
+	if err != nil {
+		return node{}, err
+	}
+	if t := p.peek(); t.kind != tEOF {
+		return node{}, p.unexpected(t)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+
+func (p *parser) peekAt(n int) token {
+	if p.i+n < len(p.tokens) {
+		return p.tokens[p.i+n]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, what string) error {
+	if t := p.next(); t.kind != kind {
+		if t.kind == tEOF {
+			return &syntaxError{"expected " + what, t.pos}
+		}
+		return &syntaxError{fmt.Sprintf("expected %s, found %q", what, t.text), t.pos}
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tEOF {
+		return &syntaxError{"unexpected end of query", t.pos}
+	}
+	return &syntaxError{fmt.Sprintf("unexpected %q", t.text), t.pos}
+}
+
+func (p *parser) expression(rbp int) (node, error) {
+	left, err := p.nud(p.next())
+	if err != nil {
+		return node{}, err
+	}
+	for rbp < bindingPowers[p.peek().kind] {
+		if left, err = p.led(p.next(), left); err != nil {
+			return node{}, err
+		}
+	}
+	return left, nil
+}
+
+// nud parses an expression that starts with t.
+func (p *parser) nud(t token) (node, error) {
+	switch t.kind {
+	case tLiteral, tRawString:
+		return node{kind: nLiteral, value: t.value}, nil
+	case tIdent:
+		return node{kind: nField, value: t.text}, nil
+	case tQuoted:
+		if p.peek().kind == tLparen {
+			return node{}, &syntaxError{"a quoted identifier can't be a function name", t.pos}
+		}
+		return node{kind: nField, value: t.value}, nil
+	case tCurrent:
+		return current, nil
+	case tStar:
+		right := current
+		if p.peek().kind != tRbracket {
+			var err error
+			if right, err = p.projectionRHS(bindingPowers[tStar]); err != nil {
+				return node{}, err
+			}
+		}
+		return node{kind: nValueProjection, children: []node{current, right}}, nil
+	case tFilter:
+		return p.filter(current)
+	case tFlatten:
+		return p.flatten(current)
+	case tLbrace:
+		return p.multiHash()
+	case tLbracket:
+		switch p.peek().kind {
+		case tNumber, tColon:
+			return p.index(current)
+		case tStar:
+			if p.peekAt(1).kind == tRbracket {
+				p.next()
+				p.next()
+				return p.project(current)
+			}
+		}
+		return p.multiList()
+	case tExpref:
+		e, err := p.expression(bindingPowers[tExpref])
+		return node{kind: nExpref, children: []node{e}}, err
+	case tNot:
+		e, err := p.expression(bindingPowers[tNot])
+		return node{kind: nNot, children: []node{e}}, err
+	case tLparen:
+		e, err := p.expression(0)
+		if err != nil {
+			return node{}, err
+		}
+		return e, p.expect(tRparen, `")"`)
+	}
+	return node{}, p.unexpected(t)
+}
+
+// led parses the rest of an expression that continues left with t.
+func (p *parser) led(t token, left node) (node, error) {
+	switch t.kind {
+	case tDot:
+		if p.peek().kind == tStar {
+			p.next()
+			right, err := p.projectionRHS(bindingPowers[tDot])
+			return node{kind: nValueProjection, children: []node{left, right}}, err
+		}
+		right, err := p.dotRHS(bindingPowers[tDot])
+		return node{kind: nSubexpr, children: []node{left, right}}, err
+	case tPipe, tOr, tAnd:
+		right, err := p.expression(bindingPowers[t.kind])
+		kind := map[tokenKind]nodeKind{tPipe: nPipe, tOr: nOr, tAnd: nAnd}[t.kind]
+		return node{kind: kind, children: []node{left, right}}, err
+	case tEQ, tNE, tLT, tLE, tGT, tGE:
+		right, err := p.expression(bindingPowers[t.kind])
+		return node{kind: nCompare, value: t.kind, children: []node{left, right}}, err
+	case tLparen:
+		if left.kind != nField {
+			return node{}, p.unexpected(t)
+		}
+		var args []node
+		for p.peek().kind != tRparen {
+			arg, err := p.expression(0)
+			if err != nil {
+				return node{}, err
+			}
+			args = append(args, arg)
+			if p.peek().kind == tComma {
+				p.next()
+			} else if p.peek().kind != tRparen {
+				return node{}, p.unexpected(p.peek())
+			}
+		}
+		p.next()
+		if err := checkArity(left.value.(string), len(args)); err != nil {
+			return node{}, &syntaxError{err.Error(), t.pos}
+		}
+		return node{kind: nFunction, value: left.value, children: args}, nil
+	case tFilter:
+		return p.filter(left)
+	case tFlatten:
+		return p.flatten(left)
+	case tLbracket:
+		if k := p.peek().kind; k == tNumber || k == tColon {
+			return p.index(left)
+		}
+		if err := p.expect(tStar, `"*", a number or a slice`); err != nil {
+			return node{}, err
+		}
+		if err := p.expect(tRbracket, `"]"`); err != nil {
+			return node{}, err
+		}
+		return p.project(left)
+	}
+	return node{}, p.unexpected(t)
+}
+
+// project parses the right side of a list projection such as [*].
+func (p *parser) project(left node) (node, error) {
+	right, err := p.projectionRHS(bindingPowers[tStar])
+	return node{kind: nProjection, children: []node{left, right}}, err
+}
+
+func (p *parser) flatten(left node) (node, error) {
+	right, err := p.projectionRHS(bindingPowers[tFlatten])
+	return node{kind: nProjection, children: []node{{kind: nFlatten, children: []node{left}}, right}}, err
+}
+
+// filter parses [?condition] after its opening token.
+func (p *parser) filter(left node) (node, error) {
+	cond, err := p.expression(0)
+	if err != nil {
+		return node{}, err
+	}
+	if err := p.expect(tRbracket, `"]"`); err != nil {
+		return node{}, err
+	}
+	right := current
+	if p.peek().kind != tFlatten {
+		if right, err = p.projectionRHS(bindingPowers[tFilter]); err != nil {
+			return node{}, err
+		}
+	}
+	return node{kind: nFilterProjection, children: []node{left, right, cond}}, nil
+}
+
+// index parses [n] or a slice [start:stop:step] after the "[".
+func (p *parser) index(left node) (node, error) {
+	var parts [3]*int
+	n := 0
+	for p.peek().kind != tRbracket {
+		switch t := p.next(); t.kind {
+		case tColon:
+			if n++; n > 2 {
+				return node{}, &syntaxError{"too many colons in slice", t.pos}
+			}
+		case tNumber:
+			if parts[n] != nil {
+				return node{}, p.unexpected(t)
+			}
+			v := t.value.(int)
+			parts[n] = &v
+		default:
+			return node{}, p.unexpected(t)
+		}
+	}
+	p.next()
+	if n == 0 {
+		idx := node{kind: nIndex, value: *parts[0]}
+		return node{kind: nSubexpr, children: []node{left, idx}}, nil
+	}
+	slice := node{kind: nSlice, value: parts}
+	return p.project(node{kind: nSubexpr, children: []node{left, slice}})
+}
+
+// projectionRHS parses what a projection applies to each element.
+func (p *parser) projectionRHS(bp int) (node, error) {
+	switch k := p.peek().kind; {
+	case bindingPowers[k] < 10:
+		return current, nil
+	case k == tLbracket || k == tFilter:
+		return p.expression(bp)
+	case k == tDot:
+		p.next()
+		return p.dotRHS(bp)
+	}
+	return node{}, p.unexpected(p.peek())
+}
+
+// dotRHS parses what follows a ".".
+func (p *parser) dotRHS(bp int) (node, error) {
+	switch p.peek().kind {
+	case tIdent, tQuoted, tStar:
+		return p.expression(bp)
+	case tLbracket:
+		p.next()
+		return p.multiList()
+	case tLbrace:
+		p.next()
+		return p.multiHash()
+	}
+	return node{}, p.unexpected(p.peek())
+}
+
+// multiList parses [a, b] after the "[".
+func (p *parser) multiList() (node, error) {
+	var elems []node
+	for {
+		e, err := p.expression(0)
+		if err != nil {
+			return node{}, err
+		}
+		elems = append(elems, e)
+		if t := p.next(); t.kind == tRbracket {
+			break
+		} else if t.kind != tComma {
+			return node{}, p.unexpected(t)
+		}
+	}
+	return node{kind: nMultiList, children: elems}, nil
+}
+
+// multiHash parses {key: a, other: b} after the "{".
+func (p *parser) multiHash() (node, error) {
+	var keys []string
+	var values []node
+	for {
+		k := p.next()
+		switch k.kind {
+		case tIdent:
+			keys = append(keys, k.text)
+		case tQuoted:
+			keys = append(keys, k.value.(string))
+		default:
+			return node{}, p.unexpected(k)
+		}
+		if err := p.expect(tColon, `":"`); err != nil {
+			return node{}, err
+		}
+		v, err := p.expression(0)
+		if err != nil {
+			return node{}, err
+		}
+		values = append(values, v)
+		if t := p.next(); t.kind == tRbrace {
+			break
+		} else if t.kind != tComma {
+			return node{}, p.unexpected(t)
+		}
+	}
+	return node{kind: nMultiHash, value: keys, children: values}, nil
+}
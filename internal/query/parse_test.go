@@ -0,0 +1,55 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{"", "unexpected end of query at position 1"},
+		{"a.", "unexpected end of query"},
+		{"a[?b == 'c'", `expected "]"`},
+		{"a b", `unexpected "b" at position 3`},
+		{"'open", "unterminated '"},
+		{"`open", "unterminated `"},
+		{"a = b", `unexpected character '='`},
+		{"[1:2:3:4]", "too many colons"},
+		{`"f"(@)`, "quoted identifier can't be a function name"},
+		{"nope(@)", "unknown function nope()"},
+		{"length(a, b)", "length() takes 1 argument, got 2"},
+		{"{1: a}", `unexpected "1"`},
+		{"a.[b", `unexpected end of query`},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestLex(t *testing.T) {
+	tokens, err := lex("a[?b>=`1`] || 'it\\'s' && !\"c d\"")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []tokenKind
+	for _, tok := range tokens {
+		kinds = append(kinds, tok.kind)
+	}
+	want := []tokenKind{tIdent, tFilter, tIdent, tGE, tLiteral, tRbracket, tOr, tRawString, tAnd, tNot, tQuoted, tEOF}
+	if len(kinds) != len(want) {
+		t.Fatalf("kinds = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("kinds = %v, want %v", kinds, want)
+		}
+	}
+	if tokens[7].value != "it's" || tokens[10].value != "c d" {
+		t.Errorf("values = %v, %v", tokens[7].value, tokens[10].value)
+	}
+}
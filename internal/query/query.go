@@ -0,0 +1,419 @@
+// Package query evaluates --query expressions against a command's JSON
+// result. The language is the core of JMESPath (https://jmespath.org):
+//
+//	available                     a field
+//	wallet.name, "odd key"        nested and quoted fields
+//	[0], [-1], [2:5], [::-1]      indexes and slices
+//	[*].amount, *.amount, []      list, object and flatten projections
+//	[?type=='debit' && amount > `1000`]
+//	                              filters
+//	[number, status], {n: number} multi-selects
+//	a || b, a && b, !a            logic
+//	length(@), sort_by(@, &amount)
+//	                              functions
+//	[*].amount | sum(@)           pipes
+//
+// Raw strings are in single quotes and JSON literals in backticks; a
+// backtick literal that isn't valid JSON is a string.
+// Strings compare by their text with < and >, so RFC 3339 times can be
+// compared too.
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/lnbotdev/cli/internal/jsontree"
+)
+
+// Query is a compiled expression.
+type Query struct {
+	root node
+}
+
+// Compile parses an expression.
+func Compile(expr string) (*Query, error) {
+	n, err := parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --query: %w", err)
+	}
+	return &Query{root: n}, nil
+}
+
+// Search evaluates the query against data, a tree from package jsontree.
+func (q *Query) Search(data any) (any, error) {
+	v, err := eval(q.root, data)
+	if err != nil {
+		return nil, fmt.Errorf("--query: %w", err)
+	}
+	return v, nil
+}
+
+// exprRef is the value of an &expression passed to a function.
+type exprRef struct {
+	n node
+}
+
+func eval(n node, v any) (any, error) {
+	switch n.kind {
+	case nCurrent:
+		return v, nil
+	case nLiteral:
+		return n.value, nil
+	case nField:
+		if o, ok := v.(jsontree.Object); ok {
+			val, _ := o.Get(n.value.(string))
+			return val, nil
+		}
+		return nil, nil
+	case nIndex:
+		l, ok := v.([]any)
+		if !ok {
+			return nil, nil
+		}
+		i := n.value.(int)
+		if i < 0 {
+			i += len(l)
+		}
+		if i < 0 || i >= len(l) {
+			return nil, nil
+		}
+		return l[i], nil
+	case nSlice:
+		l, ok := v.([]any)
+		if !ok {
+			return nil, nil
+		}
+		return slice(l, n.value.([3]*int))
+	case nSubexpr, nPipe:
+		left, err := eval(n.children[0], v)
+		if err != nil {
+			return nil, err
+		}
+		return eval(n.children[1], left)
+	case nProjection, nValueProjection, nFilterProjection:
+		return project(n, v)
+	case nFlatten:
+		left, err := eval(n.children[0], v)
+		if err != nil {
+			return nil, err
+		}
+		l, ok := left.([]any)
+		if !ok {
+			return nil, nil
+		}
+		out := []any{}
+		for _, e := range l {
+			if inner, ok := e.([]any); ok {
+				out = append(out, inner...)
+			} else {
+				out = append(out, e)
+			}
+		}
+		return out, nil
+	case nOr, nAnd:
+		left, err := eval(n.children[0], v)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(left) == (n.kind == nOr) {
+			return left, nil
+		}
+		return eval(n.children[1], v)
+	case nNot:
+		val, err := eval(n.children[0], v)
+		return !truthy(val), err
+	case nCompare:
+		left, err := eval(n.children[0], v)
+		if err != nil {
+			return nil, err
+		}
+		right, err := eval(n.children[1], v)
+		if err != nil {
+			return nil, err
+		}
+		return compare(n.value.(tokenKind), left, right), nil
+	case nMultiList:
+		if v == nil {
+			return nil, nil
+		}
+		out := make([]any, len(n.children))
+		for i, c := range n.children {
+			val, err := eval(c, v)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = val
+		}
+		return out, nil
+	case nMultiHash:
+		if v == nil {
+			return nil, nil
+		}
+		keys := n.value.([]string)
+		out := make(jsontree.Object, len(keys))
+		for i, c := range n.children {
+			val, err := eval(c, v)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = jsontree.Field{Key: keys[i], Value: val}
+		}
+		return out, nil
+	case nExpref:
+		return exprRef{n.children[0]}, nil
+	case nFunction:
+		args := make([]any, len(n.children))
+		for i, c := range n.children {
+			val, err := eval(c, v)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = val
+		}
+		return call(n.value.(string), args)
+	}
+	return nil, fmt.Errorf("unknown expression")
+}
+
+// project applies a projection's right side to each element of its left
+// side, dropping null results. Filter projections skip the elements their
+// condition is false for.
+func project(n node, v any) (any, error) {
+	left, err := eval(n.children[0], v)
+	if err != nil {
+		return nil, err
+	}
+	var elems []any
+	switch n.kind {
+	case nValueProjection:
+		o, ok := left.(jsontree.Object)
+		if !ok {
+			return nil, nil
+		}
+		for _, f := range o {
+			elems = append(elems, f.Value)
+		}
+	default:
+		l, ok := left.([]any)
+		if !ok {
+			return nil, nil
+		}
+		elems = l
+	}
+
+	out := []any{}
+	for _, e := range elems {
+		if n.kind == nFilterProjection {
+			cond, err := eval(n.children[2], e)
+			if err != nil {
+				return nil, err
+			}
+			if !truthy(cond) {
+				continue
+			}
+		}
+		val, err := eval(n.children[1], e)
+		if err != nil {
+			return nil, err
+		}
+		if val != nil {
+			out = append(out, val)
+		}
+	}
+	return out, nil
+}
+
+func slice(l []any, parts [3]*int) (any, error) {
+	step := 1
+	if parts[2] != nil {
+		step = *parts[2]
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("slice step can't be 0")
+	}
+	bound := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		i := *p
+		if i < 0 {
+			i += len(l)
+		}
+		if step > 0 {
+			return min(max(i, 0), len(l))
+		}
+		return min(max(i, -1), len(l)-1)
+	}
+	var start, stop int
+	if step > 0 {
+		start, stop = bound(parts[0], 0), bound(parts[1], len(l))
+	} else {
+		start, stop = bound(parts[0], len(l)-1), bound(parts[1], -1)
+	}
+	out := []any{}
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		out = append(out, l[i])
+	}
+	return out, nil
+}
+
+// truthy is JMESPath's truth: false, null and empty strings, lists and
+// objects are false.
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case jsontree.Object:
+		return len(v) > 0
+	}
+	return true
+}
+
+func compare(op tokenKind, a, b any) any {
+	switch op {
+	case tEQ:
+		return equal(a, b)
+	case tNE:
+		return !equal(a, b)
+	}
+	var c int
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		if !ok {
+			return nil
+		}
+		c = cmpFloat(x, y)
+	} else if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		if !ok {
+			return nil
+		}
+		c = cmpString(x, y)
+	} else {
+		return nil
+	}
+	switch op {
+	case tLT:
+		return c < 0
+	case tLE:
+		return c <= 0
+	case tGT:
+		return c > 0
+	}
+	return c >= 0
+}
+
+func cmpFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func cmpString(x, y string) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func equal(a, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	switch a := a.(type) {
+	case []any:
+		l, ok := b.([]any)
+		if !ok || len(a) != len(l) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], l[i]) {
+				return false
+			}
+		}
+		return true
+	case jsontree.Object:
+		o, ok := b.(jsontree.Object)
+		if !ok || len(a) != len(o) {
+			return false
+		}
+		for _, f := range a {
+			v, ok := o.Get(f.Key)
+			if !ok || !equal(f.Value, v) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// number returns the value of a JSON number.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// numberValue makes a JSON number of f, written without an exponent or a
+// trailing ".0".
+func numberValue(f float64) any {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil
+	}
+	return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// sortValues sorts numbers or strings, which must all be of one kind.
+func sortValues(l []any, key func(any) any) ([]any, error) {
+	keys := make([]any, len(l))
+	kind := ""
+	for i, e := range l {
+		k := key(e)
+		var kk string
+		if _, ok := number(k); ok {
+			kk = "number"
+		} else if _, ok := k.(string); ok {
+			kk = "string"
+		} else {
+			return nil, fmt.Errorf("can only sort numbers or strings, got %s", typeName(k))
+		}
+		if kind != "" && kk != kind {
+			return nil, fmt.Errorf("can't sort a mix of numbers and strings")
+		}
+		kind, keys[i] = kk, k
+	}
+	idx := make([]int, len(l))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return compare(tLT, keys[idx[i]], keys[idx[j]]) == true })
+	out := make([]any, len(l))
+	for i, j := range idx {
+		out[i] = l[j]
+	}
+	return out, nil
+}
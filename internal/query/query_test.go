@@ -0,0 +1,113 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lnbotdev/cli/internal/jsontree"
+)
+
+const txs = `[
+  {"number": 3, "type": "credit", "amount": 700, "note": "march rent", "createdAt": "2026-10-03T10:00:00Z"},
+  {"number": 2, "type": "debit", "amount": 100, "note": null, "createdAt": "2026-10-02T10:00:00Z"},
+  {"number": 1, "type": "debit", "amount": 2500, "createdAt": "2026-09-30T10:00:00Z"}
+]`
+
+const wallet = `{"walletId": "wal_1", "balance": 10599, "available": 10500, "limits": {"daily": 5000}, "tags": [["a", "b"], ["c"]]}`
+
+func mustDecode(t *testing.T, doc string) any {
+	t.Helper()
+	data, err := jsontree.Decode([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func search(t *testing.T, expr, doc string) string {
+	t.Helper()
+	data := mustDecode(t, doc)
+	q, err := Compile(expr)
+	if err != nil {
+		t.Fatalf("Compile(%q): %v", expr, err)
+	}
+	v, err := q.Search(data)
+	if err != nil {
+		t.Fatalf("Search(%q): %v", expr, err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		expr, doc, want string
+	}{
+		{"available", wallet, `10500`},
+		{"limits.daily", wallet, `5000`},
+		{`"walletId"`, wallet, `"wal_1"`},
+		{"missing.field", wallet, `null`},
+		{"tags[0][1]", wallet, `"b"`},
+		{"tags[]", wallet, `["a","b","c"]`},
+		{"{id: walletId, free: available}", wallet, `{"id":"wal_1","free":10500}`},
+		{"[walletId, balance]", wallet, `["wal_1",10599]`},
+		{"*", `{"b": 1, "a": 2}`, `[1,2]`},
+		{"[0].number", txs, `3`},
+		{"[-1].number", txs, `1`},
+		{"[*].amount", txs, `[700,100,2500]`},
+		{"[*].note", txs, `["march rent"]`},
+		{"[1:].number", txs, `[2,1]`},
+		{"[::-1].number", txs, `[1,2,3]`},
+		{"[:2]", `[1,2,3]`, `[1,2]`},
+		{"[?type=='debit'].amount", txs, `[100,2500]`},
+		{"[?type==`\"debit\"`].amount", txs, `[100,2500]`},
+		{"[?amount > `500`].number", txs, `[3,1]`},
+		{"[?amount >= `700` && type == 'credit'].number", txs, `[3]`},
+		{"[?type == 'credit' || amount < `200`].number", txs, `[3,2]`},
+		{"[?!note].number", txs, `[2,1]`},
+		{"[?createdAt < '2026-10-01'].number", txs, `[1]`},
+		{"[?type=='debit'] | [0].number", txs, `2`},
+		{"[*].[number, type]", txs, `[[3,"credit"],[2,"debit"],[1,"debit"]]`},
+		{"[*].{n: number}", txs, `[{"n":3},{"n":2},{"n":1}]`},
+		{"[?type=='debit'].amount | sum(@)", txs, `2600`},
+		{"length(@)", txs, `3`},
+		{"@", `"x"`, `"x"`},
+		{"a || b", `{"a": [], "b": "fallback"}`, `"fallback"`},
+		{"a && b", `{"a": 0, "b": "both"}`, `"both"`},
+		{"amount == `700.0`", `{"amount": 700}`, `true`},
+		{"a == b", `{"a": {"x": [1]}, "b": {"x": [1]}}`, `true`},
+		{"a < b", `{"a": "x", "b": 1}`, `null`},
+		{"foo[*].bar", `{"foo": {"bar": 1}}`, `null`},
+	}
+	for _, tt := range tests {
+		if got := search(t, tt.expr, tt.doc); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestSearch_BareLiteral(t *testing.T) {
+	if got := search(t, "[?type==`debit`].amount", txs); got != `[100,2500]` {
+		t.Errorf("amounts = %s, want [100,2500]", got)
+	}
+	if got := search(t, "`{nope}`", `{}`); got != `"{nope}"` {
+		t.Errorf("invalid JSON literal = %s, want a string", got)
+	}
+}
+
+func TestSearch_Errors(t *testing.T) {
+	data, _ := jsontree.Decode([]byte(txs))
+	for _, expr := range []string{"[::0]", "sum([*].type)", "sort_by(@, &note)"} {
+		q, err := Compile(expr)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", expr, err)
+			continue
+		}
+		if _, err := q.Search(data); err == nil {
+			t.Errorf("Search(%q) should fail", expr)
+		}
+	}
+}
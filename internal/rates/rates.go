@@ -0,0 +1,218 @@
+// Package rates looks up bitcoin exchange rates for converting fiat
+// amounts, from a local file or a configurable URL.
+//
+// Both hold a JSON object of BTC prices by currency code, either at the
+// top level or under "rates" (or "data.rates"); prices may be numbers or
+// strings:
+//
+//	{"USD": 65000.5, "EUR": "60012.10"}
+//
+// Quotes fetched from a URL are cached on disk, so that a run of commands
+// doesn't fetch the same quote over and over.
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTTL is how long a fetched quote is used before fetching again.
+const DefaultTTL = 10 * time.Minute
+
+// ErrNoSource is returned when neither a rates file nor a URL is set.
+var ErrNoSource = errors.New("no exchange rate source for fiat amounts — set LNBOT_RATES_FILE or LNBOT_RATES_URL, or run 'lnbot config set rates-url <url>'")
+
+// Quote is a set of BTC prices.
+type Quote struct {
+	Source    string             `json:"source"`
+	FetchedAt time.Time          `json:"fetched_at"`
+	Rates     map[string]float64 `json:"rates"`
+}
+
+// Source finds exchange rates. File takes precedence over URL.
+type Source struct {
+	File string
+	URL  string
+	// CachePath is where quotes fetched from URL are kept ("" for none).
+	CachePath string
+	// TTL is how long a cached quote is fresh (default DefaultTTL).
+	TTL    time.Duration
+	Client *http.Client
+
+	quote *Quote
+}
+
+// Price returns the price of 1 BTC in currency.
+func (s *Source) Price(currency string) (float64, error) {
+	q, err := s.Quote()
+	if err != nil {
+		return 0, err
+	}
+	price, ok := q.Rates[strings.ToUpper(currency)]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s in %s", strings.ToUpper(currency), q.Source)
+	}
+	return price, nil
+}
+
+// Quote returns the current quote. A quote fetched from the URL is reused
+// while it is fresh, and a stale one is used if fetching fails.
+func (s *Source) Quote() (*Quote, error) {
+	if s.quote != nil {
+		return s.quote, nil
+	}
+	switch {
+	case s.File != "":
+		data, err := os.ReadFile(s.File)
+		if err != nil {
+			return nil, fmt.Errorf("reading rates file: %w", err)
+		}
+		rates, err := ParseRates(data)
+		if err != nil {
+			return nil, fmt.Errorf("rates file %s: %w", s.File, err)
+		}
+		info, _ := os.Stat(s.File)
+		s.quote = &Quote{Source: s.File, FetchedAt: info.ModTime().UTC(), Rates: rates}
+		return s.quote, nil
+	case s.URL == "":
+		return nil, ErrNoSource
+	}
+
+	cached := s.cached()
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	if cached != nil && time.Since(cached.FetchedAt) < ttl {
+		s.quote = cached
+		return cached, nil
+	}
+
+	q, err := s.fetch()
+	if err != nil {
+		if cached != nil {
+			s.quote = cached
+			return cached, nil
+		}
+		return nil, err
+	}
+	s.quote = q
+	s.save(q)
+	return q, nil
+}
+
+func (s *Source) fetch() (*Quote, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rates URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("fetching exchange rates: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching exchange rates: %s", resp.Status)
+	}
+	rates, err := ParseRates(data)
+	if err != nil {
+		return nil, fmt.Errorf("exchange rates from %s: %w", s.URL, err)
+	}
+	return &Quote{Source: s.URL, FetchedAt: time.Now().UTC(), Rates: rates}, nil
+}
+
+// cached returns the cached quote for the URL, or nil.
+func (s *Source) cached() *Quote {
+	if s.CachePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.CachePath)
+	if err != nil {
+		return nil
+	}
+	var q Quote
+	if json.Unmarshal(data, &q) != nil || q.Source != s.URL {
+		return nil
+	}
+	return &q
+}
+
+// save caches q. Failing to cache is not an error.
+func (s *Source) save(q *Quote) {
+	if s.CachePath == "" {
+		return
+	}
+	data, err := json.Marshal(q)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(s.CachePath), 0o700) == nil {
+		os.WriteFile(s.CachePath, data, 0o600)
+	}
+}
+
+// ParseRates reads BTC prices keyed by currency code.
+func ParseRates(data []byte) (map[string]float64, error) {
+	var doc struct {
+		Rates map[string]json.RawMessage `json:"rates"`
+		Data  struct {
+			Rates map[string]json.RawMessage `json:"rates"`
+		} `json:"data"`
+	}
+	var flat map[string]json.RawMessage
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	json.Unmarshal(data, &doc)
+	raw := flat
+	switch {
+	case len(doc.Rates) > 0:
+		raw = doc.Rates
+	case len(doc.Data.Rates) > 0:
+		raw = doc.Data.Rates
+	}
+
+	rates := map[string]float64{}
+	for code, v := range raw {
+		var price float64
+		var str string
+		switch {
+		case json.Unmarshal(v, &price) == nil:
+		case json.Unmarshal(v, &str) == nil:
+			p, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				continue
+			}
+			price = p
+		default:
+			continue
+		}
+		if price > 0 {
+			rates[strings.ToUpper(code)] = price
+		}
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no exchange rates found")
+	}
+	return rates, nil
+}
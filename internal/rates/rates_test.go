@@ -0,0 +1,94 @@
+package rates
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRates(t *testing.T) {
+	tests := map[string]string{
+		"flat":     `{"USD": 65000.5, "eur": "60000"}`,
+		"rates":    `{"base": "BTC", "rates": {"USD": 65000.5, "EUR": 60000}}`,
+		"coinbase": `{"data": {"currency": "BTC", "rates": {"USD": "65000.5", "EUR": "60000"}}}`,
+	}
+	for name, doc := range tests {
+		rates, err := ParseRates([]byte(doc))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if rates["USD"] != 65000.5 || rates["EUR"] != 60000 {
+			t.Errorf("%s: rates = %v", name, rates)
+		}
+	}
+
+	for _, doc := range []string{`not json`, `{}`, `{"USD": "n/a"}`} {
+		if _, err := ParseRates([]byte(doc)); err == nil {
+			t.Errorf("ParseRates(%s) should fail", doc)
+		}
+	}
+}
+
+func TestSource_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	os.WriteFile(path, []byte(`{"USD": 65000}`), 0o600)
+
+	s := &Source{File: path, URL: "http://unused.invalid"}
+	if p, err := s.Price("usd"); err != nil || p != 65000 {
+		t.Errorf("Price = %v, %v", p, err)
+	}
+	if _, err := s.Price("JPY"); err == nil {
+		t.Error("missing currency should fail")
+	}
+}
+
+func TestSource_NoSource(t *testing.T) {
+	if _, err := (&Source{}).Price("USD"); !errors.Is(err, ErrNoSource) {
+		t.Errorf("err = %v, want ErrNoSource", err)
+	}
+}
+
+func TestSource_URLIsCached(t *testing.T) {
+	fetches := 0
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if fail {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"USD": 65000}`))
+	}))
+	defer srv.Close()
+	cache := filepath.Join(t.TempDir(), "rates-cache.json")
+
+	for i := 0; i < 2; i++ {
+		s := &Source{URL: srv.URL, CachePath: cache}
+		if p, err := s.Price("USD"); err != nil || p != 65000 {
+			t.Fatalf("Price = %v, %v", p, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("fetches = %d, want 1", fetches)
+	}
+
+	// An expired quote is fetched again, and still used if that fails.
+	fail = true
+	s := &Source{URL: srv.URL, CachePath: cache, TTL: time.Nanosecond}
+	if p, err := s.Price("USD"); err != nil || p != 65000 {
+		t.Errorf("stale Price = %v, %v", p, err)
+	}
+	if fetches != 2 {
+		t.Errorf("fetches = %d, want 2", fetches)
+	}
+
+	// Without a cache the failure is reported.
+	if _, err := (&Source{URL: srv.URL}).Price("USD"); err == nil {
+		t.Error("expected fetch error")
+	}
+}
@@ -0,0 +1,209 @@
+// Package store keeps a local mirror of a wallet's transactions, invoices
+// and payments, so that listing and reporting commands can run offline and
+// only fetch what is new when online.
+//
+// Each wallet is one JSON file named after its ID. Records are kept in
+// ascending number order; numbers are assigned by the API and never reused,
+// so a sync only has to fetch numbers above the highest one stored (and
+// refresh records that were still pending).
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+// ErrNotSynced is returned by Load when a wallet has no local mirror.
+var ErrNotSynced = errors.New("wallet has not been synced")
+
+// Store is the local mirror of one wallet.
+type Store struct {
+	WalletID   string     `json:"walletId"`
+	WalletName string     `json:"walletName,omitempty"`
+	APIURL     string     `json:"apiUrl,omitempty"`
+	SyncedAt   *time.Time `json:"syncedAt,omitempty"`
+
+	Transactions []lnbot.Transaction `json:"transactions"`
+	Invoices     []lnbot.Invoice     `json:"invoices"`
+	Payments     []lnbot.Payment     `json:"payments"`
+
+	path string
+}
+
+// Path is the mirror file for a wallet in dir.
+func Path(dir, walletID string) string {
+	return filepath.Join(dir, walletID+".json")
+}
+
+// Exists reports whether a wallet has a local mirror in dir.
+func Exists(dir, walletID string) bool {
+	_, err := os.Stat(Path(dir, walletID))
+	return err == nil
+}
+
+// New returns an empty mirror for a wallet. It is written by Save.
+func New(dir, walletID string) *Store {
+	return &Store{WalletID: walletID, path: Path(dir, walletID)}
+}
+
+// Load reads a wallet's mirror from dir.
+func Load(dir, walletID string) (*Store, error) {
+	if strings.ContainsAny(walletID, `/\`) || walletID == "" || walletID[0] == '.' {
+		return nil, fmt.Errorf("invalid wallet ID %q", walletID)
+	}
+	p := Path(dir, walletID)
+	data, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotSynced
+		}
+		return nil, err
+	}
+	s := &Store{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid local data %s: %w", p, err)
+	}
+	s.path = p
+	return s, nil
+}
+
+// FindByName returns the mirror of the wallet last synced under name.
+func FindByName(dir, name string) (*Store, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		s, err := Load(dir, id)
+		if err != nil {
+			continue
+		}
+		if s.WalletName == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no synced wallet named %q", name)
+}
+
+// Save writes the mirror atomically.
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".sync-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Query selects records the way the API's list endpoints do: newest first,
+// numbers below After (when set), at most Limit (when set). Search keeps
+// records whose text fields contain it, ignoring case.
+type Query struct {
+	Limit  int
+	After  int
+	Search string
+}
+
+// ListTransactions returns stored transactions matching q.
+func (s *Store) ListTransactions(q Query) []lnbot.Transaction {
+	return list(s.Transactions, q, func(tx lnbot.Transaction) (int, []string) {
+		return tx.Number, []string{tx.Type, deref(tx.Note), deref(tx.Reference), deref(tx.PaymentHash)}
+	})
+}
+
+// ListInvoices returns stored invoices matching q.
+func (s *Store) ListInvoices(q Query) []lnbot.Invoice {
+	return list(s.Invoices, q, func(inv lnbot.Invoice) (int, []string) {
+		return inv.Number, []string{inv.Status, deref(inv.Memo), deref(inv.Reference), inv.Bolt11}
+	})
+}
+
+// ListPayments returns stored payments matching q.
+func (s *Store) ListPayments(q Query) []lnbot.Payment {
+	return list(s.Payments, q, func(p lnbot.Payment) (int, []string) {
+		return p.Number, []string{p.Status, p.Address, deref(p.Reference), deref(p.FailureReason)}
+	})
+}
+
+func list[T any](records []T, q Query, fields func(T) (int, []string)) []T {
+	search := strings.ToLower(q.Search)
+	out := []T{}
+	for i := len(records) - 1; i >= 0; i-- {
+		n, text := fields(records[i])
+		if q.After > 0 && n >= q.After {
+			continue
+		}
+		if search != "" && !matches(text, search) {
+			continue
+		}
+		out = append(out, records[i])
+		if q.Limit > 0 && len(out) == q.Limit {
+			break
+		}
+	}
+	return out
+}
+
+func matches(fields []string, search string) bool {
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// merge adds or replaces records by number and keeps them sorted.
+func merge[T any](records []T, updates []T, number func(T) int) []T {
+	index := make(map[int]int, len(records))
+	for i, r := range records {
+		index[number(r)] = i
+	}
+	for _, u := range updates {
+		if i, ok := index[number(u)]; ok {
+			records[i] = u
+			continue
+		}
+		index[number(u)] = len(records)
+		records = append(records, u)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return number(records[i]) < number(records[j]) })
+	return records
+}
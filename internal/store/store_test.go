@@ -0,0 +1,147 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+func testStore(dir string) *Store {
+	s := New(dir, "wal_abc")
+	s.WalletName = "agent01"
+	for i := 1; i <= 5; i++ {
+		s.Transactions = append(s.Transactions, lnbot.Transaction{Number: i, Type: "credit", Amount: int64(i * 100)})
+	}
+	s.Transactions[2].Note = lnbot.Ptr("Coffee at Blue Bottle")
+	s.Invoices = []lnbot.Invoice{
+		{Number: 1, Status: "settled", Memo: lnbot.Ptr("rent")},
+		{Number: 2, Status: "pending", Reference: lnbot.Ptr("order-42")},
+	}
+	s.Payments = []lnbot.Payment{
+		{Number: 1, Status: "settled", Address: "carol@example.com"},
+		{Number: 2, Status: "failed", Address: "dave@example.com", FailureReason: lnbot.Ptr("no route found")},
+	}
+	return s
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := testStore(dir).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(Path(dir, "wal_abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("permissions = %o, want 600", perm)
+	}
+
+	s, err := Load(dir, "wal_abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Transactions) != 5 || len(s.Invoices) != 2 || s.WalletName != "agent01" {
+		t.Errorf("loaded %+v", s)
+	}
+	if !Exists(dir, "wal_abc") || Exists(dir, "wal_other") {
+		t.Error("Exists is wrong")
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir, "wal_none"); !errors.Is(err, ErrNotSynced) {
+		t.Errorf("missing store: err = %v, want ErrNotSynced", err)
+	}
+	if _, err := Load(dir, "../config"); err == nil {
+		t.Error("path traversal should be rejected")
+	}
+	os.WriteFile(Path(dir, "wal_bad"), []byte("{"), 0o600)
+	if _, err := Load(dir, "wal_bad"); err == nil {
+		t.Error("corrupt store should fail to load")
+	}
+}
+
+func TestFindByName(t *testing.T) {
+	dir := t.TempDir()
+	testStore(dir).Save()
+
+	s, err := FindByName(dir, "agent01")
+	if err != nil || s.WalletID != "wal_abc" {
+		t.Errorf("FindByName = %v, %v", s, err)
+	}
+	if _, err := FindByName(dir, "nope"); err == nil {
+		t.Error("unknown name should fail")
+	}
+	if _, err := FindByName(t.TempDir()+"/missing", "agent01"); err == nil {
+		t.Error("missing dir should fail")
+	}
+}
+
+func TestListTransactions(t *testing.T) {
+	s := testStore(t.TempDir())
+
+	tests := []struct {
+		name string
+		q    Query
+		want []int
+	}{
+		{"all newest first", Query{}, []int{5, 4, 3, 2, 1}},
+		{"limit", Query{Limit: 2}, []int{5, 4}},
+		{"after", Query{After: 4, Limit: 2}, []int{3, 2}},
+		{"search", Query{Search: "blue bottle"}, []int{3}},
+		{"search by type", Query{Search: "CREDIT", Limit: 1}, []int{5}},
+		{"no match", Query{Search: "tea"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, tx := range s.ListTransactions(tt.q) {
+				got = append(got, tx.Number)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListInvoicesAndPayments(t *testing.T) {
+	s := testStore(t.TempDir())
+
+	if invs := s.ListInvoices(Query{Search: "order-42"}); len(invs) != 1 || invs[0].Number != 2 {
+		t.Errorf("invoices = %+v", invs)
+	}
+	if pays := s.ListPayments(Query{Search: "no route"}); len(pays) != 1 || pays[0].Number != 2 {
+		t.Errorf("payments = %+v", pays)
+	}
+	if pays := s.ListPayments(Query{Search: "example.com"}); len(pays) != 2 {
+		t.Errorf("payments = %+v", pays)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	records := []lnbot.Invoice{{Number: 1, Status: "settled"}, {Number: 2, Status: "pending"}}
+	records = merge(records, []lnbot.Invoice{{Number: 4}, {Number: 2, Status: "expired"}, {Number: 3}}, invoiceNumber)
+
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want 4", len(records))
+	}
+	for i, r := range records {
+		if r.Number != i+1 {
+			t.Errorf("records not sorted: %+v", records)
+		}
+	}
+	if records[1].Status != "expired" {
+		t.Errorf("existing record should be replaced, got %+v", records[1])
+	}
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+// pageSize is the page size used when walking the API's lists.
+const pageSize = 100
+
+// Result counts what a sync changed.
+type Result struct {
+	Transactions int `json:"transactions"`
+	Invoices     int `json:"invoices"`
+	Payments     int `json:"payments"`
+	// Updated counts stored invoices and payments whose status changed.
+	Updated int `json:"updated"`
+}
+
+// Sync fetches records newer than the ones stored, and refreshes stored
+// invoices and payments that had not reached a final state. The store is
+// not saved.
+func (s *Store) Sync(ctx context.Context, w *lnbot.WalletHandle) (Result, error) {
+	return s.sync(ctx, w, true)
+}
+
+// Refresh is a cheaper Sync for bringing a mirror up to date before reading
+// it. Stored records are only updated if they appear on the pages fetched
+// for new ones, so when nothing is new it makes one request per list. The
+// store is not saved.
+func (s *Store) Refresh(ctx context.Context, w *lnbot.WalletHandle) (Result, error) {
+	return s.sync(ctx, w, false)
+}
+
+// sync fetches new records and updates stored ones seen along the way. With
+// refresh, invoices and payments that are still pending are then fetched
+// one by one.
+func (s *Store) sync(ctx context.Context, w *lnbot.WalletHandle, refresh bool) (Result, error) {
+	var res Result
+
+	wallet, err := w.Get(ctx)
+	if err != nil {
+		return res, err
+	}
+	s.WalletName = wallet.Name
+
+	txs, _, err := fetchNew(maxNumber(s.Transactions, txNumber), func(after *int) ([]lnbot.Transaction, error) {
+		return w.Transactions.List(ctx, &lnbot.ListTransactionsParams{Limit: lnbot.Ptr(pageSize), After: after})
+	}, txNumber)
+	if err != nil {
+		return res, err
+	}
+	s.Transactions = merge(s.Transactions, txs, txNumber)
+	res.Transactions = len(txs)
+
+	invs, seenInvs, err := fetchNew(maxNumber(s.Invoices, invoiceNumber), func(after *int) ([]lnbot.Invoice, error) {
+		return w.Invoices.List(ctx, &lnbot.ListInvoicesParams{Limit: lnbot.Ptr(pageSize), After: after})
+	}, invoiceNumber)
+	if err != nil {
+		return res, err
+	}
+	stale := changed(s.Invoices, seenInvs, invoiceNumber, func(inv lnbot.Invoice) string { return inv.Status })
+	if refresh {
+		seen := numbers(seenInvs, invoiceNumber)
+		for _, inv := range s.Invoices {
+			if inv.Status == "pending" && !seen[inv.Number] {
+				fresh, err := w.Invoices.Get(ctx, inv.Number)
+				if err != nil {
+					return res, err
+				}
+				if fresh.Status != inv.Status {
+					stale = append(stale, *fresh)
+				}
+			}
+		}
+	}
+	s.Invoices = merge(s.Invoices, append(invs, stale...), invoiceNumber)
+	res.Invoices = len(invs)
+	res.Updated += len(stale)
+
+	pays, seenPays, err := fetchNew(maxNumber(s.Payments, paymentNumber), func(after *int) ([]lnbot.Payment, error) {
+		return w.Payments.List(ctx, &lnbot.ListPaymentsParams{Limit: lnbot.Ptr(pageSize), After: after})
+	}, paymentNumber)
+	if err != nil {
+		return res, err
+	}
+	updated := changed(s.Payments, seenPays, paymentNumber, func(p lnbot.Payment) string { return p.Status })
+	if refresh {
+		seen := numbers(seenPays, paymentNumber)
+		for _, p := range s.Payments {
+			if p.Status != "settled" && p.Status != "failed" && !seen[p.Number] {
+				fresh, err := w.Payments.Get(ctx, p.Number)
+				if err != nil {
+					return res, err
+				}
+				if fresh.Status != p.Status {
+					updated = append(updated, *fresh)
+				}
+			}
+		}
+	}
+	s.Payments = merge(s.Payments, append(pays, updated...), paymentNumber)
+	res.Payments = len(pays)
+	res.Updated += len(updated)
+
+	now := time.Now().UTC()
+	s.SyncedAt = &now
+	return res, nil
+}
+
+// fetchNew walks a newest-first list until it reaches a number at or below
+// known, and returns the records above it. It also returns the records at
+// or below known on the last page it fetched.
+func fetchNew[T any](known int, page func(after *int) ([]T, error), number func(T) int) (out, seen []T, err error) {
+	var after *int
+	for {
+		records, err := page(after)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(records) == 0 {
+			return out, nil, nil
+		}
+		for i, r := range records {
+			if number(r) <= known {
+				return out, records[i:], nil
+			}
+			out = append(out, r)
+		}
+		last := number(records[len(records)-1])
+		if last <= 1 {
+			return out, nil, nil
+		}
+		after = lnbot.Ptr(last)
+	}
+}
+
+// changed returns the records in seen whose status differs from the stored
+// record with the same number.
+func changed[T any](stored, seen []T, number func(T) int, status func(T) string) []T {
+	old := make(map[int]string, len(stored))
+	for _, r := range stored {
+		old[number(r)] = status(r)
+	}
+	var out []T
+	for _, r := range seen {
+		if st, ok := old[number(r)]; ok && st != status(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// numbers is the set of the records' numbers.
+func numbers[T any](records []T, number func(T) int) map[int]bool {
+	set := make(map[int]bool, len(records))
+	for _, r := range records {
+		set[number(r)] = true
+	}
+	return set
+}
+
+func maxNumber[T any](records []T, number func(T) int) int {
+	if len(records) == 0 {
+		return 0
+	}
+	return number(records[len(records)-1])
+}
+
+func txNumber(tx lnbot.Transaction) int   { return tx.Number }
+func invoiceNumber(inv lnbot.Invoice) int { return inv.Number }
+func paymentNumber(p lnbot.Payment) int   { return p.Number }
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+
+	"github.com/lnbotdev/cli/internal/devserver"
+)
+
+func devWallet(t *testing.T) (*devserver.Server, *lnbot.WalletHandle) {
+	t.Helper()
+	dev := devserver.New(devserver.Options{StartingBalance: 1_000_000})
+	srv := httptest.NewServer(dev)
+	t.Cleanup(func() {
+		dev.Close()
+		srv.Close()
+	})
+	ctx := context.Background()
+	account, err := lnbot.New("", lnbot.WithBaseURL(srv.URL)).Register(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := lnbot.New(account.PrimaryKey, lnbot.WithBaseURL(srv.URL))
+	wallet, err := client.Wallets.Create(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dev, client.Wallet(wallet.WalletID)
+}
+
+func pay(t *testing.T, w *lnbot.WalletHandle, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := w.Payments.Create(context.Background(), &lnbot.CreatePaymentParams{
+			Target: "carol@example.com", Amount: lnbot.Ptr(int64(10)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	dev, w := devWallet(t)
+	ctx := context.Background()
+	pay(t, w, pageSize+10)
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 500})
+
+	s := New(t.TempDir(), "ignored")
+	res, err := s.Sync(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Transactions != pageSize+11 || res.Payments != pageSize+10 || res.Invoices != 1 || res.Updated != 0 {
+		t.Errorf("first sync = %+v", res)
+	}
+	if s.WalletName == "" || s.SyncedAt == nil {
+		t.Errorf("wallet name and sync time should be recorded: %+v", s)
+	}
+	for i, tx := range s.Transactions {
+		if tx.Number != i+1 {
+			t.Fatalf("transactions out of order at %d: %d", i, tx.Number)
+		}
+	}
+
+	// Only new records are fetched, and pending ones are refreshed.
+	pay(t, w, 2)
+	dev.SettleInvoice(inv.Bolt11)
+	res, err = s.Sync(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Transactions != 3 || res.Payments != 2 || res.Invoices != 0 || res.Updated != 1 {
+		t.Errorf("second sync = %+v", res)
+	}
+	if s.Invoices[0].Status != "settled" {
+		t.Errorf("invoice status = %s, want settled", s.Invoices[0].Status)
+	}
+	if len(s.Transactions) != pageSize+14 {
+		t.Errorf("%d transactions stored, want %d", len(s.Transactions), pageSize+14)
+	}
+
+	res, _ = s.Sync(ctx, w)
+	if res != (Result{}) {
+		t.Errorf("sync with nothing new = %+v", res)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	dev := devserver.New(devserver.Options{})
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		dev.ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		dev.Close()
+		srv.Close()
+	})
+	ctx := context.Background()
+	account, _ := lnbot.New("", lnbot.WithBaseURL(srv.URL)).Register(ctx)
+	client := lnbot.New(account.PrimaryKey, lnbot.WithBaseURL(srv.URL))
+	wallet, _ := client.Wallets.Create(ctx)
+	w := client.Wallet(wallet.WalletID)
+
+	inv, _ := w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 500})
+	w.Invoices.Create(ctx, &lnbot.CreateInvoiceParams{Amount: 600})
+	s := New(t.TempDir(), wallet.WalletID)
+	if _, err := s.Sync(ctx, w); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pending records on the first page are updated without fetching each.
+	dev.SettleInvoice(inv.Bolt11)
+	requests = 0
+	res, err := s.Refresh(ctx, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (Result{Transactions: 1, Updated: 1}) || s.Invoices[0].Status != "settled" {
+		t.Errorf("refresh = %+v, invoice %s", res, s.Invoices[0].Status)
+	}
+	if requests != 4 {
+		t.Errorf("refresh made %d requests, want 4 (wallet and three lists)", requests)
+	}
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+// Event names.
+const (
+	EventInvoiceCreated = "invoice.created"
+	EventInvoiceSettled = "invoice.settled"
+	EventInvoiceExpired = "invoice.expired"
+	EventPaymentCreated = "payment.created"
+	EventPaymentSettled = "payment.settled"
+	EventPaymentFailed  = "payment.failed"
+)
+
+// Event is a delivery's body. Data holds an invoice for "invoice.*" events
+// and a payment for "payment.*" events.
+type Event struct {
+	Type      string          `json:"event"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ParseEvent decodes a delivery's body.
+func ParseEvent(body []byte) (*Event, error) {
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, fmt.Errorf("webhook: invalid event: %w", err)
+	}
+	if ev.Type == "" {
+		return nil, fmt.Errorf("webhook: invalid event: missing event name")
+	}
+	return &ev, nil
+}
+
+// Invoice decodes the data of an "invoice.*" event.
+func (e *Event) Invoice() (*lnbot.Invoice, error) {
+	if !strings.HasPrefix(e.Type, "invoice.") {
+		return nil, fmt.Errorf("webhook: %s is not an invoice event", e.Type)
+	}
+	var inv lnbot.Invoice
+	if err := json.Unmarshal(e.Data, &inv); err != nil {
+		return nil, fmt.Errorf("webhook: invalid invoice data: %w", err)
+	}
+	return &inv, nil
+}
+
+// Payment decodes the data of a "payment.*" event.
+func (e *Event) Payment() (*lnbot.Payment, error) {
+	if !strings.HasPrefix(e.Type, "payment.") {
+		return nil, fmt.Errorf("webhook: %s is not a payment event", e.Type)
+	}
+	var p lnbot.Payment
+	if err := json.Unmarshal(e.Data, &p); err != nil {
+		return nil, fmt.Errorf("webhook: invalid payment data: %w", err)
+	}
+	return &p, nil
+}
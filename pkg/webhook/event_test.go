@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEvent(t *testing.T) {
+	ev, err := ParseEvent([]byte(`{"event":"invoice.settled","createdAt":"2024-03-01T12:00:00Z","data":{"number":7,"status":"settled","amount":1000,"memo":"coffee"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Type != EventInvoiceSettled || !ev.CreatedAt.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("event = %+v", ev)
+	}
+	inv, err := ev.Invoice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Number != 7 || inv.Amount != 1000 || inv.Status != "settled" || inv.Memo == nil || *inv.Memo != "coffee" {
+		t.Errorf("invoice = %+v", inv)
+	}
+	if _, err := ev.Payment(); err == nil {
+		t.Error("Payment on an invoice event should fail")
+	}
+
+	for _, body := range []string{"", "[]", `{"data":{}}`} {
+		if _, err := ParseEvent([]byte(body)); err == nil {
+			t.Errorf("ParseEvent(%q) should fail", body)
+		}
+	}
+}
+
+func TestEvent_Payment(t *testing.T) {
+	ev, err := ParseEvent([]byte(`{"event":"payment.failed","createdAt":"2024-03-01T12:00:00Z","data":{"number":3,"status":"failed","amount":50,"address":"fail@example.com","failureReason":"no route found"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ev.Payment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Number != 3 || p.Address != "fail@example.com" || p.FailureReason == nil || *p.FailureReason != "no route found" {
+		t.Errorf("payment = %+v", p)
+	}
+	if _, err := ev.Invoice(); err == nil {
+		t.Error("Invoice on a payment event should fail")
+	}
+}
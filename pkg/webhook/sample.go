@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	lnbot "github.com/lnbotdev/go-sdk"
+)
+
+//go:embed samples/*.json
+var samples embed.FS
+
+// SampleEvents lists the event names Sample has fixtures for.
+func SampleEvents() []string {
+	entries, _ := samples.ReadDir("samples")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sample returns a realistic example of an event, as it would be delivered
+// at now. The fixture's timestamps are moved so that the event happens at
+// now, keeping the intervals between them.
+func Sample(event string, now time.Time) (*Event, error) {
+	data, err := samples.ReadFile("samples/" + event + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("webhook: no sample for %q (have %s)", event, strings.Join(SampleEvents(), ", "))
+	}
+	now = now.UTC().Truncate(time.Second)
+
+	// Each event is anchored on the moment it describes: creation,
+	// settlement or expiry.
+	var v any
+	if strings.HasPrefix(event, "invoice.") {
+		var inv lnbot.Invoice
+		if err := json.Unmarshal(data, &inv); err != nil {
+			return nil, err
+		}
+		anchor := inv.CreatedAt
+		switch event {
+		case EventInvoiceSettled:
+			anchor = inv.SettledAt
+		case EventInvoiceExpired:
+			anchor = inv.ExpiresAt
+		}
+		shiftTimes(now.Sub(*anchor), inv.CreatedAt, inv.SettledAt, inv.ExpiresAt)
+		v = inv
+	} else {
+		var p lnbot.Payment
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, err
+		}
+		anchor := p.CreatedAt
+		if p.SettledAt != nil {
+			anchor = p.SettledAt
+		}
+		shiftTimes(now.Sub(*anchor), p.CreatedAt, p.SettledAt)
+		v = p
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{Type: event, CreatedAt: now, Data: raw}, nil
+}
+
+func shiftTimes(d time.Duration, times ...*time.Time) {
+	for _, t := range times {
+		if t != nil {
+			*t = t.Add(d)
+		}
+	}
+}
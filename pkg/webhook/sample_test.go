@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampleEvents(t *testing.T) {
+	got := strings.Join(SampleEvents(), " ")
+	want := "invoice.created invoice.expired invoice.settled payment.created payment.failed payment.settled"
+	if got != want {
+		t.Errorf("SampleEvents = %q, want %q", got, want)
+	}
+}
+
+func TestSample(t *testing.T) {
+	now := time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC)
+	for _, name := range SampleEvents() {
+		ev, err := Sample(name, now)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if ev.Type != name || !ev.CreatedAt.Equal(now) {
+			t.Errorf("%s: event = %+v", name, ev)
+		}
+		if strings.HasPrefix(name, "invoice.") {
+			if _, err := ev.Invoice(); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		} else if _, err := ev.Payment(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	ev, _ := Sample(EventInvoiceSettled, now)
+	inv, _ := ev.Invoice()
+	if inv.Status != "settled" || !inv.SettledAt.Equal(now) || !inv.CreatedAt.Equal(now.Add(-7*time.Second)) {
+		t.Errorf("invoice.settled = %+v", inv)
+	}
+	ev, _ = Sample(EventInvoiceExpired, now)
+	inv, _ = ev.Invoice()
+	if !inv.ExpiresAt.Equal(now) || !inv.CreatedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("invoice.expired = %+v", inv)
+	}
+	ev, _ = Sample(EventPaymentFailed, now)
+	p, _ := ev.Payment()
+	if p.Status != "failed" || p.FailureReason == nil || !p.CreatedAt.Equal(now) {
+		t.Errorf("payment.failed = %+v", p)
+	}
+
+	if _, err := Sample("invoice.paid", now); err == nil || !strings.Contains(err.Error(), "invoice.settled") {
+		t.Errorf("unknown event: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how far a delivery's timestamp may be from the
+// current time before Verify rejects it as a possible replay.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature means the signature does not match the body,
+	// timestamp and secret, or is missing or malformed.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrTimestampOutOfTolerance means the signature is valid but the
+	// delivery was signed too long ago (or too far in the future).
+	ErrTimestampOutOfTolerance = errors.New("webhook: timestamp outside tolerance")
+)
+
+// VerifySignature checks that signature was made for body and timestamp
+// with secret. The header may carry several space- or comma-separated
+// signatures (e.g. while a secret is rotated); one match is enough.
+func VerifySignature(secret string, timestamp int64, signature string, body []byte) error {
+	want := Sign(secret, timestamp, body)
+	for _, sig := range strings.FieldsFunc(signature, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Verify checks the signature and that timestamp is within tolerance of
+// now. A tolerance of zero or less disables the timestamp check.
+func Verify(secret string, timestamp int64, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	if err := VerifySignature(secret, timestamp, signature, body); err != nil {
+		return err
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: signed %s ago, tolerance %s", ErrTimestampOutOfTolerance, age.Round(time.Second), tolerance)
+		}
+	}
+	return nil
+}
+
+// ParseTimestamp parses a timestamp header value (Unix seconds).
+func ParseTimestamp(s string) (int64, error) {
+	ts, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("webhook: invalid timestamp %q", s)
+	}
+	return ts, nil
+}
+
+// VerifyRequest verifies an incoming delivery against secret using
+// DefaultTolerance and returns its body. r.Body is replaced so that it can
+// be read again.
+func VerifyRequest(r *http.Request, secret string) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	ts, err := ParseTimestamp(r.Header.Get(HeaderTimestamp))
+	if err != nil {
+		return nil, err
+	}
+	if err := Verify(secret, ts, r.Header.Get(HeaderSignature), body, DefaultTolerance, time.Now()); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"invoice.settled"}`)
+	sig := Sign("whsec_test", now.Unix(), body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		ts        int64
+		sig       string
+		body      string
+		tolerance time.Duration
+		want      error
+	}{
+		{"valid", "whsec_test", now.Unix(), sig, string(body), DefaultTolerance, nil},
+		{"rotated", "whsec_test", now.Unix(), "v1=00ff, " + sig, string(body), DefaultTolerance, nil},
+		{"wrong secret", "whsec_other", now.Unix(), sig, string(body), DefaultTolerance, ErrInvalidSignature},
+		{"tampered body", "whsec_test", now.Unix(), sig, `{"event":"invoice.expired"}`, DefaultTolerance, ErrInvalidSignature},
+		{"tampered timestamp", "whsec_test", now.Unix() + 1, sig, string(body), DefaultTolerance, ErrInvalidSignature},
+		{"missing", "whsec_test", now.Unix(), "", string(body), DefaultTolerance, ErrInvalidSignature},
+		{"unversioned", "whsec_test", now.Unix(), sig[3:], string(body), DefaultTolerance, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.ts, tt.sig, []byte(tt.body), tt.tolerance, now)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify_Tolerance(t *testing.T) {
+	body := []byte("{}")
+	ts := int64(1700000000)
+	sig := Sign("whsec_test", ts, body)
+	signed := time.Unix(ts, 0)
+
+	if err := Verify("whsec_test", ts, sig, body, time.Minute, signed.Add(59*time.Second)); err != nil {
+		t.Errorf("within tolerance: %v", err)
+	}
+	for _, now := range []time.Time{signed.Add(61 * time.Second), signed.Add(-61 * time.Second)} {
+		if err := Verify("whsec_test", ts, sig, body, time.Minute, now); !errors.Is(err, ErrTimestampOutOfTolerance) {
+			t.Errorf("now %s: err = %v", now.Sub(signed), err)
+		}
+	}
+	if err := Verify("whsec_test", ts, sig, body, 0, signed.Add(24*time.Hour)); err != nil {
+		t.Errorf("tolerance disabled: %v", err)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	if ts, err := ParseTimestamp(" 1700000000\n"); err != nil || ts != 1700000000 {
+		t.Errorf("ParseTimestamp = %d, %v", ts, err)
+	}
+	for _, s := range []string{"", "abc", "1.5"} {
+		if _, err := ParseTimestamp(s); err == nil {
+			t.Errorf("ParseTimestamp(%q) should fail", s)
+		}
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := []byte(`{"event":"payment.settled","data":{}}`)
+	req, err := NewRequest(context.Background(), "http://localhost/hook", "whsec_test", "payment.settled", body, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := VerifyRequest(req, "whsec_test")
+	if err != nil || string(got) != string(body) {
+		t.Fatalf("VerifyRequest = %q, %v", got, err)
+	}
+	again, _ := io.ReadAll(req.Body)
+	if string(again) != string(body) {
+		t.Errorf("body not restored: %q", again)
+	}
+
+	req, _ = NewRequest(context.Background(), "http://localhost/hook", "whsec_test", "payment.settled", body, time.Now())
+	if _, err := VerifyRequest(req, "whsec_wrong"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong secret: %v", err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	req, _ = NewRequest(context.Background(), "http://localhost/hook", "whsec_test", "payment.settled", body, old)
+	if _, err := VerifyRequest(req, "whsec_test"); !errors.Is(err, ErrTimestampOutOfTolerance) {
+		t.Errorf("stale: %v", err)
+	}
+
+	req, _ = NewRequest(context.Background(), "http://localhost/hook", "whsec_test", "payment.settled", body, time.Now())
+	req.Header.Del(HeaderTimestamp)
+	if _, err := VerifyRequest(req, "whsec_test"); err == nil {
+		t.Error("missing timestamp should fail")
+	}
+}
@@ -0,0 +1,69 @@
+// Package webhook signs and verifies ln.bot webhook deliveries.
+//
+// A delivery is an HTTP POST whose body is the event as JSON. The request
+// carries three headers: the event name, the Unix time it was signed, and
+// a signature of the form "v1=<hex>", where <hex> is the HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook's secret.
+//
+// Receivers call VerifyRequest (or Verify) and then ParseEvent:
+//
+//	body, err := webhook.VerifyRequest(r, secret)
+//	if err != nil {
+//		http.Error(w, "bad signature", http.StatusBadRequest)
+//		return
+//	}
+//	ev, err := webhook.ParseEvent(body)
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Delivery headers.
+const (
+	HeaderEvent     = "X-LnBot-Event"
+	HeaderTimestamp = "X-LnBot-Timestamp"
+	HeaderSignature = "X-LnBot-Signature"
+)
+
+// signaturePrefix versions the signature scheme.
+const signaturePrefix = "v1="
+
+// Sign returns the signature header value for body signed at timestamp
+// (Unix seconds).
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// NewRequest builds a signed delivery of body to url.
+func NewRequest(ctx context.Context, url, secret, event string, body []byte, now time.Time) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, ts, body))
+	return req, nil
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// Known-answer: HMAC-SHA256("whsec_test", "1700000000.{}").
+	got := Sign("whsec_test", 1700000000, []byte("{}"))
+	if want := "v1=35495024f4ef3f94e5a93e22221544c4b75e9a42300cd965ab81cb85cd994e91"; got != want {
+		t.Fatalf("Sign = %q, want %q", got, want)
+	}
+	if got == Sign("whsec_test", 1700000001, []byte("{}")) {
+		t.Error("timestamp should be signed")
+	}
+	if got == Sign("whsec_other", 1700000000, []byte("{}")) {
+		t.Error("secret should change the signature")
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, b := NewSecret(), NewSecret()
+	if !strings.HasPrefix(a, "whsec_") || len(a) != 6+48 || a == b {
+		t.Errorf("NewSecret = %q, %q", a, b)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	body := []byte(`{"event":"invoice.settled"}`)
+	now := time.Unix(1700000000, 0)
+	req, err := NewRequest(context.Background(), "http://localhost:3000/hook", "whsec_test", "invoice.settled", body, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "POST" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("request = %s %v", req.Method, req.Header)
+	}
+	if req.Header.Get(HeaderEvent) != "invoice.settled" || req.Header.Get(HeaderTimestamp) != "1700000000" {
+		t.Errorf("headers = %v", req.Header)
+	}
+	if req.Header.Get(HeaderSignature) != Sign("whsec_test", 1700000000, body) {
+		t.Errorf("signature = %q", req.Header.Get(HeaderSignature))
+	}
+	got, _ := io.ReadAll(req.Body)
+	if string(got) != string(body) {
+		t.Errorf("body = %s", got)
+	}
+}